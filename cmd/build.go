@@ -10,6 +10,7 @@ import (
 	"jabline/pkg/compiler"
 	"jabline/pkg/lexer"
 	"jabline/pkg/parser"
+	"jabline/pkg/vm"
 
 	"github.com/spf13/cobra"
 )
@@ -21,6 +22,12 @@ var MagicMarker = []byte{0xDE, 0xAD, 0xBE, 0xEF, 0xCA, 0xFE, 0xBA, 0xBE, 0x00, 0
 var buildCmd = &cobra.Command{
 	Use:   "build [file]",
 	Short: "Compile a Jabline program into a standalone executable",
+	Long: `Compile a Jabline program into a standalone executable. When the
+output name ends in .jbc only the serialized bytecode is written; it can be
+executed with "jabline run".
+
+Modules imported by the program are compiled and bundled into the output,
+so it does not depend on the files or working directory at runtime.`,
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		filename := args[0]
@@ -49,8 +56,18 @@ var buildCmd = &cobra.Command{
 			fmt.Printf("Compiler error: %s\n", err)
 			os.Exit(1)
 		}
+		for _, warning := range comp.Warnings() {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
 
-		bytecodeData, err := compiler.Serialize(comp.Bytecode())
+		bytecode := comp.Bytecode()
+		bytecode.Modules, err = vm.NewModuleLoader().Bundle(program)
+		if err != nil {
+			fmt.Printf("Import error: %s\n", err)
+			os.Exit(1)
+		}
+
+		bytecodeData, err := compiler.Serialize(bytecode)
 		if err != nil {
 			fmt.Printf("Serialization error: %s\n", err)
 			os.Exit(1)
@@ -63,6 +80,16 @@ var buildCmd = &cobra.Command{
 			outputName = filename[0 : len(filename)-len(ext)]
 		}
 
+		// A .jbc output is the bytecode alone, runnable with `jabline run`.
+		if filepath.Ext(outputName) == ".jbc" {
+			if err := ioutil.WriteFile(outputName, bytecodeData, 0644); err != nil {
+				fmt.Printf("Failed to write bytecode: %s\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Successfully built bytecode: %s\n", outputName)
+			return
+		}
+
 		selfPath, err := os.Executable()
 		if err != nil {
 			fmt.Printf("Failed to locate self executable: %s\n", err)
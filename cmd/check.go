@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"jabline/pkg/checker"
+	"jabline/pkg/lexer"
+	"jabline/pkg/parser"
+
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check [path...]",
+	Short: "Type-check Jabline source files",
+	Long: `Type-check the given .jb files, or every .jb file under the given
+directories, without running them. Every error is reported with its
+position as file:line:column, and the command exits with status 1 if
+there are any.
+
+Function signatures, struct fields, generic type parameters, nullable
+types (int?) and unions (int | string) are checked against their uses.
+Unannotated values are not held to a type.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+
+		files, err := sourceFiles(args)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		failed := false
+		for _, path := range files {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				fmt.Printf("Error reading file: %s\n", err)
+				failed = true
+				continue
+			}
+
+			p := parser.New(lexer.New(string(src)))
+			program := p.ParseProgram()
+			if len(p.Errors()) > 0 {
+				for _, msg := range p.Errors() {
+					fmt.Printf("%s: %s\n", path, msg)
+				}
+				failed = true
+				continue
+			}
+
+			for _, e := range checker.Check(program) {
+				fmt.Printf("%s:%s\n", path, e)
+				failed = true
+			}
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+}
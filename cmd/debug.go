@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"jabline/pkg/dap"
+
+	"github.com/spf13/cobra"
+)
+
+var debugListen string
+
+var debugCmd = &cobra.Command{
+	Use:   "debug [file]",
+	Short: "Start a Debug Adapter Protocol server",
+	Long: `Starts a Debug Adapter Protocol (DAP) server, over Stdio by default or on a
+TCP address with --listen. Editors like VS Code and Neovim use it to set
+breakpoints, step through a Jabline program, inspect its variables and
+evaluate expressions while it is paused. The file, when given, is debugged
+if the client's launch request does not name a program.
+
+Only the program's own VM is traced. The top-level code of imported modules
+and the tasks started with spawn or async run without stopping, though a
+function imported from a module can be stepped into when the program calls
+it.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := dap.Options{}
+		if len(args) > 0 {
+			opts.Program = args[0]
+		}
+
+		if debugListen != "" {
+			if err := serveDebugTCP(debugListen, opts); err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		// Stdout carries the protocol, so anything the program prints
+		// directly is captured and sent to the client as output instead.
+		out := os.Stdout
+		r, w, err := os.Pipe()
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+		os.Stdout = w
+		opts.Output = r
+
+		if err := dap.Serve(os.Stdin, out, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// serveDebugTCP waits for a single client on addr and debugs over its
+// connection.
+func serveDebugTCP(addr string, opts dap.Options) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	fmt.Fprintf(os.Stderr, "Listening for a debug client on %s\n", ln.Addr())
+
+	conn, err := ln.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return dap.Serve(conn, conn, opts)
+}
+
+func init() {
+	rootCmd.AddCommand(debugCmd)
+	debugCmd.Flags().StringVar(&debugListen, "listen", "", "Serve on a TCP address such as 127.0.0.1:4711 instead of Stdio")
+}
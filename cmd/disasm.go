@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"jabline/pkg/compiler"
+	"jabline/pkg/disasm"
+	"jabline/pkg/lexer"
+	"jabline/pkg/parser"
+
+	"github.com/spf13/cobra"
+)
+
+var disasmJSON bool
+
+var disasmCmd = &cobra.Command{
+	Use:   "disasm [file]",
+	Short: "Print the bytecode of a Jabline program",
+	Long: `Disassemble a .jb source file, a .jbc bytecode file or a binary produced
+by "jabline build". Every function is listed with decoded operands, constant
+values, labelled jump targets and source positions. Use --json for output
+meant for tools.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		filename := args[0]
+
+		bytecode, source, err := loadBytecode(filename)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		program := disasm.Disassemble(bytecode)
+		if disasmJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(program); err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		disasm.WriteText(os.Stdout, program, source)
+	},
+}
+
+// loadBytecode compiles a source file, or decodes a .jbc file or the payload
+// of a built executable. The source text is only returned for source files.
+func loadBytecode(filename string) (*compiler.Bytecode, string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if compiler.IsSerialized(data) {
+		bytecode, err := compiler.Deserialize(data)
+		return bytecode, "", err
+	}
+
+	embedded, err := EmbeddedBytecode(filename)
+	if err != nil {
+		return nil, "", err
+	}
+	if embedded != nil {
+		bytecode, err := compiler.Deserialize(embedded)
+		return bytecode, "", err
+	}
+
+	l := lexer.New(string(data))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, "", fmt.Errorf("parser errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return nil, "", fmt.Errorf("compiler error: %s", err)
+	}
+	return comp.Bytecode(), string(data), nil
+}
+
+func init() {
+	disasmCmd.Flags().BoolVar(&disasmJSON, "json", false, "Print the disassembly as JSON")
+	rootCmd.AddCommand(disasmCmd)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+)
+
+// EmbeddedBytecode returns the bytecode that `jabline build` appended to an
+// executable: the payload, its length as a little endian uint64 and then
+// MagicMarker. It returns nil when the file carries no payload.
+func EmbeddedBytecode(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	markerLen := int64(len(MagicMarker))
+	fileSize := info.Size()
+	if fileSize < markerLen+8 {
+		return nil, nil
+	}
+
+	trailer := make([]byte, markerLen+8)
+	if _, err := f.ReadAt(trailer, fileSize-markerLen-8); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(trailer[8:], MagicMarker) {
+		return nil, nil
+	}
+
+	bytecodeSize := int64(binary.LittleEndian.Uint64(trailer[:8]))
+	bytecodeStart := fileSize - markerLen - 8 - bytecodeSize
+	if bytecodeSize < 0 || bytecodeStart < 0 {
+		return nil, nil
+	}
+
+	data := make([]byte, bytecodeSize)
+	if _, err := f.ReadAt(data, bytecodeStart); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
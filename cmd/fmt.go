@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"jabline/pkg/format"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	fmtCheck bool
+	fmtWrite bool
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [path...]",
+	Short: "Format Jabline source files",
+	Long: `Format the given .jb files, or every .jb file under the given
+directories, in the canonical Jabline style. Comments and blank-line
+grouping are preserved.
+
+By default the formatted source is printed to stdout. Use --write to
+rewrite the files in place, or --check to list the files that are not
+formatted and exit with status 1 if there are any.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if fmtCheck && fmtWrite {
+			fmt.Println("Error: --check and --write cannot be used together")
+			os.Exit(1)
+		}
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+
+		files, err := sourceFiles(args)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		failed := false
+		for _, path := range files {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				fmt.Printf("Error reading file: %s\n", err)
+				failed = true
+				continue
+			}
+
+			formatted, err := format.Source(string(src))
+			if err != nil {
+				fmt.Printf("%s: %s\n", path, err)
+				failed = true
+				continue
+			}
+
+			switch {
+			case fmtCheck:
+				if formatted != string(src) {
+					fmt.Println(path)
+					failed = true
+				}
+			case fmtWrite:
+				if formatted != string(src) {
+					if err := os.WriteFile(path, []byte(formatted), 0644); err != nil {
+						fmt.Printf("Error writing file: %s\n", err)
+						failed = true
+					}
+				}
+			default:
+				fmt.Print(formatted)
+			}
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// sourceFiles expands the arguments into a sorted list of .jb files. Files
+// named explicitly are kept regardless of extension; hidden directories are
+// skipped while walking.
+func sourceFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, root)
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(path, ".jb") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func init() {
+	fmtCmd.Flags().BoolVar(&fmtCheck, "check", false, "List files whose formatting differs and exit with status 1")
+	fmtCmd.Flags().BoolVarP(&fmtWrite, "write", "w", false, "Write the result back to the source files")
+	rootCmd.AddCommand(fmtCmd)
+}
@@ -3,6 +3,9 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+
+	"jabline/pkg/vm"
 
 	"github.com/spf13/cobra"
 )
@@ -19,8 +22,15 @@ This is the command-line interpreter for Jabline that allows you to:
 To start, try running a file:
   jabline run my_file.jb`,
 	Version: "0.0.1",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		for _, entry := range modulePaths {
+			vm.ModulePaths = append(vm.ModulePaths, filepath.SplitList(entry)...)
+		}
+	},
 }
 
+var modulePaths []string
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -30,4 +40,5 @@ func Execute() {
 
 func init() {
 	rootCmd.SetVersionTemplate(`{{printf "%s version %s\n" .Name .Version}}`)
+	rootCmd.PersistentFlags().StringArrayVar(&modulePaths, "module-path", nil, "Additional directory to search for imported modules (repeatable)")
 }
@@ -18,6 +18,7 @@ var runCmd = &cobra.Command{
 	Short: "Execute a Jabline program",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		vm.SetMaxTasks(runMaxTasks)
 		filename := args[0]
 		bytes, err := ioutil.ReadFile(filename)
 		if err != nil {
@@ -25,6 +26,11 @@ var runCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if compiler.IsSerialized(bytes) {
+			runSerialized(bytes, filename)
+			return
+		}
+
 		code := string(bytes)
 		l := lexer.New(code)
 		p := parser.New(l)
@@ -44,18 +50,65 @@ var runCmd = &cobra.Command{
 			fmt.Printf("Compiler error: %s\n", err)
 			os.Exit(1)
 		}
+		for _, warning := range comp.Warnings() {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
 
 		bytecode := comp.Bytecode()
 		machine := vm.New(bytecode.Instructions, bytecode.Constants, filename)
+		machine.SetSourceMap(bytecode.SourceMap)
+		if runRace {
+			machine.DetectRaces(os.Stderr, bytecode.SymbolTable.LocalNames())
+		}
 		err = machine.Run()
 
 		if err != nil {
 			fmt.Printf("VM runtime error: %s\n", err)
 			os.Exit(1)
 		}
+		exitOnRaces(machine)
 	},
 }
 
+// runRace is set by --race, which reports the globals that tasks of the
+// program write without synchronizing.
+var runRace bool
+
+// runMaxTasks is set by --max-tasks, which bounds the tasks running at
+// once. It defaults to JABLINE_MAX_TASKS.
+var runMaxTasks int
+
+// exitOnRaces exits with status 66, as Go programs built with -race do, if
+// the program raced.
+func exitOnRaces(machine *vm.VM) {
+	if machine.Races() > 0 {
+		os.Exit(66)
+	}
+}
+
+// runSerialized executes a program compiled with `jabline build -o x.jbc`.
+func runSerialized(data []byte, filename string) {
+	bytecode, err := compiler.Deserialize(data)
+	if err != nil {
+		fmt.Printf("Error loading bytecode: %s\n", err)
+		os.Exit(1)
+	}
+
+	machine := vm.New(bytecode.Instructions, bytecode.Constants, filename)
+	machine.SetSourceMap(bytecode.SourceMap)
+	machine.SetModules(bytecode.Modules)
+	if runRace {
+		machine.DetectRaces(os.Stderr, nil)
+	}
+	if err := machine.Run(); err != nil {
+		fmt.Printf("VM runtime error: %s\n", err)
+		os.Exit(1)
+	}
+	exitOnRaces(machine)
+}
+
 func init() {
+	runCmd.Flags().BoolVar(&runRace, "race", false, "Report globals written by tasks that do not synchronize")
+	runCmd.Flags().IntVar(&runMaxTasks, "max-tasks", vm.MaxTasks(), "Maximum number of tasks running at once (0 for no limit)")
 	rootCmd.AddCommand(runCmd)
 }
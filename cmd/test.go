@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"time"
+
+	"jabline/pkg/testrunner"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	testRunPattern string
+	testParallel   int
+	testTimeout    time.Duration
+	testTAPFile    string
+	testJUnitFile  string
+)
+
+var testCmd = &cobra.Command{
+	Use:   "test [path...]",
+	Short: "Run the *_test.jb files under the given paths",
+	Long: `Discover *_test.jb files and run every top-level function whose name
+starts with "test". Each test runs in a fresh VM; a test fails when it
+raises an uncaught error.
+
+Reports can be written in TAP (--tap) and JUnit XML (--junit) formats.
+Use "-" to write a report to stdout instead of the default summary.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := testrunner.Options{
+			Parallel: testParallel,
+			Timeout:  testTimeout,
+		}
+
+		if testRunPattern != "" {
+			re, err := regexp.Compile(testRunPattern)
+			if err != nil {
+				fmt.Printf("Invalid --run pattern: %s\n", err)
+				os.Exit(1)
+			}
+			opts.Run = re
+		}
+
+		results, err := testrunner.Run(args, opts)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+
+		if testTAPFile != "-" && testJUnitFile != "-" {
+			testrunner.WriteText(os.Stdout, results)
+		}
+
+		if testTAPFile != "" {
+			err := writeReport(testTAPFile, func(w io.Writer) error {
+				testrunner.WriteTAP(w, results)
+				return nil
+			})
+			if err != nil {
+				fmt.Printf("Error writing TAP report: %s\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if testJUnitFile != "" {
+			err := writeReport(testJUnitFile, func(w io.Writer) error {
+				return testrunner.WriteJUnit(w, results)
+			})
+			if err != nil {
+				fmt.Printf("Error writing JUnit report: %s\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if !testrunner.Summarize(results).OK() {
+			os.Exit(1)
+		}
+	},
+}
+
+func writeReport(path string, write func(io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func init() {
+	testCmd.Flags().StringVar(&testRunPattern, "run", "", "Run only tests whose name matches the regular expression")
+	testCmd.Flags().IntVarP(&testParallel, "parallel", "p", runtime.NumCPU(), "Number of tests to run in parallel")
+	testCmd.Flags().DurationVar(&testTimeout, "timeout", 30*time.Second, "Timeout for each test (0 disables it)")
+	testCmd.Flags().StringVar(&testTAPFile, "tap", "", "Write a TAP report to the given file (\"-\" for stdout)")
+	testCmd.Flags().StringVar(&testJUnitFile, "junit", "", "Write a JUnit XML report to the given file (\"-\" for stdout)")
+	rootCmd.AddCommand(testCmd)
+}
@@ -1,8 +1,6 @@
 package main
 
 import (
-	"bytes"
-	"encoding/binary"
 	"fmt"
 	"os"
 
@@ -24,54 +22,10 @@ func tryRunStandalone() bool {
 		return false
 	}
 
-	f, err := os.Open(exePath)
-	if err != nil {
-		return false
-	}
-	defer f.Close()
-
-	markerLen := int64(len(cmd.MagicMarker))
-	fInfo, err := f.Stat()
-	if err != nil {
+	bytecodeData, err := cmd.EmbeddedBytecode(exePath)
+	if err != nil || bytecodeData == nil {
 		return false
 	}
-	fileSize := fInfo.Size()
-
-	if fileSize < markerLen+8 {
-		return false
-	}
-
-	_, err = f.Seek(-markerLen, 2)
-	if err != nil { return false }
-	
-	markerBuf := make([]byte, markerLen)
-	_, err = f.Read(markerBuf)
-	if err != nil { return false }
-
-	if !bytes.Equal(markerBuf, cmd.MagicMarker) {
-		return false
-	}
-
-	_, err = f.Seek(-(markerLen + 8), 2)
-	if err != nil { return false }
-	
-	sizeBuf := make([]byte, 8)
-	_, err = f.Read(sizeBuf)
-	if err != nil { return false }
-	
-	bytecodeSize := int64(binary.LittleEndian.Uint64(sizeBuf))
-
-	bytecodeStart := fileSize - markerLen - 8 - bytecodeSize
-	if bytecodeStart < 0 {
-		return false
-	}
-
-	_, err = f.Seek(bytecodeStart, 0)
-	if err != nil { return false }
-	
-	bytecodeData := make([]byte, bytecodeSize)
-	_, err = f.Read(bytecodeData)
-	if err != nil { return false }
 
 	bytecode, err := compiler.Deserialize(bytecodeData)
 	if err != nil {
@@ -80,6 +34,8 @@ func tryRunStandalone() bool {
 	}
 
 	machine := vm.New(bytecode.Instructions, bytecode.Constants, "<embedded>")
+	machine.SetSourceMap(bytecode.SourceMap)
+	machine.SetModules(bytecode.Modules)
 	err = machine.Run()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Runtime error: %s\n", err)
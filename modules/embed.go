@@ -0,0 +1,10 @@
+// Package modules embeds the Jabline standard library sources so imports
+// such as "net/http/router" resolve without a modules directory on disk.
+package modules
+
+import "embed"
+
+// FS holds every .jb file of the standard library, rooted at this directory.
+//
+//go:embed *.jb */*.jb */*/*.jb
+var FS embed.FS
@@ -98,6 +98,7 @@ func (fs *ForStatement) String() string {
 
 type ForEachStatement struct {
 	Token    token.Token
+	Key      *Identifier // the first variable of for (k, v in ...), or nil
 	Variable *Identifier
 	Iterable Expression
 	Body     *BlockStatement
@@ -106,32 +107,52 @@ type ForEachStatement struct {
 func (fes *ForEachStatement) statementNode()       {}
 func (fes *ForEachStatement) TokenLiteral() string { return fes.Token.Literal }
 func (fes *ForEachStatement) String() string {
-	out := "for (" + fes.Variable.String() + " in " + fes.Iterable.String() + ") "
+	out := "for ("
+	if fes.Key != nil {
+		out += fes.Key.String() + ", "
+	}
+	out += fes.Variable.String() + " in " + fes.Iterable.String() + ") "
 	out += fes.Body.String()
 	return out
 }
 
 type TryStatement struct {
-	Token      token.Token
-	TryBlock   *BlockStatement
-	CatchBlock *BlockStatement
-	CatchParam *Identifier
+	Token        token.Token
+	TryBlock     *BlockStatement
+	Catches      []*CatchClause
+	FinallyBlock *BlockStatement
 }
 
 func (ts *TryStatement) statementNode()       {}
 func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
 func (ts *TryStatement) String() string {
 	out := "try " + ts.TryBlock.String()
-	if ts.CatchBlock != nil {
-		out += " catch"
-		if ts.CatchParam != nil {
-			out += "(" + ts.CatchParam.String() + ")"
-		}
-		out += " " + ts.CatchBlock.String()
+	for _, cc := range ts.Catches {
+		out += " " + cc.String()
+	}
+	if ts.FinallyBlock != nil {
+		out += " finally " + ts.FinallyBlock.String()
 	}
 	return out
 }
 
+// CatchClause is one catch of a try statement. A clause whose parameter has
+// a type, as in catch (e: IOError), only catches exceptions of that type;
+// the clauses are tried in order.
+type CatchClause struct {
+	Token token.Token
+	Param *Identifier
+	Block *BlockStatement
+}
+
+func (cc *CatchClause) String() string {
+	out := "catch"
+	if cc.Param != nil {
+		out += "(" + cc.Param.String() + ")"
+	}
+	return out + " " + cc.Block.String()
+}
+
 type ThrowStatement struct {
 	Token token.Token
 	Value Expression
@@ -143,6 +164,19 @@ func (ts *ThrowStatement) String() string {
 	return "throw " + ts.Value.String()
 }
 
+// DeferStatement schedules Call to run when the enclosing function returns
+// or an exception leaves it.
+type DeferStatement struct {
+	Token token.Token
+	Call  *CallExpression
+}
+
+func (ds *DeferStatement) statementNode()       {}
+func (ds *DeferStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DeferStatement) String() string {
+	return "defer " + ds.Call.String()
+}
+
 type SwitchStatement struct {
 	Token       token.Token
 	Expression  Expression
@@ -194,3 +228,85 @@ func (dc *DefaultClause) String() string {
 	}
 	return out
 }
+
+// SelectStatement represents:
+//
+//	select { case v = <-a: ... case b <- x: ... case timeout(ms): ... default: ... }
+//
+// It waits until one of its cases can proceed and runs that case. With a
+// default clause it runs the default instead of waiting.
+type SelectStatement struct {
+	Token       token.Token // the 'select' identifier
+	Cases       []*SelectCase
+	DefaultCase *DefaultClause
+}
+
+func (ss *SelectStatement) statementNode()       {}
+func (ss *SelectStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *SelectStatement) String() string {
+	out := "select {"
+	for _, c := range ss.Cases {
+		out += c.String()
+	}
+	if ss.DefaultCase != nil {
+		out += ss.DefaultCase.String()
+	}
+	out += "}"
+	return out
+}
+
+// SelectCase is one case of a select statement: a receive from Channel,
+// whose value Name declares if set, a send of Value to Channel, or a
+// timeout after Timeout milliseconds.
+type SelectCase struct {
+	Token      token.Token // the 'case' token
+	Name       *Identifier
+	Channel    Expression
+	Value      Expression
+	Timeout    Expression
+	Statements []Statement
+}
+
+func (sc *SelectCase) statementNode()       {}
+func (sc *SelectCase) TokenLiteral() string { return sc.Token.Literal }
+func (sc *SelectCase) String() string {
+	var comm string
+	switch {
+	case sc.Timeout != nil:
+		comm = "timeout(" + sc.Timeout.String() + ")"
+	case sc.Value != nil:
+		comm = sc.Channel.String() + " <- " + sc.Value.String()
+	case sc.Name != nil:
+		comm = sc.Name.String() + " = <-" + sc.Channel.String()
+	default:
+		comm = "<-" + sc.Channel.String()
+	}
+	out := "case " + comm + ":"
+	for _, stmt := range sc.Statements {
+		out += stmt.String()
+	}
+	return out
+}
+
+// GroupStatement represents:
+//
+//	group name { spawn a(); spawn b(); }
+//
+// It runs Body, then waits for the tasks spawned meanwhile. The first task
+// to fail cancels the others and its exception is thrown again. Name, if
+// set, is bound to the group in Body, to cancel it.
+type GroupStatement struct {
+	Token token.Token // the 'group' identifier
+	Name  *Identifier
+	Body  *BlockStatement
+}
+
+func (gs *GroupStatement) statementNode()       {}
+func (gs *GroupStatement) TokenLiteral() string { return gs.Token.Literal }
+func (gs *GroupStatement) String() string {
+	out := "group "
+	if gs.Name != nil {
+		out += gs.Name.String() + " "
+	}
+	return out + gs.Body.String()
+}
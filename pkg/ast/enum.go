@@ -8,10 +8,17 @@ import (
 
 // EnumStatement represents: enum Name { Variant1, Variant2, ... }
 // Compiles to an immutable Hash constant: { "Variant1": 0, "Variant2": 1, ... }
+//
+// An algebraic enum, one with a variant carrying fields, such as
+// enum Result { Ok(value), Err(error: Error) }, compiles instead to a hash
+// of its variants: constructors for those with fields, values for the
+// others.
 type EnumStatement struct {
 	Token  token.Token   // the 'enum' token
 	Name   *Identifier   // enum name
 	Values []*Identifier // ordered list of variant names
+	// Fields holds the fields of each variant, nil for one without.
+	Fields [][]*Identifier
 }
 
 func (es *EnumStatement) statementNode()       {}
@@ -24,8 +31,33 @@ func (es *EnumStatement) String() string {
 	names := make([]string, len(es.Values))
 	for i, v := range es.Values {
 		names[i] = v.String()
+		if fields := es.VariantFields(i); fields != nil {
+			params := make([]string, len(fields))
+			for j, f := range fields {
+				params[j] = f.String()
+			}
+			names[i] += "(" + strings.Join(params, ", ") + ")"
+		}
 	}
 	out.WriteString(strings.Join(names, ", "))
 	out.WriteString(" }")
 	return out.String()
 }
+
+// VariantFields returns the fields of the i-th variant.
+func (es *EnumStatement) VariantFields(i int) []*Identifier {
+	if i < len(es.Fields) {
+		return es.Fields[i]
+	}
+	return nil
+}
+
+// Algebraic reports whether a variant of the enum carries fields.
+func (es *EnumStatement) Algebraic() bool {
+	for _, fields := range es.Fields {
+		if fields != nil {
+			return true
+		}
+	}
+	return false
+}
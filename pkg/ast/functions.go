@@ -12,6 +12,9 @@ type FunctionLiteral struct {
 	Parameters     []*Identifier
 	ReturnType     *TypeExpression
 	Body           *BlockStatement
+	// Generator marks an anonymous generator, `fn*() { ... }` or a literal
+	// whose body yields; calling it returns a Generator.
+	Generator bool
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
@@ -23,6 +26,9 @@ func (fl *FunctionLiteral) String() string {
 		params = append(params, p.String())
 	}
 	out.WriteString(fl.TokenLiteral())
+	if fl.Generator {
+		out.WriteString("*")
+	}
 	if len(fl.TypeParameters) > 0 {
 		out.WriteString("[")
 		tparams := []string{}
@@ -75,6 +81,44 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// SpreadExpression passes the elements of an array as separate arguments:
+// f(...args).
+type SpreadExpression struct {
+	Token token.Token // the '...' token
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) String() string       { return "..." + se.Value.String() }
+
+// NamedArgument passes an argument by the name of its parameter:
+// connect(url, timeout: 5000).
+type NamedArgument struct {
+	Token token.Token // the name
+	Name  *Identifier
+	Value Expression
+}
+
+func (na *NamedArgument) expressionNode()      {}
+func (na *NamedArgument) TokenLiteral() string { return na.Token.Literal }
+func (na *NamedArgument) String() string       { return na.Name.Value + ": " + na.Value.String() }
+
+// ParameterArity returns how many of params a call must pass, and whether
+// the last one collects any further arguments. The parameters with a
+// default all come after the required ones.
+func ParameterArity(params []*Identifier) (required int, variadic bool) {
+	for _, p := range params {
+		if p.Variadic {
+			return required, true
+		}
+		if p.Default == nil {
+			required++
+		}
+	}
+	return required, false
+}
+
 type FunctionStatement struct {
 	Token          token.Token
 	ReceiverName   *Identifier // The 'l' in (l Libro)
@@ -84,6 +128,9 @@ type FunctionStatement struct {
 	Parameters     []*Identifier
 	ReturnType     *TypeExpression
 	Body           *BlockStatement
+	// Generator marks a declared generator or generator method, written
+	// `fn* name()` or found to yield by the parser.
+	Generator bool
 }
 
 func (fs *FunctionStatement) statementNode()       {}
@@ -95,6 +142,9 @@ func (fs *FunctionStatement) String() string {
 		params = append(params, p.String())
 	}
 	out.WriteString(fs.TokenLiteral())
+	if fs.Generator {
+		out.WriteString("*")
+	}
 	out.WriteString(" ")
 
 	if fs.ReceiverName != nil && fs.ReceiverType != nil {
@@ -251,3 +301,19 @@ func (ae *AwaitExpression) TokenLiteral() string { return ae.Token.Literal }
 func (ae *AwaitExpression) String() string {
 	return "await " + ae.Value.String()
 }
+
+// YieldExpression suspends the enclosing generator, handing Value (or null)
+// to its consumer. It evaluates to the value sent back on resumption.
+type YieldExpression struct {
+	Token token.Token
+	Value Expression // nil for a bare `yield`
+}
+
+func (ye *YieldExpression) expressionNode()      {}
+func (ye *YieldExpression) TokenLiteral() string { return ye.Token.Literal }
+func (ye *YieldExpression) String() string {
+	if ye.Value == nil {
+		return "yield"
+	}
+	return "yield " + ye.Value.String()
+}
@@ -6,13 +6,32 @@ type Identifier struct {
 	Token token.Token
 	Value string
 	Type  *TypeExpression
+	// Pattern, on a declared name, destructures the value it receives:
+	// let [a, b] = ..., fn f({ name }) or for ([k, v] in ...). Value is
+	// then a hidden name holding the whole value.
+	Pattern Pattern
+	// Default, on a parameter, is the value it takes when the argument is
+	// left out or null.
+	Default Expression
+	// Variadic marks a ...rest parameter, which collects the remaining
+	// arguments in an array.
+	Variadic bool
 }
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string {
-	if i.Type != nil {
-		return i.Value + ": " + i.Type.String()
+	name := i.Value
+	if i.Pattern != nil {
+		name = i.Pattern.String()
+	} else if i.Type != nil {
+		name += ": " + i.Type.String()
 	}
-	return i.Value
+	if i.Variadic {
+		name = "..." + name
+	}
+	if i.Default != nil {
+		name += " = " + i.Default.String()
+	}
+	return name
 }
@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"sort"
 	"strings"
 
 	"jabline/pkg/token"
@@ -107,6 +108,7 @@ func (hl *HashLiteral) String() string {
 	for key, value := range hl.Pairs {
 		pairs = append(pairs, key.String()+": "+value.String())
 	}
+	sort.Strings(pairs)
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
 	out.WriteString("}")
@@ -0,0 +1,281 @@
+package ast
+
+import (
+	"strings"
+
+	"jabline/pkg/token"
+)
+
+// Pattern is the left-hand side of a match arm. It tests the shape of a
+// value and binds the parts of it that it names.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// MatchExpression represents: match (subject) { pattern [if guard] => body, ... }
+// It evaluates to the body of the first arm whose pattern matches, or null
+// if none does.
+type MatchExpression struct {
+	Token   token.Token // the 'match' identifier
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	arms := make([]string, len(me.Arms))
+	for i, arm := range me.Arms {
+		arms[i] = arm.String()
+	}
+	return "match (" + me.Subject.String() + ") { " + strings.Join(arms, ", ") + " }"
+}
+
+// MatchArm is one arm of a match expression. An expression body is parsed
+// as a block holding just that expression.
+type MatchArm struct {
+	Token   token.Token // the first token of the pattern
+	Pattern Pattern
+	Guard   Expression
+	Body    *BlockStatement
+}
+
+func (ma *MatchArm) String() string {
+	out := ma.Pattern.String()
+	if ma.Guard != nil {
+		out += " if " + ma.Guard.String()
+	}
+	return out + " => " + ma.Body.String()
+}
+
+// WildcardPattern is _, which matches anything and binds nothing.
+type WildcardPattern struct {
+	Token token.Token
+}
+
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) String() string       { return "_" }
+
+// BindingPattern is a name, which matches anything and binds it.
+type BindingPattern struct {
+	Token token.Token
+	Name  *Identifier
+}
+
+func (bp *BindingPattern) patternNode()         {}
+func (bp *BindingPattern) TokenLiteral() string { return bp.Token.Literal }
+func (bp *BindingPattern) String() string       { return bp.Name.String() }
+
+// ValuePattern matches a value equal to a literal or to a qualified name
+// such as the enum variant Color.Red.
+type ValuePattern struct {
+	Token token.Token
+	Value Expression
+}
+
+func (vp *ValuePattern) patternNode()         {}
+func (vp *ValuePattern) TokenLiteral() string { return vp.Token.Literal }
+func (vp *ValuePattern) String() string       { return patternValueString(vp.Value) }
+
+// patternValueString writes a qualified name as it appears in the source,
+// Color.Red rather than (Color."Red").
+func patternValueString(e Expression) string {
+	if idx, ok := e.(*IndexExpression); ok {
+		if name, ok := idx.Index.(*StringLiteral); ok {
+			return patternValueString(idx.Left) + "." + name.Value
+		}
+	}
+	return e.String()
+}
+
+// RangePattern matches a number from Low up to High, which is excluded
+// (1..10) or included (1..=10).
+type RangePattern struct {
+	Token     token.Token
+	Low       Expression
+	High      Expression
+	Inclusive bool
+}
+
+func (rp *RangePattern) patternNode()         {}
+func (rp *RangePattern) TokenLiteral() string { return rp.Token.Literal }
+func (rp *RangePattern) String() string {
+	op := ".."
+	if rp.Inclusive {
+		op = "..="
+	}
+	return rp.Low.String() + op + rp.High.String()
+}
+
+// ArrayPattern matches an array element by element: [first, second]. With
+// a rest element, [first, ...rest], the array may be longer and Rest, if
+// named, binds the elements after the others.
+type ArrayPattern struct {
+	Token    token.Token
+	Elements []Pattern
+	HasRest  bool
+	Rest     *Identifier
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	elems := make([]string, 0, len(ap.Elements)+1)
+	for _, el := range ap.Elements {
+		elems = append(elems, el.String())
+	}
+	if ap.HasRest {
+		rest := "..."
+		if ap.Rest != nil {
+			rest += ap.Rest.String()
+		}
+		elems = append(elems, rest)
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+// HashPattern matches a hash holding each of its keys with a value matching
+// the key's pattern. Other keys are ignored.
+type HashPattern struct {
+	Token   token.Token
+	Entries []*HashPatternEntry
+}
+
+type HashPatternEntry struct {
+	Key   Expression
+	Value Pattern
+}
+
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) String() string {
+	entries := make([]string, len(hp.Entries))
+	for i, e := range hp.Entries {
+		entries[i] = e.Key.String() + ": " + e.Value.String()
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+// StructPattern matches an instance of a struct whose listed fields match
+// their patterns. A field without a pattern, as in User { name }, binds its
+// value to its own name.
+type StructPattern struct {
+	Token  token.Token
+	Name   *Identifier
+	Fields []*FieldPattern
+}
+
+type FieldPattern struct {
+	Name    *Identifier
+	Pattern Pattern
+}
+
+func (sp *StructPattern) patternNode()         {}
+func (sp *StructPattern) TokenLiteral() string { return sp.Token.Literal }
+func (sp *StructPattern) String() string {
+	fields := make([]string, len(sp.Fields))
+	for i, f := range sp.Fields {
+		fields[i] = f.Name.String() + ": " + f.Pattern.String()
+	}
+	return sp.Name.String() + " { " + strings.Join(fields, ", ") + " }"
+}
+
+// VariantPattern matches a value of an algebraic enum made by one of its
+// variants, Result.Ok(value), whose fields match the patterns in order.
+type VariantPattern struct {
+	Token     token.Token
+	Variant   Expression // the qualified name of the variant
+	Arguments []Pattern
+}
+
+func (vp *VariantPattern) patternNode()         {}
+func (vp *VariantPattern) TokenLiteral() string { return vp.Token.Literal }
+func (vp *VariantPattern) String() string {
+	args := make([]string, len(vp.Arguments))
+	for i, arg := range vp.Arguments {
+		args[i] = arg.String()
+	}
+	return patternValueString(vp.Variant) + "(" + strings.Join(args, ", ") + ")"
+}
+
+// ObjectPattern destructures a hash or struct instance by name:
+// { name, age: years = 0 }. A property without a pattern binds its value to
+// its own name.
+type ObjectPattern struct {
+	Token      token.Token
+	Properties []*PropertyPattern
+}
+
+type PropertyPattern struct {
+	Key   *Identifier
+	Value Pattern
+}
+
+func (op *ObjectPattern) patternNode()         {}
+func (op *ObjectPattern) TokenLiteral() string { return op.Token.Literal }
+func (op *ObjectPattern) String() string {
+	props := make([]string, len(op.Properties))
+	for i, p := range op.Properties {
+		props[i] = p.Key.String() + ": " + p.Value.String()
+	}
+	return "{ " + strings.Join(props, ", ") + " }"
+}
+
+// DefaultPattern destructures Default in place of a missing (null) value.
+type DefaultPattern struct {
+	Token   token.Token // the '=' token
+	Target  Pattern
+	Default Expression
+}
+
+func (dp *DefaultPattern) patternNode()         {}
+func (dp *DefaultPattern) TokenLiteral() string { return dp.Token.Literal }
+func (dp *DefaultPattern) String() string {
+	return dp.Target.String() + " = " + dp.Default.String()
+}
+
+// PatternBindings returns the names a pattern binds, in source order.
+func PatternBindings(p Pattern) []*Identifier {
+	switch p := p.(type) {
+	case *BindingPattern:
+		return []*Identifier{p.Name}
+	case *ArrayPattern:
+		var names []*Identifier
+		for _, el := range p.Elements {
+			names = append(names, PatternBindings(el)...)
+		}
+		if p.Rest != nil {
+			names = append(names, p.Rest)
+		}
+		return names
+	case *HashPattern:
+		var names []*Identifier
+		for _, e := range p.Entries {
+			names = append(names, PatternBindings(e.Value)...)
+		}
+		return names
+	case *StructPattern:
+		var names []*Identifier
+		for _, f := range p.Fields {
+			names = append(names, PatternBindings(f.Pattern)...)
+		}
+		return names
+	case *VariantPattern:
+		var names []*Identifier
+		for _, arg := range p.Arguments {
+			names = append(names, PatternBindings(arg)...)
+		}
+		return names
+	case *ObjectPattern:
+		var names []*Identifier
+		for _, prop := range p.Properties {
+			names = append(names, PatternBindings(prop.Value)...)
+		}
+		return names
+	case *DefaultPattern:
+		return PatternBindings(p.Target)
+	}
+	return nil
+}
@@ -20,8 +20,8 @@ func (ss *ServiceStatement) String() string {
 	out.WriteString(ss.Name.String())
 	out.WriteString(" {\n")
 
-	for name, val := range ss.Fields {
-		out.WriteString("  " + name + ": " + val.String() + "\n")
+	for _, name := range sortedKeys(ss.Fields) {
+		out.WriteString("  " + name + ": " + ss.Fields[name].String() + "\n")
 	}
 	
 	for _, method := range ss.Methods {
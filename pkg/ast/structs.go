@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"sort"
 	"strings"
 
 	"jabline/pkg/token"
@@ -10,23 +11,36 @@ type TypeExpression struct {
 	Token     token.Token
 	Value     string
 	Arguments []*TypeExpression // Para Genéricos: Array[int] -> Base: "Array", Arguments: ["int"]
+	// Nullable marks T?, which also admits null.
+	Nullable bool
+	// Union holds the alternatives of A | B. Value is empty for a union.
+	Union []*TypeExpression
 }
 
 func (te *TypeExpression) expressionNode()      {}
 func (te *TypeExpression) TokenLiteral() string { return te.Token.Literal }
 func (te *TypeExpression) String() string {
-	if len(te.Arguments) == 0 {
-		return te.Value
+	if len(te.Union) > 0 {
+		alts := []string{}
+		for _, alt := range te.Union {
+			alts = append(alts, alt.String())
+		}
+		return strings.Join(alts, " | ")
 	}
 	var out strings.Builder
 	out.WriteString(te.Value)
-	out.WriteString("[")
-	args := []string{}
-	for _, arg := range te.Arguments {
-		args = append(args, arg.String())
+	if len(te.Arguments) > 0 {
+		out.WriteString("[")
+		args := []string{}
+		for _, arg := range te.Arguments {
+			args = append(args, arg.String())
+		}
+		out.WriteString(strings.Join(args, ", "))
+		out.WriteString("]")
+	}
+	if te.Nullable {
+		out.WriteString("?")
 	}
-	out.WriteString(strings.Join(args, ", "))
-	out.WriteString("]")
 	return out.String()
 }
 
@@ -56,8 +70,8 @@ func (ss *StructStatement) String() string {
 	out.WriteString(" { ")
 
 	fields := []string{}
-	for name, typeExpr := range ss.Fields {
-		fields = append(fields, name+": "+typeExpr.String())
+	for _, name := range sortedKeys(ss.Fields) {
+		fields = append(fields, name+": "+ss.Fields[name].String())
 	}
 	out.WriteString(strings.Join(fields, ", "))
 	out.WriteString(" }")
@@ -78,10 +92,21 @@ func (sl *StructLiteral) String() string {
 	out.WriteString(" { ")
 
 	fields := []string{}
-	for name, value := range sl.Fields {
-		fields = append(fields, name+": "+value.String())
+	for _, name := range sortedKeys(sl.Fields) {
+		fields = append(fields, name+": "+sl.Fields[name].String())
 	}
 	out.WriteString(strings.Join(fields, ", "))
 	out.WriteString(" }")
 	return out.String()
 }
+
+// sortedKeys returns the keys of a field map in a stable order so String()
+// output does not depend on map iteration.
+func sortedKeys[V any](fields map[string]V) []string {
+	keys := make([]string, 0, len(fields))
+	for name := range fields {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return keys
+}
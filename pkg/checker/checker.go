@@ -0,0 +1,335 @@
+// Package checker is a static type checker for Jabline programs. It runs
+// over the AST before compilation and reports every type error it finds,
+// with its position, instead of stopping at the first one.
+//
+// Unannotated code is mostly typed as any and accepted; the checker
+// reports what the annotations contradict.
+package checker
+
+import (
+	"fmt"
+	"sort"
+
+	"jabline/pkg/ast"
+	"jabline/pkg/object"
+	"jabline/pkg/token"
+)
+
+// Error is a type error at a position in the source.
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// Check type-checks program and returns its errors sorted by position.
+func Check(program *ast.Program) []Error {
+	c := &checker{
+		scope:   newScope(nil),
+		structs: make(map[string]*structInfo),
+		enums:   make(map[string]*ast.EnumStatement),
+		opaque:  make(map[string]bool),
+	}
+	c.checkStatements(program.Statements)
+
+	// Annotations of hoisted declarations are resolved twice.
+	seen := make(map[Error]bool)
+	errs := c.errors[:0]
+	for _, err := range c.errors {
+		if !seen[err] {
+			seen[err] = true
+			errs = append(errs, err)
+		}
+	}
+	c.errors = errs
+
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		if c.errors[i].Line != c.errors[j].Line {
+			return c.errors[i].Line < c.errors[j].Line
+		}
+		return c.errors[i].Column < c.errors[j].Column
+	})
+	return c.errors
+}
+
+type structInfo struct {
+	typeParams []string
+	fields     map[string]*Type
+	methods    map[string]*Type
+}
+
+type scope struct {
+	vars map[string]*Type
+	// narrowed holds the types of variables known not to be null here,
+	// which reads see but assignments are not held to.
+	narrowed   map[string]*Type
+	typeParams map[string]bool
+	outer      *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{
+		vars:       make(map[string]*Type),
+		narrowed:   make(map[string]*Type),
+		typeParams: make(map[string]bool),
+		outer:      outer,
+	}
+}
+
+// lookup returns the type of the variable name as read here.
+func (s *scope) lookup(name string) (*Type, bool) {
+	for ; s != nil; s = s.outer {
+		if t, ok := s.narrowed[name]; ok {
+			return t, true
+		}
+		if t, ok := s.vars[name]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// declared returns the type the variable name was declared with.
+func (s *scope) declared(name string) (*Type, bool) {
+	for ; s != nil; s = s.outer {
+		if t, ok := s.vars[name]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func (s *scope) isTypeParam(name string) bool {
+	for ; s != nil; s = s.outer {
+		if s.typeParams[name] {
+			return true
+		}
+	}
+	return false
+}
+
+type checker struct {
+	errors  []Error
+	scope   *scope
+	structs map[string]*structInfo
+	enums   map[string]*ast.EnumStatement
+	// opaque holds imported names, which may be used as types the checker
+	// knows nothing about.
+	opaque map[string]bool
+	// result is the declared result of the function being checked, nil
+	// outside functions.
+	result *Type
+	// yields is the declared type of the values the generator being checked
+	// yields, nil outside generators.
+	yields *Type
+}
+
+func (c *checker) errorf(tok token.Token, format string, args ...interface{}) {
+	c.errors = append(c.errors, Error{Line: tok.Line, Column: tok.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) push() { c.scope = newScope(c.scope) }
+
+func (c *checker) pop() { c.scope = c.scope.outer }
+
+// resolve turns a type annotation into a Type, reporting unknown names.
+func (c *checker) resolve(te *ast.TypeExpression) *Type {
+	if te == nil {
+		return Any
+	}
+	if len(te.Union) > 0 {
+		members := make([]*Type, len(te.Union))
+		for i, alt := range te.Union {
+			members[i] = c.resolve(alt)
+		}
+		return union(members...)
+	}
+
+	t := c.resolveNamed(te)
+	if te.Nullable {
+		return union(t, Null)
+	}
+	return t
+}
+
+func (c *checker) resolveNamed(te *ast.TypeExpression) *Type {
+	args := make([]*Type, len(te.Arguments))
+	for i, arg := range te.Arguments {
+		args[i] = c.resolve(arg)
+	}
+
+	switch name := te.Value; {
+	case name == "any":
+		return Any
+	case name == "null":
+		return Null
+	case name == "fn":
+		return AnyFn
+	case basicNames[name]:
+		return basic(name)
+	case name == "Array" || name == "array":
+		if len(args) == 0 {
+			return arrayOf(Any)
+		}
+		c.checkTypeArgCount(te, 1, len(args))
+		return arrayOf(args[0])
+	case name == "Hash" || name == "hash" || name == "Map" || name == "map":
+		switch len(args) {
+		case 0:
+			return hashOf(Any, Any)
+		case 1:
+			return hashOf(String, args[0])
+		}
+		c.checkTypeArgCount(te, 2, len(args))
+		return hashOf(args[0], args[1])
+	case c.scope.isTypeParam(name):
+		return &Type{Kind: ParamKind, Name: name}
+	case c.enums[name] != nil && !c.enums[name].Algebraic():
+		// Enum variants are integers at runtime.
+		return Int
+	case c.enums[name] != nil:
+		return &Type{Kind: NamedKind, Name: name}
+	case c.opaque[name]:
+		return Any
+	case object.IsErrorKind(name) && c.structs[name] == nil:
+		return &Type{Kind: NamedKind, Name: name}
+	}
+
+	info, ok := c.structs[te.Value]
+	if !ok {
+		c.errorf(te.Token, "undefined type %s", te.Value)
+		return Any
+	}
+	if len(args) > 0 {
+		c.checkTypeArgCount(te, len(info.typeParams), len(args))
+	}
+	return &Type{Kind: NamedKind, Name: te.Value, Args: args}
+}
+
+func (c *checker) checkTypeArgCount(te *ast.TypeExpression, want, got int) {
+	if want != got {
+		c.errorf(te.Token, "wrong number of type arguments for %s: want %d, got %d", te.Value, want, got)
+	}
+}
+
+// signature builds the type of a function from its declaration. Type
+// parameters must already be in scope.
+func (c *checker) signature(typeParams, params []*ast.Identifier, result *ast.TypeExpression) *Type {
+	fn := &Type{Kind: FuncKind, Result: c.resolve(result)}
+	for _, tp := range typeParams {
+		fn.TypeParams = append(fn.TypeParams, tp.Value)
+	}
+	for _, p := range params {
+		t := c.resolve(p.Type)
+		if p.Variadic && p.Type == nil {
+			t = arrayOf(Any)
+		}
+		fn.Params = append(fn.Params, t)
+		fn.ParamNames = append(fn.ParamNames, p.Value)
+		if p.Default != nil {
+			fn.Optional++
+		}
+	}
+	_, fn.Variadic = ast.ParameterArity(params)
+	return fn
+}
+
+// declare hoists the structs, enums and functions declared in stmts, so
+// that they can be used before their declaration.
+func (c *checker) declare(stmts []ast.Statement) {
+	var structs []*ast.StructStatement
+	var funcs []*ast.FunctionStatement
+	for _, stmt := range stmts {
+		if export, ok := stmt.(*ast.ExportStatement); ok && export.Statement != nil {
+			stmt = export.Statement
+		}
+		switch s := stmt.(type) {
+		case *ast.StructStatement:
+			info := &structInfo{fields: make(map[string]*Type), methods: make(map[string]*Type)}
+			for _, tp := range s.TypeParameters {
+				info.typeParams = append(info.typeParams, tp.Value)
+			}
+			c.structs[s.Name.Value] = info
+			structs = append(structs, s)
+		case *ast.EnumStatement:
+			c.enums[s.Name.Value] = s
+			c.scope.vars[s.Name.Value] = Any
+		case *ast.FunctionStatement:
+			funcs = append(funcs, s)
+		case *ast.AsyncFunctionStatement:
+			// Calling an async function yields a promise, not its result.
+			c.scope.vars[s.Name.Value] = AnyFn
+		}
+	}
+
+	// Fields and signatures may refer to any of the declared types.
+	for _, s := range structs {
+		info := c.structs[s.Name.Value]
+		c.push()
+		for _, tp := range info.typeParams {
+			c.scope.typeParams[tp] = true
+		}
+		for name, te := range s.Fields {
+			info.fields[name] = c.resolve(te)
+		}
+		c.pop()
+	}
+	for _, s := range funcs {
+		c.push()
+		for _, tp := range s.TypeParameters {
+			c.scope.typeParams[tp.Value] = true
+		}
+		sig := c.signature(s.TypeParameters, s.Parameters, s.ReturnType)
+		c.pop()
+		if s.Generator && sig.Result != nil {
+			sig.Result = Any
+		}
+
+		if s.ReceiverType == nil {
+			c.scope.vars[s.Name.Value] = sig
+			continue
+		}
+		if info, ok := c.structs[s.ReceiverType.Value]; ok {
+			info.methods[s.Name.Value] = sig
+		} else if c.enums[s.ReceiverType.Value] == nil {
+			c.errorf(s.ReceiverType.Token, "undefined type %s", s.ReceiverType.Value)
+		}
+	}
+}
+
+// member returns the type of the field or method name of t, or false if
+// t has no such member. Members of values the checker cannot see into are
+// typed any.
+func (c *checker) member(t *Type, name string) (*Type, bool) {
+	switch t.Kind {
+	case NamedKind:
+		info, ok := c.structs[t.Name]
+		if !ok {
+			return Any, true
+		}
+		bindings := make(map[string]*Type)
+		for i, tp := range info.typeParams {
+			if i < len(t.Args) {
+				bindings[tp] = t.Args[i]
+			} else {
+				bindings[tp] = Any
+			}
+		}
+		if f, ok := info.fields[name]; ok {
+			return subst(f, bindings), true
+		}
+		if m, ok := info.methods[name]; ok {
+			return subst(m, bindings), true
+		}
+		return nil, false
+	case HashKind:
+		return t.Args[1], true
+	case BasicKind, ArrayKind, FuncKind, NullKind:
+		return nil, false
+	}
+	return Any, true
+}
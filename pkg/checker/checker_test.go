@@ -0,0 +1,135 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+
+	"jabline/pkg/lexer"
+	"jabline/pkg/parser"
+)
+
+func check(t *testing.T, input string) []Error {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return Check(program)
+}
+
+func TestCheckErrors(t *testing.T) {
+	const decls = `
+struct Point { x: int, y: int }
+struct Box[T] { v: T }
+fn (p Point) norm(): float { return 1.5; }
+fn add(a: int, b: int): int { return a + b; }
+fn id[T](x: T): T { return x; }
+fn find(n: int): Point? { if (n > 0) { return Point{ x: n, y: 0 }; } return null; }
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let a: int = "s";`, "8:14: cannot use string as int in declaration of a"},
+		{`let a = add(1, "two");`, "cannot use string as int in argument 2 to add"},
+		{`let a = add(1);`, "wrong number of arguments to add: want 2, got 1"},
+		{`let a = Point{ x: 1, z: 2 };`, "unknown field z in Point literal"},
+		{`let a = Point{ x: 1, y: "2" };`, "cannot use string as int in field y of Point"},
+		{`let a: string = id(3);`, "cannot use int as string in declaration of a"},
+		{`let a: int = id[string]("s");`, "cannot use string as int in declaration of a"},
+		{`let a: Box[int] = Box[string]{ v: "x" };`, "cannot use Box[string] as Box[int]"},
+		{`let a: Box[int, int]? = null;`, "wrong number of type arguments for Box: want 1, got 2"},
+		{`let a = find(1); echo(a.x);`, "Point? may be null when accessing x"},
+		{`let a: Nope = 1;`, "undefined type Nope"},
+		{`let a: int8 = 1; let b: int16 = 2; let c = a + b;`, "mismatched types int8 and int16 for operator +"},
+		{`let a: int | string = true;`, "cannot use bool as int | string"},
+		{`let a: int = null;`, "cannot use null as int"},
+		{`let a: Array[Point] = [Point{ x: 1, y: 2 }]; let s: string = a[0].x;`, "cannot use int as string"},
+		{`let a = Point{ x: 1, y: 2 }; echo(a.z);`, "Point has no field or method z"},
+		{`let a: string = Point{ x: 1, y: 2 }.norm();`, "cannot use float as string"},
+		{`fn f(): string { return 1; }`, "cannot use int as string in return"},
+		{`let a = 1; a = "s";`, "cannot use string as int in assignment to a"},
+		{`let a = 5; a();`, "cannot call int"},
+		{`let a = "s" - 1;`, "invalid operation: string - int"},
+		{`fn* nums(): int { yield 1; yield "s"; }`, "cannot use string as int in yield"},
+		{`let h = {"a": 1}; for (k, v in h) { let s: string = v; }`, "cannot use int as string in declaration of s"},
+		{`let a = make_chan(); select { case v = <-a: echo(v); case timeout("1s"): }`, "timeout of select case must be int milliseconds, got string"},
+		{`try { f(); } catch (e: IOError) { let t: TimeoutError = e; }`, "cannot use IOError as TimeoutError"},
+		{`try { f(); } catch (e: Oops) { }`, "undefined type Oops"},
+		{`let [a, b] = [1, 2]; let s: string = b;`, "cannot use int as string in declaration of s"},
+		{`let { x, y: why = 0 } = Point{ x: 1, y: 2 }; let s: string = why;`, "cannot use int as string in declaration of s"},
+		{`let { z } = Point{ x: 1, y: 2 };`, "Point has no field or method z"},
+		{`let [a] = 5;`, "cannot destructure int as an array"},
+		{`let s = "a"; [s] = [1];`, "cannot use int as string in assignment to s"},
+		{`fn f({ x }: Point): string { return x; }`, "cannot use int as string in return"},
+		{`for ([k, v] in [["a", "b"]]) { let n: int = v; }`, "cannot use string as int in declaration of n"},
+		{`let a = match (Point{ x: 1, y: 2 }) { Point { x } => { let s: string = x; s } };`, "cannot use int as string in declaration of s"},
+		{`let a = match (1) { Point { z } => z };`, "Point has no field z"},
+		{`let a = match (1) { 1.."z" => 1 };`, "range pattern bound \"z\" is not a number"},
+		{`fn f(a, b = 1) { } f();`, "wrong number of arguments to f: want 1 to 2, got 0"},
+		{`fn f(a, ...rest) { } f();`, "wrong number of arguments to f: want 1 or more, got 0"},
+		{`fn f(a: int, ...rest: Array[string]) { } f(1, "a", 2);`, "cannot use int as string in argument 3 to f"},
+		{`fn f(timeout: int = "slow") { }`, "cannot use string as int in default of parameter timeout"},
+		{`fn f(...rest: int) { }`, "the rest parameter rest must be an array, not int"},
+		{`fn f(url, timeout = 1) { } f("u", retries: 2);`, "f has no parameter named retries"},
+		{`fn f(url, timeout = 1) { } f("u", url: "v");`, "argument url of f is given twice"},
+		{`fn f(url, timeout = 1) { } f(timeout: 2);`, "missing argument url in call to f"},
+		{`fn f(url, timeout: int = 1) { } f("u", timeout: "x");`, "cannot use string as int in argument timeout to f"},
+		{`fn f(a) { } f(...5);`, "cannot spread int into arguments"},
+		{`enum R { Ok(v), Err(msg: string) } let r = R.Err(1);`, "cannot use int as string in argument 1 to Err"},
+		{`enum R { Ok(v), Err(msg: string) } let r: int = R.Ok(1);`, "cannot use R as int in declaration of r"},
+		{`enum R { Ok(v), None } let r = R.Some;`, "enum R has no variant Some"},
+		{`enum R { Ok(v), None } let a = match (R.None) { R.Ok(x, y) => x };`, "wrong number of fields in pattern for R.Ok: want 1, got 2"},
+		{`enum R { Ok(n: int), None } let a = match (R.None) { R.Ok(n) => { let s: string = n; s } };`, "cannot use int as string in declaration of s"},
+	}
+
+	for _, tt := range tests {
+		errs := check(t, decls+tt.input)
+		if len(errs) != 1 {
+			t.Errorf("%s: expected 1 error, got %v", tt.input, errs)
+			continue
+		}
+		if got := errs[0].Error(); !strings.Contains(got, tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestCheckAccepts(t *testing.T) {
+	tests := []string{
+		`let a: int? = null; a = 1; a = null;`,
+		`let a: int | string = 1; a = "s";`,
+		`let a: float = 1; let b: int8 = 1; let c: float32 = 2.5;`,
+		`let a: int8 = 1; let b = a + 1; let c: int8 = b;`,
+		`struct Box[T] { v: T } fn get[T](b: Box[T]): T { return b.v; } let n: int = get(Box[int]{ v: 1 });`,
+		`struct P { x: int } fn f(p: P?): int { if (p == null) { return 0; } return p.x; }`,
+		`struct P { x: int } fn f(p: P?): int { if (p != null) { return p.x; } return 0; }`,
+		`struct P { x: int } fn f(p: P?): int { return p?.x ?? 0; }`,
+		`let a = [1, "two", null]; let h = {"n": 1, "s": "x"}; let n = h["n"] + 1;`,
+		`import * as m from "math"; let x: m = m.sqrt(4);`,
+		`let a: Array[int] = []; for (x in a) { let y: int = x; }`,
+		`let f = (x: int): int => x * 2; let n: int = f(2);`,
+		`fn g(cb: fn): any { return cb(1); } g((x) => x);`,
+		`let s: string = "n=" + 1;`,
+		`let n: int = len([1, 2]);`,
+		`enum Color { Red, Green } let c: Color = Color.Red;`,
+		`fn later(): int { return first(); } fn first(): int { return 1; }`,
+		`fn* nums(): int { yield 1; } let g = nums(); for (x in g) { echo(x); }`,
+		`let h = {"a": 1}; for (k in h) { let s: string = k; } for (i, c in "hé") { let n: int = i; }`,
+		`try { f(); } catch (e: TimeoutError) { let io: IOError = e; let m = e.message; } catch (e) { }`,
+		`let e: Error = ValueError("bad", IOError("io"));`,
+		`fn f(url: string, timeout: int = 1000, ...tags: Array[string]) { } f("u"); f("u", 5, "a", "b"); f("u", timeout: 5);`,
+		`fn f(a, b = a * 2) { return a + b; } let args = [1, 2]; f(...args); f(1, ...args);`,
+		`struct P { x: int } fn (p P) move(dx: int = 0, dy: int = 0) { } P{ x: 1 }.move(dy: 2);`,
+		`enum R { Ok(v), Err(msg: string), None } fn (r R) ok(): bool { return r != R.None; } let r: R = R.Err(msg: "x"); r.ok(); let m = r.msg;`,
+		`let a = make_chan(); select { case v = <-a: let n: int = v; case a <- 1: default: }`,
+		`fn f() { } group g { spawn f(); if (g.cancelled()) { g.cancel(); } } let group = 1;`,
+	}
+
+	for _, input := range tests {
+		if errs := check(t, input); len(errs) > 0 {
+			t.Errorf("%s: unexpected errors %v", input, errs)
+		}
+	}
+}
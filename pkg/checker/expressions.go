@@ -0,0 +1,786 @@
+package checker
+
+import (
+	"fmt"
+
+	"jabline/pkg/ast"
+	"jabline/pkg/object"
+	"jabline/pkg/token"
+)
+
+// builtinResults are the result types of the builtins whose result is
+// known; the others return any.
+var builtinResults = map[string]*Type{
+	"len":      Int,
+	"cap":      Int,
+	"type":     String,
+	"is_error": Bool,
+	"keys":     arrayOf(Any),
+	"values":   arrayOf(Any),
+	"int8":     basic("int8"),
+	"int16":    basic("int16"),
+	"int32":    basic("int32"),
+	"int64":    basic("int64"),
+	"uint8":    basic("uint8"),
+	"uint16":   basic("uint16"),
+	"uint32":   basic("uint32"),
+	"uint64":   basic("uint64"),
+	"float32":  basic("float32"),
+	"float64":  basic("float64"),
+}
+
+func init() {
+	// The error constructors return an error of their kind.
+	for _, kind := range object.ErrorKinds {
+		builtinResults[kind] = &Type{Kind: NamedKind, Name: kind}
+	}
+}
+
+// infer checks expr and returns its type.
+func (c *checker) infer(expr ast.Expression) *Type {
+	switch e := expr.(type) {
+	case nil:
+		return Any
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.FloatLiteral:
+		return Float
+	case *ast.StringLiteral:
+		return String
+	case *ast.TemplateLiteral:
+		for _, part := range e.Expressions {
+			c.infer(part)
+		}
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.Null:
+		return Null
+	case *ast.Identifier:
+		if t, ok := c.scope.lookup(e.Value); ok {
+			return t
+		}
+		if _, ok := builtinResults[e.Value]; ok {
+			return AnyFn
+		}
+		return Any
+	case *ast.ArrayLiteral:
+		var elems []*Type
+		for _, el := range e.Elements {
+			elems = append(elems, c.infer(el))
+		}
+		return arrayOf(common(elems))
+	case *ast.HashLiteral:
+		var keys, values []*Type
+		for k, v := range e.Pairs {
+			keys = append(keys, c.infer(k))
+			values = append(values, c.infer(v))
+		}
+		return hashOf(common(keys), common(values))
+	case *ast.PrefixExpression:
+		return c.inferPrefix(e)
+	case *ast.InfixExpression:
+		return c.inferInfix(e)
+	case *ast.PostfixExpression:
+		return c.infer(e.Left)
+	case *ast.IfExpression:
+		c.inferIf(e)
+		return Any
+	case *ast.MatchExpression:
+		c.inferMatch(e)
+		return Any
+	case *ast.TernaryExpression:
+		c.infer(e.Condition)
+		return union(c.infer(e.TrueValue), c.infer(e.FalseValue))
+	case *ast.NullishCoalescingExpression:
+		return union(nonNull(c.infer(e.Left)), c.infer(e.Right))
+	case *ast.OptionalChainingExpression:
+		left := nonNull(c.infer(e.Left))
+		if name, ok := e.Right.(*ast.Identifier); ok {
+			return union(c.memberOf(left, name.Value, name.Token), Null)
+		}
+		return Any
+	case *ast.IndexExpression:
+		left := c.infer(e.Left)
+		name, ok := e.Index.(*ast.StringLiteral)
+		if !ok {
+			return Any
+		}
+		if t := c.variantType(e.Left, name); t != nil {
+			return t
+		}
+		return c.memberOf(left, name.Value, name.Token)
+	case *ast.ArrayIndexExpression:
+		return c.inferIndex(c.infer(e.Left), c.infer(e.Index))
+	case *ast.InstantiatedExpression:
+		return c.inferInstantiated(e)
+	case *ast.CallExpression:
+		return c.inferCall(e)
+	case *ast.StructLiteral:
+		return c.inferStructLiteral(e)
+	case *ast.FunctionLiteral:
+		return c.checkFunction(e.TypeParameters, e.Parameters, e.ReturnType, e.Body, e.Generator)
+	case *ast.AsyncFunctionLiteral:
+		c.checkFunction(e.TypeParameters, e.Parameters, e.ReturnType, e.Body, false)
+		return AnyFn
+	case *ast.ArrowFunction:
+		return c.inferArrow(e)
+	case *ast.SpawnExpression:
+		c.infer(e.Call)
+		return Any
+	case *ast.AwaitExpression:
+		c.infer(e.Value)
+		return Any
+	case *ast.YieldExpression:
+		c.checkYield(e)
+		return Any
+	}
+	return Any
+}
+
+// common is the element type of a collection literal: the type all its
+// elements share, or any for an empty or mixed literal, which is usually
+// a record whose fields the checker does not track.
+func common(ts []*Type) *Type {
+	if len(ts) == 0 {
+		return Any
+	}
+	if u := union(ts...); u.Kind != UnionKind || nullable(u) && len(u.Members) == 2 {
+		return u
+	}
+	return Any
+}
+
+func (c *checker) inferIf(e *ast.IfExpression) {
+	c.infer(e.Condition)
+	name, isNull, ok := nullTest(e.Condition)
+	t, found := c.scope.lookup(name)
+	narrow := func(block *ast.BlockStatement) {
+		c.push()
+		c.scope.narrowed[name] = nonNull(t)
+		c.checkStatements(block.Statements)
+		c.pop()
+	}
+
+	switch {
+	case ok && found && !isNull:
+		narrow(e.Consequence)
+	default:
+		c.checkBlock(e.Consequence)
+	}
+	if e.Alternative == nil {
+		return
+	}
+	if ok && found && isNull {
+		narrow(e.Alternative)
+	} else {
+		c.checkBlock(e.Alternative)
+	}
+}
+
+// inferMatch checks each arm with the names its pattern binds in scope.
+// The fields of a struct pattern have their declared types; other names
+// could hold anything.
+func (c *checker) inferMatch(e *ast.MatchExpression) {
+	c.infer(e.Subject)
+	for _, arm := range e.Arms {
+		c.push()
+		c.bindPattern(arm.Pattern, Any)
+		if arm.Guard != nil {
+			c.infer(arm.Guard)
+		}
+		c.checkStatements(arm.Body.Statements)
+		c.pop()
+	}
+}
+
+func (c *checker) bindPattern(p ast.Pattern, t *Type) {
+	switch p := p.(type) {
+	case *ast.BindingPattern:
+		c.scope.vars[p.Name.Value] = t
+	case *ast.ValuePattern:
+		c.infer(p.Value)
+	case *ast.RangePattern:
+		for _, bound := range []ast.Expression{p.Low, p.High} {
+			if bt := c.infer(bound); bt.Kind != AnyKind && !isNumeric(bt) {
+				c.errorf(tokenOf(bound), "range pattern bound %s is not a number", bound)
+			}
+		}
+	case *ast.ArrayPattern:
+		for _, el := range p.Elements {
+			c.bindPattern(el, Any)
+		}
+		if p.Rest != nil {
+			c.scope.vars[p.Rest.Value] = Any
+		}
+	case *ast.HashPattern:
+		for _, entry := range p.Entries {
+			c.bindPattern(entry.Value, Any)
+		}
+	case *ast.StructPattern:
+		info, ok := c.structs[p.Name.Value]
+		if !ok && !c.opaque[p.Name.Value] && !object.IsErrorKind(p.Name.Value) {
+			c.errorf(p.Name.Token, "undefined type %s", p.Name.Value)
+		}
+		for _, f := range p.Fields {
+			ft := Any
+			if ok && len(info.typeParams) == 0 {
+				declared, has := info.fields[f.Name.Value]
+				if !has {
+					c.errorf(f.Name.Token, "%s has no field %s", p.Name.Value, f.Name.Value)
+				} else {
+					ft = declared
+				}
+			}
+			c.bindPattern(f.Pattern, ft)
+		}
+	case *ast.VariantPattern:
+		c.infer(p.Variant)
+		fields := c.variantFields(p)
+		for i, arg := range p.Arguments {
+			at := Any
+			if i < len(fields) {
+				at = c.resolve(fields[i].Type)
+			}
+			c.bindPattern(arg, at)
+		}
+	}
+}
+
+// variantType types a variant of an algebraic enum declared in this file:
+// a variant with fields is a function of them returning the enum, and one
+// without is a value of the enum. It returns nil for anything else.
+func (c *checker) variantType(left ast.Expression, name *ast.StringLiteral) *Type {
+	ident, ok := left.(*ast.Identifier)
+	if !ok || c.enums[ident.Value] == nil || !c.enums[ident.Value].Algebraic() {
+		return nil
+	}
+
+	enum := c.enums[ident.Value]
+	result := &Type{Kind: NamedKind, Name: enum.Name.Value}
+	for i, v := range enum.Values {
+		if v.Value != name.Value {
+			continue
+		}
+		fields := enum.VariantFields(i)
+		if fields == nil {
+			return result
+		}
+		fn := &Type{Kind: FuncKind, Result: result}
+		for _, f := range fields {
+			fn.Params = append(fn.Params, c.resolve(f.Type))
+			fn.ParamNames = append(fn.ParamNames, f.Value)
+		}
+		return fn
+	}
+	c.errorf(name.Token, "enum %s has no variant %s", ident.Value, name.Value)
+	return Any
+}
+
+// variantFields returns the fields of the variant a variant pattern names,
+// if it is one of an enum declared in this file, after checking that the
+// pattern has one argument for each of them.
+func (c *checker) variantFields(p *ast.VariantPattern) []*ast.Identifier {
+	idx, ok := p.Variant.(*ast.IndexExpression)
+	if !ok {
+		return nil
+	}
+	name, ok := idx.Left.(*ast.Identifier)
+	variant, ok2 := idx.Index.(*ast.StringLiteral)
+	if !ok || !ok2 || c.enums[name.Value] == nil {
+		return nil
+	}
+
+	enum := c.enums[name.Value]
+	for i, v := range enum.Values {
+		if v.Value != variant.Value {
+			continue
+		}
+		fields := enum.VariantFields(i)
+		switch {
+		case fields == nil:
+			c.errorf(p.Token, "variant %s.%s has no fields to match", name.Value, variant.Value)
+		case len(fields) != len(p.Arguments):
+			c.errorf(p.Token, "wrong number of fields in pattern for %s.%s: want %d, got %d", name.Value, variant.Value, len(fields), len(p.Arguments))
+		}
+		return fields
+	}
+	c.errorf(p.Token, "enum %s has no variant %s", name.Value, variant.Value)
+	return nil
+}
+
+func (c *checker) inferPrefix(e *ast.PrefixExpression) *Type {
+	right := c.infer(e.Right)
+	switch e.Operator {
+	case "!":
+		return Bool
+	case "-":
+		if right.Kind == AnyKind || right.Kind == ParamKind {
+			return Any
+		}
+		if !isNumeric(right) {
+			c.errorf(e.Token, "invalid operation: -%s", right)
+			return Any
+		}
+	case "~":
+		if right.Kind == AnyKind || right.Kind == ParamKind {
+			return Any
+		}
+		if !isInteger(right) {
+			c.errorf(e.Token, "invalid operation: ~%s", right)
+			return Any
+		}
+	}
+	return right
+}
+
+func (c *checker) inferInfix(e *ast.InfixExpression) *Type {
+	left := c.infer(e.Left)
+	right := c.infer(e.Right)
+
+	switch e.Operator {
+	case "==", "!=":
+		return Bool
+	case "&&", "||":
+		if left.Kind == BasicKind && left.Name == "bool" && right.Kind == BasicKind && right.Name == "bool" {
+			return Bool
+		}
+		return Any
+	}
+
+	if left.Kind == AnyKind || right.Kind == AnyKind || left.Kind == ParamKind || right.Kind == ParamKind {
+		switch e.Operator {
+		case "<", ">", "<=", ">=":
+			return Bool
+		}
+		return Any
+	}
+
+	switch e.Operator {
+	case "+":
+		// Adding a string to anything concatenates.
+		if isString(left) || isString(right) {
+			return String
+		}
+	case "<", ">", "<=", ">=":
+		if isString(left) && isString(right) {
+			return Bool
+		}
+	}
+
+	if !isNumeric(left) || !isNumeric(right) {
+		c.errorf(e.Token, "invalid operation: %s %s %s", left, e.Operator, right)
+		return Any
+	}
+
+	result := c.numericResult(e, left, right)
+	switch e.Operator {
+	case "<", ">", "<=", ">=":
+		return Bool
+	case "%", "&", "|", "^", "<<", ">>":
+		if !isInteger(left) || !isInteger(right) {
+			c.errorf(e.Token, "invalid operation: %s %s %s", left, e.Operator, right)
+			return Any
+		}
+	}
+	return result
+}
+
+// numericResult mirrors the runtime: a plain number adopts the sized type
+// of the other operand, and two different sized types do not mix.
+func (c *checker) numericResult(e *ast.InfixExpression, left, right *Type) *Type {
+	plain := func(t *Type) bool { return t.Name == "int" || t.Name == "float" }
+	switch {
+	case left.Name == right.Name:
+		return left
+	case plain(left) && plain(right):
+		return Float
+	case plain(left):
+		return right
+	case plain(right):
+		return left
+	}
+	c.errorf(e.Token, "mismatched types %s and %s for operator %s", left, right, e.Operator)
+	return Any
+}
+
+// memberOf types the field or method access t.name.
+func (c *checker) memberOf(t *Type, name string, tok token.Token) *Type {
+	if nullable(t) && t.Kind != NullKind {
+		c.errorf(tok, "%s may be null when accessing %s", t, name)
+		t = nonNull(t)
+	}
+	if t.Kind == UnionKind {
+		var members []*Type
+		for _, m := range t.Members {
+			members = append(members, c.memberOf(m, name, tok))
+		}
+		return union(members...)
+	}
+
+	m, ok := c.member(t, name)
+	if !ok {
+		c.errorf(tok, "%s has no field or method %s", t, name)
+		return Any
+	}
+	return m
+}
+
+func (c *checker) inferIndex(left, index *Type) *Type {
+	switch left.Kind {
+	case ArrayKind:
+		return left.Args[0]
+	case HashKind:
+		return left.Args[1]
+	case BasicKind:
+		if left.Name == "string" {
+			return String
+		}
+	}
+	return Any
+}
+
+// inferInstantiated types f[int]. The parser also produces it for a[i]
+// when the index is a plain identifier, which is indexing.
+func (c *checker) inferInstantiated(e *ast.InstantiatedExpression) *Type {
+	left := c.infer(e.Left)
+	if ident, ok := e.Left.(*ast.Identifier); ok {
+		if _, isStruct := c.structs[ident.Value]; isStruct {
+			if _, shadowed := c.scope.lookup(ident.Value); !shadowed {
+				return Any
+			}
+		}
+	}
+
+	if left.Kind == FuncKind && len(left.TypeParams) > 0 {
+		return c.instantiate(left, e.TypeArguments, e.Token)
+	}
+	if len(e.TypeArguments) == 1 && len(e.TypeArguments[0].Arguments) == 0 && !e.TypeArguments[0].Nullable {
+		index := Any
+		if t, ok := c.scope.lookup(e.TypeArguments[0].Value); ok {
+			index = t
+		}
+		return c.inferIndex(left, index)
+	}
+	return Any
+}
+
+// instantiate binds the type parameters of a generic function to explicit
+// type arguments.
+func (c *checker) instantiate(fn *Type, args []*ast.TypeExpression, tok token.Token) *Type {
+	if len(args) != len(fn.TypeParams) {
+		c.errorf(tok, "wrong number of type arguments: want %d, got %d", len(fn.TypeParams), len(args))
+		return AnyFn
+	}
+	bindings := make(map[string]*Type)
+	for i, tp := range fn.TypeParams {
+		bindings[tp] = c.resolve(args[i])
+	}
+	inst := subst(fn, bindings)
+	inst.TypeParams = nil
+	return inst
+}
+
+func (c *checker) inferCall(e *ast.CallExpression) *Type {
+	fn := c.infer(e.Function)
+	// Only the positional arguments before any spread one have a known
+	// parameter.
+	var args []*Type
+	var named []*ast.NamedArgument
+	var namedTypes []*Type
+	spread := false
+	for _, arg := range e.Arguments {
+		switch a := arg.(type) {
+		case *ast.SpreadExpression:
+			c.checkSpread(a)
+			spread = true
+		case *ast.NamedArgument:
+			named = append(named, a)
+			namedTypes = append(namedTypes, c.infer(a.Value))
+		default:
+			t := c.infer(arg)
+			if !spread {
+				args = append(args, t)
+			}
+		}
+	}
+
+	if ident, ok := e.Function.(*ast.Identifier); ok {
+		if _, declared := c.scope.lookup(ident.Value); !declared {
+			if result, ok := builtinResults[ident.Value]; ok {
+				return result
+			}
+		}
+	}
+
+	switch fn.Kind {
+	case AnyKind, ParamKind:
+		return Any
+	case UnionKind:
+		if nullable(fn) {
+			c.errorf(tokenOf(e.Function), "cannot call %s, which may be null", fn)
+		}
+		return Any
+	case FuncKind:
+	default:
+		c.errorf(tokenOf(e.Function), "cannot call %s", fn)
+		return Any
+	}
+	if fn.Result == nil {
+		return Any
+	}
+
+	if len(fn.TypeParams) > 0 {
+		if len(e.TypeArguments) > 0 {
+			fn = c.instantiate(fn, e.TypeArguments, e.Token)
+		} else {
+			bindings := make(map[string]*Type)
+			for i, p := range fn.Params {
+				if i < len(args) {
+					unify(p, args[i], fn.TypeParams, bindings)
+				}
+			}
+			// Parameters that could not be inferred accept anything.
+			for _, tp := range fn.TypeParams {
+				if _, ok := bindings[tp]; !ok {
+					bindings[tp] = Any
+				}
+			}
+			fn = subst(fn, bindings)
+		}
+	}
+
+	name := calleeName(e.Function)
+	fixed := len(fn.Params)
+	if fn.Variadic {
+		fixed--
+	}
+	required := fixed - fn.Optional
+	if !spread && len(named) == 0 && (len(args) < required || len(args) > fixed && !fn.Variadic) {
+		c.errorf(tokenOf(e.Function), "wrong number of arguments to %s: want %s, got %d", name, arity(fn), len(args))
+		return fn.Result
+	}
+	for i, arg := range args {
+		p := restElement(fn)
+		if i < fixed {
+			p = fn.Params[i]
+		} else if !fn.Variadic {
+			c.errorf(tokenOf(e.Arguments[i]), "too many arguments to %s: want %s", name, arity(fn))
+			break
+		}
+		if !assignable(p, arg) {
+			c.errorf(tokenOf(e.Arguments[i]), "cannot use %s as %s in argument %d to %s", arg, p, i+1, name)
+		}
+	}
+	if len(named) > 0 && len(fn.ParamNames) == len(fn.Params) {
+		c.checkNamedArguments(fn, name, len(args), named, namedTypes, spread)
+	}
+	return fn.Result
+}
+
+// checkNamedArguments checks the arguments of a call passed by name, after
+// the given positional ones, against the parameters of fn.
+func (c *checker) checkNamedArguments(fn *Type, name string, given int, named []*ast.NamedArgument, types []*Type, spread bool) {
+	fixed := len(fn.Params)
+	if fn.Variadic {
+		fixed--
+	}
+	passed := make([]bool, fixed)
+	for i := 0; i < given && i < fixed; i++ {
+		passed[i] = true
+	}
+	for i, arg := range named {
+		index := -1
+		for j, param := range fn.ParamNames[:fixed] {
+			if param == arg.Name.Value {
+				index = j
+				break
+			}
+		}
+		switch {
+		case index < 0 && fn.Variadic && fn.ParamNames[fixed] == arg.Name.Value:
+			c.errorf(arg.Token, "the rest parameter %s of %s cannot be passed by name", arg.Name.Value, name)
+		case index < 0:
+			c.errorf(arg.Token, "%s has no parameter named %s", name, arg.Name.Value)
+		case passed[index]:
+			c.errorf(arg.Token, "argument %s of %s is given twice", arg.Name.Value, name)
+		default:
+			passed[index] = true
+			if !assignable(fn.Params[index], types[i]) {
+				c.errorf(tokenOf(arg.Value), "cannot use %s as %s in argument %s to %s", types[i], fn.Params[index], arg.Name.Value, name)
+			}
+		}
+	}
+	if spread {
+		return
+	}
+	for i := 0; i < fixed-fn.Optional; i++ {
+		if !passed[i] {
+			c.errorf(named[0].Token, "missing argument %s in call to %s", fn.ParamNames[i], name)
+		}
+	}
+}
+
+// checkSpread checks that a spread argument is an array.
+func (c *checker) checkSpread(e *ast.SpreadExpression) {
+	switch t := c.infer(e.Value); t.Kind {
+	case NullKind, BasicKind, HashKind, FuncKind, NamedKind:
+		c.errorf(tokenOf(e.Value), "cannot spread %s into arguments", t)
+	}
+}
+
+// arity describes how many arguments a call to fn passes.
+func arity(fn *Type) string {
+	fixed := len(fn.Params)
+	if fn.Variadic {
+		return fmt.Sprintf("%d or more", fixed-1-fn.Optional)
+	}
+	if fn.Optional > 0 {
+		return fmt.Sprintf("%d to %d", fixed-fn.Optional, fixed)
+	}
+	return fmt.Sprint(fixed)
+}
+
+// restElement is the type of the arguments the rest parameter of fn
+// collects.
+func restElement(fn *Type) *Type {
+	if !fn.Variadic {
+		return Any
+	}
+	if rest := fn.Params[len(fn.Params)-1]; rest.Kind == ArrayKind {
+		return rest.Args[0]
+	}
+	return Any
+}
+
+func calleeName(fn ast.Expression) string {
+	switch f := fn.(type) {
+	case *ast.Identifier:
+		return f.Value
+	case *ast.IndexExpression:
+		if name, ok := f.Index.(*ast.StringLiteral); ok {
+			return name.Value
+		}
+	case *ast.InstantiatedExpression:
+		return calleeName(f.Left)
+	}
+	return "function"
+}
+
+func (c *checker) inferStructLiteral(e *ast.StructLiteral) *Type {
+	var name string
+	var typeArgs []*ast.TypeExpression
+	switch n := e.Name.(type) {
+	case *ast.Identifier:
+		name = n.Value
+	case *ast.InstantiatedExpression:
+		if ident, ok := n.Left.(*ast.Identifier); ok {
+			name = ident.Value
+			typeArgs = n.TypeArguments
+		}
+	}
+
+	info, ok := c.structs[name]
+	if !ok {
+		for _, v := range e.Fields {
+			c.infer(v)
+		}
+		return Any
+	}
+
+	t := &Type{Kind: NamedKind, Name: name}
+	if len(typeArgs) > 0 {
+		t = c.resolve(&ast.TypeExpression{Token: tokenOf(e.Name), Value: name, Arguments: typeArgs})
+	}
+	for field, value := range e.Fields {
+		actual := c.infer(value)
+		if _, isField := info.fields[field]; !isField {
+			c.errorf(tokenOf(value), "unknown field %s in %s literal", field, name)
+			continue
+		}
+		expected, _ := c.member(t, field)
+		if !assignable(expected, actual) {
+			c.errorf(tokenOf(value), "cannot use %s as %s in field %s of %s", actual, expected, field, name)
+		}
+	}
+	return t
+}
+
+func (c *checker) inferArrow(e *ast.ArrowFunction) *Type {
+	c.push()
+	defer c.pop()
+	sig := c.signature(nil, e.Parameters, e.ReturnType)
+	c.declareParameters(e.Parameters, sig)
+
+	outer := c.result
+	c.result = sig.Result
+	defer func() { c.result = outer }()
+
+	body := c.infer(e.Body)
+	if e.ReturnType == nil {
+		sig.Result = body
+	} else if !assignable(sig.Result, body) {
+		c.errorf(tokenOf(e.Body), "cannot use %s as %s in return", body, sig.Result)
+	}
+	return sig
+}
+
+// tokenOf returns the token an error about expr is reported at: its
+// leftmost token, so that the position points at the start of it.
+func tokenOf(expr ast.Node) token.Token {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		return e.Token
+	case *ast.IntegerLiteral:
+		return e.Token
+	case *ast.FloatLiteral:
+		return e.Token
+	case *ast.StringLiteral:
+		return e.Token
+	case *ast.TemplateLiteral:
+		return e.Token
+	case *ast.Boolean:
+		return e.Token
+	case *ast.Null:
+		return e.Token
+	case *ast.ArrayLiteral:
+		return e.Token
+	case *ast.HashLiteral:
+		return e.Token
+	case *ast.PrefixExpression:
+		return e.Token
+	case *ast.InfixExpression:
+		return tokenOf(e.Left)
+	case *ast.PostfixExpression:
+		return tokenOf(e.Left)
+	case *ast.TernaryExpression:
+		return tokenOf(e.Condition)
+	case *ast.NullishCoalescingExpression:
+		return tokenOf(e.Left)
+	case *ast.OptionalChainingExpression:
+		return tokenOf(e.Left)
+	case *ast.IndexExpression:
+		return tokenOf(e.Left)
+	case *ast.ArrayIndexExpression:
+		return tokenOf(e.Left)
+	case *ast.InstantiatedExpression:
+		return tokenOf(e.Left)
+	case *ast.CallExpression:
+		return tokenOf(e.Function)
+	case *ast.StructLiteral:
+		return tokenOf(e.Name)
+	case *ast.FunctionLiteral:
+		return e.Token
+	case *ast.ArrowFunction:
+		return e.Token
+	case *ast.IfExpression:
+		return e.Token
+	case *ast.SpawnExpression:
+		return e.Token
+	case *ast.AwaitExpression:
+		return e.Token
+	case *ast.YieldExpression:
+		return e.Token
+	}
+	return token.Token{}
+}
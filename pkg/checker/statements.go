@@ -0,0 +1,425 @@
+package checker
+
+import (
+	"jabline/pkg/ast"
+)
+
+func (c *checker) checkStatements(stmts []ast.Statement) {
+	c.declare(stmts)
+	for _, stmt := range stmts {
+		c.checkStatement(stmt)
+	}
+}
+
+func (c *checker) checkBlock(block *ast.BlockStatement) {
+	if block == nil {
+		return
+	}
+	c.push()
+	c.checkStatements(block.Statements)
+	c.pop()
+}
+
+func (c *checker) checkStatement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		c.checkDeclaration(s.Name, s.Type, s.Value)
+	case *ast.ConstStatement:
+		c.checkDeclaration(s.Name, s.Type, s.Value)
+	case *ast.AssignmentStatement:
+		c.checkAssignment(s)
+	case *ast.ExpressionStatement:
+		if s.Expression != nil {
+			c.infer(s.Expression)
+			c.narrowAfter(s.Expression)
+		}
+	case *ast.EchoStatement:
+		for _, v := range s.Values {
+			c.infer(v)
+		}
+	case *ast.ReturnStatement:
+		c.checkReturn(s)
+	case *ast.BlockStatement:
+		c.checkBlock(s)
+	case *ast.FunctionStatement:
+		c.checkFunctionStatement(s)
+	case *ast.AsyncFunctionStatement:
+		c.checkFunction(s.TypeParameters, s.Parameters, s.ReturnType, s.Body, false)
+	case *ast.WhileStatement:
+		c.infer(s.Condition)
+		c.checkBlock(s.Body)
+	case *ast.ForStatement:
+		c.push()
+		if s.Init != nil {
+			c.checkStatement(s.Init)
+		}
+		if s.Condition != nil {
+			c.infer(s.Condition)
+		}
+		if s.Update != nil {
+			c.checkStatement(s.Update)
+		}
+		c.checkBlock(s.Body)
+		c.pop()
+	case *ast.ForEachStatement:
+		c.push()
+		key, value := iterationTypes(c.infer(s.Iterable), s.Key != nil)
+		if s.Key != nil {
+			c.scope.vars[s.Key.Value] = key
+		}
+		c.declareName(s.Variable, value)
+		c.checkBlock(s.Body)
+		c.pop()
+	case *ast.TryStatement:
+		c.checkBlock(s.TryBlock)
+		for _, cc := range s.Catches {
+			c.checkCatch(cc.Param, cc.Block)
+		}
+		c.checkBlock(s.FinallyBlock)
+	case *ast.DeferStatement:
+		c.infer(s.Call)
+	case *ast.RetryStatement:
+		c.infer(s.Attempts)
+		c.checkBlock(s.RetryBlock)
+		c.checkCatch(s.CatchParam, s.CatchBlock)
+	case *ast.ThrowStatement:
+		c.infer(s.Value)
+	case *ast.SwitchStatement:
+		c.infer(s.Expression)
+		for _, cc := range s.Cases {
+			c.infer(cc.Value)
+			c.push()
+			c.checkStatements(cc.Statements)
+			c.pop()
+		}
+		if s.DefaultCase != nil {
+			c.push()
+			c.checkStatements(s.DefaultCase.Statements)
+			c.pop()
+		}
+	case *ast.SelectStatement:
+		for _, sc := range s.Cases {
+			c.infer(sc.Channel)
+			c.infer(sc.Value)
+			if t := c.infer(sc.Timeout); !assignable(Int, t) {
+				c.errorf(tokenOf(sc.Timeout), "timeout of select case must be int milliseconds, got %s", t)
+			}
+			c.push()
+			if sc.Name != nil {
+				c.scope.vars[sc.Name.Value] = Any
+			}
+			c.checkStatements(sc.Statements)
+			c.pop()
+		}
+		if s.DefaultCase != nil {
+			c.push()
+			c.checkStatements(s.DefaultCase.Statements)
+			c.pop()
+		}
+	case *ast.GroupStatement:
+		c.push()
+		if s.Name != nil {
+			c.scope.vars[s.Name.Value] = Any
+		}
+		c.checkStatements(s.Body.Statements)
+		c.pop()
+	case *ast.ServiceStatement:
+		for _, v := range s.Fields {
+			c.infer(v)
+		}
+		for _, m := range s.Methods {
+			c.checkFunction(m.TypeParameters, m.Parameters, m.ReturnType, m.Body, m.Generator)
+		}
+		c.scope.vars[s.Name.Value] = Any
+	case *ast.ImportStatement:
+		c.checkImport(s)
+	case *ast.ExportStatement:
+		if s.Statement != nil {
+			c.checkStatement(s.Statement)
+		}
+	}
+}
+
+func (c *checker) checkDeclaration(name *ast.Identifier, te *ast.TypeExpression, value ast.Expression) {
+	actual := c.infer(value)
+	if te == nil {
+		c.declareName(name, widen(actual))
+		return
+	}
+
+	declared := c.resolve(te)
+	if !assignable(declared, actual) {
+		c.errorf(tokenOf(value), "cannot use %s as %s in declaration of %s", actual, declared, name)
+	}
+	c.declareName(name, declared)
+}
+
+// declareName gives a declared name, or each name its destructuring
+// pattern binds, its type.
+func (c *checker) declareName(name *ast.Identifier, t *Type) {
+	if name.Pattern == nil {
+		c.scope.vars[name.Value] = t
+		return
+	}
+	c.destructure(name.Pattern, t, func(id *ast.Identifier, t *Type) {
+		c.scope.vars[id.Value] = widen(t)
+	})
+}
+
+// destructure passes each name a destructuring pattern binds to bind,
+// along with the type of the part of t it receives.
+func (c *checker) destructure(pattern ast.Pattern, t *Type, bind func(*ast.Identifier, *Type)) {
+	switch p := pattern.(type) {
+	case *ast.BindingPattern:
+		bind(p.Name, t)
+	case *ast.DefaultPattern:
+		c.destructure(p.Target, union(nonNull(t), c.infer(p.Default)), bind)
+	case *ast.ArrayPattern:
+		elem := Any
+		switch t.Kind {
+		case ArrayKind:
+			elem = t.Args[0]
+		case AnyKind, ParamKind:
+		default:
+			c.errorf(p.Token, "cannot destructure %s as an array", t)
+		}
+		for _, el := range p.Elements {
+			c.destructure(el, elem, bind)
+		}
+		if p.Rest != nil {
+			bind(p.Rest, arrayOf(elem))
+		}
+	case *ast.ObjectPattern:
+		for _, prop := range p.Properties {
+			field := Any
+			switch t.Kind {
+			case HashKind:
+				field = t.Args[1]
+			case NamedKind:
+				field = c.memberOf(t, prop.Key.Value, prop.Key.Token)
+			case AnyKind, ParamKind:
+			default:
+				c.errorf(p.Token, "cannot destructure %s by field name", t)
+				return
+			}
+			c.destructure(prop.Value, field, bind)
+		}
+	}
+}
+
+// widen gives an unannotated variable a type that later assignments can
+// reasonably be held to: an initial null says nothing about what the
+// variable will hold.
+func widen(t *Type) *Type {
+	if t.Kind == NullKind {
+		return Any
+	}
+	return t
+}
+
+func (c *checker) checkAssignment(s *ast.AssignmentStatement) {
+	value := c.infer(s.Value)
+
+	switch left := s.Left.(type) {
+	case *ast.Identifier:
+		if left.Pattern != nil {
+			c.destructure(left.Pattern, value, func(id *ast.Identifier, t *Type) {
+				if declared, ok := c.scope.declared(id.Value); ok && !assignable(declared, t) {
+					c.errorf(id.Token, "cannot use %s as %s in assignment to %s", t, declared, id.Value)
+				}
+			})
+			return
+		}
+		declared, ok := c.scope.declared(left.Value)
+		if !ok {
+			return
+		}
+		if !assignable(declared, value) {
+			c.errorf(tokenOf(s.Value), "cannot use %s as %s in assignment to %s", value, declared, left.Value)
+		}
+	default:
+		target := c.infer(s.Left)
+		if !assignable(target, value) {
+			c.errorf(tokenOf(s.Value), "cannot use %s as %s in assignment", value, target)
+		}
+	}
+}
+
+func (c *checker) checkReturn(s *ast.ReturnStatement) {
+	if s.ReturnValue == nil {
+		if c.result != nil && c.result.Kind != AnyKind && !nullable(c.result) {
+			c.errorf(s.Token, "missing return value, expected %s", c.result)
+		}
+		return
+	}
+
+	actual := c.infer(s.ReturnValue)
+	if c.result != nil && !assignable(c.result, actual) {
+		c.errorf(tokenOf(s.ReturnValue), "cannot use %s as %s in return", actual, c.result)
+	}
+}
+
+func (c *checker) checkCatch(param *ast.Identifier, block *ast.BlockStatement) {
+	if block == nil {
+		return
+	}
+	c.push()
+	if param != nil {
+		c.scope.vars[param.Value] = c.resolve(param.Type)
+	}
+	c.checkStatements(block.Statements)
+	c.pop()
+}
+
+func (c *checker) checkImport(s *ast.ImportStatement) {
+	var names []*ast.Identifier
+	if s.DefaultImport != nil {
+		names = append(names, s.DefaultImport)
+	}
+	if s.NamespaceAlias != nil {
+		names = append(names, s.NamespaceAlias)
+	}
+	for _, item := range s.NamedImports {
+		if item.Alias != nil {
+			names = append(names, item.Alias)
+		} else {
+			names = append(names, item.Name)
+		}
+	}
+	for _, name := range names {
+		c.scope.vars[name.Value] = Any
+		c.opaque[name.Value] = true
+	}
+}
+
+func (c *checker) checkFunctionStatement(s *ast.FunctionStatement) {
+	if s.ReceiverName == nil {
+		c.checkFunction(s.TypeParameters, s.Parameters, s.ReturnType, s.Body, s.Generator)
+		return
+	}
+
+	c.push()
+	receiver := Any
+	if _, ok := c.structs[s.ReceiverType.Value]; ok || c.enums[s.ReceiverType.Value] != nil {
+		receiver = &Type{Kind: NamedKind, Name: s.ReceiverType.Value}
+	}
+	c.scope.vars[s.ReceiverName.Value] = receiver
+	c.checkFunction(s.TypeParameters, s.Parameters, s.ReturnType, s.Body, s.Generator)
+	c.pop()
+}
+
+// checkFunction checks a function body against its declared parameter and
+// result types, and returns the function's type. The declared result of a
+// generator is the type of the values it yields; calling it returns the
+// generator.
+func (c *checker) checkFunction(typeParams, params []*ast.Identifier, result *ast.TypeExpression, body *ast.BlockStatement, generator bool) *Type {
+	c.push()
+	defer c.pop()
+	for _, tp := range typeParams {
+		c.scope.typeParams[tp.Value] = true
+	}
+	sig := c.signature(typeParams, params, result)
+	c.declareParameters(params, sig)
+
+	outer, outerYields := c.result, c.yields
+	c.result, c.yields = sig.Result, nil
+	if generator {
+		c.result, c.yields = nil, sig.Result
+		if sig.Result != nil {
+			sig.Result = Any
+		}
+	}
+	c.checkBlock(body)
+	c.result, c.yields = outer, outerYields
+	return sig
+}
+
+// declareParameters declares the parameters of a function with the types
+// of its signature. A default is checked against the type of its
+// parameter, and may refer to the parameters before it.
+func (c *checker) declareParameters(params []*ast.Identifier, sig *Type) {
+	for i, p := range params {
+		if p.Default != nil {
+			if t := c.infer(p.Default); !assignable(sig.Params[i], t) {
+				c.errorf(tokenOf(p.Default), "cannot use %s as %s in default of parameter %s", t, sig.Params[i], p.Value)
+			}
+		}
+		if t := sig.Params[i]; p.Variadic && t.Kind != ArrayKind && t.Kind != AnyKind {
+			c.errorf(p.Token, "the rest parameter %s must be an array, not %s", p.Value, t)
+		}
+		c.declareName(p, sig.Params[i])
+	}
+}
+
+func (c *checker) checkYield(e *ast.YieldExpression) {
+	actual := Null
+	if e.Value != nil {
+		actual = c.infer(e.Value)
+	}
+	if c.yields != nil && !assignable(c.yields, actual) {
+		c.errorf(e.Token, "cannot use %s as %s in yield", actual, c.yields)
+	}
+}
+
+// narrowAfter handles `if (x == null) { return; }`: when the consequence
+// always leaves the block, x is not null in the statements that follow.
+func (c *checker) narrowAfter(expr ast.Expression) {
+	ifExpr, ok := expr.(*ast.IfExpression)
+	if !ok || ifExpr.Alternative != nil || !terminates(ifExpr.Consequence) {
+		return
+	}
+	if name, isNull, ok := nullTest(ifExpr.Condition); ok && isNull {
+		if t, found := c.scope.lookup(name); found {
+			c.scope.narrowed[name] = nonNull(t)
+		}
+	}
+}
+
+func terminates(block *ast.BlockStatement) bool {
+	if block == nil || len(block.Statements) == 0 {
+		return false
+	}
+	switch block.Statements[len(block.Statements)-1].(type) {
+	case *ast.ReturnStatement, *ast.ThrowStatement, *ast.BreakStatement, *ast.ContinueStatement:
+		return true
+	}
+	return false
+}
+
+// nullTest recognises `x == null` and `x != null` (either way round) and
+// reports the variable and whether the condition holds when it is null.
+func nullTest(cond ast.Expression) (name string, isNull bool, ok bool) {
+	infix, ok := cond.(*ast.InfixExpression)
+	if !ok || (infix.Operator != "==" && infix.Operator != "!=") {
+		return "", false, false
+	}
+	ident, ok := infix.Left.(*ast.Identifier)
+	other := infix.Right
+	if !ok {
+		ident, ok = infix.Right.(*ast.Identifier)
+		other = infix.Left
+	}
+	if _, isNullLit := other.(*ast.Null); !ok || !isNullLit {
+		return "", false, false
+	}
+	return ident.Value, infix.Operator == "==", true
+}
+
+// iterationTypes returns the types of the loop variables of a for-in over t.
+// With a single variable, which gets the value, a hash yields its keys.
+func iterationTypes(t *Type, pairs bool) (key, value *Type) {
+	switch t.Kind {
+	case ArrayKind:
+		return Int, t.Args[0]
+	case HashKind:
+		if !pairs {
+			return nil, t.Args[0]
+		}
+		return t.Args[0], t.Args[1]
+	case BasicKind:
+		if t.Name == "string" {
+			return Int, String
+		}
+	}
+	return Any, Any
+}
@@ -0,0 +1,344 @@
+package checker
+
+import (
+	"strings"
+
+	"jabline/pkg/object"
+)
+
+// Kind classifies a Type.
+type Kind int
+
+const (
+	AnyKind Kind = iota
+	NullKind
+	BasicKind // int, float, string, bool and the sized numbers
+	ArrayKind // Args[0] is the element type
+	HashKind  // Args[0] is the key type, Args[1] the value type
+	FuncKind  // Params and Result; a nil Result stands for any function
+	NamedKind // a struct, with its type arguments in Args
+	ParamKind // a type parameter of the enclosing generic declaration
+	UnionKind // Members; T? is the union of T and null
+)
+
+// Type is the static type of an expression.
+type Type struct {
+	Kind       Kind
+	Name       string
+	Args       []*Type
+	Params     []*Type
+	Result     *Type
+	TypeParams []string
+	Members    []*Type
+	// ParamNames, Optional and Variadic describe the parameters of a
+	// declared function: their names, how many of the last ones have a
+	// default, and whether the very last collects the remaining arguments.
+	ParamNames []string
+	Optional   int
+	Variadic   bool
+}
+
+var (
+	Any    = &Type{Kind: AnyKind}
+	Null   = &Type{Kind: NullKind}
+	Int    = basic("int")
+	Float  = basic("float")
+	String = basic("string")
+	Bool   = basic("bool")
+	AnyFn  = &Type{Kind: FuncKind}
+)
+
+func basic(name string) *Type { return &Type{Kind: BasicKind, Name: name} }
+
+func arrayOf(elem *Type) *Type { return &Type{Kind: ArrayKind, Args: []*Type{elem}} }
+
+func hashOf(key, value *Type) *Type { return &Type{Kind: HashKind, Args: []*Type{key, value}} }
+
+var basicNames = map[string]bool{
+	"int": true, "float": true, "string": true, "bool": true,
+	"int8": true, "int16": true, "int32": true, "int64": true,
+	"uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true,
+}
+
+func isSizedInteger(t *Type) bool {
+	if t.Kind != BasicKind {
+		return false
+	}
+	switch t.Name {
+	case "int8", "int16", "int32", "int64", "uint8", "uint16", "uint32", "uint64":
+		return true
+	}
+	return false
+}
+
+func isSizedFloat(t *Type) bool {
+	return t.Kind == BasicKind && (t.Name == "float32" || t.Name == "float64")
+}
+
+func isString(t *Type) bool {
+	return t.Kind == BasicKind && t.Name == "string"
+}
+
+func isNumeric(t *Type) bool {
+	return t.Kind == BasicKind && t.Name != "string" && t.Name != "bool"
+}
+
+func isInteger(t *Type) bool {
+	return t.Kind == BasicKind && (t.Name == "int" || isSizedInteger(t))
+}
+
+// union builds the union of ts, flattening nested unions and dropping
+// duplicates. A union with a single member is that member.
+func union(ts ...*Type) *Type {
+	var members []*Type
+	for _, t := range ts {
+		if t.Kind == AnyKind {
+			return Any
+		}
+		alts := []*Type{t}
+		if t.Kind == UnionKind {
+			alts = t.Members
+		}
+		for _, alt := range alts {
+			dup := false
+			for _, m := range members {
+				if identical(m, alt) {
+					dup = true
+					break
+				}
+			}
+			if !dup {
+				members = append(members, alt)
+			}
+		}
+	}
+	if len(members) == 1 {
+		return members[0]
+	}
+	return &Type{Kind: UnionKind, Members: members}
+}
+
+func nullable(t *Type) bool {
+	switch t.Kind {
+	case NullKind:
+		return true
+	case UnionKind:
+		for _, m := range t.Members {
+			if m.Kind == NullKind {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nonNull removes null from t, as after a `t != null` test.
+func nonNull(t *Type) *Type {
+	if t.Kind != UnionKind {
+		return t
+	}
+	var members []*Type
+	for _, m := range t.Members {
+		if m.Kind != NullKind {
+			members = append(members, m)
+		}
+	}
+	if len(members) == 0 {
+		return Null
+	}
+	return union(members...)
+}
+
+func identical(a, b *Type) bool {
+	return assignable(a, b) && assignable(b, a)
+}
+
+// assignable reports whether a value of type src may be stored where dst
+// is expected. Plain numbers convert to float and to the sized types, as
+// the runtime check does.
+func assignable(dst, src *Type) bool {
+	if dst.Kind == AnyKind || src.Kind == AnyKind {
+		return true
+	}
+	if src.Kind == UnionKind {
+		for _, m := range src.Members {
+			if !assignable(dst, m) {
+				return false
+			}
+		}
+		return true
+	}
+	if dst.Kind == UnionKind {
+		for _, m := range dst.Members {
+			if assignable(m, src) {
+				return true
+			}
+		}
+		return false
+	}
+	if dst.Kind != src.Kind {
+		return false
+	}
+
+	switch dst.Kind {
+	case NullKind:
+		return true
+	case BasicKind:
+		switch {
+		case dst.Name == src.Name:
+			return true
+		case src.Name == "int":
+			return dst.Name == "float" || isSizedInteger(dst) || isSizedFloat(dst)
+		case src.Name == "float":
+			return isSizedFloat(dst)
+		}
+		return false
+	case ArrayKind, HashKind:
+		for i := range dst.Args {
+			if !assignable(dst.Args[i], src.Args[i]) {
+				return false
+			}
+		}
+		return true
+	case FuncKind:
+		if dst.Result == nil || src.Result == nil {
+			return true
+		}
+		return len(dst.Params) == len(src.Params) && assignable(dst.Result, src.Result)
+	case NamedKind:
+		if object.IsErrorKind(dst.Name) && object.IsErrorKind(src.Name) {
+			return (&object.Error{Kind: src.Name}).Is(dst.Name)
+		}
+		if dst.Name != src.Name {
+			return false
+		}
+		if len(dst.Args) != len(src.Args) {
+			// Box and Box[int] are compatible; the arguments of a literal
+			// without explicit ones are not known.
+			return len(dst.Args) == 0 || len(src.Args) == 0
+		}
+		for i := range dst.Args {
+			if !identical(dst.Args[i], src.Args[i]) {
+				return false
+			}
+		}
+		return true
+	case ParamKind:
+		return dst.Name == src.Name
+	}
+	return false
+}
+
+// subst replaces the type parameters in t by their bindings.
+func subst(t *Type, bindings map[string]*Type) *Type {
+	if len(bindings) == 0 || t == nil {
+		return t
+	}
+	switch t.Kind {
+	case ParamKind:
+		if b, ok := bindings[t.Name]; ok {
+			return b
+		}
+		return t
+	case ArrayKind, HashKind, NamedKind:
+		c := *t
+		c.Args = substAll(t.Args, bindings)
+		return &c
+	case FuncKind:
+		c := *t
+		c.Params = substAll(t.Params, bindings)
+		c.Result = subst(t.Result, bindings)
+		return &c
+	case UnionKind:
+		return union(substAll(t.Members, bindings)...)
+	}
+	return t
+}
+
+func substAll(ts []*Type, bindings map[string]*Type) []*Type {
+	out := make([]*Type, len(ts))
+	for i, t := range ts {
+		out[i] = subst(t, bindings)
+	}
+	return out
+}
+
+// unify binds the type parameters of a generic signature found in param
+// to the corresponding parts of arg. The first binding wins; mismatches
+// are reported when the argument is checked against the bound signature.
+func unify(param, arg *Type, typeParams []string, bindings map[string]*Type) {
+	switch param.Kind {
+	case ParamKind:
+		for _, tp := range typeParams {
+			if tp == param.Name {
+				if _, bound := bindings[tp]; !bound && arg.Kind != AnyKind && arg.Kind != NullKind {
+					bindings[tp] = arg
+				}
+				return
+			}
+		}
+	case ArrayKind, HashKind, NamedKind:
+		if arg.Kind == param.Kind && len(arg.Args) == len(param.Args) {
+			for i := range param.Args {
+				unify(param.Args[i], arg.Args[i], typeParams, bindings)
+			}
+		}
+	case UnionKind:
+		for _, m := range param.Members {
+			unify(m, nonNull(arg), typeParams, bindings)
+		}
+	}
+}
+
+func (t *Type) String() string {
+	switch t.Kind {
+	case AnyKind:
+		return "any"
+	case NullKind:
+		return "null"
+	case ArrayKind:
+		return "Array[" + t.Args[0].String() + "]"
+	case HashKind:
+		return "Hash[" + t.Args[0].String() + ", " + t.Args[1].String() + "]"
+	case FuncKind:
+		if t.Result == nil {
+			return "fn"
+		}
+		params := make([]string, len(t.Params))
+		for i, p := range t.Params {
+			params[i] = p.String()
+		}
+		if t.Variadic {
+			params[len(params)-1] = "..." + params[len(params)-1]
+		}
+		return "fn(" + strings.Join(params, ", ") + "): " + t.Result.String()
+	case NamedKind:
+		if len(t.Args) == 0 {
+			return t.Name
+		}
+		args := make([]string, len(t.Args))
+		for i, a := range t.Args {
+			args[i] = a.String()
+		}
+		return t.Name + "[" + strings.Join(args, ", ") + "]"
+	case UnionKind:
+		var alts []string
+		hasNull := false
+		for _, m := range t.Members {
+			if m.Kind == NullKind {
+				hasNull = true
+				continue
+			}
+			alts = append(alts, m.String())
+		}
+		if hasNull && len(alts) == 1 {
+			return alts[0] + "?"
+		}
+		if hasNull {
+			alts = append(alts, "null")
+		}
+		return strings.Join(alts, " | ")
+	}
+	return t.Name
+}
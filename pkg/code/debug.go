@@ -6,3 +6,15 @@ type SourcePos struct {
 }
 
 type SourceMap map[int]SourcePos
+
+// Lookup returns the position of the instruction at ip, or of the closest
+// instruction before it. Caller frames save an ip that points at the operand
+// of their OpCall rather than at the opcode, so an exact match is not enough.
+func (sm SourceMap) Lookup(ip int) (SourcePos, bool) {
+	for ; ip >= 0; ip-- {
+		if pos, ok := sm[ip]; ok {
+			return pos, true
+		}
+	}
+	return SourcePos{}, false
+}
@@ -65,4 +65,22 @@ var definitions = map[Opcode]*Definition{
 	OpRecvChannel:       {"OpRecvChannel", []int{}},
 	OpCurrentClosure:    {"OpCurrentClosure", []int{}},
 	OpInstantiate:       {"OpInstantiate", []int{1}},
+	OpConcat:            {"OpConcat", []int{2}},
+	OpYield:             {"OpYield", []int{}},
+	OpGetIterator:       {"OpGetIterator", []int{}},
+	OpIterNext:          {"OpIterNext", []int{}},
+	OpIterNextPair:      {"OpIterNextPair", []int{}},
+	OpDefer:             {"OpDefer", []int{1}},
+	OpIsType:            {"OpIsType", []int{2}},
+	OpMatchValue:        {"OpMatchValue", []int{}},
+	OpMatchRange:        {"OpMatchRange", []int{1}},
+	OpMatchArray:        {"OpMatchArray", []int{2, 1}},
+	OpMatchHash:         {"OpMatchHash", []int{1}},
+	OpArrayRest:         {"OpArrayRest", []int{2}},
+	OpCallArgs:          {"OpCallArgs", []int{2, 2}},
+	OpSelect:            {"OpSelect", []int{2, 1}},
+	OpRecvChannelOk:     {"OpRecvChannelOk", []int{}},
+	OpGroup:             {"OpGroup", []int{}},
+	OpGroupEnd:          {"OpGroupEnd", []int{}},
+	OpJumpNotMissing:    {"OpJumpNotMissing", []int{2}},
 }
@@ -1,5 +1,11 @@
 package code
 
+// InstructionSetVersion identifies the opcode numbering and operand layout
+// understood by this VM. It must be bumped whenever an opcode is added or
+// the meaning of one changes; a VM refuses bytecode built for any other
+// instruction set.
+const InstructionSetVersion = 11
+
 type Opcode byte
 
 const (
@@ -75,4 +81,42 @@ const (
 	OpRecvChannel
 	OpCurrentClosure
 	OpInstantiate
+	OpConcat
+
+	// Instruction set 2: generators and the iterator protocol of for-in.
+	OpYield
+	OpGetIterator
+	OpIterNext
+
+	// Instruction set 3: two variable for-in.
+	OpIterNextPair
+
+	// Instruction set 4: defer.
+	OpDefer
+
+	// Instruction set 5: typed catch clauses.
+	OpIsType
+
+	// Instruction set 6: pattern matching.
+	OpMatchValue
+	OpMatchRange
+	OpMatchArray
+	OpMatchHash
+	OpArrayRest
+
+	// Instruction set 7: spread and named arguments.
+	OpCallArgs
+
+	// Instruction set 8: select over channels.
+	OpSelect
+
+	// Instruction set 9: receive reporting whether the channel is open.
+	OpRecvChannelOk
+
+	// Instruction set 10: task groups.
+	OpGroup
+	OpGroupEnd
+
+	// Instruction set 11: defaults of parameters left out by a call.
+	OpJumpNotMissing
 )
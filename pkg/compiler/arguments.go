@@ -0,0 +1,84 @@
+package compiler
+
+import (
+	"fmt"
+
+	"jabline/pkg/ast"
+	"jabline/pkg/code"
+	"jabline/pkg/object"
+	"jabline/pkg/symbol"
+)
+
+// compileParameterDefault gives the parameter in sym its default when the
+// call left it out, which the VM marks apart from an explicit null.
+func (c *Compiler) compileParameterDefault(sym symbol.Symbol, def ast.Expression) error {
+	c.emit(code.OpGetLocal, sym.Index)
+	jumpNotMissing := c.emit(code.OpJumpNotMissing, 9999)
+	c.emit(code.OpPop)
+	if err := c.Compile(def); err != nil {
+		return err
+	}
+	c.changeOperand(jumpNotMissing, len(c.currentInstructions()))
+	c.emit(code.OpSetLocal, sym.Index)
+	return nil
+}
+
+// describeParameters records on fn what a call needs to know about its
+// parameters besides their number.
+func describeParameters(fn *object.CompiledFunction, receiver *ast.Identifier, params []*ast.Identifier) {
+	if receiver != nil {
+		fn.ParameterNames = append(fn.ParameterNames, receiver.Value)
+	}
+	for _, p := range params {
+		fn.ParameterNames = append(fn.ParameterNames, p.Value)
+		if p.Default != nil {
+			fn.NumOptional++
+		}
+	}
+	_, fn.Variadic = ast.ParameterArity(params)
+}
+
+// plainArguments reports whether args are all passed by position.
+func plainArguments(args []ast.Expression) bool {
+	for _, arg := range args {
+		switch arg.(type) {
+		case *ast.SpreadExpression, *ast.NamedArgument:
+			return false
+		}
+	}
+	return true
+}
+
+// compileArguments pushes the arguments of a call and emits it. Calls
+// with spread or named arguments, or too many for the operand of OpCall,
+// use OpCallArgs, whose shape constant says for each value on the stack
+// whether it is positional (""), an array to spread ("...") or the
+// argument of the parameter it names.
+func (c *Compiler) compileArguments(args []ast.Expression) error {
+	plain := plainArguments(args) && len(args) <= 255
+	if !plain && len(args) > 65535 {
+		return fmt.Errorf("too many arguments in call: %d", len(args))
+	}
+
+	shape := make([]object.Object, len(args))
+	for i, arg := range args {
+		kind := ""
+		switch a := arg.(type) {
+		case *ast.SpreadExpression:
+			kind, arg = "...", a.Value
+		case *ast.NamedArgument:
+			kind, arg = a.Name.Value, a.Value
+		}
+		if err := c.Compile(arg); err != nil {
+			return err
+		}
+		shape[i] = &object.String{Value: kind}
+	}
+
+	if plain {
+		c.emit(code.OpCall, len(args))
+		return nil
+	}
+	c.emit(code.OpCallArgs, len(args), c.addConstant(&object.Array{Elements: shape}))
+	return nil
+}
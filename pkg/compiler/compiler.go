@@ -20,6 +20,11 @@ type Compiler struct {
 	currentNode        ast.Node
 	exports            map[string]int
 	expectedReturnType string
+
+	// enums holds the enums declared so far, to check that a match on one
+	// covers all its variants and gives each the right number of fields.
+	enums    map[string]*ast.EnumStatement
+	warnings []string
 }
 
 type LoopScope struct {
@@ -28,12 +33,22 @@ type LoopScope struct {
 	ContinueJumps []int // Jumps to patch for continue statements
 }
 
+// TryScope is a try statement being compiled. A return, break or continue
+// leaving it must first end its handler and run its finally block.
+type TryScope struct {
+	Finally    *ast.BlockStatement
+	HasHandler bool // an OpTry handler is open
+	LoopIndex  int  // the loop the try statement is in
+	Group      bool // a group statement, whose tasks must end first
+}
+
 type CompilationScope struct {
 	instructions        code.Instructions
 	sourceMap           code.SourceMap
 	lastInstruction     EmittedInstruction
 	previousInstruction EmittedInstruction
 	expectedReturnType  string
+	tries               []TryScope
 }
 
 type EmittedInstruction struct {
@@ -63,6 +78,7 @@ func New() *Compiler {
 		loops:       []LoopScope{},
 		loopIndex:   -1,
 		exports:     make(map[string]int),
+		enums:       make(map[string]*ast.EnumStatement),
 	}
 
 	return c
@@ -81,12 +97,21 @@ type Bytecode struct {
 	SymbolTable  *symbol.SymbolTable // Corrected
 	SourceMap    code.SourceMap
 	Exports      map[string]int
+	// File is the source path of a bundled module.
+	File string
+	// Modules holds the imports bundled into a built program, keyed by the
+	// import path as written in the source.
+	Modules map[string]*Bytecode
 }
 
 func (c *Compiler) currentInstructions() code.Instructions {
 	return c.scopes[c.scopeIndex].instructions
 }
 
+func (c *Compiler) currentSourceMap() code.SourceMap {
+	return c.scopes[c.scopeIndex].sourceMap
+}
+
 func (c *Compiler) setInstructions(ins code.Instructions) {
 	c.scopes[c.scopeIndex].instructions = ins
 }
@@ -114,6 +139,10 @@ func (c *Compiler) emit(op code.Opcode, operands ...int) int {
 	ins := code.Make(op, operands...)
 	pos := c.addInstruction(ins)
 
+	if srcPos, ok := nodePosition(c.currentNode); ok {
+		c.scopes[c.scopeIndex].sourceMap[pos] = srcPos
+	}
+
 	c.setLastInstruction(op, pos)
 
 	return pos
@@ -222,9 +251,49 @@ func (c *Compiler) leaveLoop() LoopScope {
 	return loop
 }
 
-func (c *Compiler) Compile(node ast.Node) error {
+func (c *Compiler) enterTry(finally *ast.BlockStatement, hasHandler bool) {
+	scope := &c.scopes[c.scopeIndex]
+	scope.tries = append(scope.tries, TryScope{Finally: finally, HasHandler: hasHandler, LoopIndex: c.loopIndex})
+}
+
+func (c *Compiler) leaveTry() {
+	scope := &c.scopes[c.scopeIndex]
+	scope.tries = scope.tries[:len(scope.tries)-1]
+}
+
+// leaveTries emits what a jump out of the try statements entered inside
+// loop loopIndex needs, innermost first: an OpEndTry for each open handler
+// and a copy of each finally block. A finally block is compiled as if the
+// try statements it belongs to had already been left. Pass -1 to leave all
+// of them, as a return does.
+func (c *Compiler) leaveTries(loopIndex int) error {
+	tries := c.scopes[c.scopeIndex].tries
+	defer func() { c.scopes[c.scopeIndex].tries = tries }()
+
+	for i := len(tries) - 1; i >= 0 && tries[i].LoopIndex >= loopIndex; i-- {
+		c.scopes[c.scopeIndex].tries = tries[:i:i]
+		if tries[i].HasHandler {
+			c.emit(code.OpEndTry)
+		}
+		if tries[i].Group {
+			c.emit(code.OpNull)
+			c.emitGroupEnd()
+		}
+		if tries[i].Finally != nil {
+			if err := c.Compile(tries[i].Finally); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
+func (c *Compiler) Compile(node ast.Node) error {
+	// Instructions emitted after a child node has been compiled still belong
+	// to this node, so restore it on the way out.
+	prevNode := c.currentNode
 	c.currentNode = node
+	defer func() { c.currentNode = prevNode }()
 
 	switch node := node.(type) {
 	case *ast.Program:
@@ -265,6 +334,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 		return c.compileStructStatement(node)
 	case *ast.ThrowStatement:
 		return c.compileThrowStatement(node)
+	case *ast.DeferStatement:
+		return c.compileDeferStatement(node)
 	case *ast.TryStatement:
 		return c.compileTryStatement(node)
 	case *ast.RetryStatement:
@@ -273,6 +344,10 @@ func (c *Compiler) Compile(node ast.Node) error {
 		return c.compileServiceStatement(node)
 	case *ast.SwitchStatement:
 		return c.compileSwitchStatement(node)
+	case *ast.SelectStatement:
+		return c.compileSelectStatement(node)
+	case *ast.GroupStatement:
+		return c.compileGroupStatement(node)
 	case *ast.EnumStatement:
 		return c.compileEnumStatement(node)
 	case *ast.ConstStatement:
@@ -292,6 +367,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 		return c.compileNullishCoalescingExpression(node)
 	case *ast.OptionalChainingExpression:
 		return c.compileOptionalChainingExpression(node)
+	case *ast.MatchExpression:
+		return c.compileMatchExpression(node)
 	case *ast.TernaryExpression:
 		return c.compileTernaryExpression(node)
 	case *ast.Identifier:
@@ -334,6 +411,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 		return c.compileAsyncFunctionStatement(node)
 	case *ast.AwaitExpression:
 		return c.compileAwaitExpression(node)
+	case *ast.YieldExpression:
+		return c.compileYieldExpression(node)
 	case *ast.SpawnExpression:
 		return c.compileSpawnExpression(node)
 
@@ -7,6 +7,7 @@ import (
 	"jabline/pkg/lexer"
 	"jabline/pkg/object"
 	"jabline/pkg/parser"
+	"strings"
 	"testing"
 )
 
@@ -70,6 +71,119 @@ func TestEnumCompilation(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestTemplateLiteralCompilation(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "`sum: ${1 + 2}!`",
+			expectedConstants: []interface{}{"sum: ", int64(1), int64(2), "!"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpAdd),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpConcat, 3),
+			},
+		},
+		{
+			input:             "`${1}`",
+			expectedConstants: []interface{}{int64(1)},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConcat, 1),
+			},
+		},
+		{
+			input:             "``",
+			expectedConstants: []interface{}{""},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConcat, 1),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestSizedTypeChecking(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`int8(1) + int8(2)`, ""},
+		{`let a = int8(1); a * 2`, ""},
+		{`float32(1) + 0.5`, ""},
+		{`let a = uint16(1); a << int8(3)`, ""},
+		{`let a: uint8 = 200; a`, ""},
+		{`int8(1) + int16(2)`, "mismatched types int8 and int16 for operator +"},
+		{`let a = uint32(1); let b = int32(2); a < b`, "mismatched types uint32 and int32 for operator <"},
+		{`int8(1) * 1.5`, "mismatched types int8 and float for operator *"},
+		{`float32(1) == float64(1)`, "mismatched types float32 and float64 for operator =="},
+		{`int8(1) + 300`, "constant 300 overflows int8"},
+		{`let a: int8 = int16(1);`, "type mismatch: expected int8, got int16"},
+	}
+
+	for _, tt := range tests {
+		err := New().Compile(parse(tt.input))
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: unexpected error: %s", tt.input, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: want error %q, got %v", tt.input, tt.err, err)
+		}
+	}
+}
+
+func TestMatchExhaustivenessWarning(t *testing.T) {
+	const color = "enum Color { Red, Green, Blue }\n"
+	const result = "enum Result { Ok(value), Err(error) }\n"
+	tests := []struct {
+		input   string
+		warning string
+	}{
+		{color + `match (Color.Red) { Color.Red => 1, Color.Green => 2 }`, "line 2, column 1: non-exhaustive match on enum Color: missing Blue"},
+		{color + `match (Color.Red) { Color.Red => 1, Color.Green if true => 2 }`, "missing Green, Blue"},
+		{color + `match (Color.Red) { Color.Red => 1, Color.Green => 2, Color.Blue => 3 }`, ""},
+		{color + `match (Color.Red) { Color.Red => 1, _ => 2 }`, ""},
+		{color + `match (Color.Red) { Color.Red => 1, c => c }`, ""},
+		{`match (1) { 1 => "one" }`, ""},
+		{result + `match (Result.Ok(1)) { Result.Ok(1) => 1, Result.Err(e) => 2 }`, "missing Ok"},
+		{result + `match (Result.Ok(1)) { Result.Ok(1) => 1, Result.Ok(v) => v, Result.Err(_) => 2 }`, ""},
+		{result + `match (Result.Ok(1)) { Result.Ok => 1, Result.Err(e) => e }`, ""},
+	}
+
+	for _, tt := range tests {
+		c := New()
+		if err := c.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("%s: compile error: %s", tt.input, err)
+		}
+		warnings := c.Warnings()
+		switch {
+		case tt.warning == "" && len(warnings) > 0:
+			t.Errorf("%s: unexpected warnings %v", tt.input, warnings)
+		case tt.warning != "" && (len(warnings) != 1 || !strings.Contains(warnings[0], tt.warning)):
+			t.Errorf("%s: want warning %q, got %v", tt.input, tt.warning, warnings)
+		}
+	}
+}
+
+func TestVariantPatternErrors(t *testing.T) {
+	const shape = "enum Shape { Circle(r), Rect(w, h), Empty }\n"
+	tests := map[string]string{
+		shape + `match (Shape.Empty) { Shape.Rect(w) => w }`:   "wrong number of fields in pattern for Shape.Rect: want 2, got 1",
+		shape + `match (Shape.Empty) { Shape.Empty(x) => x }`:  "variant Shape.Empty has no fields to match",
+		shape + `match (Shape.Empty) { Shape.Square(s) => s }`: "enum Shape has no variant Square",
+	}
+
+	for input, want := range tests {
+		err := New().Compile(parse(input))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: want error %q, got %v", input, want, err)
+		}
+	}
+}
+
 func runCompilerTests(t *testing.T, tests []compilerTestCase) {
 	t.Helper()
 
@@ -8,7 +8,34 @@ import (
 	"jabline/pkg/symbol" // New import
 )
 
-func (c *Compiler) compileTemplateLiteral(node *ast.TemplateLiteral) error { return nil }
+func (c *Compiler) compileTemplateLiteral(node *ast.TemplateLiteral) error {
+	numParts := 0
+
+	for i, part := range node.Parts {
+		if part != "" {
+			c.emit(code.OpConstant, c.addConstant(&object.String{Value: part}))
+			numParts++
+		}
+
+		if i < len(node.Expressions) {
+			if err := c.Compile(node.Expressions[i]); err != nil {
+				return err
+			}
+			numParts++
+		}
+	}
+
+	if numParts == 0 {
+		c.emit(code.OpConstant, c.addConstant(&object.String{Value: ""}))
+		numParts++
+	}
+
+	// OpConcat always runs, even for a single part, so that a lone
+	// interpolation like `${n}` still evaluates to a string.
+	c.emit(code.OpConcat, numParts)
+	return nil
+}
+
 func (c *Compiler) compileNullishCoalescingExpression(node *ast.NullishCoalescingExpression) error {
 	if err := c.Compile(node.Left); err != nil {
 		return err
@@ -162,6 +189,10 @@ func (c *Compiler) compilePrefixExpression(node *ast.PrefixExpression) error {
 }
 
 func (c *Compiler) compileInfixExpression(node *ast.InfixExpression) error {
+	if err := c.checkInfixTypes(node); err != nil {
+		return err
+	}
+
 	if node.Operator == "<" {
 		// Reorder operands for < because we only have OpGreaterThan
 		if err := c.Compile(node.Right); err != nil {
@@ -284,9 +315,7 @@ func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
 	}
 
 	// If there is an `else`, we need to jump over it if the `if` was true
-	if c.lastInstructionIs(code.OpPop) {
-		c.removeLastPop()
-	}
+	c.keepBranchValue()
 
 	// Emit an `OpJump` with a bogus value
 	jumpPos := c.emit(code.OpJump, 9999)
@@ -301,9 +330,7 @@ func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
 		if err := c.Compile(node.Alternative); err != nil {
 			return err
 		}
-		if c.lastInstructionIs(code.OpPop) {
-			c.removeLastPop()
-		}
+		c.keepBranchValue()
 	}
 
 	afterAlternativePos := len(c.currentInstructions())
@@ -312,18 +339,25 @@ func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
 	return nil
 }
 
+// keepBranchValue leaves the value of the branch just compiled on the
+// stack: that of its final expression statement, or null if it ends with
+// another kind of statement, such as an assignment or a loop.
+func (c *Compiler) keepBranchValue() {
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+}
+
 func (c *Compiler) compileCallExpression(node *ast.CallExpression) error {
 	if err := c.Compile(node.Function); err != nil {
 		return err
 	}
 
-	for _, arg := range node.Arguments {
-		if err := c.Compile(arg); err != nil {
-			return err
-		}
+	if err := c.compileArguments(node.Arguments); err != nil {
+		return err
 	}
-
-	c.emit(code.OpCall, len(node.Arguments))
 	// c.emit(code.OpPop) // Removed to prevent stack underflow if needed, but standard is to keep it if it's expression statement.
 	// Actually, CallExpression is an expression. It pushes a value.
 	// If it is used as a statement, compileExpressionStatement emits OpPop.
@@ -333,8 +367,8 @@ func (c *Compiler) compileCallExpression(node *ast.CallExpression) error {
 
 func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral) error {
 	returnType := ""
-	if node.ReturnType != nil {
-		returnType = node.ReturnType.Value
+	if node.ReturnType != nil && !node.Generator {
+		returnType = node.ReturnType.String()
 	}
 
 	c.enterScopeWithType(returnType)
@@ -346,9 +380,17 @@ func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral) error {
 	for _, p := range node.Parameters {
 		paramType := ""
 		if p.Type != nil {
-			paramType = p.Type.Value
+			paramType = p.Type.String()
+		}
+		sym := c.symbolTable.DefineWithType(p.Value, paramType)
+		if p.Default != nil {
+			if err := c.compileParameterDefault(sym, p.Default); err != nil {
+				return err
+			}
 		}
-		c.symbolTable.DefineWithType(p.Value, paramType)
+	}
+	if err := c.destructureParameters(node.Parameters); err != nil {
+		return err
 	}
 
 	if err := c.Compile(node.Body); err != nil {
@@ -364,6 +406,8 @@ func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral) error {
 
 	freeSymbols := c.symbolTable.FreeSymbols
 	numLocals := c.symbolTable.NumDefinitions() // Access via getter // Corrected
+	localNames, freeNames := c.symbolTable.LocalNames(), c.symbolTable.FreeNames()
+	sourceMap := c.currentSourceMap()
 	instructions := c.leaveScope()
 
 	for _, s := range freeSymbols {
@@ -381,9 +425,14 @@ func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral) error {
 
 	compiledFn := &object.CompiledFunction{
 		Instructions:  instructions,
+		SourceMap:     sourceMap,
 		NumLocals:     numLocals,
+		LocalNames:    localNames,
+		FreeNames:     freeNames,
 		NumParameters: len(node.Parameters),
+		IsGenerator:   node.Generator,
 	}
+	describeParameters(compiledFn, nil, node.Parameters)
 	c.emit(code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
 
 	return nil
@@ -392,7 +441,7 @@ func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral) error {
 func (c *Compiler) compileAsyncFunctionLiteral(node *ast.AsyncFunctionLiteral) error {
 	returnType := ""
 	if node.ReturnType != nil {
-		returnType = node.ReturnType.Value
+		returnType = node.ReturnType.String()
 	}
 
 	c.enterScopeWithType(returnType)
@@ -404,9 +453,17 @@ func (c *Compiler) compileAsyncFunctionLiteral(node *ast.AsyncFunctionLiteral) e
 	for _, p := range node.Parameters {
 		paramType := ""
 		if p.Type != nil {
-			paramType = p.Type.Value
+			paramType = p.Type.String()
+		}
+		sym := c.symbolTable.DefineWithType(p.Value, paramType)
+		if p.Default != nil {
+			if err := c.compileParameterDefault(sym, p.Default); err != nil {
+				return err
+			}
 		}
-		c.symbolTable.DefineWithType(p.Value, paramType)
+	}
+	if err := c.destructureParameters(node.Parameters); err != nil {
+		return err
 	}
 
 	if err := c.Compile(node.Body); err != nil {
@@ -422,6 +479,8 @@ func (c *Compiler) compileAsyncFunctionLiteral(node *ast.AsyncFunctionLiteral) e
 
 	freeSymbols := c.symbolTable.FreeSymbols
 	numLocals := c.symbolTable.NumDefinitions()
+	localNames, freeNames := c.symbolTable.LocalNames(), c.symbolTable.FreeNames()
+	sourceMap := c.currentSourceMap()
 	instructions := c.leaveScope()
 
 	for _, s := range freeSymbols {
@@ -444,11 +503,15 @@ func (c *Compiler) compileAsyncFunctionLiteral(node *ast.AsyncFunctionLiteral) e
 
 	compiledFn := &object.CompiledFunction{
 		Instructions:   instructions,
+		SourceMap:      sourceMap,
 		NumLocals:      numLocals,
+		LocalNames:     localNames,
+		FreeNames:      freeNames,
 		NumParameters:  len(node.Parameters),
 		IsAsync:        true,
 		TypeParameters: typeParams,
 	}
+	describeParameters(compiledFn, nil, node.Parameters)
 	c.emit(code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
 
 	return nil
@@ -457,7 +520,7 @@ func (c *Compiler) compileAsyncFunctionLiteral(node *ast.AsyncFunctionLiteral) e
 func (c *Compiler) compileArrowFunction(node *ast.ArrowFunction) error {
 	returnType := ""
 	if node.ReturnType != nil {
-		returnType = node.ReturnType.Value
+		returnType = node.ReturnType.String()
 	}
 
 	c.enterScopeWithType(returnType)
@@ -465,9 +528,17 @@ func (c *Compiler) compileArrowFunction(node *ast.ArrowFunction) error {
 	for _, p := range node.Parameters {
 		paramType := ""
 		if p.Type != nil {
-			paramType = p.Type.Value
+			paramType = p.Type.String()
+		}
+		sym := c.symbolTable.DefineWithType(p.Value, paramType)
+		if p.Default != nil {
+			if err := c.compileParameterDefault(sym, p.Default); err != nil {
+				return err
+			}
 		}
-		c.symbolTable.DefineWithType(p.Value, paramType)
+	}
+	if err := c.destructureParameters(node.Parameters); err != nil {
+		return err
 	}
 
 	// For arrow functions, the body is an expression
@@ -485,6 +556,8 @@ func (c *Compiler) compileArrowFunction(node *ast.ArrowFunction) error {
 
 	freeSymbols := c.symbolTable.FreeSymbols
 	numLocals := c.symbolTable.NumDefinitions()
+	localNames, freeNames := c.symbolTable.LocalNames(), c.symbolTable.FreeNames()
+	sourceMap := c.currentSourceMap()
 	instructions := c.leaveScope()
 
 	for _, s := range freeSymbols {
@@ -502,14 +575,33 @@ func (c *Compiler) compileArrowFunction(node *ast.ArrowFunction) error {
 
 	compiledFn := &object.CompiledFunction{
 		Instructions:  instructions,
+		SourceMap:     sourceMap,
 		NumLocals:     numLocals,
+		LocalNames:    localNames,
+		FreeNames:     freeNames,
 		NumParameters: len(node.Parameters),
 	}
+	describeParameters(compiledFn, nil, node.Parameters)
 	c.emit(code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
 
 	return nil
 }
 
+func (c *Compiler) compileYieldExpression(node *ast.YieldExpression) error {
+	if c.scopeIndex == 0 {
+		return fmt.Errorf("yield outside of a function")
+	}
+	if node.Value != nil {
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+	} else {
+		c.emit(code.OpNull)
+	}
+	c.emit(code.OpYield)
+	return nil
+}
+
 func (c *Compiler) compileAwaitExpression(node *ast.AwaitExpression) error {
 	if err := c.Compile(node.Value); err != nil {
 		return err
@@ -524,6 +616,10 @@ func (c *Compiler) compileSpawnExpression(node *ast.SpawnExpression) error {
 		return err
 	}
 
+	if !plainArguments(node.Call.Arguments) {
+		return fmt.Errorf("spawn does not take spread or named arguments")
+	}
+
 	// Compile arguments, pushing to stack.
 	for _, a := range node.Call.Arguments {
 		if err := c.Compile(a); err != nil {
@@ -17,9 +17,10 @@ func (c *Compiler) compileFunctionStatement(node *ast.FunctionStatement) error {
 		fnName = node.Name.Value
 	}
 
+	// The return type of a generator is the type of the values it yields.
 	returnType := ""
-	if node.ReturnType != nil {
-		returnType = node.ReturnType.Value
+	if node.ReturnType != nil && !node.Generator {
+		returnType = node.ReturnType.String()
 	}
 
 	outerSym := c.symbolTable.DefineWithType(fnName, returnType) // Define the function name in the outer scope.
@@ -41,18 +42,27 @@ func (c *Compiler) compileFunctionStatement(node *ast.FunctionStatement) error {
 	for _, p := range node.Parameters {
 		paramType := ""
 		if p.Type != nil {
-			paramType = p.Type.Value
+			paramType = p.Type.String()
 		}
 		sym := c.symbolTable.DefineWithType(p.Value, paramType)
+		if p.Default != nil {
+			if err := c.compileParameterDefault(sym, p.Default); err != nil {
+				return err
+			}
+		}
 
 		// If the parameter has a type annotation, insert runtime check
 		if p.Type != nil {
-			typeIdx := c.addConstant(&object.String{Value: p.Type.Value})
+			typeIdx := c.addConstant(&object.String{Value: p.Type.String()})
 			c.emit(code.OpGetLocal, sym.Index)
 			c.emit(code.OpCheckType, typeIdx)
-			c.emit(code.OpPop) // CheckType inspects the top of stack, Pop removes it
+			// CheckType may have converted the argument to a sized type
+			c.emit(code.OpSetLocal, sym.Index)
 		}
 	}
+	if err := c.destructureParameters(node.Parameters); err != nil {
+		return err
+	}
 
 	if err := c.Compile(node.Body); err != nil {
 		return err
@@ -67,7 +77,9 @@ func (c *Compiler) compileFunctionStatement(node *ast.FunctionStatement) error {
 
 	freeSymbols := c.symbolTable.FreeSymbols
 	numLocals := c.symbolTable.NumDefinitions() // Access via getter
-	instructions := c.leaveScope()              // Exit the function's scope
+	localNames, freeNames := c.symbolTable.LocalNames(), c.symbolTable.FreeNames()
+	sourceMap := c.currentSourceMap()
+	instructions := c.leaveScope() // Exit the function's scope
 
 	for _, s := range freeSymbols {
 		switch s.Scope {
@@ -94,11 +106,16 @@ func (c *Compiler) compileFunctionStatement(node *ast.FunctionStatement) error {
 
 	compiledFn := &object.CompiledFunction{
 		Instructions:   instructions,
+		SourceMap:      sourceMap,
 		NumLocals:      numLocals,
+		LocalNames:     localNames,
+		FreeNames:      freeNames,
 		NumParameters:  numParams,
+		IsGenerator:    node.Generator,
 		Name:           fnName,
 		TypeParameters: typeParams,
 	}
+	describeParameters(compiledFn, node.ReceiverName, node.Parameters)
 	// Emits the closure onto the stack
 	c.emit(code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
 
@@ -121,7 +138,7 @@ func (c *Compiler) compileFunctionStatement(node *ast.FunctionStatement) error {
 func (c *Compiler) compileAsyncFunctionStatement(node *ast.AsyncFunctionStatement) error {
 	returnType := ""
 	if node.ReturnType != nil {
-		returnType = node.ReturnType.Value
+		returnType = node.ReturnType.String()
 	}
 
 	outerSym := c.symbolTable.DefineWithType(node.Name.Value, returnType)
@@ -136,17 +153,25 @@ func (c *Compiler) compileAsyncFunctionStatement(node *ast.AsyncFunctionStatemen
 	for _, p := range node.Parameters {
 		paramType := ""
 		if p.Type != nil {
-			paramType = p.Type.Value
+			paramType = p.Type.String()
 		}
 		sym := c.symbolTable.DefineWithType(p.Value, paramType)
+		if p.Default != nil {
+			if err := c.compileParameterDefault(sym, p.Default); err != nil {
+				return err
+			}
+		}
 
 		if p.Type != nil {
-			typeIdx := c.addConstant(&object.String{Value: p.Type.Value})
+			typeIdx := c.addConstant(&object.String{Value: p.Type.String()})
 			c.emit(code.OpGetLocal, sym.Index)
 			c.emit(code.OpCheckType, typeIdx)
-			c.emit(code.OpPop)
+			c.emit(code.OpSetLocal, sym.Index)
 		}
 	}
+	if err := c.destructureParameters(node.Parameters); err != nil {
+		return err
+	}
 
 	if err := c.Compile(node.Body); err != nil {
 		return err
@@ -161,6 +186,8 @@ func (c *Compiler) compileAsyncFunctionStatement(node *ast.AsyncFunctionStatemen
 
 	freeSymbols := c.symbolTable.FreeSymbols
 	numLocals := c.symbolTable.NumDefinitions()
+	localNames, freeNames := c.symbolTable.LocalNames(), c.symbolTable.FreeNames()
+	sourceMap := c.currentSourceMap()
 	instructions := c.leaveScope()
 
 	for _, s := range freeSymbols {
@@ -174,12 +201,16 @@ func (c *Compiler) compileAsyncFunctionStatement(node *ast.AsyncFunctionStatemen
 
 	compiledFn := &object.CompiledFunction{
 		Instructions:   instructions,
+		SourceMap:      sourceMap,
 		NumLocals:      numLocals,
+		LocalNames:     localNames,
+		FreeNames:      freeNames,
 		NumParameters:  len(node.Parameters),
 		IsAsync:        true,
 		Name:           node.Name.Value,
 		TypeParameters: typeParams,
 	}
+	describeParameters(compiledFn, nil, node.Parameters)
 	c.emit(code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
 
 	if outerSym.Scope == symbol.GlobalScope {
@@ -202,12 +233,19 @@ func (c *Compiler) compileReturnStatement(node *ast.ReturnStatement) error {
 		if err := c.Compile(node.ReturnValue); err != nil {
 			return err
 		}
+		// The value stays on the stack while enclosing finally blocks run
+		if err := c.leaveTries(-1); err != nil {
+			return err
+		}
 		c.emit(code.OpReturnValue)
 	} else {
 		// If return type is expected but no value provided
 		if c.expectedReturnType != "" && c.expectedReturnType != "any" {
 			return fmt.Errorf("compile error: return type mismatch - expected %s, got void", c.expectedReturnType)
 		}
+		if err := c.leaveTries(-1); err != nil {
+			return err
+		}
 		c.emit(code.OpReturn)
 	}
 	return nil
@@ -0,0 +1,443 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"jabline/pkg/ast"
+	"jabline/pkg/code"
+	"jabline/pkg/object"
+	"jabline/pkg/symbol"
+)
+
+// Warnings returns what the compiler found suspicious but still compiled,
+// such as a match on an enum that misses some of its variants.
+func (c *Compiler) Warnings() []string {
+	return c.warnings
+}
+
+func (c *Compiler) warn(node ast.Node, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if pos, ok := nodePosition(node); ok {
+		msg = fmt.Sprintf("line %d, column %d: %s", pos.Line, pos.Column, msg)
+	}
+	c.warnings = append(c.warnings, msg)
+}
+
+// compileMatchExpression tests the arms in order. The subject is kept in a
+// variable the patterns read from, and the names an arm binds are only
+// defined until the end of the arm.
+func (c *Compiler) compileMatchExpression(node *ast.MatchExpression) error {
+	if err := c.Compile(node.Subject); err != nil {
+		return err
+	}
+	subject := c.symbolTable.Define("$$match$$")
+	c.setSymbol(subject)
+
+	var endJumps []int
+	for _, arm := range node.Arms {
+		type shadowed struct {
+			sym symbol.Symbol
+			ok  bool
+		}
+		saved := map[string]shadowed{}
+		for _, name := range ast.PatternBindings(arm.Pattern) {
+			if _, done := saved[name.Value]; !done {
+				sym, ok := c.symbolTable.Lookup(name.Value)
+				saved[name.Value] = shadowed{sym, ok}
+			}
+		}
+
+		fails, err := c.compilePattern(arm.Pattern, func() { c.getSymbol(subject) })
+		if err != nil {
+			return err
+		}
+		if arm.Guard != nil {
+			if err := c.Compile(arm.Guard); err != nil {
+				return err
+			}
+			fails = append(fails, c.emit(code.OpJumpNotTruthy, 9999))
+		}
+
+		if err := c.Compile(arm.Body); err != nil {
+			return err
+		}
+		c.keepBranchValue()
+		endJumps = append(endJumps, c.emit(code.OpJump, 9999))
+
+		for _, pos := range fails {
+			c.changeOperand(pos, len(c.currentInstructions()))
+		}
+		for name, s := range saved {
+			c.symbolTable.Restore(name, s.sym, s.ok)
+		}
+	}
+	c.emit(code.OpNull)
+
+	for _, pos := range endJumps {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+	c.checkExhaustive(node)
+	return nil
+}
+
+// compilePattern emits the tests of a pattern against the value load
+// pushes, binding the names it holds. It returns the jumps to take when
+// the value does not match.
+func (c *Compiler) compilePattern(pattern ast.Pattern, load func()) ([]int, error) {
+	switch p := pattern.(type) {
+	case *ast.WildcardPattern:
+		return nil, nil
+
+	case *ast.BindingPattern:
+		load()
+		c.setSymbol(c.symbolTable.Define(p.Name.Value))
+		return nil, nil
+
+	case *ast.ValuePattern:
+		load()
+		if err := c.Compile(p.Value); err != nil {
+			return nil, err
+		}
+		c.emit(code.OpMatchValue)
+		return []int{c.emit(code.OpJumpNotTruthy, 9999)}, nil
+
+	case *ast.RangePattern:
+		load()
+		if err := c.Compile(p.Low); err != nil {
+			return nil, err
+		}
+		if err := c.Compile(p.High); err != nil {
+			return nil, err
+		}
+		inclusive := 0
+		if p.Inclusive {
+			inclusive = 1
+		}
+		c.emit(code.OpMatchRange, inclusive)
+		return []int{c.emit(code.OpJumpNotTruthy, 9999)}, nil
+
+	case *ast.ArrayPattern:
+		load()
+		hasRest := 0
+		if p.HasRest {
+			hasRest = 1
+		}
+		c.emit(code.OpMatchArray, len(p.Elements), hasRest)
+		fails := []int{c.emit(code.OpJumpNotTruthy, 9999)}
+
+		for i, el := range p.Elements {
+			index := c.addConstant(&object.Integer{Value: int64(i)})
+			elFails, err := c.compilePattern(el, func() {
+				load()
+				c.emit(code.OpConstant, index)
+				c.emit(code.OpIndex)
+			})
+			if err != nil {
+				return nil, err
+			}
+			fails = append(fails, elFails...)
+		}
+		if p.Rest != nil {
+			load()
+			c.emit(code.OpArrayRest, len(p.Elements))
+			c.setSymbol(c.symbolTable.Define(p.Rest.Value))
+		}
+		return fails, nil
+
+	case *ast.HashPattern:
+		if len(p.Entries) > 255 {
+			return nil, fmt.Errorf("hash pattern has more than 255 keys")
+		}
+		keys := make([]int, len(p.Entries))
+		load()
+		for i, e := range p.Entries {
+			switch k := e.Key.(type) {
+			case *ast.StringLiteral:
+				keys[i] = c.addConstant(&object.String{Value: k.Value})
+			case *ast.IntegerLiteral:
+				keys[i] = c.addConstant(&object.Integer{Value: k.Value})
+			default:
+				return nil, fmt.Errorf("hash pattern key %s is not a string or integer", e.Key)
+			}
+			c.emit(code.OpConstant, keys[i])
+		}
+		c.emit(code.OpMatchHash, len(p.Entries))
+		fails := []int{c.emit(code.OpJumpNotTruthy, 9999)}
+
+		for i, e := range p.Entries {
+			key := keys[i]
+			entryFails, err := c.compilePattern(e.Value, func() {
+				load()
+				c.emit(code.OpConstant, key)
+				c.emit(code.OpIndex)
+			})
+			if err != nil {
+				return nil, err
+			}
+			fails = append(fails, entryFails...)
+		}
+		return fails, nil
+
+	case *ast.StructPattern:
+		load()
+		c.emit(code.OpIsType, c.addConstant(&object.String{Value: p.Name.Value}))
+		fails := []int{c.emit(code.OpJumpNotTruthy, 9999)}
+
+		for _, f := range p.Fields {
+			name := c.addConstant(&object.String{Value: f.Name.Value})
+			fieldFails, err := c.compilePattern(f.Pattern, func() {
+				load()
+				c.emit(code.OpConstant, name)
+				c.emit(code.OpIndex)
+			})
+			if err != nil {
+				return nil, err
+			}
+			fails = append(fails, fieldFails...)
+		}
+		return fails, nil
+
+	case *ast.VariantPattern:
+		if err := c.checkVariantPattern(p); err != nil {
+			return nil, err
+		}
+		load()
+		if err := c.Compile(p.Variant); err != nil {
+			return nil, err
+		}
+		c.emit(code.OpMatchValue)
+		fails := []int{c.emit(code.OpJumpNotTruthy, 9999)}
+
+		for i, arg := range p.Arguments {
+			index := c.addConstant(&object.Integer{Value: int64(i)})
+			argFails, err := c.compilePattern(arg, func() {
+				load()
+				c.emit(code.OpConstant, index)
+				c.emit(code.OpIndex)
+			})
+			if err != nil {
+				return nil, err
+			}
+			fails = append(fails, argFails...)
+		}
+		return fails, nil
+	}
+	return nil, fmt.Errorf("unknown pattern %T", pattern)
+}
+
+// checkExhaustive warns about a match on the variants of an enum declared
+// in this file that leaves some of them out and has no arm matching
+// anything else.
+func (c *Compiler) checkExhaustive(node *ast.MatchExpression) {
+	enum := ""
+	covered := map[string]bool{}
+	for _, arm := range node.Arms {
+		switch p := arm.Pattern.(type) {
+		case *ast.WildcardPattern, *ast.BindingPattern:
+			if arm.Guard == nil {
+				return
+			}
+		case *ast.ValuePattern:
+			name, variant, ok := enumVariant(p.Value)
+			if !ok || enum != "" && name != enum {
+				continue
+			}
+			if _, known := c.enums[name]; !known {
+				continue
+			}
+			enum = name
+			if arm.Guard == nil {
+				covered[variant] = true
+			}
+		case *ast.VariantPattern:
+			name, variant, ok := enumVariant(p.Variant)
+			if !ok || enum != "" && name != enum {
+				continue
+			}
+			if _, known := c.enums[name]; !known {
+				continue
+			}
+			enum = name
+			if arm.Guard == nil && matchesAny(p.Arguments) {
+				covered[variant] = true
+			}
+		}
+	}
+	if enum == "" {
+		return
+	}
+
+	var missing []string
+	for _, variant := range c.enums[enum].Values {
+		if !covered[variant.Value] {
+			missing = append(missing, variant.Value)
+		}
+	}
+	if len(missing) > 0 {
+		c.warn(node, "non-exhaustive match on enum %s: missing %s", enum, strings.Join(missing, ", "))
+	}
+}
+
+// matchesAny reports whether the patterns match any value, so that a
+// variant pattern made of them covers the whole variant.
+func matchesAny(patterns []ast.Pattern) bool {
+	for _, p := range patterns {
+		switch p.(type) {
+		case *ast.WildcardPattern, *ast.BindingPattern:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// checkVariantPattern checks a variant pattern on an enum declared in this
+// file against the variant: it must have fields, one for each pattern.
+func (c *Compiler) checkVariantPattern(p *ast.VariantPattern) error {
+	name, variant, ok := enumVariant(p.Variant)
+	if !ok || c.enums[name] == nil {
+		return nil
+	}
+	enum := c.enums[name]
+	for i, v := range enum.Values {
+		if v.Value != variant {
+			continue
+		}
+		fields := enum.VariantFields(i)
+		switch {
+		case fields == nil:
+			return fmt.Errorf("variant %s.%s has no fields to match", name, variant)
+		case len(fields) != len(p.Arguments):
+			return fmt.Errorf("wrong number of fields in pattern for %s.%s: want %d, got %d", name, variant, len(fields), len(p.Arguments))
+		}
+		return nil
+	}
+	return fmt.Errorf("enum %s has no variant %s", name, variant)
+}
+
+// enumVariant splits a pattern value of the form Enum.Variant.
+func enumVariant(e ast.Expression) (string, string, bool) {
+	idx, ok := e.(*ast.IndexExpression)
+	if !ok {
+		return "", "", false
+	}
+	name, ok := idx.Left.(*ast.Identifier)
+	variant, ok2 := idx.Index.(*ast.StringLiteral)
+	if !ok || !ok2 {
+		return "", "", false
+	}
+	return name.Value, variant.Value, true
+}
+
+// compileDestructured compiles the value a declaration or assignment
+// destructures with pattern, if any. A receive destructured into two
+// names, as in `let [v, ok] = <-ch`, gives the value received and whether
+// the channel was still open.
+func (c *Compiler) compileDestructured(pattern ast.Pattern, value ast.Expression) error {
+	arr, isArray := pattern.(*ast.ArrayPattern)
+	recv, isRecv := value.(*ast.PrefixExpression)
+	if !isArray || len(arr.Elements) != 2 || arr.HasRest || !isRecv || recv.Operator != "<-" {
+		return c.Compile(value)
+	}
+	if err := c.Compile(recv.Right); err != nil {
+		return err
+	}
+	c.emit(code.OpRecvChannelOk)
+	return nil
+}
+
+// destructure binds the names of a destructuring pattern to the parts of
+// the value held in sym. bind stores the value on top of the stack in one
+// of the names.
+func (c *Compiler) destructure(pattern ast.Pattern, sym symbol.Symbol, bind func(*ast.Identifier) error) error {
+	return c.compileDestructuring(pattern, func() { c.getSymbol(sym) }, bind)
+}
+
+// destructureParameters unpacks the parameters declared as patterns, once
+// all of them have their slots.
+func (c *Compiler) destructureParameters(params []*ast.Identifier) error {
+	for _, p := range params {
+		if p.Pattern == nil {
+			continue
+		}
+		sym, _ := c.symbolTable.Resolve(p.Value)
+		if err := c.destructure(p.Pattern, sym, c.defineName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defineName declares a variable holding the value on top of the stack.
+func (c *Compiler) defineName(ident *ast.Identifier) error {
+	c.setSymbol(c.symbolTable.Define(ident.Value))
+	return nil
+}
+
+// compileDestructuring reads each part of the value load pushes straight
+// from it by index or name, without building any intermediate value.
+func (c *Compiler) compileDestructuring(pattern ast.Pattern, load func(), bind func(*ast.Identifier) error) error {
+	switch p := pattern.(type) {
+	case *ast.WildcardPattern:
+		return nil
+
+	case *ast.BindingPattern:
+		load()
+		return bind(p.Name)
+
+	case *ast.DefaultPattern:
+		load()
+		jumpNotNull := c.emit(code.OpJumpNotNull, 9999)
+		c.emit(code.OpPop)
+		if err := c.Compile(p.Default); err != nil {
+			return err
+		}
+		c.changeOperand(jumpNotNull, len(c.currentInstructions()))
+
+		if target, ok := p.Target.(*ast.BindingPattern); ok {
+			return bind(target.Name)
+		}
+		value := c.symbolTable.Define("$$default$$")
+		c.setSymbol(value)
+		return c.destructure(p.Target, value, bind)
+
+	case *ast.ArrayPattern:
+		load()
+		c.emit(code.OpCheckType, c.addConstant(&object.String{Value: "array"}))
+		c.emit(code.OpPop)
+
+		for i, el := range p.Elements {
+			index := c.addConstant(&object.Integer{Value: int64(i)})
+			err := c.compileDestructuring(el, func() {
+				load()
+				c.emit(code.OpConstant, index)
+				c.emit(code.OpIndex)
+			}, bind)
+			if err != nil {
+				return err
+			}
+		}
+		if p.Rest != nil {
+			load()
+			c.emit(code.OpArrayRest, len(p.Elements))
+			return bind(p.Rest)
+		}
+		return nil
+
+	case *ast.ObjectPattern:
+		for _, prop := range p.Properties {
+			key := c.addConstant(&object.String{Value: prop.Key.Value})
+			err := c.compileDestructuring(prop.Value, func() {
+				load()
+				c.emit(code.OpConstant, key)
+				c.emit(code.OpIndex)
+			}, bind)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%s cannot be destructured into", pattern)
+}
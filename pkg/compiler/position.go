@@ -0,0 +1,31 @@
+package compiler
+
+import (
+	"jabline/pkg/ast"
+	"jabline/pkg/code"
+	"jabline/pkg/token"
+	"reflect"
+)
+
+var tokenType = reflect.TypeOf(token.Token{})
+
+// nodePosition returns the source position of a node's leading token. AST
+// nodes carry their token in a field named Token; nodes without one (such as
+// *ast.Program) have no position.
+func nodePosition(node ast.Node) (code.SourcePos, bool) {
+	v := reflect.ValueOf(node)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return code.SourcePos{}, false
+	}
+
+	field := v.Elem().FieldByName("Token")
+	if !field.IsValid() || field.Type() != tokenType {
+		return code.SourcePos{}, false
+	}
+
+	tok := field.Interface().(token.Token)
+	if tok.Line == 0 {
+		return code.SourcePos{}, false
+	}
+	return code.SourcePos{Line: tok.Line, Column: tok.Column}, true
+}
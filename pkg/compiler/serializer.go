@@ -2,62 +2,642 @@ package compiler
 
 import (
 	"bytes"
-	"encoding/gob"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"sort"
+
 	"jabline/pkg/code"
 	"jabline/pkg/object"
 )
 
-type SerializableBytecode struct {
-	Instructions code.Instructions
-	Constants    []object.Object
-	SourceMap    code.SourceMap
+// Compiled programs are stored in the .jbc format:
+//
+//	magic        4 bytes  "\x7fJBC"
+//	format       uint16   FormatVersion
+//	isa          uint16   code.InstructionSetVersion the program was built for
+//	payload      ...      instructions, source map, exports, constants,
+//	                      bundled modules (each file once, with a payload
+//	                      of its own without modules, then every import
+//	                      path naming one of them by index)
+//	checksum     uint32   CRC-32 (IEEE) of everything before it
+//
+// Fixed-width fields are little endian. Inside the payload, lengths and
+// integers are varints and every constant is prefixed by a one byte tag, so
+// the decoder never needs outside type information.
+
+// FormatVersion is the version of the .jbc container layout. Files of any
+// other version are rejected and must be rebuilt.
+const FormatVersion = 7
+
+var Magic = []byte{0x7f, 'J', 'B', 'C'}
+
+const (
+	headerSize   = 8
+	checksumSize = 4
+)
+
+const (
+	tagNull byte = iota
+	tagBoolean
+	tagInteger
+	tagFloat
+	tagString
+	tagInt8
+	tagInt16
+	tagInt32
+	tagInt64
+	tagUInt8
+	tagUInt16
+	tagUInt32
+	tagUInt64
+	tagFloat32
+	tagFloat64
+	tagArray
+	tagHash
+	tagStruct
+	tagCompiledFunction
+	tagEnumVariant
+	tagEnumValue
+)
+
+var ErrInvalidBytecode = errors.New("invalid bytecode")
+
+// IsSerialized reports whether data starts with the .jbc magic number.
+func IsSerialized(data []byte) bool {
+	return bytes.HasPrefix(data, Magic)
 }
 
+// Serialize encodes bytecode in the .jbc format.
 func Serialize(b *Bytecode) ([]byte, error) {
+	e := &encoder{}
+	e.buf.Write(Magic)
+	binary.Write(&e.buf, binary.LittleEndian, uint16(FormatVersion))
+	binary.Write(&e.buf, binary.LittleEndian, uint16(code.InstructionSetVersion))
 
-	registerTypes()
+	if err := e.payload(b); err != nil {
+		return nil, err
+	}
 
-	sb := SerializableBytecode{
-		Instructions: b.Instructions,
-		Constants:    b.Constants,
-		SourceMap:    b.SourceMap,
+	names := make([]string, 0, len(b.Modules))
+	for name := range b.Modules {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-	err := enc.Encode(sb)
-	if err != nil {
-		return nil, err
+	// Import paths naming the same file share one module, so it is written
+	// once and the paths refer to it by index.
+	var files []*Bytecode
+	index := make(map[string]int, len(names))
+	for _, name := range names {
+		module := b.Modules[name]
+		if _, ok := index[module.File]; !ok {
+			index[module.File] = len(files)
+			files = append(files, module)
+		}
+	}
+	e.uvarint(uint64(len(files)))
+	for _, module := range files {
+		e.string(module.File)
+		if err := e.payload(module); err != nil {
+			return nil, fmt.Errorf("module '%s': %w", module.File, err)
+		}
+	}
+	e.uvarint(uint64(len(names)))
+	for _, name := range names {
+		e.string(name)
+		e.uvarint(uint64(index[b.Modules[name].File]))
 	}
-	return buf.Bytes(), nil
+
+	binary.Write(&e.buf, binary.LittleEndian, crc32.ChecksumIEEE(e.buf.Bytes()))
+	return e.buf.Bytes(), nil
 }
 
+// Deserialize decodes bytecode produced by Serialize. It rejects data with a
+// bad checksum, an unknown format version or an instruction set other than
+// the one this VM implements.
 func Deserialize(data []byte) (*Bytecode, error) {
-	registerTypes()
+	if len(data) < headerSize+checksumSize || !bytes.Equal(data[:len(Magic)], Magic) {
+		return nil, fmt.Errorf("%w: missing .jbc header", ErrInvalidBytecode)
+	}
 
-	var sb SerializableBytecode
-	buf := bytes.NewBuffer(data)
-	dec := gob.NewDecoder(buf)
-	err := dec.Decode(&sb)
-	if err != nil {
-		return nil, err
+	body := data[:len(data)-checksumSize]
+	sum := binary.LittleEndian.Uint32(data[len(data)-checksumSize:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidBytecode)
+	}
+
+	format := binary.LittleEndian.Uint16(data[4:6])
+	if format != FormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d (this VM reads %d)", ErrInvalidBytecode, format, FormatVersion)
+	}
+	isa := binary.LittleEndian.Uint16(data[6:8])
+	if isa != code.InstructionSetVersion {
+		return nil, fmt.Errorf("%w: built for instruction set %d, this VM runs %d", ErrInvalidBytecode, isa, code.InstructionSetVersion)
+	}
+
+	d := &decoder{data: body, pos: headerSize}
+	b := d.payload()
+
+	d.modules(b)
+
+	if d.err == nil && d.pos != len(d.data) {
+		d.fail("%d trailing bytes", len(d.data)-d.pos)
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return b, nil
+}
+
+// modules reads the bundled modules section into b.Modules, giving every
+// import path of one file the same module.
+func (d *decoder) modules(b *Bytecode) {
+	files := make([]*Bytecode, d.count())
+	for i := 0; i < len(files) && d.err == nil; i++ {
+		file := d.string()
+		files[i] = d.payload()
+		files[i].File = file
+	}
+	n := d.count()
+	if n == 0 || d.err != nil {
+		return
+	}
+	b.Modules = make(map[string]*Bytecode, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		name := d.string()
+		at := d.uvarint()
+		if at >= uint64(len(files)) {
+			d.fail("module '%s' refers to file %d of %d", name, at, len(files))
+			return
+		}
+		b.Modules[name] = files[at]
+	}
+}
+
+func (e *encoder) payload(b *Bytecode) error {
+	e.bytes(b.Instructions)
+	e.sourceMap(b.SourceMap)
+
+	exports := make([]string, 0, len(b.Exports))
+	for name := range b.Exports {
+		exports = append(exports, name)
 	}
+	sort.Strings(exports)
+	e.uvarint(uint64(len(exports)))
+	for _, name := range exports {
+		e.string(name)
+		e.varint(int64(b.Exports[name]))
+	}
+
+	e.uvarint(uint64(len(b.Constants)))
+	for _, c := range b.Constants {
+		if err := e.object(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	return &Bytecode{
-		Instructions: sb.Instructions,
-		Constants:    sb.Constants,
-		SourceMap:    sb.SourceMap,
-	}, nil
+type encoder struct {
+	buf bytes.Buffer
 }
 
-func registerTypes() {
+func (e *encoder) uvarint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	e.buf.Write(tmp[:binary.PutUvarint(tmp[:], v)])
+}
+
+func (e *encoder) varint(v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	e.buf.Write(tmp[:binary.PutVarint(tmp[:], v)])
+}
 
-	gob.Register(&object.Integer{})
-	gob.Register(&object.String{})
-	gob.Register(&object.Boolean{})
-	gob.Register(&object.Null{})
-	gob.Register(&object.CompiledFunction{})
-	gob.Register(&object.Closure{})
-	gob.Register(&object.Channel{})
+func (e *encoder) bytes(b []byte) {
+	e.uvarint(uint64(len(b)))
+	e.buf.Write(b)
+}
+
+func (e *encoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+func (e *encoder) bool(v bool) {
+	if v {
+		e.buf.WriteByte(1)
+	} else {
+		e.buf.WriteByte(0)
+	}
+}
+
+func (e *encoder) float(v float64) {
+	binary.Write(&e.buf, binary.LittleEndian, math.Float64bits(v))
+}
+
+func (e *encoder) strings(list []string) {
+	e.uvarint(uint64(len(list)))
+	for _, s := range list {
+		e.string(s)
+	}
+}
+
+func (e *encoder) sourceMap(sm code.SourceMap) {
+	ips := make([]int, 0, len(sm))
+	for ip := range sm {
+		ips = append(ips, ip)
+	}
+	sort.Ints(ips)
 
+	e.uvarint(uint64(len(ips)))
+	for _, ip := range ips {
+		pos := sm[ip]
+		e.uvarint(uint64(ip))
+		e.varint(int64(pos.Line))
+		e.varint(int64(pos.Column))
+	}
+}
+
+func (e *encoder) object(obj object.Object) error {
+	switch o := obj.(type) {
+	case *object.Null:
+		e.buf.WriteByte(tagNull)
+	case *object.Boolean:
+		e.buf.WriteByte(tagBoolean)
+		e.bool(o.Value)
+	case *object.Integer:
+		e.buf.WriteByte(tagInteger)
+		e.varint(o.Value)
+	case *object.Float:
+		e.buf.WriteByte(tagFloat)
+		e.float(o.Value)
+	case *object.String:
+		e.buf.WriteByte(tagString)
+		e.string(o.Value)
+	case *object.Int8:
+		e.buf.WriteByte(tagInt8)
+		e.varint(int64(o.Value))
+	case *object.Int16:
+		e.buf.WriteByte(tagInt16)
+		e.varint(int64(o.Value))
+	case *object.Int32:
+		e.buf.WriteByte(tagInt32)
+		e.varint(int64(o.Value))
+	case *object.Int64:
+		e.buf.WriteByte(tagInt64)
+		e.varint(o.Value)
+	case *object.UInt8:
+		e.buf.WriteByte(tagUInt8)
+		e.uvarint(uint64(o.Value))
+	case *object.UInt16:
+		e.buf.WriteByte(tagUInt16)
+		e.uvarint(uint64(o.Value))
+	case *object.UInt32:
+		e.buf.WriteByte(tagUInt32)
+		e.uvarint(uint64(o.Value))
+	case *object.UInt64:
+		e.buf.WriteByte(tagUInt64)
+		e.uvarint(o.Value)
+	case *object.Float32:
+		e.buf.WriteByte(tagFloat32)
+		binary.Write(&e.buf, binary.LittleEndian, math.Float32bits(o.Value))
+	case *object.Float64:
+		e.buf.WriteByte(tagFloat64)
+		e.float(o.Value)
+	case *object.Array:
+		e.buf.WriteByte(tagArray)
+		e.uvarint(uint64(len(o.Elements)))
+		for _, el := range o.Elements {
+			if err := e.object(el); err != nil {
+				return err
+			}
+		}
+	case *object.Hash:
+		e.buf.WriteByte(tagHash)
+		keys := make([]object.HashKey, 0, len(o.Pairs))
+		for k := range o.Pairs {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].Type != keys[j].Type {
+				return keys[i].Type < keys[j].Type
+			}
+			return keys[i].Value < keys[j].Value
+		})
+		e.uvarint(uint64(len(keys)))
+		for _, k := range keys {
+			pair := o.Pairs[k]
+			if err := e.object(pair.Key); err != nil {
+				return err
+			}
+			if err := e.object(pair.Value); err != nil {
+				return err
+			}
+		}
+	case *object.Struct:
+		e.buf.WriteByte(tagStruct)
+		e.string(o.Name)
+		e.strings(o.TypeParameters)
+		names := make([]string, 0, len(o.Fields))
+		for name := range o.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		e.uvarint(uint64(len(names)))
+		for _, name := range names {
+			e.string(name)
+			e.string(o.Fields[name])
+		}
+	case *object.CompiledFunction:
+		e.buf.WriteByte(tagCompiledFunction)
+		e.bytes(o.Instructions)
+		e.uvarint(uint64(o.NumLocals))
+		e.uvarint(uint64(o.NumParameters))
+		e.sourceMap(o.SourceMap)
+		e.string(o.File)
+		e.bool(o.IsAsync)
+		e.string(o.Name)
+		e.strings(o.TypeParameters)
+		e.strings(o.LocalNames)
+		e.strings(o.FreeNames)
+		e.bool(o.IsGenerator)
+		e.strings(o.ParameterNames)
+		e.uvarint(uint64(o.NumOptional))
+		e.bool(o.Variadic)
+	case *object.EnumVariant:
+		e.buf.WriteByte(tagEnumVariant)
+		e.string(o.Enum)
+		e.string(o.Name)
+		e.strings(o.Fields)
+		e.strings(o.Types)
+	case *object.EnumValue:
+		e.buf.WriteByte(tagEnumValue)
+		if err := e.object(o.Variant); err != nil {
+			return err
+		}
+		e.uvarint(uint64(len(o.Values)))
+		for _, value := range o.Values {
+			if err := e.object(value); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot serialize constant of type %s", obj.Type())
+	}
+	return nil
+}
+
+// decoder reads the payload. The first error is kept and every later read
+// returns a zero value, so callers only need to check err once.
+type decoder struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (d *decoder) payload() *Bytecode {
+	b := &Bytecode{}
+	b.Instructions = d.bytes()
+	b.SourceMap = d.sourceMap()
+
+	if n := d.count(); n > 0 {
+		b.Exports = make(map[string]int, n)
+		for i := 0; i < n; i++ {
+			name := d.string()
+			b.Exports[name] = int(d.varint())
+		}
+	}
+
+	n := d.count()
+	b.Constants = make([]object.Object, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		b.Constants = append(b.Constants, d.object())
+	}
+	return b
+}
+
+func (d *decoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w: %s at offset %d", ErrInvalidBytecode, fmt.Sprintf(format, args...), d.pos)
+	}
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.data)-d.pos {
+		d.fail("unexpected end of data")
+		return nil
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *decoder) byte() byte {
+	b := d.take(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		d.fail("malformed varint")
+		return 0
+	}
+	d.pos += n
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.data[d.pos:])
+	if n <= 0 {
+		d.fail("malformed varint")
+		return 0
+	}
+	d.pos += n
+	return v
+}
+
+// count reads a length prefix. Every counted item takes at least one byte,
+// so a count larger than the remaining data is rejected before allocating.
+func (d *decoder) count() int {
+	n := d.uvarint()
+	if n > uint64(len(d.data)-d.pos) {
+		d.fail("length %d exceeds remaining data", n)
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) bytes() []byte {
+	b := d.take(d.count())
+	if len(b) == 0 {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+func (d *decoder) string() string {
+	return string(d.take(d.count()))
+}
+
+func (d *decoder) bool() bool {
+	return d.byte() != 0
+}
+
+func (d *decoder) float() float64 {
+	b := d.take(8)
+	if b == nil {
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+func (d *decoder) strings() []string {
+	n := d.count()
+	list := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		list = append(list, d.string())
+	}
+	return list
+}
+
+func (d *decoder) sourceMap() code.SourceMap {
+	n := d.count()
+	if n == 0 {
+		return nil
+	}
+	sm := make(code.SourceMap, n)
+	for i := 0; i < n; i++ {
+		ip := int(d.uvarint())
+		line := int(d.varint())
+		column := int(d.varint())
+		sm[ip] = code.SourcePos{Line: line, Column: column}
+	}
+	return sm
+}
+
+func (d *decoder) object() object.Object {
+	switch tag := d.byte(); tag {
+	case tagNull:
+		return object.NullValue
+	case tagBoolean:
+		if d.bool() {
+			return object.TrueValue
+		}
+		return object.FalseValue
+	case tagInteger:
+		return &object.Integer{Value: d.varint()}
+	case tagFloat:
+		return &object.Float{Value: d.float()}
+	case tagString:
+		return &object.String{Value: d.string()}
+	case tagInt8:
+		return &object.Int8{Value: int8(d.varint())}
+	case tagInt16:
+		return &object.Int16{Value: int16(d.varint())}
+	case tagInt32:
+		return &object.Int32{Value: int32(d.varint())}
+	case tagInt64:
+		return &object.Int64{Value: d.varint()}
+	case tagUInt8:
+		return &object.UInt8{Value: uint8(d.uvarint())}
+	case tagUInt16:
+		return &object.UInt16{Value: uint16(d.uvarint())}
+	case tagUInt32:
+		return &object.UInt32{Value: uint32(d.uvarint())}
+	case tagUInt64:
+		return &object.UInt64{Value: d.uvarint()}
+	case tagFloat32:
+		b := d.take(4)
+		if b == nil {
+			return nil
+		}
+		return &object.Float32{Value: math.Float32frombits(binary.LittleEndian.Uint32(b))}
+	case tagFloat64:
+		return &object.Float64{Value: d.float()}
+	case tagArray:
+		n := d.count()
+		elements := make([]object.Object, 0, n)
+		for i := 0; i < n && d.err == nil; i++ {
+			elements = append(elements, d.object())
+		}
+		return &object.Array{Elements: elements}
+	case tagHash:
+		n := d.count()
+		pairs := make(map[object.HashKey]object.HashPair, n)
+		for i := 0; i < n && d.err == nil; i++ {
+			key := d.object()
+			value := d.object()
+			hashable, ok := key.(object.Hashable)
+			if !ok {
+				if d.err == nil {
+					d.fail("unusable hash key")
+				}
+				return nil
+			}
+			pairs[hashable.HashKey()] = object.HashPair{Key: key, Value: value}
+		}
+		return &object.Hash{Pairs: pairs}
+	case tagStruct:
+		s := &object.Struct{Name: d.string(), TypeParameters: d.strings()}
+		n := d.count()
+		s.Fields = make(map[string]string, n)
+		for i := 0; i < n; i++ {
+			name := d.string()
+			s.Fields[name] = d.string()
+		}
+		return s
+	case tagCompiledFunction:
+		fn := &object.CompiledFunction{
+			Instructions:   d.bytes(),
+			NumLocals:      int(d.uvarint()),
+			NumParameters:  int(d.uvarint()),
+			SourceMap:      d.sourceMap(),
+			File:           d.string(),
+			IsAsync:        d.bool(),
+			Name:           d.string(),
+			TypeParameters: d.strings(),
+		}
+		fn.LocalNames = d.strings()
+		fn.FreeNames = d.strings()
+		fn.IsGenerator = d.bool()
+		fn.ParameterNames = d.strings()
+		fn.NumOptional = int(d.uvarint())
+		fn.Variadic = d.bool()
+		return fn
+	case tagEnumVariant:
+		return &object.EnumVariant{Enum: d.string(), Name: d.string(), Fields: d.strings(), Types: d.strings()}
+	case tagEnumValue:
+		variant, ok := d.object().(*object.EnumVariant)
+		if !ok {
+			if d.err == nil {
+				d.fail("enum value without a variant")
+			}
+			return nil
+		}
+		n := d.count()
+		values := make([]object.Object, 0, n)
+		for i := 0; i < n && d.err == nil; i++ {
+			values = append(values, d.object())
+		}
+		return &object.EnumValue{Variant: variant, Values: values}
+	default:
+		d.pos--
+		d.fail("unknown constant tag %d", tag)
+		return nil
+	}
 }
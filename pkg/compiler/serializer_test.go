@@ -0,0 +1,203 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"reflect"
+	"testing"
+
+	"jabline/pkg/code"
+	"jabline/pkg/object"
+)
+
+func TestSerializeRoundTripConstants(t *testing.T) {
+	key := &object.String{Value: "RED"}
+	constants := []object.Object{
+		&object.Null{},
+		&object.Boolean{Value: true},
+		&object.Integer{Value: -42},
+		&object.Float{Value: 3.25},
+		&object.String{Value: "héllo"},
+		&object.Int8{Value: -8},
+		&object.Int16{Value: -16},
+		&object.Int32{Value: -32},
+		&object.Int64{Value: -64},
+		&object.UInt8{Value: 8},
+		&object.UInt16{Value: 16},
+		&object.UInt32{Value: 32},
+		&object.UInt64{Value: 1 << 63},
+		&object.Float32{Value: 1.5},
+		&object.Float64{Value: -2.5},
+		&object.Array{Elements: []object.Object{&object.Integer{Value: 1}, &object.String{Value: "two"}}},
+		&object.Hash{Pairs: map[object.HashKey]object.HashPair{
+			key.HashKey(): {Key: key, Value: &object.Integer{Value: 0}},
+		}},
+		&object.Struct{
+			Name:           "Box",
+			TypeParameters: []string{"T"},
+			Fields:         map[string]string{"value": "T", "label": "string"},
+		},
+		&object.CompiledFunction{
+			Instructions:   code.Make(code.OpConstant, 1),
+			NumLocals:      2,
+			NumParameters:  1,
+			SourceMap:      code.SourceMap{0: {Line: 3, Column: 5}},
+			File:           "/lib/box.jb",
+			IsAsync:        true,
+			Name:           "unbox",
+			TypeParameters: []string{"T"},
+			LocalNames:     []string{"box", "value"},
+			FreeNames:      []string{"outer"},
+			ParameterNames: []string{"box"},
+		},
+		&object.CompiledFunction{
+			Instructions:   code.Make(code.OpGetLocal, 0),
+			NumLocals:      1,
+			NumParameters:  1,
+			IsGenerator:    true,
+			Name:           "count",
+			TypeParameters: []string{},
+			LocalNames:     []string{"n"},
+			FreeNames:      []string{},
+			ParameterNames: []string{"n"},
+		},
+		&object.CompiledFunction{
+			Instructions:   code.Make(code.OpReturn),
+			NumLocals:      3,
+			NumParameters:  3,
+			Name:           "connect",
+			TypeParameters: []string{},
+			LocalNames:     []string{"url", "timeout", "rest"},
+			FreeNames:      []string{},
+			ParameterNames: []string{"url", "timeout", "rest"},
+			NumOptional:    1,
+			Variadic:       true,
+		},
+		&object.EnumVariant{Enum: "Result", Name: "Err", Fields: []string{"error"}, Types: []string{"Error"}},
+		&object.EnumValue{
+			Variant: &object.EnumVariant{Enum: "State", Name: "Idle", Fields: []string{}, Types: []string{}},
+			Values:  []object.Object{},
+		},
+	}
+
+	bytecode := &Bytecode{
+		Instructions: code.Make(code.OpConstant, 0),
+		Constants:    constants,
+		SourceMap:    code.SourceMap{0: {Line: 1, Column: 1}},
+		Exports:      map[string]int{"unbox": 0},
+	}
+
+	data, err := Serialize(bytecode)
+	if err != nil {
+		t.Fatalf("Serialize failed: %s", err)
+	}
+	decoded, err := Deserialize(data)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %s", err)
+	}
+
+	if !reflect.DeepEqual(decoded.Instructions, bytecode.Instructions) {
+		t.Errorf("instructions differ. want=%v, got=%v", bytecode.Instructions, decoded.Instructions)
+	}
+	if !reflect.DeepEqual(decoded.SourceMap, bytecode.SourceMap) {
+		t.Errorf("source map differs. want=%v, got=%v", bytecode.SourceMap, decoded.SourceMap)
+	}
+	if !reflect.DeepEqual(decoded.Exports, bytecode.Exports) {
+		t.Errorf("exports differ. want=%v, got=%v", bytecode.Exports, decoded.Exports)
+	}
+	if len(decoded.Constants) != len(constants) {
+		t.Fatalf("wrong number of constants. want=%d, got=%d", len(constants), len(decoded.Constants))
+	}
+	for i, want := range constants {
+		if !reflect.DeepEqual(decoded.Constants[i], want) {
+			t.Errorf("constant %d (%s) differs. want=%#v, got=%#v", i, want.Type(), want, decoded.Constants[i])
+		}
+	}
+	// The VM compares null and booleans by identity.
+	if decoded.Constants[0] != object.NullValue || decoded.Constants[1] != object.TrueValue {
+		t.Errorf("null and true must decode to the shared objects, got %p and %p", decoded.Constants[0], decoded.Constants[1])
+	}
+}
+
+func TestSerializeCompiledProgram(t *testing.T) {
+	input := `
+struct Point { x: float, y: float }
+enum Color { RED, GREEN }
+fn scale(p, k) { return Point{ x: p.x * k, y: p.y * k }; }
+let r = scale(Point{ x: 1.5, y: 2.0 }, 2);
+`
+	program := parse(input)
+	comp := New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := comp.Bytecode()
+
+	data, err := Serialize(bytecode)
+	if err != nil {
+		t.Fatalf("Serialize failed: %s", err)
+	}
+	decoded, err := Deserialize(data)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %s", err)
+	}
+	if !reflect.DeepEqual(decoded.Constants, bytecode.Constants) {
+		t.Errorf("constants differ after round trip")
+	}
+
+	again, err := Serialize(decoded)
+	if err != nil {
+		t.Fatalf("second Serialize failed: %s", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("serialization is not deterministic")
+	}
+}
+
+func TestDeserializeRejectsBadData(t *testing.T) {
+	data, err := Serialize(&Bytecode{Instructions: code.Make(code.OpNull)})
+	if err != nil {
+		t.Fatalf("Serialize failed: %s", err)
+	}
+
+	resign := func(b []byte) []byte {
+		body := b[:len(b)-checksumSize]
+		binary.LittleEndian.PutUint32(b[len(b)-checksumSize:], crc32.ChecksumIEEE(body))
+		return b
+	}
+
+	corrupt := append([]byte{}, data...)
+	corrupt[headerSize] ^= 0xff
+
+	newerISA := append([]byte{}, data...)
+	binary.LittleEndian.PutUint16(newerISA[6:8], code.InstructionSetVersion+1)
+
+	olderISA := append([]byte{}, data...)
+	binary.LittleEndian.PutUint16(olderISA[6:8], code.InstructionSetVersion-1)
+
+	newerFormat := append([]byte{}, data...)
+	binary.LittleEndian.PutUint16(newerFormat[4:6], FormatVersion+1)
+
+	olderFormat := append([]byte{}, data...)
+	binary.LittleEndian.PutUint16(olderFormat[4:6], FormatVersion-1)
+
+	truncated := append([]byte{}, data[:headerSize+1]...)
+	truncated = append(truncated, 0, 0, 0, 0)
+
+	tests := map[string][]byte{
+		"empty":          nil,
+		"gob":            []byte("\x0f\xff\x81\x03\x01\x01"),
+		"checksum":       corrupt,
+		"newer isa":      resign(newerISA),
+		"older isa":      resign(olderISA),
+		"newer format":   resign(newerFormat),
+		"older format":   resign(olderFormat),
+		"truncated body": resign(truncated),
+	}
+	for name, input := range tests {
+		if _, err := Deserialize(input); !errors.Is(err, ErrInvalidBytecode) {
+			t.Errorf("%s: expected ErrInvalidBytecode, got %v", name, err)
+		}
+	}
+}
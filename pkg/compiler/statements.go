@@ -9,13 +9,13 @@ import (
 )
 
 func (c *Compiler) compileLetStatement(node *ast.LetStatement) error {
-	if err := c.Compile(node.Value); err != nil {
+	if err := c.compileDestructured(node.Name.Pattern, node.Value); err != nil {
 		return err
 	}
 
 	var typeName string
 	if node.Type != nil {
-		typeName = node.Type.Value
+		typeName = node.Type.String()
 		valType := c.inferType(node.Value)
 		if err := c.checkTypeMatch(typeName, valType, node.Value); err != nil {
 			// Add file:line:col info to the error
@@ -37,6 +37,9 @@ func (c *Compiler) compileLetStatement(node *ast.LetStatement) error {
 		c.emit(code.OpSetLocal, sym.Index)
 	}
 
+	if node.Name.Pattern != nil {
+		return c.destructure(node.Name.Pattern, sym, c.defineName)
+	}
 	return nil
 }
 
@@ -78,17 +81,38 @@ func (c *Compiler) compileAssignmentStatement(node *ast.AssignmentStatement) err
 		return nil
 	}
 
+	// We only support assignment to identifiers for now (e.g. x = 5), or
+	// to the names of a destructuring pattern
+	ident, ok := node.Left.(*ast.Identifier)
+	if !ok {
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		return fmt.Errorf("assignment target must be an identifier")
+	}
+
 	// Compile the value to be assigned
-	if err := c.Compile(node.Value); err != nil {
+	if err := c.compileDestructured(ident.Pattern, node.Value); err != nil {
 		return err
 	}
+	if ident.Pattern != nil {
+		value := c.symbolTable.Define(ident.Value)
+		c.setSymbol(value)
+		return c.destructure(ident.Pattern, value, c.assignName)
+	}
 
-	// We only support assignment to identifiers for now (e.g. x = 5)
-	ident, ok := node.Left.(*ast.Identifier)
-	if !ok {
-		return fmt.Errorf("assignment target must be an identifier")
+	// Static type validation for assignment
+	if sym, ok := c.symbolTable.Resolve(ident.Value); ok && !c.symbolTable.IsConstant(ident.Value) {
+		valType := c.inferType(node.Value)
+		if err := c.checkTypeMatch(sym.DataType, valType, node.Value); err != nil {
+			return fmt.Errorf("compile error: assignment to '%s' failed - %s", ident.Value, err)
+		}
 	}
+	return c.assignName(ident)
+}
 
+// assignName stores the value on top of the stack in an existing variable.
+func (c *Compiler) assignName(ident *ast.Identifier) error {
 	// Reject assignments to constants
 	if c.symbolTable.IsConstant(ident.Value) {
 		return fmt.Errorf("cannot assign to constant '%s'", ident.Value)
@@ -98,11 +122,9 @@ func (c *Compiler) compileAssignmentStatement(node *ast.AssignmentStatement) err
 	if !ok {
 		return fmt.Errorf("undefined variable %s", ident.Value)
 	}
-
-	// Static type validation for assignment
-	valType := c.inferType(node.Value)
-	if err := c.checkTypeMatch(sym.DataType, valType, node.Value); err != nil {
-		return fmt.Errorf("compile error: assignment to '%s' failed - %s", ident.Value, err)
+	if _, ok := sizedType(sym.DataType); ok {
+		typeIdx := c.addConstant(&object.String{Value: sym.DataType})
+		c.emit(code.OpCheckType, typeIdx)
 	}
 
 	switch sym.Scope {
@@ -220,10 +242,13 @@ func (c *Compiler) compileForStatement(node *ast.ForStatement) error {
 }
 
 func (c *Compiler) compileBreakStatement(node *ast.BreakStatement) error {
-	jumpPos := c.emit(code.OpJump, 9999)
 	if c.loopIndex < 0 {
 		return fmt.Errorf("break statement outside of loop")
 	}
+	if err := c.leaveTries(c.loopIndex); err != nil {
+		return err
+	}
+	jumpPos := c.emit(code.OpJump, 9999)
 	c.loops[c.loopIndex].BreakPos = append(c.loops[c.loopIndex].BreakPos, jumpPos)
 	return nil
 }
@@ -232,6 +257,9 @@ func (c *Compiler) compileContinueStatement(node *ast.ContinueStatement) error {
 	if c.loopIndex < 0 {
 		return fmt.Errorf("continue statement outside of loop")
 	}
+	if err := c.leaveTries(c.loopIndex); err != nil {
+		return err
+	}
 	pos := c.loops[c.loopIndex].ContinuePos
 	if pos == -1 {
 		jumpPos := c.emit(code.OpJump, 9999)
@@ -439,75 +467,164 @@ func (c *Compiler) compileThrowStatement(node *ast.ThrowStatement) error {
 func (c *Compiler) compileTryStatement(node *ast.TryStatement) error {
 	// Do NOT create a new CompilationScope (c.enterScope), because that resets instruction offsets.
 	// We want OpTry/OpJump offsets to be relative to the current function's bytecode.
-	// However, we DO want a new SymbolScope for the catch block variables.
-
-	// Manually create a new symbol scope
-	originalSymbolTable := c.symbolTable
-	c.symbolTable = symbol.NewEnclosedSymbolTable(originalSymbolTable)
-
-	// Restore symbol table on exit
-	defer func() {
-		c.symbolTable = originalSymbolTable
-	}()
+	// The blocks share the symbol table of the function, like loop bodies, so
+	// the catch variable is one of its locals.
 
 	// 1. Emit OpTry with a placeholder operand (points to catch block start)
 	opTryPos := c.emit(code.OpTry, 9999) // Placeholder for CatchIP
 
 	// 2. Compile TryBlock
+	c.enterTry(node.FinallyBlock, true)
 	if err := c.Compile(node.TryBlock); err != nil {
 		return err
 	}
+	c.leaveTry()
 
-	// Remove potential OpPop after TryBlock if it's the last instruction
-	if c.lastInstructionIs(code.OpPop) {
-		c.removeLastPop()
-	}
-
-	// Emit OpEndTry immediately after TryBlock (path of success)
+	// Emit OpEndTry immediately after TryBlock (path of success), then jump
+	// to the finally block, or past the catch block if there is none
 	c.emit(code.OpEndTry)
+	normalJumps := []int{c.emit(code.OpJump, 9999)}
+
+	// 3. Catch clauses. The VM pushes the exception object before jumping
+	// here. Typed clauses test it in turn; if none matches it is thrown
+	// again.
+	rethrowTryPos := opTryPos
+	if len(node.Catches) > 0 || node.FinallyBlock == nil {
+		c.changeOperand(opTryPos, len(c.currentInstructions()))
+		rethrowTryPos = -1
+
+		// An exception thrown by a catch clause still runs the finally block
+		if len(node.Catches) > 0 && node.FinallyBlock != nil {
+			rethrowTryPos = c.emit(code.OpTry, 9999)
+			c.enterTry(node.FinallyBlock, true)
+		}
 
-	// 3. Emit OpJump with a placeholder operand (points after catch block)
-	jumpAfterCatchPos := c.emit(code.OpJump, 9999) // Placeholder to jump over catch block
-
-	// 4. Mark catch block start and patch OpTry operand
-	catchStartPos := len(c.currentInstructions())
-	c.changeOperand(opTryPos, catchStartPos) // OpTry now points to catch block
+		// The exception is kept in a variable while typed clauses test it,
+		// and otherwise bound directly.
+		var caught symbol.Symbol
+		typed := false
+		for _, clause := range node.Catches {
+			typed = typed || (clause.Param != nil && clause.Param.Type != nil)
+		}
+		if typed {
+			caught = c.symbolTable.Define("$$caught$$")
+			c.setSymbol(caught)
+		} else if len(node.Catches) == 0 || node.Catches[0].Param == nil {
+			c.emit(code.OpPop)
+		}
 
-	// Handle CatchBlock if present
-	if node.CatchBlock != nil {
-		// If CatchParam is defined, define it in the symbol table and store the exception object
-		if node.CatchParam != nil {
-			sym := c.symbolTable.Define(node.CatchParam.Value)
-			// The VM pushes the exception object onto the stack before jumping to catchStartPos
-			// So, we need to pop it and set it as a local variable.
-			if sym.Scope == symbol.GlobalScope {
-				c.emit(code.OpSetGlobal, sym.Index)
+		var clauseJumps []int
+		catchesAll := len(node.Catches) == 0
+		for _, clause := range node.Catches {
+			nextClause := -1
+			if clause.Param != nil && clause.Param.Type != nil {
+				c.getSymbol(caught)
+				typeIdx := c.addConstant(&object.String{Value: clause.Param.Type.String()})
+				c.emit(code.OpIsType, typeIdx)
+				nextClause = c.emit(code.OpJumpNotTruthy, 9999)
 			} else {
-				c.emit(code.OpSetLocal, sym.Index)
+				catchesAll = true
 			}
-		} else {
-			// If no catch param, pop the exception object from the stack
-			c.emit(code.OpPop)
+
+			if clause.Param != nil {
+				if typed {
+					c.getSymbol(caught)
+				}
+				c.setSymbol(c.symbolTable.Define(clause.Param.Value))
+			}
+			if err := c.Compile(clause.Block); err != nil {
+				return err
+			}
+			clauseJumps = append(clauseJumps, c.emit(code.OpJump, 9999))
+
+			if nextClause >= 0 {
+				c.changeOperand(nextClause, len(c.currentInstructions()))
+			}
+		}
+		if !catchesAll {
+			c.getSymbol(caught)
+			c.emit(code.OpThrow)
 		}
 
-		// Compile CatchBlock
-		if err := c.Compile(node.CatchBlock); err != nil {
+		for _, pos := range clauseJumps {
+			c.changeOperand(pos, len(c.currentInstructions()))
+		}
+		if len(node.Catches) > 0 && node.FinallyBlock != nil {
+			c.leaveTry()
+			c.emit(code.OpEndTry)
+		}
+		normalJumps = append(normalJumps, c.emit(code.OpJump, 9999))
+	}
+
+	// 4. Finally block, once for the paths that leave normally and once for
+	// an exception, which is thrown again after it
+	if node.FinallyBlock != nil {
+		for _, pos := range normalJumps {
+			c.changeOperand(pos, len(c.currentInstructions()))
+		}
+		if err := c.Compile(node.FinallyBlock); err != nil {
 			return err
 		}
-		// Remove potential OpPop after CatchBlock
-		if c.lastInstructionIs(code.OpPop) {
-			c.removeLastPop()
+		normalJumps = []int{c.emit(code.OpJump, 9999)}
+
+		if rethrowTryPos >= 0 {
+			c.changeOperand(rethrowTryPos, len(c.currentInstructions()))
+		}
+		exceptionSym := c.symbolTable.Define("$$exception$$")
+		c.setSymbol(exceptionSym)
+		if err := c.Compile(node.FinallyBlock); err != nil {
+			return err
 		}
-	} else {
-		c.emit(code.OpPop) // Pop the exception that OpTry pushed on failed try.
+		c.getSymbol(exceptionSym)
+		c.emit(code.OpThrow)
 	}
 
-	// 5. Mark end of try/catch and patch OpJump operand
+	// 5. Mark end of the statement and patch the jumps to it
 	endTryPos := len(c.currentInstructions())
-	c.changeOperand(jumpAfterCatchPos, endTryPos) // Jump after catch block
+	for _, pos := range normalJumps {
+		c.changeOperand(pos, endTryPos)
+	}
+
+	return nil
+}
+
+// setSymbol stores the value on top of the stack in a global or local
+// variable, and getSymbol pushes its value.
+func (c *Compiler) setSymbol(sym symbol.Symbol) {
+	if sym.Scope == symbol.GlobalScope {
+		c.emit(code.OpSetGlobal, sym.Index)
+	} else {
+		c.emit(code.OpSetLocal, sym.Index)
+	}
+}
+
+func (c *Compiler) getSymbol(sym symbol.Symbol) {
+	if sym.Scope == symbol.GlobalScope {
+		c.emit(code.OpGetGlobal, sym.Index)
+	} else {
+		c.emit(code.OpGetLocal, sym.Index)
+	}
+}
+
+func (c *Compiler) compileDeferStatement(node *ast.DeferStatement) error {
+	if c.scopeIndex == 0 {
+		return fmt.Errorf("defer outside of a function")
+	}
 
+	// The callee and arguments are evaluated now, the call is made when the
+	// function returns.
+	if err := c.Compile(node.Call.Function); err != nil {
+		return err
+	}
+	for _, arg := range node.Call.Arguments {
+		if err := c.Compile(arg); err != nil {
+			return err
+		}
+	}
+	c.emit(code.OpDefer, len(node.Call.Arguments))
 	return nil
 }
+
 func (c *Compiler) compileSwitchStatement(node *ast.SwitchStatement) error {
 	if err := c.Compile(node.Expression); err != nil {
 		return err
@@ -561,6 +678,129 @@ func (c *Compiler) compileSwitchStatement(node *ast.SwitchStatement) error {
 
 	return nil
 }
+
+// compileSelectStatement pushes the operands of every case for OpSelect,
+// which leaves the value received and the index of the case chosen, then
+// runs that case like a switch on the index.
+func (c *Compiler) compileSelectStatement(node *ast.SelectStatement) error {
+	if len(node.Cases) == 0 && node.DefaultCase == nil {
+		return fmt.Errorf("select statement has no cases")
+	}
+
+	shape := make([]object.Object, len(node.Cases))
+	for i, sc := range node.Cases {
+		kind := "recv"
+		operands := []ast.Expression{sc.Channel}
+		switch {
+		case sc.Timeout != nil:
+			kind, operands = "timeout", []ast.Expression{sc.Timeout}
+		case sc.Value != nil:
+			kind, operands = "send", []ast.Expression{sc.Channel, sc.Value}
+		}
+		for _, operand := range operands {
+			if err := c.Compile(operand); err != nil {
+				return err
+			}
+		}
+		shape[i] = &object.String{Value: kind}
+	}
+	hasDefault := 0
+	if node.DefaultCase != nil {
+		hasDefault = 1
+	}
+	c.emit(code.OpSelect, c.addConstant(&object.Array{Elements: shape}), hasDefault)
+
+	var jumpToEnds []int
+	for i, sc := range node.Cases {
+		c.emit(code.OpDup)
+		c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: int64(i)}))
+		c.emit(code.OpEqual)
+		jumpNotChosen := c.emit(code.OpJumpNotTruthy, 9999)
+		c.emit(code.OpPop) // the index
+
+		// The received value is only defined in the case that names it.
+		var shadowed symbol.Symbol
+		var existed bool
+		if sc.Name != nil {
+			shadowed, existed = c.symbolTable.Lookup(sc.Name.Value)
+			c.setSymbol(c.symbolTable.Define(sc.Name.Value))
+		} else {
+			c.emit(code.OpPop)
+		}
+		for _, s := range sc.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+		if sc.Name != nil {
+			c.symbolTable.Restore(sc.Name.Value, shadowed, existed)
+		}
+		jumpToEnds = append(jumpToEnds, c.emit(code.OpJump, 9999))
+
+		c.changeOperand(jumpNotChosen, len(c.currentInstructions()))
+	}
+
+	c.emit(code.OpPop) // the index
+	c.emit(code.OpPop) // the value
+	if node.DefaultCase != nil {
+		for _, s := range node.DefaultCase.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, pos := range jumpToEnds {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+	return nil
+}
+
+// compileGroupStatement runs the body under a handler, so that the tasks
+// of the group end however the body is left. OpGroupEnd waits for them and
+// leaves the exception to throw, or null, in place of the body's.
+func (c *Compiler) compileGroupStatement(node *ast.GroupStatement) error {
+	c.emit(code.OpGroup)
+	var shadowed symbol.Symbol
+	var existed bool
+	if node.Name != nil {
+		shadowed, existed = c.symbolTable.Lookup(node.Name.Value)
+		c.setSymbol(c.symbolTable.Define(node.Name.Value))
+	} else {
+		c.emit(code.OpPop)
+	}
+
+	opTryPos := c.emit(code.OpTry, 9999)
+	scope := &c.scopes[c.scopeIndex]
+	scope.tries = append(scope.tries, TryScope{HasHandler: true, LoopIndex: c.loopIndex, Group: true})
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+	c.leaveTry()
+	c.emit(code.OpEndTry)
+	c.emit(code.OpNull)
+
+	// The exception thrown by the body, or null, is on the stack.
+	c.changeOperand(opTryPos, len(c.currentInstructions()))
+	c.emitGroupEnd()
+
+	if node.Name != nil {
+		c.symbolTable.Restore(node.Name.Value, shadowed, existed)
+	}
+	return nil
+}
+
+// emitGroupEnd ends the innermost group statement, throwing the exception
+// OpGroupEnd leaves unless it is null.
+func (c *Compiler) emitGroupEnd() {
+	c.emit(code.OpGroupEnd)
+	c.emit(code.OpDup)
+	jumpNull := c.emit(code.OpJumpNotTruthy, 9999)
+	c.emit(code.OpThrow)
+	c.changeOperand(jumpNull, len(c.currentInstructions()))
+	c.emit(code.OpPop)
+}
+
 func (c *Compiler) compileConstStatement(node *ast.ConstStatement) error {
 	if err := c.Compile(node.Value); err != nil {
 		return err
@@ -568,7 +808,7 @@ func (c *Compiler) compileConstStatement(node *ast.ConstStatement) error {
 
 	var typeName string
 	if node.Type != nil {
-		typeName = node.Type.Value
+		typeName = node.Type.String()
 		valType := c.inferType(node.Value)
 		if err := c.checkTypeMatch(typeName, valType, node.Value); err != nil {
 			return fmt.Errorf("compile error: constant '%s' type mismatch - %s", node.Name.Value, err)
@@ -596,7 +836,10 @@ func (c *Compiler) compileEnumStatement(node *ast.EnumStatement) error {
 
 	for i, variant := range node.Values {
 		keyObj := &object.String{Value: variant.Value}
-		valObj := &object.Integer{Value: int64(i)}
+		var valObj object.Object = &object.Integer{Value: int64(i)}
+		if node.Algebraic() {
+			valObj = variantObject(node, i)
+		}
 		pairs[keyObj.HashKey()] = object.HashPair{Key: keyObj, Value: valObj}
 	}
 
@@ -605,6 +848,7 @@ func (c *Compiler) compileEnumStatement(node *ast.EnumStatement) error {
 	c.emit(code.OpConstant, constIdx)
 
 	sym := c.symbolTable.DefineConst(node.Name.Value)
+	c.enums[node.Name.Value] = node
 
 	if sym.Scope == symbol.GlobalScope {
 		c.emit(code.OpSetGlobal, sym.Index)
@@ -615,6 +859,25 @@ func (c *Compiler) compileEnumStatement(node *ast.EnumStatement) error {
 	return nil
 }
 
+// variantObject makes the i-th variant of an algebraic enum: a constructor
+// if it has fields, or else the value it stands for.
+func variantObject(node *ast.EnumStatement, i int) object.Object {
+	variant := &object.EnumVariant{Enum: node.Name.Value, Name: node.Values[i].Value}
+	fields := node.VariantFields(i)
+	if fields == nil {
+		return &object.EnumValue{Variant: variant}
+	}
+	for _, f := range fields {
+		typeName := ""
+		if f.Type != nil {
+			typeName = f.Type.String()
+		}
+		variant.Fields = append(variant.Fields, f.Value)
+		variant.Types = append(variant.Types, typeName)
+	}
+	return variant
+}
+
 func (c *Compiler) compileEchoStatement(node *ast.EchoStatement) error {
 	sym, ok := c.symbolTable.Resolve("echo") // Renamed variable
 	if !ok {
@@ -704,140 +967,96 @@ func (c *Compiler) compileExportStatement(node *ast.ExportStatement) error {
 
 		switch stmt := node.Statement.(type) {
 		case *ast.LetStatement:
-			c.symbolTable.MarkExported(stmt.Name.Value)
+			c.markExported(stmt.Name.Value)
 		case *ast.ConstStatement:
-			c.symbolTable.MarkExported(stmt.Name.Value)
+			c.markExported(stmt.Name.Value)
 		case *ast.FunctionStatement:
-			c.symbolTable.MarkExported(stmt.Name.Value)
+			c.markExported(stmt.Name.Value)
 		case *ast.StructStatement:
-			c.symbolTable.MarkExported(stmt.Name.Value)
+			c.markExported(stmt.Name.Value)
 		case *ast.EnumStatement:
-			c.symbolTable.MarkExported(stmt.Name.Value)
+			c.markExported(stmt.Name.Value)
 		case *ast.ServiceStatement:
-			c.symbolTable.MarkExported(stmt.Name.Value)
+			c.markExported(stmt.Name.Value)
 		}
 	}
 	return nil
 }
+
+// markExported flags the symbol as exported and, for globals, records its
+// slot in Bytecode.Exports so a module can be linked without its symbol
+// table.
+func (c *Compiler) markExported(name string) {
+	c.symbolTable.MarkExported(name)
+	if sym, ok := c.symbolTable.GetStore()[name]; ok && sym.Scope == symbol.GlobalScope {
+		c.exports[name] = sym.Index
+	}
+}
 func (c *Compiler) compileForEachStatement(node *ast.ForEachStatement) error {
 	// Do NOT enter a new scope. Use the current function's scope for locals.
 
-	// 1. Compile the Iterable expression and store it in a temporary local variable
+	// 1. Compile the Iterable expression and keep an iterator over it in a
+	// temporary variable. Arrays, strings and hashes are stepped through
+	// directly, channels are received from and generators are resumed for
+	// each value.
 	if err := c.Compile(node.Iterable); err != nil {
 		return err
 	}
-	iterableSym := c.symbolTable.Define("$$iterable$$") // Define a temporary symbol
-	if iterableSym.Scope == symbol.GlobalScope {
-		c.emit(code.OpSetGlobal, iterableSym.Index)
+	c.emit(code.OpGetIterator)
+	iteratorSym := c.symbolTable.Define("$$iterator$$") // Define a temporary symbol
+	if iteratorSym.Scope == symbol.GlobalScope {
+		c.emit(code.OpSetGlobal, iteratorSym.Index)
 	} else {
-		c.emit(code.OpSetLocal, iterableSym.Index)
-	}
-
-	// 2. Initialize a temporary index variable to 0
-	indexSym := c.symbolTable.Define("$$index$$") // Define a temporary symbol
-	c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: 0}))
-	if indexSym.Scope == symbol.GlobalScope {
-		c.emit(code.OpSetGlobal, indexSym.Index)
-	} else {
-		c.emit(code.OpSetLocal, indexSym.Index)
+		c.emit(code.OpSetLocal, iteratorSym.Index)
 	}
 
 	loopStartPos := len(c.currentInstructions()) // Mark the start of the loop
+	c.enterLoop(loopStartPos)                    // continue fetches the next item
 
-	// 3. Condition: while (index < len(iterable))
-	c.enterLoop(-1) // Use -1 so continue statements are collected and patched to increment
-
-	// Get length of iterable
-	lenSym, ok := c.symbolTable.Resolve("len") // Assume 'len' builtin is available
-	if !ok {
-		return fmt.Errorf("builtin 'len' not found for ForEachStatement")
-	}
-	c.emit(code.OpGetBuiltin, lenSym.Index)
-
-	if iterableSym.Scope == symbol.GlobalScope {
-		c.emit(code.OpGetGlobal, iterableSym.Index)
+	// 2. Condition: OpIterNext pushes the next item and whether there was one
+	if iteratorSym.Scope == symbol.GlobalScope {
+		c.emit(code.OpGetGlobal, iteratorSym.Index)
 	} else {
-		c.emit(code.OpGetLocal, iterableSym.Index)
+		c.emit(code.OpGetLocal, iteratorSym.Index)
 	}
-
-	c.emit(code.OpCall, 1) // Call len(iterable)
-
-	// Get current index
-	if indexSym.Scope == symbol.GlobalScope {
-		c.emit(code.OpGetGlobal, indexSym.Index)
+	if node.Key != nil {
+		c.emit(code.OpIterNextPair) // Stack: [key, item, true] or [false]
 	} else {
-		c.emit(code.OpGetLocal, indexSym.Index)
+		c.emit(code.OpIterNext) // Stack: [item, true] or [false]
 	}
-
-	// Compare: index < len(iterable)
-	c.emit(code.OpGreaterThan) // Stack: [len, index]. OpGreaterThan -> [len > index] (true if elements remain)
-
-	// Jump if not truthy (i.e., len <= index, loop finished)
 	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999) // Placeholder to jump out of loop
 
-	// 4. Get current item: let item = iterable[index];
-	if iterableSym.Scope == symbol.GlobalScope {
-		c.emit(code.OpGetGlobal, iterableSym.Index)
-	} else {
-		c.emit(code.OpGetLocal, iterableSym.Index)
-	}
-
-	if indexSym.Scope == symbol.GlobalScope {
-		c.emit(code.OpGetGlobal, indexSym.Index)
-	} else {
-		c.emit(code.OpGetLocal, indexSym.Index)
-	}
-
-	c.emit(code.OpIndex) // Stack: [item]
-
-	// Store item in the user-defined variable for the loop body
+	// 3. Store item (and key) in the user-defined variables for the loop body
 	itemVarSym := c.symbolTable.Define(node.Variable.Value) // Define user's loop variable
 	if itemVarSym.Scope == symbol.GlobalScope {
 		c.emit(code.OpSetGlobal, itemVarSym.Index)
 	} else {
 		c.emit(code.OpSetLocal, itemVarSym.Index)
 	}
-
-	// 5. Compile Body
-	if err := c.Compile(node.Body); err != nil {
-		return err
-	}
-
-	// Remove last OpPop if it exists
-	if c.lastInstructionIs(code.OpPop) {
-		c.removeLastPop()
+	if node.Key != nil {
+		keyVarSym := c.symbolTable.Define(node.Key.Value)
+		if keyVarSym.Scope == symbol.GlobalScope {
+			c.emit(code.OpSetGlobal, keyVarSym.Index)
+		} else {
+			c.emit(code.OpSetLocal, keyVarSym.Index)
+		}
 	}
-
-	// Handle continue statements
-	loopScope := c.leaveLoop()
-	// Update continue jumps to point to increment step
-	continuePos := len(c.currentInstructions())
-
-	// 6. Increment index: index = index + 1;
-	if indexSym.Scope == symbol.GlobalScope {
-		c.emit(code.OpGetGlobal, indexSym.Index)
-	} else {
-		c.emit(code.OpGetLocal, indexSym.Index)
+	if node.Variable.Pattern != nil {
+		if err := c.destructure(node.Variable.Pattern, itemVarSym, c.defineName); err != nil {
+			return err
+		}
 	}
 
-	c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: 1}))
-	c.emit(code.OpAdd)
-
-	if indexSym.Scope == symbol.GlobalScope {
-		c.emit(code.OpSetGlobal, indexSym.Index)
-	} else {
-		c.emit(code.OpSetLocal, indexSym.Index)
+	// 4. Compile Body
+	if err := c.Compile(node.Body); err != nil {
+		return err
 	}
 
-	// 7. Jump back to loop start
+	// 5. Jump back to loop start
 	c.emit(code.OpJump, loopStartPos)
+	loopScope := c.leaveLoop()
 
-	// Patch continue jumps to point to increment
-	for _, pos := range loopScope.ContinueJumps {
-		c.changeOperand(pos, continuePos)
-	}
-
-	// 8. Patch jump out of loop (after loop body)
+	// 6. Patch jump out of loop
 	afterLoopPos := len(c.currentInstructions())
 	c.changeOperand(jumpNotTruthyPos, afterLoopPos)
 
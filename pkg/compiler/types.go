@@ -3,8 +3,31 @@ package compiler
 import (
 	"fmt"
 	"jabline/pkg/ast"
+	"jabline/pkg/object"
+	"jabline/pkg/symbol"
+	"strings"
 )
 
+// sizedType returns the runtime type of a sized numeric type name such as
+// "int8" or "float32".
+func sizedType(name string) (object.ObjectType, bool) {
+	t := object.ObjectType(strings.ToUpper(name))
+	if name != strings.ToLower(name) || !object.IsSizedType(t) {
+		return "", false
+	}
+	return t, true
+}
+
+func isSizedInteger(name string) bool {
+	t, ok := sizedType(name)
+	return ok && !object.IsFloatType(t)
+}
+
+func isSizedFloat(name string) bool {
+	t, ok := sizedType(name)
+	return ok && object.IsFloatType(t)
+}
+
 // inferType tries to determine the type of an AST node at compile time.
 func (c *Compiler) inferType(node ast.Node) string {
 	res := ""
@@ -22,21 +45,23 @@ func (c *Compiler) inferType(node ast.Node) string {
 		if ok {
 			res = sym.DataType
 		}
+	case *ast.PrefixExpression:
+		if n.Operator == "-" || n.Operator == "~" {
+			res = c.inferType(n.Right)
+		}
 	case *ast.InfixExpression:
 		res = c.inferInfixType(n)
 	case *ast.CallExpression:
 		res = c.inferCallType(n)
 	case *ast.FunctionLiteral:
 		if n.ReturnType != nil {
-			res = n.ReturnType.Value
+			res = n.ReturnType.String()
 		}
 	case *ast.ArrowFunction:
 		if n.ReturnType != nil {
-			res = n.ReturnType.Value
+			res = n.ReturnType.String()
 		}
 	}
-	// Uncomment for noisy debug
-	// fmt.Printf("DEBUG: inferType(%T) -> %q\n", node, res)
 	return res
 }
 
@@ -44,6 +69,13 @@ func (c *Compiler) inferInfixType(node *ast.InfixExpression) string {
 	leftType := c.inferType(node.Left)
 	rightType := c.inferType(node.Right)
 
+	if _, ok := sizedType(leftType); ok {
+		return sizedInfixType(node.Operator, leftType, rightType)
+	}
+	if _, ok := sizedType(rightType); ok {
+		return sizedInfixType(node.Operator, leftType, rightType)
+	}
+
 	// Basic numeric promotion
 	if leftType == "float" || rightType == "float" {
 		if (leftType == "int" || leftType == "float") && (rightType == "int" || rightType == "float") {
@@ -70,9 +102,84 @@ func (c *Compiler) inferInfixType(node *ast.InfixExpression) string {
 	return leftType // Fallback
 }
 
+// sizedInfixType is the type of an operation with a sized operand: plain
+// ints and floats take the sized type, as the VM converts them.
+func sizedInfixType(operator, leftType, rightType string) string {
+	switch operator {
+	case "==", "!=", "<", ">", "<=", ">=":
+		return "bool"
+	case "<<", ">>":
+		return leftType
+	case "+":
+		if leftType == "string" || rightType == "string" {
+			return "string"
+		}
+	}
+	switch {
+	case leftType == rightType:
+		return leftType
+	case leftType == "int" || leftType == "float" && isSizedFloat(rightType):
+		return rightType
+	case rightType == "int" || rightType == "float" && isSizedFloat(leftType):
+		return leftType
+	}
+	return ""
+}
+
+// checkInfixTypes rejects operations mixing two different sized types, or
+// a sized type with a value it cannot be converted to implicitly, when the
+// types are known at compile time.
+func (c *Compiler) checkInfixTypes(node *ast.InfixExpression) error {
+	switch node.Operator {
+	case "&&", "||", "<<", ">>", "<-":
+		return nil
+	}
+	leftType := c.inferType(node.Left)
+	rightType := c.inferType(node.Right)
+	if leftType == "string" || rightType == "string" {
+		return nil
+	}
+
+	check := func(sized, other string, otherNode ast.Node) error {
+		t, ok := sizedType(sized)
+		if !ok {
+			return nil
+		}
+		switch {
+		case other == sized, other == "":
+			return nil
+		case other == "int":
+			if lit, ok := otherNode.(*ast.IntegerLiteral); ok && !object.IsFloatType(t) && !object.FitsInteger(t, lit.Value) {
+				return fmt.Errorf("constant %d overflows %s", lit.Value, sized)
+			}
+			return nil
+		case other == "float" && isSizedFloat(sized):
+			return nil
+		case other == "float", isSizedInteger(other), isSizedFloat(other):
+			return fmt.Errorf("mismatched types %s and %s for operator %s", leftType, rightType, node.Operator)
+		}
+		return nil
+	}
+
+	if err := check(leftType, rightType, node.Right); err != nil {
+		return fmt.Errorf("compile error: %s", err)
+	}
+	if err := check(rightType, leftType, node.Left); err != nil {
+		return fmt.Errorf("compile error: %s", err)
+	}
+	return nil
+}
+
 func (c *Compiler) inferCallType(node *ast.CallExpression) string {
 	if ident, ok := node.Function.(*ast.Identifier); ok {
 		sym, ok := c.symbolTable.Resolve(ident.Value)
+		if ok && sym.Scope == symbol.BuiltinScope {
+			// The conversion builtins are named after the type they return.
+			if _, sized := sizedType(ident.Value); sized {
+				return ident.Value
+			}
+			return ""
+		}
 		if ok {
 			// For now, we'd need to store function return types in the symbol table.
 			// This will be added in the next step.
@@ -83,10 +190,14 @@ func (c *Compiler) inferCallType(node *ast.CallExpression) string {
 }
 
 func (c *Compiler) checkTypeMatch(expected, actual string, node ast.Node) error {
-	// fmt.Printf("DEBUG: checkTypeMatch(expected=%q, actual=%q)\n", expected, actual)
 	if expected == "" || actual == "" || expected == "any" || actual == "any" {
 		return nil
 	}
+	// Generic, nullable and union types are left to the static checker
+	// and the runtime check.
+	if strings.ContainsAny(expected, "[?|") || strings.ContainsAny(actual, "[?|") {
+		return nil
+	}
 
 	if expected != actual {
 		// Allow int to float promotion implicitly in some cases?
@@ -94,6 +205,13 @@ func (c *Compiler) checkTypeMatch(expected, actual string, node ast.Node) error
 		if expected == "float" && actual == "int" {
 			return nil
 		}
+		// Plain numbers are converted to the declared sized type.
+		if isSizedInteger(expected) && actual == "int" {
+			return nil
+		}
+		if isSizedFloat(expected) && (actual == "int" || actual == "float") {
+			return nil
+		}
 		return fmt.Errorf("type mismatch: expected %s, got %s", expected, actual)
 	}
 
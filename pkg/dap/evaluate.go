@@ -0,0 +1,110 @@
+package dap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"jabline/pkg/compiler"
+	"jabline/pkg/lexer"
+	"jabline/pkg/object"
+	"jabline/pkg/parser"
+	"jabline/pkg/stdlib"
+	"jabline/pkg/symbol"
+	"jabline/pkg/vm"
+)
+
+// evalLimits keeps an expression typed in the debug console from hanging
+// the session.
+var evalLimits = vm.Limits{MaxInstructions: 1_000_000}
+
+func (s *session) evaluate(args evaluateArguments) (interface{}, error) {
+	m, err := s.pausedVM()
+	if err != nil {
+		return nil, err
+	}
+	depth := m.Depth() - 1
+	if args.FrameID != nil {
+		depth = *args.FrameID
+	}
+	if depth < 0 || depth >= m.Depth() {
+		return nil, fmt.Errorf("unknown frame %d", depth)
+	}
+
+	result, err := s.eval(m, depth, args.Expression)
+	if err != nil {
+		return nil, err
+	}
+	v := s.variable("", result)
+	return evaluateResponse{Result: v.Value, Type: v.Type, VariablesReference: v.VariablesReference}, nil
+}
+
+// eval runs src with the variables of the frame at depth in scope. The
+// program's globals keep their slots and the frame's locals and captured
+// variables are added after them, shadowing globals of the same name, so
+// the expression compiles as a top-level program. It works on copies:
+// assignments do not change the paused program's variables, although
+// mutating an array or hash it refers to does.
+func (s *session) eval(m *vm.VM, depth int, src string) (object.Object, error) {
+	p := parser.New(lexer.New(src))
+	prog := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "; "))
+	}
+
+	symbols := symbol.NewSymbolTable()
+	for i, b := range stdlib.Registry {
+		symbols.DefineBuiltin(i, b.Name)
+	}
+
+	store := m.Globals(depth)
+	globals := make([]object.Object, vm.GlobalsSize)
+	copy(globals, store)
+
+	if sameStore(store, m.Globals(0)) {
+		names := map[int]string{}
+		count := 0
+		for _, sym := range s.program.bytecode.SymbolTable.GetStore() {
+			if sym.Scope == symbol.GlobalScope {
+				names[sym.Index] = sym.Name
+				count = max(count, sym.Index+1)
+			}
+		}
+		for i := 0; i < count; i++ {
+			name, ok := names[i]
+			if !ok {
+				// Keeps the slot taken by a name no expression can use.
+				name = fmt.Sprintf(" %d", i)
+			}
+			symbols.Define(name)
+		}
+	}
+
+	for _, v := range append(m.Locals(depth), m.Free(depth)...) {
+		sym := symbols.Define(v.Name)
+		if sym.Index >= len(globals) {
+			return nil, errors.New("too many variables in scope")
+		}
+		globals[sym.Index] = v.Value
+	}
+
+	comp := compiler.NewWithState(symbols, []object.Object{})
+	if err := comp.Compile(prog); err != nil {
+		return nil, err
+	}
+	b := comp.Bytecode()
+
+	machine := vm.NewWithGlobalsStore(b.Instructions, b.Constants, globals, "<eval>")
+	machine.SetLimits(evalLimits)
+	if err := machine.Run(); err != nil {
+		var rtErr *vm.RuntimeError
+		if errors.As(err, &rtErr) {
+			return nil, errors.New(rtErr.Message)
+		}
+		return nil, err
+	}
+	if result := machine.StackTop(); result != nil {
+		return result, nil
+	}
+	return vm.Null, nil
+}
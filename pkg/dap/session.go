@@ -0,0 +1,577 @@
+// Package dap implements the Debug Adapter Protocol for Jabline programs,
+// so editors such as VS Code and Neovim can set line breakpoints, step
+// through code, inspect variables and evaluate expressions while the
+// program is paused.
+//
+// A session debugs a single program, run on one VM. Modules execute their
+// top-level code in VMs of their own, which are not traced, but functions
+// they export can be stepped into once imported. Spawned and async tasks
+// also run on VMs of their own and are not traced either.
+package dap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"jabline/pkg/code"
+	"jabline/pkg/compiler"
+	"jabline/pkg/lexer"
+	"jabline/pkg/object"
+	"jabline/pkg/parser"
+	"jabline/pkg/vm"
+)
+
+// The program runs on a single thread as far as the client is concerned.
+const threadID = 1
+
+type stepMode int
+
+const (
+	modeRun stepMode = iota
+	modeStepIn
+	modeStepOver
+	modeStepOut
+)
+
+// program is the compiled launch target.
+type program struct {
+	path     string
+	bytecode *compiler.Bytecode
+	// lines holds, per file, the lines instructions were compiled from.
+	lines map[string]map[int]bool
+}
+
+type session struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	writeMu sync.Mutex
+	seq     int
+
+	defaultProgram string
+	program        *program
+	stopOnEntry    bool
+	configured     bool
+	started        bool
+	cancel         context.CancelFunc
+
+	// mu guards the state shared with the goroutine running the program.
+	mu              sync.Mutex
+	breakpoints     map[string]map[int]bool
+	breakOnRaised   bool
+	breakOnUncaught bool
+	mode            stepMode
+	stepDepth       int
+	pauseRequested  bool
+	entry           bool
+	terminating     bool
+	// lines is the line last executed at each frame depth, so a line is
+	// only reported once however many instructions it compiled to.
+	lines     []int
+	prevDepth int
+	// paused is the VM while it waits in stop, and handles the variable
+	// references handed out since it stopped.
+	paused  *vm.VM
+	handles []handle
+	resume  chan struct{}
+}
+
+// Options configures a debug session.
+type Options struct {
+	// Program is launched when the client's launch request does not name
+	// one.
+	Program string
+	// Output, when set, is forwarded to the client as program output. It
+	// catches what is written to the process's stdout directly rather than
+	// through the VM, which would otherwise corrupt a session over stdio.
+	Output io.Reader
+}
+
+// Serve runs a debug session over r and w until the client disconnects or
+// r is exhausted.
+func Serve(r io.Reader, w io.Writer, opts Options) error {
+	s := &session{
+		in:              bufio.NewReader(r),
+		out:             w,
+		defaultProgram:  opts.Program,
+		breakpoints:     make(map[string]map[int]bool),
+		breakOnUncaught: true,
+		resume:          make(chan struct{}),
+	}
+	if opts.Output != nil {
+		go io.Copy(&outputWriter{s: s, category: "stdout"}, opts.Output)
+	}
+	return s.serve()
+}
+
+func (s *session) serve() error {
+	defer s.terminate()
+
+	for {
+		data, err := readMessage(s.in)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fmt.Errorf("malformed message: %w", err)
+		}
+
+		body, err := s.dispatch(&req)
+		if err != nil {
+			s.respond(&req, nil, err)
+		} else {
+			s.respond(&req, body, nil)
+		}
+
+		switch req.Command {
+		case "initialize":
+			s.event("initialized", nil)
+		case "launch", "configurationDone":
+			s.start()
+		case "disconnect":
+			return nil
+		}
+	}
+}
+
+func (s *session) dispatch(req *request) (interface{}, error) {
+	switch req.Command {
+	case "initialize":
+		return capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsEvaluateForHovers:        true,
+			SupportsTerminateRequest:         true,
+			ExceptionBreakpointFilters: []exceptionBreakpointFilter{
+				{Filter: "raised", Label: "All Exceptions"},
+				{Filter: "uncaught", Label: "Uncaught Exceptions", Default: true},
+			},
+		}, nil
+	case "launch":
+		var args launchArguments
+		if err := decode(req, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.launch(args)
+	case "setBreakpoints":
+		var args setBreakpointsArguments
+		if err := decode(req, &args); err != nil {
+			return nil, err
+		}
+		return s.setBreakpoints(args), nil
+	case "setExceptionBreakpoints":
+		var args setExceptionBreakpointsArguments
+		if err := decode(req, &args); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		s.breakOnRaised, s.breakOnUncaught = false, false
+		for _, f := range args.Filters {
+			switch f {
+			case "raised":
+				s.breakOnRaised = true
+			case "uncaught":
+				s.breakOnUncaught = true
+			}
+		}
+		s.mu.Unlock()
+		return nil, nil
+	case "configurationDone":
+		s.configured = true
+		return nil, nil
+	case "threads":
+		return map[string]interface{}{"threads": []thread{{ID: threadID, Name: "main"}}}, nil
+	case "stackTrace":
+		return s.stackTrace()
+	case "scopes":
+		var args frameArguments
+		if err := decode(req, &args); err != nil {
+			return nil, err
+		}
+		return s.scopes(args.FrameID)
+	case "variables":
+		var args variablesArguments
+		if err := decode(req, &args); err != nil {
+			return nil, err
+		}
+		return s.variables(args.VariablesReference)
+	case "evaluate":
+		var args evaluateArguments
+		if err := decode(req, &args); err != nil {
+			return nil, err
+		}
+		return s.evaluate(args)
+	case "continue":
+		s.resumeWith(modeRun)
+		return map[string]interface{}{"allThreadsContinued": true}, nil
+	case "next":
+		s.resumeWith(modeStepOver)
+		return nil, nil
+	case "stepIn":
+		s.resumeWith(modeStepIn)
+		return nil, nil
+	case "stepOut":
+		s.resumeWith(modeStepOut)
+		return nil, nil
+	case "pause":
+		s.mu.Lock()
+		s.pauseRequested = true
+		s.mu.Unlock()
+		return nil, nil
+	case "terminate", "disconnect":
+		s.terminate()
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported request %q", req.Command)
+}
+
+func decode(req *request, args interface{}) error {
+	if len(req.Arguments) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(req.Arguments, args); err != nil {
+		return fmt.Errorf("invalid arguments for %s: %w", req.Command, err)
+	}
+	return nil
+}
+
+func (s *session) send(msg interface{}) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.seq++
+	switch m := msg.(type) {
+	case *response:
+		m.Seq = s.seq
+	case *event:
+		m.Seq = s.seq
+	}
+	writeMessage(s.out, msg)
+}
+
+func (s *session) respond(req *request, body interface{}, err error) {
+	resp := &response{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: err == nil, Body: body}
+	if err != nil {
+		resp.Message = err.Error()
+	}
+	s.send(resp)
+}
+
+func (s *session) event(name string, body interface{}) {
+	s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+// outputWriter forwards what the program writes as output events.
+type outputWriter struct {
+	s        *session
+	category string
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.s.event("output", outputEvent{Category: w.category, Output: string(p)})
+	return len(p), nil
+}
+
+func (s *session) launch(args launchArguments) error {
+	path := args.Program
+	if path == "" {
+		path = s.defaultProgram
+	}
+	if path == "" {
+		return errors.New("no program to debug")
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.ReadFile(abs)
+	if err != nil {
+		return err
+	}
+	p := parser.New(lexer.New(string(src)))
+	prog := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return fmt.Errorf("parser errors: %v", p.Errors())
+	}
+	comp := compiler.New()
+	if err := comp.Compile(prog); err != nil {
+		return fmt.Errorf("compiler error: %s", err)
+	}
+
+	s.program = &program{path: abs, bytecode: comp.Bytecode()}
+	s.program.lines = codeLines(abs, s.program.bytecode)
+	s.stopOnEntry = args.StopOnEntry
+	return nil
+}
+
+// codeLines collects the lines of each file that have instructions, so
+// breakpoints can be moved to the next line that does.
+func codeLines(mainFile string, b *compiler.Bytecode) map[string]map[int]bool {
+	lines := make(map[string]map[int]bool)
+	add := func(file string, sm code.SourceMap) {
+		if file == "" {
+			file = mainFile
+		}
+		if lines[file] == nil {
+			lines[file] = make(map[int]bool)
+		}
+		for _, pos := range sm {
+			if pos.Line > 0 {
+				lines[file][pos.Line] = true
+			}
+		}
+	}
+
+	add(mainFile, b.SourceMap)
+	for _, c := range b.Constants {
+		if fn, ok := c.(*object.CompiledFunction); ok {
+			add(fn.File, fn.SourceMap)
+		}
+	}
+	return lines
+}
+
+func (s *session) setBreakpoints(args setBreakpointsArguments) interface{} {
+	path, err := filepath.Abs(args.Source.Path)
+	if err != nil {
+		path = args.Source.Path
+	}
+
+	requested := args.Lines
+	if len(args.Breakpoints) > 0 {
+		requested = requested[:0]
+		for _, bp := range args.Breakpoints {
+			requested = append(requested, bp.Line)
+		}
+	}
+
+	var known map[int]bool
+	if s.program != nil {
+		known = s.program.lines[path]
+	}
+
+	lines := make(map[int]bool)
+	result := make([]breakpoint, 0, len(requested))
+	for _, line := range requested {
+		bp := breakpoint{Verified: true, Line: line, Source: &args.Source}
+		if known != nil {
+			bp.Verified = false
+			bp.Message = "no code on or after this line"
+			for l := line; l <= line+maxBreakpointShift; l++ {
+				if known[l] {
+					bp.Verified, bp.Line, bp.Message = true, l, ""
+					break
+				}
+			}
+		}
+		if bp.Verified {
+			lines[bp.Line] = true
+		}
+		result = append(result, bp)
+	}
+
+	s.mu.Lock()
+	s.breakpoints[path] = lines
+	s.mu.Unlock()
+
+	return map[string]interface{}{"breakpoints": result}
+}
+
+// maxBreakpointShift bounds how far a breakpoint on a line without code,
+// such as a comment or a closing brace, is moved down.
+const maxBreakpointShift = 20
+
+// start runs the program once it is launched and the client is done
+// configuring breakpoints.
+func (s *session) start() {
+	if s.started || s.program == nil || !s.configured {
+		return
+	}
+	s.started = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.mu.Lock()
+	if s.stopOnEntry {
+		s.entry = true
+	}
+	s.mu.Unlock()
+
+	b := s.program.bytecode
+	machine := vm.New(b.Instructions, b.Constants, s.program.path)
+	machine.SetSourceMap(b.SourceMap)
+	machine.SetOutput(&outputWriter{s: s, category: "stdout"}, &outputWriter{s: s, category: "stderr"})
+	machine.SetDebugger(s)
+
+	go func() {
+		err := machine.RunContext(ctx)
+
+		s.mu.Lock()
+		terminating := s.terminating
+		s.mu.Unlock()
+
+		exitCode := 0
+		if err != nil && !terminating {
+			s.event("output", outputEvent{Category: "stderr", Output: err.Error() + "\n"})
+			exitCode = 1
+		}
+		s.event("exited", map[string]int{"exitCode": exitCode})
+		s.event("terminated", nil)
+	}()
+}
+
+// terminate stops the program. A paused program is resumed so it can
+// notice the cancellation.
+func (s *session) terminate() {
+	s.mu.Lock()
+	if s.terminating {
+		s.mu.Unlock()
+		return
+	}
+	s.terminating = true
+	paused := s.paused != nil
+	s.paused = nil
+	s.mu.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+	if paused {
+		s.resume <- struct{}{}
+	}
+}
+
+// resumeWith lets a paused program continue in the given mode.
+func (s *session) resumeWith(mode stepMode) {
+	s.mu.Lock()
+	if s.paused == nil {
+		s.mu.Unlock()
+		return
+	}
+	s.mode = mode
+	s.stepDepth = s.paused.Depth()
+	s.paused = nil
+	s.mu.Unlock()
+
+	s.resume <- struct{}{}
+}
+
+// Step implements vm.Debugger. It decides whether the instruction about to
+// execute starts a line the client wants to stop at.
+func (s *session) Step(m *vm.VM) {
+	file, line := m.Position()
+	depth := m.Depth()
+
+	s.mu.Lock()
+	for len(s.lines) <= depth {
+		s.lines = append(s.lines, 0)
+	}
+	// A frame deeper than the last one seen is a new call.
+	for d := s.prevDepth + 1; d <= depth; d++ {
+		s.lines[d] = 0
+	}
+	s.prevDepth = depth
+
+	if s.terminating || line == 0 {
+		s.mu.Unlock()
+		return
+	}
+	newLine := s.lines[depth] != line
+	s.lines[depth] = line
+
+	reason := ""
+	switch {
+	case s.pauseRequested:
+		reason = "pause"
+	case !newLine:
+	case s.entry:
+		reason = "entry"
+	case s.breakpoints[file][line]:
+		reason = "breakpoint"
+	case s.mode == modeStepIn:
+		reason = "step"
+	case s.mode == modeStepOver && depth <= s.stepDepth:
+		reason = "step"
+	case s.mode == modeStepOut && depth < s.stepDepth:
+		reason = "step"
+	}
+	if reason == "" {
+		s.mu.Unlock()
+		return
+	}
+	s.stop(m, reason, "")
+}
+
+// Exception implements vm.Debugger.
+func (s *session) Exception(m *vm.VM, err error, uncaught bool) {
+	s.mu.Lock()
+	if s.terminating || !(s.breakOnRaised || uncaught && s.breakOnUncaught) {
+		s.mu.Unlock()
+		return
+	}
+	s.stop(m, "exception", err.Error())
+}
+
+// stop pauses the program until the client resumes it. It is called with
+// s.mu held and releases it.
+func (s *session) stop(m *vm.VM, reason, text string) {
+	s.paused = m
+	s.handles = nil
+	s.mode = modeRun
+	s.pauseRequested = false
+	s.entry = false
+	s.mu.Unlock()
+
+	body := stoppedEvent{Reason: reason, ThreadID: threadID, AllThreadsStopped: true}
+	if text != "" {
+		body.Description = "Paused on exception"
+		body.Text = text
+	}
+	s.event("stopped", body)
+
+	<-s.resume
+}
+
+// pausedVM returns the paused VM, or an error when the program is running.
+func (s *session) pausedVM() (*vm.VM, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused == nil {
+		return nil, errors.New("the program is not paused")
+	}
+	return s.paused, nil
+}
+
+func (s *session) stackTrace() (interface{}, error) {
+	m, err := s.pausedVM()
+	if err != nil {
+		return nil, err
+	}
+
+	calls := m.CallStack()
+	frames := make([]stackFrame, 0, len(calls))
+	for depth := len(calls) - 1; depth >= 0; depth-- {
+		call := calls[depth]
+		frames = append(frames, stackFrame{
+			ID:     depth,
+			Name:   call.Function,
+			Source: &source{Name: filepath.Base(call.File), Path: call.File},
+			Line:   call.Line,
+			Column: call.Column,
+		})
+	}
+	return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}, nil
+}
@@ -0,0 +1,344 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// client drives a session the way an editor would.
+type client struct {
+	t    *testing.T
+	w    io.Writer
+	seq  int
+	msgs chan map[string]interface{}
+}
+
+func newClient(t *testing.T, opts Options) *client {
+	t.Helper()
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() {
+		Serve(inR, outW, opts)
+		outW.Close()
+	}()
+	t.Cleanup(func() { inW.Close() })
+
+	c := &client{t: t, w: inW, msgs: make(chan map[string]interface{}, 64)}
+	go func() {
+		defer close(c.msgs)
+		r := bufio.NewReader(outR)
+		for {
+			data, err := readMessage(r)
+			if err != nil {
+				return
+			}
+			var msg map[string]interface{}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				return
+			}
+			c.msgs <- msg
+		}
+	}()
+	return c
+}
+
+func (c *client) request(command string, args interface{}) map[string]interface{} {
+	c.t.Helper()
+	c.seq++
+	raw, _ := json.Marshal(args)
+	if err := writeMessage(c.w, request{Seq: c.seq, Command: command, Arguments: raw}); err != nil {
+		c.t.Fatalf("writing %s: %s", command, err)
+	}
+	resp := c.expect("response", command)
+	if resp["success"] != true {
+		c.t.Fatalf("%s failed: %v", command, resp["message"])
+	}
+	body, _ := resp["body"].(map[string]interface{})
+	return body
+}
+
+// expect skips messages until one of the given type named name arrives.
+func (c *client) expect(typ, name string) map[string]interface{} {
+	c.t.Helper()
+	key := "event"
+	if typ == "response" {
+		key = "command"
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg, ok := <-c.msgs:
+			if !ok {
+				c.t.Fatalf("session ended waiting for %s %s", typ, name)
+			}
+			if msg["type"] == typ && msg[key] == name {
+				return msg
+			}
+		case <-timeout:
+			c.t.Fatalf("timed out waiting for %s %s", typ, name)
+		}
+	}
+}
+
+func TestBreakpointInspectAndContinue(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.jb")
+	src := `let total = 10;
+fn add(a, b) {
+    let sum = a + b;
+    return sum;
+}
+echo(add(total, 5));
+`
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newClient(t, Options{Program: path})
+	c.request("initialize", map[string]string{"adapterID": "jabline"})
+	c.expect("event", "initialized")
+	c.request("launch", launchArguments{})
+	bps := c.request("setBreakpoints", setBreakpointsArguments{
+		Source:      source{Path: path},
+		Breakpoints: []sourceBreakpoint{{Line: 4}},
+	})
+	if got := bps["breakpoints"].([]interface{})[0].(map[string]interface{}); got["verified"] != true {
+		t.Fatalf("breakpoint not verified: %v", got)
+	}
+	c.request("configurationDone", nil)
+
+	stopped := c.expect("event", "stopped")
+	if reason := stopped["body"].(map[string]interface{})["reason"]; reason != "breakpoint" {
+		t.Fatalf("wrong stop reason. want=breakpoint, got=%v", reason)
+	}
+
+	trace := c.request("stackTrace", map[string]int{"threadId": threadID})
+	frames := trace["stackFrames"].([]interface{})
+	if len(frames) != 2 {
+		t.Fatalf("wrong number of frames. want=2, got=%d", len(frames))
+	}
+	top := frames[0].(map[string]interface{})
+	if top["name"] != "add" || top["line"] != float64(4) {
+		t.Fatalf("wrong top frame: %v", top)
+	}
+	frameID := int(top["id"].(float64))
+
+	scopes := c.request("scopes", frameArguments{FrameID: frameID})["scopes"].([]interface{})
+	locals := scopes[0].(map[string]interface{})
+	if locals["name"] != scopeLocals {
+		t.Fatalf("wrong first scope: %v", locals)
+	}
+	ref := int(locals["variablesReference"].(float64))
+	vars := c.request("variables", variablesArguments{VariablesReference: ref})["variables"].([]interface{})
+	want := map[string]string{"a": "10", "b": "5", "sum": "15"}
+	for _, v := range vars {
+		v := v.(map[string]interface{})
+		if want[v["name"].(string)] != v["value"] {
+			t.Errorf("wrong value for %v: %v", v["name"], v["value"])
+		}
+		delete(want, v["name"].(string))
+	}
+	if len(want) != 0 {
+		t.Errorf("missing locals: %v", want)
+	}
+
+	eval := c.request("evaluate", evaluateArguments{Expression: "sum * 2 + total", FrameID: &frameID})
+	if eval["result"] != "40" {
+		t.Errorf("wrong evaluate result. want=40, got=%v", eval["result"])
+	}
+
+	c.request("continue", map[string]int{"threadId": threadID})
+	output := ""
+	for {
+		msg, ok := <-c.msgs
+		if !ok {
+			t.Fatal("session ended before the program exited")
+		}
+		if msg["type"] != "event" {
+			continue
+		}
+		body, _ := msg["body"].(map[string]interface{})
+		if msg["event"] == "output" {
+			output += body["output"].(string)
+			continue
+		}
+		if msg["event"] == "exited" {
+			if code := body["exitCode"]; code != float64(0) {
+				t.Errorf("wrong exit code. want=0, got=%v", code)
+			}
+			break
+		}
+	}
+	if output != "15\n" {
+		t.Errorf("wrong output. want=%q, got=%q", "15\n", output)
+	}
+}
+
+// launch starts a session debugging src, stopping at the given lines and
+// on the given exception filters (nil keeps the default).
+func launch(t *testing.T, src string, lines []int, filters []string) *client {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "main.jb")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newClient(t, Options{Program: path})
+	c.request("initialize", map[string]string{"adapterID": "jabline"})
+	c.expect("event", "initialized")
+	c.request("launch", launchArguments{})
+	if len(lines) > 0 {
+		c.request("setBreakpoints", setBreakpointsArguments{Source: source{Path: path}, Lines: lines})
+	}
+	if filters != nil {
+		c.request("setExceptionBreakpoints", setExceptionBreakpointsArguments{Filters: filters})
+	}
+	c.request("configurationDone", nil)
+	return c
+}
+
+// stopped waits for the program to stop for reason and returns the name
+// and line of the top frame.
+func (c *client) stopped(reason string) (string, int) {
+	c.t.Helper()
+	body := c.expect("event", "stopped")["body"].(map[string]interface{})
+	if body["reason"] != reason {
+		c.t.Fatalf("wrong stop reason. want=%s, got=%v", reason, body["reason"])
+	}
+	frames := c.request("stackTrace", map[string]int{"threadId": threadID})["stackFrames"].([]interface{})
+	top := frames[0].(map[string]interface{})
+	return top["name"].(string), int(top["line"].(float64))
+}
+
+// exited waits for the program to end, failing if it stops first, and
+// returns its exit code.
+func (c *client) exited() int {
+	c.t.Helper()
+	for {
+		msg, ok := <-c.msgs
+		if !ok {
+			c.t.Fatal("session ended before the program exited")
+		}
+		if msg["type"] != "event" {
+			continue
+		}
+		switch msg["event"] {
+		case "stopped":
+			c.t.Fatalf("unexpected stop: %v", msg["body"])
+		case "exited":
+			return int(msg["body"].(map[string]interface{})["exitCode"].(float64))
+		}
+	}
+}
+
+func TestStepping(t *testing.T) {
+	src := `fn add(a, b) {
+    let sum = a + b;
+    return sum;
+}
+let x = add(1, 2);
+let y = x + 1;
+echo(y);
+`
+	c := launch(t, src, []int{5}, nil)
+	if name, line := c.stopped("breakpoint"); name != "<main>" || line != 5 {
+		t.Fatalf("wrong breakpoint stop: %s:%d", name, line)
+	}
+
+	steps := []struct {
+		command string
+		name    string
+		line    int
+	}{
+		{"stepIn", "add", 2},
+		{"next", "add", 3},
+		{"stepOut", "<main>", 6},
+		{"next", "<main>", 7},
+	}
+	for _, st := range steps {
+		c.request(st.command, map[string]int{"threadId": threadID})
+		if name, line := c.stopped("step"); name != st.name || line != st.line {
+			t.Fatalf("%s: want to stop at %s:%d, got %s:%d", st.command, st.name, st.line, name, line)
+		}
+	}
+
+	c.request("continue", map[string]int{"threadId": threadID})
+	if code := c.exited(); code != 0 {
+		t.Errorf("wrong exit code. want=0, got=%d", code)
+	}
+}
+
+func TestNextStepsOverCalls(t *testing.T) {
+	src := `fn add(a, b) {
+    return a + b;
+}
+let x = add(1, 2);
+echo(x);
+`
+	c := launch(t, src, []int{4}, nil)
+	c.stopped("breakpoint")
+	c.request("next", map[string]int{"threadId": threadID})
+	if name, line := c.stopped("step"); name != "<main>" || line != 5 {
+		t.Fatalf("next: want to stop at <main>:5, got %s:%d", name, line)
+	}
+	c.request("continue", map[string]int{"threadId": threadID})
+	c.exited()
+}
+
+func TestExceptionBreakpoints(t *testing.T) {
+	caught := `fn risky() {
+    throw "boom";
+}
+try {
+    risky();
+} catch (e) {
+    echo(e);
+}
+`
+	uncaught := `fn risky() {
+    throw "boom";
+}
+risky();
+`
+
+	// All exceptions stop, caught or not, at the throw.
+	c := launch(t, caught, nil, []string{"raised"})
+	body := c.expect("event", "stopped")["body"].(map[string]interface{})
+	if body["reason"] != "exception" || body["text"] != "boom" {
+		t.Fatalf("wrong stop: %v", body)
+	}
+	frames := c.request("stackTrace", map[string]int{"threadId": threadID})["stackFrames"].([]interface{})
+	if top := frames[0].(map[string]interface{}); top["name"] != "risky" || top["line"] != float64(2) {
+		t.Fatalf("wrong top frame: %v", top)
+	}
+	c.request("continue", map[string]int{"threadId": threadID})
+	if code := c.exited(); code != 0 {
+		t.Errorf("wrong exit code. want=0, got=%d", code)
+	}
+
+	// By default only uncaught exceptions stop.
+	c = launch(t, caught, nil, nil)
+	if code := c.exited(); code != 0 {
+		t.Errorf("wrong exit code. want=0, got=%d", code)
+	}
+	c = launch(t, uncaught, nil, nil)
+	if name, line := c.stopped("exception"); name != "risky" || line != 2 {
+		t.Fatalf("wrong exception stop: %s:%d", name, line)
+	}
+	c.request("continue", map[string]int{"threadId": threadID})
+	if code := c.exited(); code != 1 {
+		t.Errorf("wrong exit code. want=1, got=%d", code)
+	}
+
+	// Without filters nothing stops.
+	c = launch(t, uncaught, nil, []string{})
+	if code := c.exited(); code != 1 {
+		t.Errorf("wrong exit code. want=1, got=%d", code)
+	}
+}
@@ -0,0 +1,173 @@
+package dap
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"jabline/pkg/object"
+	"jabline/pkg/symbol"
+	"jabline/pkg/vm"
+)
+
+const (
+	scopeLocals  = "Locals"
+	scopeClosure = "Closure"
+	scopeGlobals = "Globals"
+)
+
+// handle is what a variablesReference stands for: one of the scopes of a
+// frame, or a value whose elements can be expanded.
+type handle struct {
+	depth int
+	scope string
+	value object.Object
+}
+
+// newHandle registers h and returns its variablesReference. References
+// are only valid until the program resumes.
+func (s *session) newHandle(h handle) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handles = append(s.handles, h)
+	return len(s.handles)
+}
+
+func (s *session) handle(ref int) (handle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ref < 1 || ref > len(s.handles) {
+		return handle{}, fmt.Errorf("unknown variables reference %d", ref)
+	}
+	return s.handles[ref-1], nil
+}
+
+func (s *session) scopes(depth int) (interface{}, error) {
+	m, err := s.pausedVM()
+	if err != nil {
+		return nil, err
+	}
+	if depth < 0 || depth >= m.Depth() {
+		return nil, fmt.Errorf("unknown frame %d", depth)
+	}
+
+	scopes := []scope{}
+	if depth > 0 {
+		scopes = append(scopes, scope{Name: scopeLocals, VariablesReference: s.newHandle(handle{depth: depth, scope: scopeLocals})})
+		if len(m.Free(depth)) > 0 {
+			scopes = append(scopes, scope{Name: scopeClosure, VariablesReference: s.newHandle(handle{depth: depth, scope: scopeClosure})})
+		}
+	}
+	if s.globals(m, depth) != nil {
+		scopes = append(scopes, scope{Name: scopeGlobals, VariablesReference: s.newHandle(handle{depth: depth, scope: scopeGlobals})})
+	}
+	return map[string]interface{}{"scopes": scopes}, nil
+}
+
+func (s *session) variables(ref int) (interface{}, error) {
+	m, err := s.pausedVM()
+	if err != nil {
+		return nil, err
+	}
+	h, err := s.handle(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var vars []vm.Variable
+	switch h.scope {
+	case scopeLocals:
+		vars = m.Locals(h.depth)
+	case scopeClosure:
+		vars = m.Free(h.depth)
+	case scopeGlobals:
+		vars = s.globals(m, h.depth)
+	default:
+		vars = elements(h.value)
+	}
+
+	out := make([]variable, 0, len(vars))
+	for _, v := range vars {
+		out = append(out, s.variable(v.Name, v.Value))
+	}
+	return map[string]interface{}{"variables": out}, nil
+}
+
+// globals lists the defined globals visible from the frame at depth, by
+// name. Only the program's own globals are named: a function imported from
+// a module runs against the module's globals, whose symbols are unknown.
+func (s *session) globals(m *vm.VM, depth int) []vm.Variable {
+	store := m.Globals(depth)
+	if !sameStore(store, m.Globals(0)) {
+		return nil
+	}
+
+	vars := []vm.Variable{}
+	for _, sym := range s.program.bytecode.SymbolTable.GetStore() {
+		if sym.Scope != symbol.GlobalScope || sym.Index >= len(store) || store[sym.Index] == nil {
+			continue
+		}
+		vars = append(vars, vm.Variable{Name: sym.Name, Value: store[sym.Index]})
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
+func sameStore(a, b []object.Object) bool {
+	return len(a) > 0 && len(b) > 0 && &a[0] == &b[0]
+}
+
+// variable describes obj for the client, with a reference to expand it
+// when it has elements.
+func (s *session) variable(name string, obj object.Object) variable {
+	if obj == nil {
+		obj = vm.Null
+	}
+	v := variable{Name: name, Value: display(obj), Type: string(obj.Type())}
+	if len(elements(obj)) > 0 {
+		v.VariablesReference = s.newHandle(handle{value: obj})
+	}
+	return v
+}
+
+// elements lists the members of arrays, hashes, struct instances and enum
+// values.
+func elements(obj object.Object) []vm.Variable {
+	var vars []vm.Variable
+	switch o := obj.(type) {
+	case *object.Array:
+		for i, el := range o.Elements {
+			vars = append(vars, vm.Variable{Name: "[" + strconv.Itoa(i) + "]", Value: el})
+		}
+	case *object.Hash:
+		for _, pair := range o.Pairs {
+			vars = append(vars, vm.Variable{Name: display(pair.Key), Value: pair.Value})
+		}
+		sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	case *object.Instance:
+		for name, value := range o.Fields {
+			vars = append(vars, vm.Variable{Name: name, Value: value})
+		}
+		sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	case *object.EnumValue:
+		for i, name := range o.Variant.Fields {
+			vars = append(vars, vm.Variable{Name: name, Value: o.Values[i]})
+		}
+	}
+	return vars
+}
+
+func display(obj object.Object) string {
+	switch o := obj.(type) {
+	case nil:
+		return "null"
+	case *object.String:
+		return strconv.Quote(o.Value)
+	case *object.Closure:
+		if o.Fn.Name != "" {
+			return "fn " + o.Fn.Name
+		}
+		return "fn <anonymous>"
+	}
+	return obj.Inspect()
+}
@@ -0,0 +1,325 @@
+// Package disasm turns compiled bytecode into a readable listing: one block
+// per function with decoded operands, resolved constants, labelled jump
+// targets and the source position of every instruction.
+package disasm
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"jabline/pkg/code"
+	"jabline/pkg/compiler"
+	"jabline/pkg/object"
+	"jabline/pkg/stdlib"
+)
+
+// Program is the disassembly of a whole bytecode unit.
+type Program struct {
+	Functions []Function `json:"functions"`
+	Constants []Constant `json:"constants"`
+}
+
+type Constant struct {
+	Index int    `json:"index"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Function is the top-level code (Constant is -1) or a compiled function
+// from the constant pool.
+type Function struct {
+	Name          string        `json:"name"`
+	Constant      int           `json:"constant"`
+	File          string        `json:"file,omitempty"`
+	NumLocals     int           `json:"numLocals"`
+	NumParameters int           `json:"numParameters"`
+	NumOptional   int           `json:"numOptional,omitempty"`
+	Variadic      bool          `json:"variadic,omitempty"`
+	Instructions  []Instruction `json:"instructions"`
+}
+
+type Instruction struct {
+	Offset   int    `json:"offset"`
+	Label    string `json:"label,omitempty"`
+	Op       string `json:"op"`
+	Operands []int  `json:"operands"`
+	// Target is the label of the jump destination for branching opcodes.
+	Target string `json:"target,omitempty"`
+	// Comment describes the operands: a constant's value or a builtin's name.
+	Comment string `json:"comment,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// jumpOps are the opcodes whose first operand is an instruction offset.
+var jumpOps = map[code.Opcode]bool{
+	code.OpJump:              true,
+	code.OpJumpNotTruthy:     true,
+	code.OpJumpNotNull:       true,
+	code.OpJumpNotTruthyKeep: true,
+	code.OpJumpTruthyKeep:    true,
+	code.OpJumpIfEqual:       true,
+	code.OpJumpIfTrue:        true,
+	code.OpJumpNotMissing:    true,
+	code.OpTry:               true,
+}
+
+// constantOperands lists, per opcode, which operands index the constant pool.
+var constantOperands = map[code.Opcode][]int{
+	code.OpConstant:       {0},
+	code.OpClosure:        {0},
+	code.OpCheckType:      {0},
+	code.OpIsType:         {0},
+	code.OpRegisterMethod: {0, 1},
+	code.OpService:        {0},
+	code.OpCallArgs:       {1},
+	code.OpSelect:         {0},
+}
+
+// Disassemble decodes the top-level instructions and every compiled function
+// in the constant pool.
+func Disassemble(b *compiler.Bytecode) *Program {
+	p := &Program{}
+
+	p.Functions = append(p.Functions, function("<main>", -1, b.Instructions, b.SourceMap, b.Constants))
+
+	for i, c := range b.Constants {
+		p.Constants = append(p.Constants, Constant{Index: i, Type: string(c.Type()), Value: describe(c)})
+
+		fn, ok := c.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+		name := fn.Name
+		if name == "" {
+			name = "<anonymous>"
+		}
+		f := function(name, i, fn.Instructions, fn.SourceMap, b.Constants)
+		f.File = fn.File
+		f.NumLocals = fn.NumLocals
+		f.NumParameters = fn.NumParameters
+		f.NumOptional = fn.NumOptional
+		f.Variadic = fn.Variadic
+		p.Functions = append(p.Functions, f)
+	}
+
+	return p
+}
+
+func function(name string, index int, ins code.Instructions, sm code.SourceMap, constants []object.Object) Function {
+	f := Function{Name: name, Constant: index, Instructions: []Instruction{}}
+
+	// Jump targets are collected first so labels can be numbered in
+	// address order.
+	targets := map[int]bool{}
+	for ip := 0; ip < len(ins); {
+		def, err := code.Lookup(ins[ip])
+		if err != nil {
+			ip++
+			continue
+		}
+		operands, read := readOperands(def, ins[ip+1:])
+		if jumpOps[code.Opcode(ins[ip])] && len(operands) > 0 {
+			targets[operands[0]] = true
+		}
+		ip += 1 + read
+	}
+
+	offsets := make([]int, 0, len(targets))
+	for t := range targets {
+		offsets = append(offsets, t)
+	}
+	sort.Ints(offsets)
+	labels := make(map[int]string, len(offsets))
+	for i, t := range offsets {
+		labels[t] = fmt.Sprintf("L%d", i+1)
+	}
+
+	for ip := 0; ip < len(ins); {
+		in := Instruction{Offset: ip, Label: labels[ip], Operands: []int{}}
+		if pos, ok := sm[ip]; ok {
+			in.Line, in.Column = pos.Line, pos.Column
+		}
+
+		def, err := code.Lookup(ins[ip])
+		if err != nil {
+			in.Op = "???"
+			in.Error = err.Error()
+			f.Instructions = append(f.Instructions, in)
+			ip++
+			continue
+		}
+
+		op := code.Opcode(ins[ip])
+		operands, read := readOperands(def, ins[ip+1:])
+		in.Op = def.Name
+		in.Operands = operands
+		if read < operandWidth(def) {
+			in.Error = "truncated operands"
+		}
+
+		var comments []string
+		if jumpOps[op] && len(operands) > 0 {
+			if label, ok := labels[operands[0]]; ok && operands[0] <= len(ins) {
+				in.Target = label
+			} else {
+				in.Target = fmt.Sprintf("%04d", operands[0])
+				in.Error = "jump target outside function"
+			}
+		}
+		for _, i := range constantOperands[op] {
+			if i >= len(operands) {
+				continue
+			}
+			if idx := operands[i]; idx < len(constants) {
+				comments = append(comments, describe(constants[idx]))
+			} else {
+				comments = append(comments, fmt.Sprintf("<constant %d out of range>", idx))
+			}
+		}
+		if op == code.OpGetBuiltin && len(operands) > 0 {
+			if idx := operands[0]; idx < len(stdlib.Registry) {
+				comments = append(comments, stdlib.Registry[idx].Name)
+			}
+		}
+		in.Comment = strings.Join(comments, ", ")
+
+		f.Instructions = append(f.Instructions, in)
+		ip += 1 + read
+	}
+
+	// A jump to the end of the function has no instruction to carry its
+	// label, so it gets a trailing pseudo entry.
+	if label, ok := labels[len(ins)]; ok {
+		f.Instructions = append(f.Instructions, Instruction{Offset: len(ins), Label: label, Op: "<end>", Operands: []int{}})
+	}
+
+	return f
+}
+
+// readOperands is code.ReadOperands without reading past the end of
+// truncated instructions.
+func readOperands(def *code.Definition, ins code.Instructions) ([]int, int) {
+	if len(ins) < operandWidth(def) {
+		return []int{}, len(ins)
+	}
+	return code.ReadOperands(def, ins)
+}
+
+func operandWidth(def *code.Definition) int {
+	width := 0
+	for _, w := range def.OperandWidths {
+		width += w
+	}
+	return width
+}
+
+const maxCommentLen = 48
+
+// describe renders a constant for listings.
+func describe(obj object.Object) string {
+	var s string
+	switch o := obj.(type) {
+	case *object.String:
+		s = fmt.Sprintf("%q", o.Value)
+	case *object.CompiledFunction:
+		name := o.Name
+		if name == "" {
+			name = "<anonymous>"
+		}
+		s = "fn " + name
+	case *object.Struct:
+		s = "struct " + o.Name
+	default:
+		s = strings.Join(strings.Fields(obj.Inspect()), " ")
+	}
+	if len(s) > maxCommentLen {
+		s = s[:maxCommentLen-3] + "..."
+	}
+	return s
+}
+
+// WriteText writes the human readable listing. When source is not empty the
+// text of each source line is printed above the first instruction compiled
+// from it.
+func WriteText(w io.Writer, p *Program, source string) {
+	var lines []string
+	if source != "" {
+		lines = strings.Split(source, "\n")
+	}
+
+	for i, f := range p.Functions {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		header := f.Name
+		if f.Constant >= 0 {
+			header = fmt.Sprintf("%s (constant %d)", f.Name, f.Constant)
+		}
+		fmt.Fprintf(w, "== %s ==\n", header)
+		if f.File != "" {
+			fmt.Fprintf(w, "; file: %s\n", f.File)
+		}
+		params := fmt.Sprint(f.NumParameters)
+		if f.NumOptional > 0 {
+			params += fmt.Sprintf(" (%d optional)", f.NumOptional)
+		}
+		if f.Variadic {
+			params += " (variadic)"
+		}
+		fmt.Fprintf(w, "; params: %s, locals: %d\n", params, f.NumLocals)
+
+		lastLine := 0
+		for _, in := range f.Instructions {
+			if in.Label != "" {
+				fmt.Fprintf(w, "%s:\n", in.Label)
+			}
+			if in.Op == "<end>" {
+				continue
+			}
+			if in.Line > 0 && in.Line != lastLine {
+				if in.Line <= len(lines) && f.File == "" {
+					fmt.Fprintf(w, "    ; %d | %s\n", in.Line, strings.TrimSpace(lines[in.Line-1]))
+				}
+				lastLine = in.Line
+			}
+
+			fmt.Fprintf(w, "    %04d  %-20s %s\n", in.Offset, in.Op, strings.TrimRight(formatOperands(in), " "))
+		}
+	}
+}
+
+func formatOperands(in Instruction) string {
+	var out strings.Builder
+	if in.Target != "" {
+		out.WriteString(in.Target)
+		for _, o := range in.Operands[1:] {
+			fmt.Fprintf(&out, " %d", o)
+		}
+	} else {
+		for i, o := range in.Operands {
+			if i > 0 {
+				out.WriteByte(' ')
+			}
+			fmt.Fprintf(&out, "%d", o)
+		}
+	}
+
+	var notes []string
+	if in.Comment != "" {
+		notes = append(notes, in.Comment)
+	}
+	if in.Line > 0 {
+		notes = append(notes, fmt.Sprintf("@%d:%d", in.Line, in.Column))
+	}
+	if in.Error != "" {
+		notes = append(notes, "ERROR: "+in.Error)
+	}
+	if len(notes) > 0 {
+		return fmt.Sprintf("%-12s ; %s", out.String(), strings.Join(notes, "  "))
+	}
+	return out.String()
+}
@@ -0,0 +1,94 @@
+package disasm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"jabline/pkg/compiler"
+	"jabline/pkg/lexer"
+	"jabline/pkg/parser"
+)
+
+func compile(t *testing.T, input string) *compiler.Bytecode {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	return comp.Bytecode()
+}
+
+func TestDisassemble(t *testing.T) {
+	input := `fn pick(x) {
+    if (x) { return "yes"; }
+    return len([1]);
+}
+pick(true);`
+
+	program := Disassemble(compile(t, input))
+
+	if len(program.Functions) != 2 {
+		t.Fatalf("wrong number of functions. want=2, got=%d", len(program.Functions))
+	}
+	main, pick := program.Functions[0], program.Functions[1]
+	if main.Name != "<main>" || main.Constant != -1 {
+		t.Errorf("first function is not <main>: %+v", main)
+	}
+	if pick.Name != "pick" || pick.NumParameters != 1 {
+		t.Errorf("wrong function metadata: %+v", pick)
+	}
+
+	var jump, target, constant, builtin *Instruction
+	for i := range pick.Instructions {
+		in := &pick.Instructions[i]
+		switch {
+		case in.Op == "OpJumpNotTruthy":
+			jump = in
+		case in.Op == "OpConstant" && in.Comment == `"yes"`:
+			constant = in
+		case in.Op == "OpGetBuiltin":
+			builtin = in
+		}
+		if in.Label != "" && jump != nil && in.Label == jump.Target {
+			target = in
+		}
+	}
+
+	if jump == nil || jump.Target != "L1" {
+		t.Fatalf("conditional jump is not labelled: %+v", jump)
+	}
+	if target == nil || target.Offset != jump.Operands[0] {
+		t.Errorf("label L1 is not placed at the jump target %d", jump.Operands[0])
+	}
+	if constant == nil || constant.Line != 2 {
+		t.Errorf("constant is not resolved or has no line: %+v", constant)
+	}
+	if builtin == nil || builtin.Comment != "len" {
+		t.Errorf("builtin name is not resolved: %+v", builtin)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	input := "let a = 1;\nlet b = a + 2;"
+
+	var out bytes.Buffer
+	WriteText(&out, Disassemble(compile(t, input)), input)
+
+	for _, want := range []string{
+		"== <main> ==",
+		"; 2 | let b = a + 2;",
+		"OpConstant           1            ; 2  @2:13",
+		"OpAdd",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("listing is missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,529 @@
+// Package format implements the canonical source formatter used by
+// `jabline fmt` and the language server.
+//
+// The formatter works on the token stream rather than the AST so that
+// comments survive and constructs are kept in source order. Line breaks are
+// kept where the author put them (runs of blank lines collapse to one);
+// indentation and the spacing between tokens on a line are normalized.
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"jabline/pkg/lexer"
+	"jabline/pkg/parser"
+	"jabline/pkg/token"
+)
+
+const indentUnit = "    "
+
+// Source formats a complete source file.
+func Source(src string) (string, error) {
+	lines, err := layout(src)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	for _, l := range lines {
+		out.WriteString(l.text)
+		out.WriteByte('\n')
+	}
+	return out.String(), nil
+}
+
+// Range formats the whole file but only returns the text that replaces the
+// original lines startLine through endLine (1-based, inclusive), including a
+// trailing newline. Indentation is still computed from the full file.
+func Range(src string, startLine, endLine int) (string, error) {
+	lines, err := layout(src)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	for _, l := range lines {
+		if l.orig >= startLine && l.orig <= endLine {
+			out.WriteString(l.text)
+			out.WriteByte('\n')
+		}
+	}
+	return out.String(), nil
+}
+
+// outLine is a formatted line together with the line of the original source
+// it was produced from.
+type outLine struct {
+	orig int
+	text string
+}
+
+type openBracket struct {
+	line    int
+	ternary int  // pending '?' waiting for their ':' at this nesting level
+	cases   bool // the body of a switch or select, once its first case is seen
+}
+
+type formatter struct {
+	toks []token.Token
+
+	lines     []outLine
+	cur       strings.Builder
+	curOrig   int
+	stack     []openBracket
+	ternary   int // pending '?' at the top level
+	prevEnd   int // line on which the previous token ended
+	prevIdx   int
+	prevUnary bool
+}
+
+func layout(src string) ([]outLine, error) {
+	p := parser.New(lexer.New(src))
+	p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		return nil, fmt.Errorf("%s", errs[0])
+	}
+
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &formatter{toks: toks, prevIdx: -1}
+	f.run()
+
+	formatted := make([]string, len(f.lines))
+	for i, l := range f.lines {
+		formatted[i] = l.text
+	}
+	if err := sameTokens(toks, strings.Join(formatted, "\n")); err != nil {
+		return nil, err
+	}
+
+	return f.lines, nil
+}
+
+func tokenize(src string) ([]token.Token, error) {
+	l := lexer.NewRaw(src)
+	var toks []token.Token
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.EOF {
+			return toks, nil
+		}
+		if tok.Type == token.ILLEGAL {
+			return nil, fmt.Errorf("line %d, column %d: illegal character %q", tok.Line, tok.Column, tok.Literal)
+		}
+		toks = append(toks, tok)
+	}
+}
+
+// sameTokens guards against spacing decisions that would change how the
+// output lexes (for example gluing "-" and "-x" into "--x").
+func sameTokens(want []token.Token, formatted string) error {
+	got, err := tokenize(formatted)
+	if err != nil {
+		return err
+	}
+	if len(got) != len(want) {
+		return fmt.Errorf("formatter changed the token stream (%d tokens, want %d)", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Type != want[i].Type || got[i].Literal != want[i].Literal {
+			return fmt.Errorf("line %d, column %d: formatter changed %q into %q", want[i].Line, want[i].Column, want[i].Literal, got[i].Literal)
+		}
+	}
+	return nil
+}
+
+func (f *formatter) run() {
+	for i, tok := range f.toks {
+		if i == 0 || tok.Line > f.prevEnd {
+			f.newLine(i)
+		} else if f.needsSpace(i) {
+			f.cur.WriteByte(' ')
+		}
+
+		f.cur.WriteString(text(tok))
+		f.track(i)
+
+		f.prevUnary = f.isUnary(i)
+		f.prevIdx = i
+		f.prevEnd = tok.Line + strings.Count(text(tok), "\n")
+	}
+	f.flush()
+}
+
+func (f *formatter) newLine(i int) {
+	tok := f.toks[i]
+	hadLine := f.prevIdx >= 0
+	f.flush()
+
+	if hadLine && tok.Line-f.prevEnd > 1 && !isOpener(f.toks[f.prevIdx].Type) && !isCloser(tok.Type) {
+		f.lines = append(f.lines, outLine{orig: f.prevEnd + 1})
+	}
+
+	// Closers at the start of a line are indented like the line that opened
+	// them, so look past them before measuring the depth.
+	depth := len(f.stack)
+	for j := i; j < len(f.toks) && f.toks[j].Line == tok.Line && isCloser(f.toks[j].Type) && depth > 0; j++ {
+		depth--
+	}
+	level := openLines(f.stack[:depth]) + caseBodies(f.stack[:depth])
+	if depth > 0 && f.stack[depth-1].cases && startsCase(tok.Type) {
+		level--
+	}
+	if f.continues(i) {
+		level++
+	}
+
+	f.curOrig = tok.Line
+	f.cur.WriteString(strings.Repeat(indentUnit, level))
+}
+
+func (f *formatter) flush() {
+	if f.curOrig == 0 {
+		return
+	}
+	for n, line := range strings.Split(f.cur.String(), "\n") {
+		f.lines = append(f.lines, outLine{orig: f.curOrig + n, text: line})
+	}
+	f.cur.Reset()
+	f.curOrig = 0
+}
+
+// continues reports whether the line starting at token i continues an
+// expression from the previous line and so gets an extra indent.
+func (f *formatter) continues(i int) bool {
+	switch f.toks[i].Type {
+	case token.DOT, token.OPTIONAL_CHAINING, token.AND, token.OR, token.NULLISH_COALESCING, token.QUESTION:
+		return true
+	case token.COLON:
+		return f.pendingTernary() > 0
+	}
+
+	prev := f.lastCodeToken()
+	if prev < 0 || f.toks[prev].Type == token.QUESTION && f.marksNullable(prev) {
+		return false
+	}
+	return isBinaryOperator(f.toks[prev].Type) && !f.isUnary(prev)
+}
+
+func (f *formatter) lastCodeToken() int {
+	for j := f.prevIdx; j >= 0; j-- {
+		if f.toks[j].Type != token.COMMENT {
+			return j
+		}
+	}
+	return -1
+}
+
+func (f *formatter) pendingTernary() int {
+	if len(f.stack) == 0 {
+		return f.ternary
+	}
+	return f.stack[len(f.stack)-1].ternary
+}
+
+func (f *formatter) addTernary(n int) {
+	if len(f.stack) == 0 {
+		f.ternary += n
+	} else {
+		f.stack[len(f.stack)-1].ternary += n
+	}
+}
+
+func (f *formatter) track(i int) {
+	tok := f.toks[i]
+	switch {
+	case isOpener(tok.Type):
+		f.stack = append(f.stack, openBracket{line: tok.Line})
+	case isCloser(tok.Type):
+		if len(f.stack) > 0 {
+			f.stack = f.stack[:len(f.stack)-1]
+		}
+	case startsCase(tok.Type) && tok.Line > f.prevEnd:
+		if len(f.stack) > 0 {
+			f.stack[len(f.stack)-1].cases = true
+		}
+	case tok.Type == token.QUESTION && !f.marksNullable(i):
+		f.addTernary(1)
+	case tok.Type == token.COLON && f.pendingTernary() > 0:
+		f.addTernary(-1)
+	}
+}
+
+// openLines counts the distinct lines holding the open brackets, so that
+// several brackets opened on one line (`foo({`) only indent once.
+func openLines(stack []openBracket) int {
+	n := 0
+	last := -1
+	for _, b := range stack {
+		if b.line != last {
+			n++
+			last = b.line
+		}
+	}
+	return n
+}
+
+// caseBodies counts the switch and select bodies in stack. The statements
+// of a case are indented one level past its case line.
+func caseBodies(stack []openBracket) int {
+	n := 0
+	for _, b := range stack {
+		if b.cases {
+			n++
+		}
+	}
+	return n
+}
+
+func startsCase(t token.TokenType) bool {
+	return t == token.CASE || t == token.DEFAULT
+}
+
+func (f *formatter) needsSpace(i int) bool {
+	if !f.wantSpace(i) {
+		prev := f.toks[f.prevIdx]
+		return !joinable(prev, f.toks[i])
+	}
+	return true
+}
+
+func (f *formatter) wantSpace(i int) bool {
+	prev := f.toks[f.prevIdx]
+	cur := f.toks[i]
+
+	switch {
+	case cur.Type == token.COMMENT:
+		return true
+	case f.prevUnary:
+		return false
+	case prev.Type == token.LPAREN || prev.Type == token.LBRACKET:
+		return false
+	case cur.Type == token.RPAREN || cur.Type == token.RBRACKET:
+		return false
+	case cur.Type == token.RBRACE:
+		return prev.Type != token.LBRACE
+	case prev.Type == token.LBRACE:
+		return true
+	case cur.Type == token.COMMA || cur.Type == token.SEMICOLON:
+		return false
+	case prev.Type == token.COMMA || prev.Type == token.SEMICOLON:
+		return true
+	case cur.Type == token.DOT || prev.Type == token.DOT,
+		cur.Type == token.OPTIONAL_CHAINING || prev.Type == token.OPTIONAL_CHAINING,
+		cur.Type == token.RANGE || prev.Type == token.RANGE,
+		cur.Type == token.RANGE_INCLUSIVE || prev.Type == token.RANGE_INCLUSIVE,
+		prev.Type == token.ELLIPSIS:
+		return false
+	case f.marksGenerator(i):
+		return false
+	case cur.Type == token.LBRACE && prev.Type == token.IDENT:
+		return f.declaresName(f.prevIdx) || f.opensBlockStatement(f.prevIdx)
+	case cur.Type == token.LPAREN:
+		return !f.callsParen(f.prevIdx)
+	case cur.Type == token.LBRACKET:
+		return !endsOperand(prev.Type) || prev.Type == token.RBRACE
+	case cur.Type == token.INCREMENT || cur.Type == token.DECREMENT:
+		return !endsOperand(prev.Type)
+	case cur.Type == token.COLON:
+		return f.pendingTernary() > 0
+	case cur.Type == token.QUESTION:
+		return !f.marksNullable(i)
+	}
+	return true
+}
+
+// marksNullable reports whether the '?' at i ends a nullable type (`int?`)
+// rather than starting a conditional: it follows a type name and is
+// followed by something that cannot begin the conditional's branch.
+func (f *formatter) marksNullable(i int) bool {
+	if i == 0 {
+		return false
+	}
+	switch prev := f.toks[i-1].Type; {
+	case prev == token.IDENT, prev == token.RBRACKET, prev == token.NULL, isTypeKeyword(prev):
+	default:
+		return false
+	}
+	if i+1 >= len(f.toks) || f.toks[i+1].Line != f.toks[i].Line {
+		return true
+	}
+	switch f.toks[i+1].Type {
+	case token.ASSIGN, token.COMMA, token.RPAREN, token.RBRACKET, token.RBRACE,
+		token.SEMICOLON, token.BIT_OR, token.EOF:
+		return true
+	case token.LBRACE:
+		// `fn f(): int? {` opens the body; `c ? { ... } : d` is a hash.
+		closing := matching(f.toks, i+1)
+		return closing < 0 || closing+1 >= len(f.toks) || f.toks[closing+1].Type != token.COLON
+	}
+	return false
+}
+
+// callsParen reports whether a '(' following token i belongs to it without
+// a space: calls, casts and `fn(...)` literals, but not method receivers
+// (`fn (r Rect) area()`) or control keywords (`if (...)`, `match (...) {`).
+func (f *formatter) callsParen(i int) bool {
+	switch prev := f.toks[i]; {
+	case prev.Type == token.IDENT && prev.Literal == "match":
+		closing := matching(f.toks, i+1)
+		return closing < 0 || closing+1 >= len(f.toks) || f.toks[closing+1].Type != token.LBRACE
+	case prev.Type == token.IDENT, prev.Type == token.RPAREN, prev.Type == token.RBRACKET,
+		prev.Type == token.ECHO, isTypeKeyword(prev.Type):
+		return true
+	case prev.Type == token.FUNCTION, f.marksGenerator(i):
+		closing := matching(f.toks, i+1)
+		return closing < 0 || closing+1 >= len(f.toks) || f.toks[closing+1].Type != token.IDENT
+	}
+	return false
+}
+
+// marksGenerator reports whether the token at i is the '*' of `fn*`.
+func (f *formatter) marksGenerator(i int) bool {
+	return i > 0 && f.toks[i].Type == token.ASTERISK && f.toks[i-1].Type == token.FUNCTION
+}
+
+// declaresName reports whether the identifier at i is the name in a
+// `struct`, `enum` or `service` declaration; otherwise an identifier
+// followed by '{' starts a struct literal (`Point{ x: 1 }`).
+func (f *formatter) declaresName(i int) bool {
+	if i == 0 {
+		return false
+	}
+	switch f.toks[i-1].Type {
+	case token.STRUCT, token.ENUM, token.SERVICE:
+		return true
+	}
+	return false
+}
+
+// opensBlockStatement reports whether the identifier at i comes before the
+// block of a select or group statement rather than a struct literal: it is
+// a `select` or `group` beginning a statement, or the name of a group.
+func (f *formatter) opensBlockStatement(i int) bool {
+	switch f.toks[i].Literal {
+	case "select", "group":
+		return f.beginsStatement(i)
+	}
+	return i > 0 && f.toks[i-1].Literal == "group" && f.beginsStatement(i-1)
+}
+
+// beginsStatement reports whether the token at i begins a statement.
+func (f *formatter) beginsStatement(i int) bool {
+	if i == 0 || f.toks[i-1].Line != f.toks[i].Line {
+		return true
+	}
+	switch f.toks[i-1].Type {
+	case token.SEMICOLON, token.LBRACE, token.RBRACE, token.COLON:
+		return true
+	}
+	return false
+}
+
+func (f *formatter) isUnary(i int) bool {
+	switch f.toks[i].Type {
+	case token.BANG, token.BIT_NOT:
+		return true
+	case token.MINUS, token.PLUS, token.ARROW_LEFT, token.INCREMENT, token.DECREMENT:
+		prev := -1
+		for j := i - 1; j >= 0; j-- {
+			if f.toks[j].Type != token.COMMENT {
+				prev = j
+				break
+			}
+		}
+		if prev < 0 {
+			return true
+		}
+		return !endsOperand(f.toks[prev].Type)
+	}
+	return false
+}
+
+// joinable reports whether two tokens still lex as the same two tokens when
+// written without a space between them.
+func joinable(a, b token.Token) bool {
+	if a.Type == token.COMMENT || b.Type == token.COMMENT {
+		return false
+	}
+	l := lexer.NewRaw(text(a) + text(b))
+	first, second, third := l.NextToken(), l.NextToken(), l.NextToken()
+	return first.Type == a.Type && first.Literal == a.Literal &&
+		second.Type == b.Type && second.Literal == b.Literal &&
+		third.Type == token.EOF
+}
+
+// matching returns the index of the bracket closing the one at i, or -1.
+func matching(toks []token.Token, i int) int {
+	if i >= len(toks) || !isOpener(toks[i].Type) {
+		return -1
+	}
+	depth := 0
+	for j := i; j < len(toks); j++ {
+		switch {
+		case isOpener(toks[j].Type):
+			depth++
+		case isCloser(toks[j].Type):
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+func text(tok token.Token) string {
+	switch tok.Type {
+	case token.STRING:
+		return `"` + tok.Literal + `"`
+	case token.TEMPLATE_LITERAL:
+		return "`" + tok.Literal + "`"
+	}
+	return tok.Literal
+}
+
+func isOpener(t token.TokenType) bool {
+	return t == token.LPAREN || t == token.LBRACKET || t == token.LBRACE
+}
+
+func isCloser(t token.TokenType) bool {
+	return t == token.RPAREN || t == token.RBRACKET || t == token.RBRACE
+}
+
+func endsOperand(t token.TokenType) bool {
+	switch t {
+	case token.IDENT, token.INT, token.FLOAT, token.STRING, token.TEMPLATE_LITERAL,
+		token.TRUE, token.FALSE, token.NULL,
+		token.RPAREN, token.RBRACKET, token.RBRACE,
+		token.INCREMENT, token.DECREMENT:
+		return true
+	}
+	return isTypeKeyword(t)
+}
+
+func isBinaryOperator(t token.TokenType) bool {
+	switch t {
+	case token.ASSIGN, token.PLUS_ASSIGN, token.SUB_ASSIGN, token.MUL_ASSIGN, token.DIV_ASSIGN,
+		token.PLUS, token.MINUS, token.ASTERISK, token.SLASH, token.MOD,
+		token.BIT_AND, token.BIT_OR, token.BIT_XOR, token.SHIFT_LEFT, token.SHIFT_RIGHT,
+		token.AND, token.OR, token.LT, token.GT, token.LT_EQ, token.GT_EQ, token.EQ, token.NOT_EQ,
+		token.QUESTION, token.ARROW, token.ARROW_LEFT, token.NULLISH_COALESCING:
+		return true
+	}
+	return false
+}
+
+func isTypeKeyword(t token.TokenType) bool {
+	switch t {
+	case token.STRING_TYPE, token.INT_TYPE, token.INT8_TYPE, token.INT16_TYPE, token.INT32_TYPE,
+		token.INT64_TYPE, token.UINT8_TYPE, token.UINT16_TYPE, token.UINT32_TYPE, token.UINT64_TYPE,
+		token.FLOAT_TYPE, token.FLOAT32_TYPE, token.FLOAT64_TYPE, token.BOOL_TYPE:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package format
+
+import "testing"
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=1+2;", "let x = 1 + 2;\n"},
+		{"fn add(a,b){\nreturn a+b;\n}", "fn add(a, b) {\n    return a + b;\n}\n"},
+		{"let p = Point { x: 1, y: -2 };", "let p = Point{ x: 1, y: -2 };\n"},
+		{"if(x>1){\necho(x);\n}else{\nx++;\n}", "if (x > 1) {\n    echo(x);\n} else {\n    x++;\n}\n"},
+		{"let a = [ 1,2 ] ;\nlet b = a[ 0 ];", "let a = [1, 2];\nlet b = a[0];\n"},
+		{"let y = x ? 1 : 2;", "let y = x ? 1 : 2;\n"},
+		{"let a : int ? = null;\nfn f(b: Box[int] ?, c: int|string): int? {\n}", "let a: int? = null;\nfn f(b: Box[int]?, c: int | string): int? {\n}\n"},
+		{"let t = `a ${ b } c`;", "let t = `a ${ b } c`;\n"},
+		{
+			"let s = match(x){\n1 .. 5=>\"few\",\n[a, ... rest] if a>0=>rest,\n_=>null\n};",
+			"let s = match (x) {\n    1..5 => \"few\",\n    [a, ...rest] if a > 0 => rest,\n    _ => null\n};\n",
+		},
+		{"select{\ncase v = <-ch:\necho(v);\n}", "select {\n    case v = <-ch:\n        echo(v);\n}\n"},
+		{
+			"select {\ncase v = <-ch:\nif (v) {\necho(v);\n}\ncase out <- 1:\ndefault:\necho(0);\n}",
+			"select {\n    case v = <-ch:\n        if (v) {\n            echo(v);\n        }\n    case out <- 1:\n    default:\n        echo(0);\n}\n",
+		},
+		{
+			"switch(x){\ncase 1:\necho(1);\ndefault:\nselect {\ncase <-ch:\necho(2);\n}\n}",
+			"switch (x) {\n    case 1:\n        echo(1);\n    default:\n        select {\n            case <-ch:\n                echo(2);\n        }\n}\n",
+		},
+		{"group g{\nspawn f();\n}\ngroup{\nspawn f();\n}", "group g {\n    spawn f();\n}\ngroup {\n    spawn f();\n}\n"},
+		{"fn *gen(n) {\nyield n*2;\n}\nlet g = fn * () { yield; };", "fn* gen(n) {\n    yield n * 2;\n}\nlet g = fn*() { yield; };\n"},
+		{
+			"// leading comment\nlet a = 1; // trailing\n\n\n\nlet b = 2;\n/* block */\n",
+			"// leading comment\nlet a = 1; // trailing\n\nlet b = 2;\n/* block */\n",
+		},
+		{
+			"fn f() {\n\n    let a = 1;\n\n}\n",
+			"fn f() {\n    let a = 1;\n}\n",
+		},
+		{
+			"let r = items\n.map(f)\n.filter(g);",
+			"let r = items\n    .map(f)\n    .filter(g);\n",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Source(tt.input)
+		if err != nil {
+			t.Errorf("Source(%q) returned error: %s", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("Source(%q) wrong.\nwant=%q\ngot= %q", tt.input, tt.expected, got)
+			continue
+		}
+
+		again, err := Source(got)
+		if err != nil || again != got {
+			t.Errorf("formatting is not idempotent for %q.\nfirst= %q\nsecond=%q (err=%v)", tt.input, got, again, err)
+		}
+	}
+}
+
+func TestSourceRejectsInvalidInput(t *testing.T) {
+	if _, err := Source("let = ;"); err == nil {
+		t.Fatalf("expected an error for a program that does not parse")
+	}
+}
+
+func TestRange(t *testing.T) {
+	input := "fn f() {\nlet a=1;\n  let b=2;\nreturn a+b;\n}\n"
+
+	got, err := Range(input, 2, 3)
+	if err != nil {
+		t.Fatalf("Range returned error: %s", err)
+	}
+	expected := "    let a = 1;\n    let b = 2;\n"
+	if got != expected {
+		t.Fatalf("Range wrong.\nwant=%q\ngot= %q", expected, got)
+	}
+}
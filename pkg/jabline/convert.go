@@ -0,0 +1,624 @@
+package jabline
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"jabline/pkg/object"
+)
+
+var (
+	objectType = reflect.TypeOf((*object.Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// FromGo converts a Go value into a Jabline object. Numbers, strings and
+// booleans map to their primitives, slices and arrays to arrays, maps and
+// structs to hashes and functions to builtins (see Runtime.Register). Struct
+// fields are named by their `jabline` tag, or by the field name; unexported
+// fields and fields tagged "-" are skipped. Unsigned integers too large for
+// an int become UInt64 values. An object.Object is returned unchanged, and
+// a value that contains itself is an error.
+func FromGo(v interface{}) (object.Object, error) {
+	if v == nil {
+		return object.NullValue, nil
+	}
+	if obj, ok := v.(object.Object); ok {
+		return obj, nil
+	}
+	return fromValue(reflect.ValueOf(v), map[visit]bool{})
+}
+
+// visit is a pointer, map or slice being converted, so that fromValue can
+// tell a value that refers back to one containing it.
+type visit struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+// visitOf returns the visit of a non-nil pointer or map, or of a non-empty
+// slice.
+func visitOf(v reflect.Value) (visit, bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map:
+		return visit{ptr: v.Pointer(), typ: v.Type()}, !v.IsNil()
+	case reflect.Slice:
+		return visit{ptr: v.Pointer(), len: v.Len(), typ: v.Type()}, v.Len() > 0
+	}
+	return visit{}, false
+}
+
+// fromValue converts v, the pointers, maps and slices in path being those
+// that contain it.
+func fromValue(v reflect.Value, path map[visit]bool) (object.Object, error) {
+	if !v.IsValid() {
+		return object.NullValue, nil
+	}
+	if v.Type().Implements(objectType) && v.CanInterface() {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return object.NullValue, nil
+		}
+		return v.Interface().(object.Object), nil
+	}
+	if at, ok := visitOf(v); ok {
+		if path[at] {
+			return nil, fmt.Errorf("cannot convert %s: it contains itself", v.Type())
+		}
+		path[at] = true
+		defer delete(path, at)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return object.TrueValue, nil
+		}
+		return object.FalseValue, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return &object.UInt64{Value: v.Uint()}, nil
+		}
+		return &object.Integer{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: v.Float()}, nil
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return object.NullValue, nil
+		}
+		return fromValue(v.Elem(), path)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return object.NullValue, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return &object.String{Value: string(bytesOf(v))}, nil
+		}
+		elements := make([]object.Object, v.Len())
+		for i := range elements {
+			el, err := fromValue(v.Index(i), path)
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			elements[i] = el
+		}
+		return &object.Array{Elements: elements}, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return object.NullValue, nil
+		}
+		pairs := make(map[object.HashKey]object.HashPair, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := fromValue(iter.Key(), path)
+			if err != nil {
+				return nil, err
+			}
+			hashable, ok := key.(object.Hashable)
+			if !ok {
+				return nil, fmt.Errorf("unusable as hash key: %s", iter.Key().Type())
+			}
+			value, err := fromValue(iter.Value(), path)
+			if err != nil {
+				return nil, fmt.Errorf("key %v: %w", iter.Key().Interface(), err)
+			}
+			pairs[hashable.HashKey()] = object.HashPair{Key: key, Value: value}
+		}
+		return &object.Hash{Pairs: pairs}, nil
+
+	case reflect.Struct:
+		pairs := make(map[object.HashKey]object.HashPair)
+		for _, f := range structFields(v.Type()) {
+			value, err := fromValue(v.Field(f.index), path)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.name, err)
+			}
+			key := &object.String{Value: f.name}
+			pairs[key.HashKey()] = object.HashPair{Key: key, Value: value}
+		}
+		return &object.Hash{Pairs: pairs}, nil
+
+	case reflect.Func:
+		if v.IsNil() {
+			return object.NullValue, nil
+		}
+		return wrapFunc(v)
+	}
+
+	return nil, fmt.Errorf("cannot convert %s to a Jabline value", v.Type())
+}
+
+func bytesOf(v reflect.Value) []byte {
+	if v.Kind() == reflect.Slice {
+		return v.Bytes()
+	}
+	b := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+	return b
+}
+
+type structField struct {
+	name  string
+	index int
+}
+
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("jabline"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, structField{name: name, index: i})
+	}
+	return fields
+}
+
+// ToGo converts a Jabline object into a plain Go value: int64, float64,
+// string, bool, nil, []interface{} for arrays and map[string]interface{}
+// for hashes with string keys and struct instances. Sized numbers keep their
+// Go type. Anything else, such as functions, is returned as the object
+// itself. An array, hash or instance that contains itself is an error.
+func ToGo(obj object.Object) (interface{}, error) {
+	return toGo(obj, map[object.Object]bool{})
+}
+
+// toGo converts obj, the arrays, hashes and instances in path being those
+// that contain it.
+func toGo(obj object.Object, path map[object.Object]bool) (interface{}, error) {
+	switch obj.(type) {
+	case *object.Array, *object.Hash, *object.Instance:
+		if path[obj] {
+			return nil, fmt.Errorf("cannot convert %s: it contains itself", obj.Type())
+		}
+		path[obj] = true
+		defer delete(path, obj)
+	}
+
+	switch o := obj.(type) {
+	case nil, *object.Null:
+		return nil, nil
+	case *object.Integer:
+		return o.Value, nil
+	case *object.Float:
+		return o.Value, nil
+	case *object.String:
+		return o.Value, nil
+	case *object.Boolean:
+		return o.Value, nil
+	case *object.Int8:
+		return o.Value, nil
+	case *object.Int16:
+		return o.Value, nil
+	case *object.Int32:
+		return o.Value, nil
+	case *object.Int64:
+		return o.Value, nil
+	case *object.UInt8:
+		return o.Value, nil
+	case *object.UInt16:
+		return o.Value, nil
+	case *object.UInt32:
+		return o.Value, nil
+	case *object.UInt64:
+		return o.Value, nil
+	case *object.Float32:
+		return o.Value, nil
+	case *object.Float64:
+		return o.Value, nil
+	case *object.Array:
+		out := make([]interface{}, len(o.Elements))
+		for i, el := range o.Elements {
+			value, err := toGo(el, path)
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			out[i] = value
+		}
+		return out, nil
+	case *object.Hash:
+		if !stringKeyed(o) {
+			out := make(map[interface{}]interface{}, len(o.Pairs))
+			for _, pair := range o.Pairs {
+				key, err := toGo(pair.Key, path)
+				if err != nil {
+					return nil, err
+				}
+				value, err := toGo(pair.Value, path)
+				if err != nil {
+					return nil, fmt.Errorf("key %s: %w", pair.Key.Inspect(), err)
+				}
+				out[key] = value
+			}
+			return out, nil
+		}
+		out := make(map[string]interface{}, len(o.Pairs))
+		for _, pair := range o.Pairs {
+			key := pair.Key.(*object.String).Value
+			value, err := toGo(pair.Value, path)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", key, err)
+			}
+			out[key] = value
+		}
+		return out, nil
+	case *object.Instance:
+		out := make(map[string]interface{}, len(o.Fields))
+		for name, field := range o.Fields {
+			value, err := toGo(field, path)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", name, err)
+			}
+			out[name] = value
+		}
+		return out, nil
+	}
+	return obj, nil
+}
+
+func stringKeyed(h *object.Hash) bool {
+	for _, pair := range h.Pairs {
+		if _, ok := pair.Key.(*object.String); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Decode stores obj in the value pointed to by out, converting as needed.
+// Hashes and struct instances decode into structs (matching fields the way
+// FromGo names them) and maps; arrays into slices and arrays. Numbers that
+// do not fit the target type are an error.
+func Decode(obj object.Object, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("decode target must be a non-nil pointer")
+	}
+	return decodeValue(obj, v.Elem())
+}
+
+func decodeValue(obj object.Object, v reflect.Value) error {
+	t := v.Type()
+
+	if t == objectType {
+		v.Set(reflect.ValueOf(&obj).Elem())
+		return nil
+	}
+	if _, isNull := obj.(*object.Null); isNull || obj == nil {
+		v.Set(reflect.Zero(t))
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		goValue, err := ToGo(obj)
+		if err != nil {
+			return err
+		}
+		if goValue == nil {
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+		rv := reflect.ValueOf(goValue)
+		if !rv.Type().AssignableTo(t) {
+			return typeError(obj, t)
+		}
+		v.Set(rv)
+		return nil
+
+	case reflect.Ptr:
+		elem := reflect.New(t.Elem())
+		if err := decodeValue(obj, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+
+	case reflect.Bool:
+		b, ok := obj.(*object.Boolean)
+		if !ok {
+			return typeError(obj, t)
+		}
+		v.SetBool(b.Value)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := integerOf(obj)
+		if !ok {
+			return typeError(obj, t)
+		}
+		if v.OverflowInt(n) {
+			return fmt.Errorf("%d overflows %s", n, t)
+		}
+		v.SetInt(n)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u, ok := obj.(*object.UInt64); ok {
+			if v.OverflowUint(u.Value) {
+				return fmt.Errorf("%d overflows %s", u.Value, t)
+			}
+			v.SetUint(u.Value)
+			return nil
+		}
+		n, ok := integerOf(obj)
+		if !ok {
+			return typeError(obj, t)
+		}
+		if n < 0 || v.OverflowUint(uint64(n)) {
+			return fmt.Errorf("%d overflows %s", n, t)
+		}
+		v.SetUint(uint64(n))
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		switch n := scalarToGo(obj).(type) {
+		case float64:
+			v.SetFloat(n)
+		case float32:
+			v.SetFloat(float64(n))
+		default:
+			i, ok := integerOf(obj)
+			if !ok {
+				return typeError(obj, t)
+			}
+			v.SetFloat(float64(i))
+		}
+		return nil
+
+	case reflect.String:
+		s, ok := obj.(*object.String)
+		if !ok {
+			return typeError(obj, t)
+		}
+		v.SetString(s.Value)
+		return nil
+
+	case reflect.Slice:
+		if s, ok := obj.(*object.String); ok && t.Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(s.Value))
+			return nil
+		}
+		arr, ok := obj.(*object.Array)
+		if !ok {
+			return typeError(obj, t)
+		}
+		slice := reflect.MakeSlice(t, len(arr.Elements), len(arr.Elements))
+		for i, el := range arr.Elements {
+			if err := decodeValue(el, slice.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+		v.Set(slice)
+		return nil
+
+	case reflect.Array:
+		arr, ok := obj.(*object.Array)
+		if !ok {
+			return typeError(obj, t)
+		}
+		if len(arr.Elements) != t.Len() {
+			return fmt.Errorf("cannot decode array of %d elements into %s", len(arr.Elements), t)
+		}
+		for i, el := range arr.Elements {
+			if err := decodeValue(el, v.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		entries, ok := entriesOf(obj)
+		if !ok {
+			return typeError(obj, t)
+		}
+		m := reflect.MakeMapWithSize(t, len(entries))
+		for _, e := range entries {
+			key := reflect.New(t.Key()).Elem()
+			if err := decodeValue(e.Key, key); err != nil {
+				return fmt.Errorf("key %s: %w", e.Key.Inspect(), err)
+			}
+			value := reflect.New(t.Elem()).Elem()
+			if err := decodeValue(e.Value, value); err != nil {
+				return fmt.Errorf("key %s: %w", e.Key.Inspect(), err)
+			}
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+		return nil
+
+	case reflect.Struct:
+		entries, ok := entriesOf(obj)
+		if !ok {
+			return typeError(obj, t)
+		}
+		byName := make(map[string]object.Object, len(entries))
+		for _, e := range entries {
+			if key, ok := e.Key.(*object.String); ok {
+				byName[key.Value] = e.Value
+			}
+		}
+		for _, f := range structFields(t) {
+			value, ok := byName[f.name]
+			if !ok {
+				continue
+			}
+			if err := decodeValue(value, v.Field(f.index)); err != nil {
+				return fmt.Errorf("field %s: %w", f.name, err)
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("cannot decode into %s", t)
+}
+
+// scalarToGo converts obj if it is a number, string, boolean or null.
+func scalarToGo(obj object.Object) interface{} {
+	switch obj.(type) {
+	case *object.Array, *object.Hash, *object.Instance:
+		return nil
+	}
+	value, _ := ToGo(obj)
+	return value
+}
+
+func typeError(obj object.Object, t reflect.Type) error {
+	return fmt.Errorf("cannot decode %s into %s", obj.Type(), t)
+}
+
+func integerOf(obj object.Object) (int64, bool) {
+	switch n := scalarToGo(obj).(type) {
+	case int64:
+		return n, true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), n <= math.MaxInt64
+	}
+	return 0, false
+}
+
+// entriesOf returns the key/value pairs of a hash or struct instance in a
+// stable order.
+func entriesOf(obj object.Object) ([]object.HashPair, bool) {
+	var entries []object.HashPair
+	switch o := obj.(type) {
+	case *object.Hash:
+		for _, pair := range o.Pairs {
+			entries = append(entries, pair)
+		}
+	case *object.Instance:
+		for name, value := range o.Fields {
+			entries = append(entries, object.HashPair{Key: &object.String{Value: name}, Value: value})
+		}
+	default:
+		return nil, false
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key.Inspect() < entries[j].Key.Inspect()
+	})
+	return entries, true
+}
+
+// wrapFunc turns a Go function into a builtin. Arguments are decoded into
+// the parameter types and results converted back with FromGo. A trailing
+// error result that is not nil becomes a Jabline error value.
+func wrapFunc(fn reflect.Value) (*object.Builtin, error) {
+	if native, ok := fn.Interface().(func(args ...object.Object) object.Object); ok {
+		return &object.Builtin{Fn: native}, nil
+	}
+
+	t := fn.Type()
+	numOut := t.NumOut()
+	returnsErr := numOut > 0 && t.Out(numOut-1) == errorType
+	if numOut > 2 || (numOut == 2 && !returnsErr) {
+		return nil, fmt.Errorf("function %s must return at most a value and an error", t)
+	}
+
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		in, err := decodeArgs(t, args)
+		if err != nil {
+			return &object.Error{Message: err.Error()}
+		}
+
+		out := fn.Call(in)
+		if returnsErr {
+			if errVal := out[numOut-1]; !errVal.IsNil() {
+				return &object.Error{Message: errVal.Interface().(error).Error()}
+			}
+			out = out[:numOut-1]
+		}
+		if len(out) == 0 {
+			return object.NullValue
+		}
+
+		result, err := fromValue(out[0], map[visit]bool{})
+		if err != nil {
+			return &object.Error{Message: err.Error()}
+		}
+		return result
+	}}, nil
+}
+
+func decodeArgs(t reflect.Type, args []object.Object) ([]reflect.Value, error) {
+	numIn := t.NumIn()
+	fixed := numIn
+	if t.IsVariadic() {
+		fixed--
+	}
+
+	if len(args) < fixed || (!t.IsVariadic() && len(args) != numIn) {
+		want := fmt.Sprintf("%d", numIn)
+		if t.IsVariadic() {
+			want = fmt.Sprintf("at least %d", fixed)
+		}
+		return nil, fmt.Errorf("wrong number of arguments. got=%d, want=%s", len(args), want)
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var pt reflect.Type
+		if i < fixed {
+			pt = t.In(i)
+		} else {
+			pt = t.In(fixed).Elem()
+		}
+		v := reflect.New(pt).Elem()
+		if err := decodeValue(arg, v); err != nil {
+			return nil, fmt.Errorf("argument %d: %s", i+1, strings.TrimPrefix(err.Error(), "cannot decode "))
+		}
+		in[i] = v
+	}
+	return in, nil
+}
@@ -0,0 +1,274 @@
+// Package jabline embeds the Jabline interpreter in Go programs.
+//
+// A Runtime keeps its globals between calls, so a script can be loaded once
+// with RunFile or Eval and its functions invoked later with Call:
+//
+//	rt := jabline.New(jabline.Options{Stdout: &buf})
+//	rt.Register("lookup", func(id int) (User, error) { ... })
+//	if _, err := rt.RunFile(ctx, "handlers.jb"); err != nil { ... }
+//	result, err := rt.Call("handle", request)
+//
+// Go values cross into the runtime through FromGo and come back through
+// ToGo or Decode.
+package jabline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"jabline/pkg/ast"
+	"jabline/pkg/code"
+	"jabline/pkg/compiler"
+	"jabline/pkg/lexer"
+	"jabline/pkg/object"
+	"jabline/pkg/parser"
+	"jabline/pkg/symbol"
+	"jabline/pkg/vm"
+)
+
+type Options struct {
+	// Stdout and Stderr receive what scripts write. They default to the
+	// process's own.
+	Stdout io.Writer
+	Stderr io.Writer
+	// ModulePaths are searched for imports after the default locations.
+	ModulePaths []string
+	// Limits bounds each Eval, RunFile and Call separately.
+	Limits vm.Limits
+}
+
+// Runtime is a Jabline interpreter with its own globals, registered
+// functions and imported modules. Its methods may be called from several
+// goroutines, but only one script runs at a time.
+type Runtime struct {
+	mu        sync.Mutex
+	symbols   *symbol.SymbolTable
+	constants []object.Object
+	globals   []object.Object
+	loader    *vm.ModuleLoader
+	stdout    io.Writer
+	stderr    io.Writer
+	limits    vm.Limits
+}
+
+func New(opts Options) *Runtime {
+	loader := vm.NewModuleLoader()
+	for _, path := range opts.ModulePaths {
+		loader.AddPath(path)
+	}
+
+	stdout, stderr := opts.Stdout, opts.Stderr
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+
+	return &Runtime{
+		symbols:   compiler.New().GetSymbolTable(),
+		constants: []object.Object{},
+		globals:   make([]object.Object, vm.GlobalsSize),
+		loader:    loader,
+		stdout:    stdout,
+		stderr:    stderr,
+		limits:    opts.Limits,
+	}
+}
+
+// Eval compiles and runs src against the runtime's globals and returns the
+// value of its last expression statement, or nil.
+func (r *Runtime) Eval(ctx context.Context, src string) (object.Object, error) {
+	return r.eval(ctx, src, "<eval>")
+}
+
+// RunFile runs the script at path like Eval. Relative imports in it are
+// resolved the same way as for `jabline run`.
+func (r *Runtime) RunFile(ctx context.Context, path string) (object.Object, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.eval(ctx, string(src), path)
+}
+
+func (r *Runtime) eval(ctx context.Context, src, filename string) (object.Object, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, fmt.Errorf("%s: parser errors:\n\t%s", filename, strings.Join(p.Errors(), "\n\t"))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bytecode, err := r.compile(program)
+	if err != nil {
+		return nil, fmt.Errorf("%s: compiler error: %w", filename, err)
+	}
+	return r.run(ctx, bytecode.Instructions, bytecode.Constants, bytecode.SourceMap, filename)
+}
+
+// compile compiles program against the runtime's symbol table. The
+// constant pool is only kept when compilation succeeds.
+func (r *Runtime) compile(program *ast.Program) (*compiler.Bytecode, error) {
+	comp := compiler.NewWithState(r.symbols, r.constants)
+	if err := comp.Compile(program); err != nil {
+		return nil, err
+	}
+	bytecode := comp.Bytecode()
+	r.constants = bytecode.Constants
+	return bytecode, nil
+}
+
+// run executes ins until it finishes or ctx is done. Errors raised by the
+// VM can be inspected with errors.Is, for example against ctx.Err() or
+// vm.ErrInstructionLimit.
+func (r *Runtime) run(ctx context.Context, ins code.Instructions, constants []object.Object, sourceMap code.SourceMap, filename string) (object.Object, error) {
+	machine := vm.NewWithLoader(ins, constants, filename, r.loader)
+	machine.SetGlobals(r.globals)
+	machine.SetOutput(r.stdout, r.stderr)
+	machine.SetLimits(r.limits)
+	if sourceMap != nil {
+		machine.SetSourceMap(sourceMap)
+	}
+
+	if err := machine.RunContext(ctx); err != nil {
+		return nil, err
+	}
+	// The compiler leaves the value of a trailing expression statement on
+	// the stack.
+	return machine.StackTop(), nil
+}
+
+// Call invokes the global function name with args converted by FromGo.
+func (r *Runtime) Call(name string, args ...interface{}) (object.Object, error) {
+	return r.CallContext(context.Background(), name, args...)
+}
+
+func (r *Runtime) CallContext(ctx context.Context, name string, args ...interface{}) (object.Object, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sym, ok := r.symbols.Resolve(name)
+	if !ok {
+		return nil, fmt.Errorf("undefined function: %s", name)
+	}
+
+	var ins code.Instructions
+	switch sym.Scope {
+	case symbol.GlobalScope:
+		ins = append(ins, code.Make(code.OpGetGlobal, sym.Index)...)
+	case symbol.BuiltinScope:
+		ins = append(ins, code.Make(code.OpGetBuiltin, sym.Index)...)
+	default:
+		return nil, fmt.Errorf("%s is not a function", name)
+	}
+
+	// The arguments are appended to a copy of the constant pool so they do
+	// not outlive the call. OpConstant, like OpCallArgs, indexes the pool
+	// with a 16-bit operand.
+	if len(args) > 65535 {
+		return nil, fmt.Errorf("too many arguments in call: %d", len(args))
+	}
+	if len(r.constants)+len(args) > 65535 {
+		return nil, fmt.Errorf("too many constants to call %s with %d arguments", name, len(args))
+	}
+	constants := append([]object.Object{}, r.constants...)
+	for i, arg := range args {
+		obj, err := FromGo(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i+1, err)
+		}
+		ins = append(ins, code.Make(code.OpConstant, len(constants))...)
+		constants = append(constants, obj)
+	}
+	if len(args) <= 255 {
+		ins = append(ins, code.Make(code.OpCall, len(args))...)
+	} else {
+		// As the compiler does, calls with more arguments than the operand
+		// of OpCall holds use OpCallArgs, all of them positional.
+		shape := make([]object.Object, len(args))
+		for i := range shape {
+			shape[i] = &object.String{Value: ""}
+		}
+		ins = append(ins, code.Make(code.OpCallArgs, len(args), len(constants))...)
+		constants = append(constants, &object.Array{Elements: shape})
+	}
+
+	return r.run(ctx, ins, constants, nil, "<call "+name+">")
+}
+
+// Register defines a global named name bound to fn, which must be a Go
+// function. Arguments are decoded into its parameter types; its result, if
+// any, is converted with FromGo, and a non-nil trailing error is returned to
+// the script as an error value. A func(args ...object.Object) object.Object
+// is used as is.
+func (r *Runtime) Register(name string, fn interface{}) error {
+	builtin, err := FromGo(fn)
+	if err != nil {
+		return err
+	}
+	if _, ok := builtin.(*object.Builtin); !ok {
+		return fmt.Errorf("cannot register %T as a function", fn)
+	}
+	return r.Set(name, builtin)
+}
+
+// Set defines or overwrites the global name.
+func (r *Runtime) Set(name string, value interface{}) error {
+	obj, err := FromGo(value)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sym, ok := r.symbols.Resolve(name)
+	if !ok || sym.Scope != symbol.GlobalScope {
+		sym = r.symbols.Define(name)
+	}
+	if sym.Index >= len(r.globals) {
+		return fmt.Errorf("too many globals defining %s", name)
+	}
+	r.globals[sym.Index] = obj
+	return nil
+}
+
+// Get returns the value of the global name.
+func (r *Runtime) Get(name string) (object.Object, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sym, ok := r.symbols.Resolve(name)
+	if !ok || sym.Scope != symbol.GlobalScope || r.globals[sym.Index] == nil {
+		return nil, false
+	}
+	return r.globals[sym.Index], true
+}
+
+// RegisterModule makes members importable as the module name. Values are
+// converted with FromGo, so functions become callable from scripts:
+//
+//	rt.RegisterModule("geo", map[string]interface{}{"distance": distance})
+func (r *Runtime) RegisterModule(name string, members map[string]interface{}) error {
+	pairs := make(map[object.HashKey]object.HashPair, len(members))
+	for member, value := range members {
+		obj, err := FromGo(value)
+		if err != nil {
+			return fmt.Errorf("module %s: %s: %w", name, member, err)
+		}
+		key := &object.String{Value: member}
+		pairs[key.HashKey()] = object.HashPair{Key: key, Value: obj}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loader.RegisterNative(name, &object.Hash{Pairs: pairs})
+	return nil
+}
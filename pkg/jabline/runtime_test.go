@@ -0,0 +1,257 @@
+package jabline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"jabline/pkg/object"
+)
+
+type point struct {
+	X     int64  `jabline:"x"`
+	Y     int64  `jabline:"y"`
+	Label string `jabline:"label"`
+	skip  bool
+}
+
+func TestEvalKeepsGlobals(t *testing.T) {
+	var out bytes.Buffer
+	rt := New(Options{Stdout: &out})
+	ctx := context.Background()
+
+	if _, err := rt.Eval(ctx, `let total = 40; fn add(a, b) { return a + b; }`); err != nil {
+		t.Fatalf("Eval failed: %s", err)
+	}
+	result, err := rt.Eval(ctx, `echo("total", total); add(total, 2);`)
+	if err != nil {
+		t.Fatalf("Eval failed: %s", err)
+	}
+	if got, err := ToGo(result); err != nil || got != int64(42) {
+		t.Errorf("wrong result. want=42, got=%v, %v", got, err)
+	}
+	if out.String() != "total 40\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+
+	if _, err := rt.Eval(ctx, `let = ;`); err == nil {
+		t.Errorf("expected a parser error")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := rt.Eval(cancelled, `1;`); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestCallAndRegister(t *testing.T) {
+	rt := New(Options{})
+
+	err := rt.Register("scale", func(p point, factor int) (point, error) {
+		if factor == 0 {
+			return point{}, errors.New("zero factor")
+		}
+		return point{X: p.X * int64(factor), Y: p.Y * int64(factor), Label: p.Label}, nil
+	})
+	if err != nil {
+		t.Fatalf("Register failed: %s", err)
+	}
+	if _, err := rt.Eval(context.Background(), `fn double(p) { return scale(p, 2); }`); err != nil {
+		t.Fatalf("Eval failed: %s", err)
+	}
+
+	result, err := rt.Call("double", point{X: 1, Y: 2, Label: "a"})
+	if err != nil {
+		t.Fatalf("Call failed: %s", err)
+	}
+	var got point
+	if err := Decode(result, &got); err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+	if want := (point{X: 2, Y: 4, Label: "a"}); got != want {
+		t.Errorf("wrong result. want=%+v, got=%+v", want, got)
+	}
+
+	result, err = rt.Call("scale", point{}, 0)
+	if err != nil {
+		t.Fatalf("Call failed: %s", err)
+	}
+	if e, ok := result.(*object.Error); !ok || e.Message != "zero factor" {
+		t.Errorf("expected the Go error as an error value, got %s", result.Inspect())
+	}
+
+	if _, err := rt.Call("missing"); err == nil {
+		t.Errorf("expected an error calling an undefined function")
+	}
+
+	if _, err := rt.Eval(context.Background(), `fn count(...xs) { return len(xs); }`); err != nil {
+		t.Fatalf("Eval failed: %s", err)
+	}
+	many := make([]interface{}, 300)
+	result, err = rt.Call("count", many...)
+	if err != nil {
+		t.Fatalf("Call failed: %s", err)
+	}
+	if result.Inspect() != "300" {
+		t.Errorf("want 300 arguments, got %s", result.Inspect())
+	}
+	// The program's own constants leave no room for 65535 more.
+	if _, err := rt.Call("count", make([]interface{}, 65535)...); err == nil || !strings.Contains(err.Error(), "too many constants") {
+		t.Errorf("expected an error when the arguments overflow the constant pool, got %v", err)
+	}
+}
+
+func TestGoValuesCompareWithLiterals(t *testing.T) {
+	rt := New(Options{})
+	if err := rt.Set("flag", true); err != nil {
+		t.Fatalf("Set failed: %s", err)
+	}
+	if err := rt.Set("nothing", nil); err != nil {
+		t.Fatalf("Set failed: %s", err)
+	}
+	if err := rt.Register("isOk", func() bool { return true }); err != nil {
+		t.Fatalf("Register failed: %s", err)
+	}
+	if err := rt.Register("lookup", func() *point { return nil }); err != nil {
+		t.Fatalf("Register failed: %s", err)
+	}
+
+	result, err := rt.Eval(context.Background(), `[flag == true, nothing == null, isOk() == true, lookup() == null, !flag == false]`)
+	if err != nil {
+		t.Fatalf("Eval failed: %s", err)
+	}
+	if result.Inspect() != "[true, true, true, true, true]" {
+		t.Errorf("Go values must equal the script literals, got %s", result.Inspect())
+	}
+}
+
+func TestRegisterModuleAndModulePaths(t *testing.T) {
+	dir := t.TempDir()
+	lib := `export fn greet(name) { return "hello " + name; }`
+	if err := os.WriteFile(filepath.Join(dir, "greeter.jb"), []byte(lib), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rt := New(Options{ModulePaths: []string{dir}})
+	err := rt.RegisterModule("host", map[string]interface{}{
+		"version": "1.2",
+		"upper":   strings.ToUpper,
+	})
+	if err != nil {
+		t.Fatalf("RegisterModule failed: %s", err)
+	}
+
+	result, err := rt.Eval(context.Background(), `import * as host from "host";
+import * as g from "greeter";
+host.upper(g.greet(host.version));`)
+	if err != nil {
+		t.Fatalf("Eval failed: %s", err)
+	}
+	if got, err := ToGo(result); err != nil || got != "HELLO 1.2" {
+		t.Errorf("wrong result. want=%q, got=%v, %v", "HELLO 1.2", got, err)
+	}
+}
+
+func TestConversionRoundTrip(t *testing.T) {
+	in := map[string]interface{}{
+		"name":   "jab",
+		"tags":   []string{"a", "b"},
+		"counts": map[string]int{"x": 1},
+		"ratio":  0.5,
+		"ok":     true,
+		"none":   nil,
+		"point":  &point{X: 3, skip: true},
+	}
+	obj, err := FromGo(in)
+	if err != nil {
+		t.Fatalf("FromGo failed: %s", err)
+	}
+
+	want := map[string]interface{}{
+		"name":   "jab",
+		"tags":   []interface{}{"a", "b"},
+		"counts": map[string]interface{}{"x": int64(1)},
+		"ratio":  0.5,
+		"ok":     true,
+		"none":   nil,
+		"point":  map[string]interface{}{"x": int64(3), "y": int64(0), "label": ""},
+	}
+	if got, err := ToGo(obj); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong round trip.\nwant=%#v\ngot=%#v, %v", want, got, err)
+	}
+
+	var small int8
+	if err := Decode(&object.Integer{Value: 300}, &small); err == nil {
+		t.Errorf("expected an overflow error decoding 300 into int8")
+	}
+	if _, err := FromGo(make(chan int)); err == nil {
+		t.Errorf("expected an error converting a channel")
+	}
+
+	big, err := FromGo(uint64(math.MaxUint64))
+	if err != nil || big.Inspect() != "18446744073709551615" {
+		t.Errorf("want the uint64 unchanged, got %v, %v", big, err)
+	}
+	var back uint64
+	if err := Decode(big, &back); err != nil || back != math.MaxUint64 {
+		t.Errorf("want %d decoded, got %d, %v", uint64(math.MaxUint64), back, err)
+	}
+
+	type node struct{ Next *node }
+	loop := &node{}
+	loop.Next = loop
+	if _, err := FromGo(loop); err == nil {
+		t.Errorf("expected an error converting a value that contains itself")
+	}
+	self := []interface{}{nil}
+	self[0] = self
+	if _, err := FromGo(self); err == nil {
+		t.Errorf("expected an error converting a slice that contains itself")
+	}
+	shared := &point{X: 1}
+	if _, err := FromGo([]*point{shared, shared}); err != nil {
+		t.Errorf("a value shared twice is not a cycle: %s", err)
+	}
+}
+
+func TestToGoRejectsCycles(t *testing.T) {
+	rt := New(Options{})
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"array", `let a = [1]; set(a, 0, a); a;`},
+		{"hash", `let h = {"n": 1}; set(h, "self", [h]); h;`},
+	}
+
+	for _, tt := range tests {
+		result, err := rt.Eval(ctx, tt.input)
+		if err != nil {
+			t.Fatalf("%s: Eval failed: %s", tt.name, err)
+		}
+		if _, err := ToGo(result); err == nil || !strings.Contains(err.Error(), "contains itself") {
+			t.Errorf("%s: expected ToGo to reject a value that contains itself, got %v", tt.name, err)
+		}
+		var out interface{}
+		if err := Decode(result, &out); err == nil {
+			t.Errorf("%s: expected Decode to reject a value that contains itself", tt.name)
+		}
+	}
+
+	shared, err := rt.Eval(ctx, `let s = [1]; [s, s];`)
+	if err != nil {
+		t.Fatalf("Eval failed: %s", err)
+	}
+	if _, err := ToGo(shared); err != nil {
+		t.Errorf("a value shared twice is not a cycle: %s", err)
+	}
+}
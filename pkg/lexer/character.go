@@ -35,3 +35,13 @@ func isLetter(ch byte) bool {
 func isDigit(ch byte) bool {
 	return ch >= '0' && ch <= '9'
 }
+
+// sliceFrom returns the input from start up to the current position. The
+// position can run one past the end of the input after reading EOF.
+func (l *Lexer) sliceFrom(start int) string {
+	end := min(l.position, len(l.input))
+	if start > end {
+		return ""
+	}
+	return l.input[start:end]
+}
@@ -12,6 +12,13 @@ type Lexer struct {
 	ch           byte
 	line         int
 	column       int
+
+	// Position of the first character of the token being read.
+	tokLine   int
+	tokColumn int
+
+	// raw lexers emit comments and keep string literals unescaped.
+	raw bool
 }
 
 func New(input string) *Lexer {
@@ -24,17 +31,40 @@ func New(input string) *Lexer {
 	return l
 }
 
+// NewRaw creates a lexer for tools that need to reproduce the source, such
+// as the formatter: comments are returned as COMMENT tokens and string
+// literals keep their escape sequences as written.
+func NewRaw(input string) *Lexer {
+	l := New(input)
+	l.raw = true
+	return l
+}
+
+// NewAt creates a lexer whose first character is reported at the given line
+// and column. It is used to lex source fragments embedded in a larger file,
+// such as the expressions inside template literal interpolations.
+func NewAt(input string, line, column int) *Lexer {
+	l := &Lexer{
+		input:  input,
+		line:   line,
+		column: column - 1,
+	}
+	l.readChar()
+	return l
+}
+
 func (l *Lexer) newToken(tokenType token.TokenType, literal string) token.Token {
 	return token.Token{
 		Type:    tokenType,
 		Literal: literal,
-		Line:    l.line,
-		Column:  l.column,
+		Line:    l.tokLine,
+		Column:  l.tokColumn,
 	}
 }
 
 func (l *Lexer) NextToken() token.Token {
 	l.skipWhitespace()
+	l.tokLine, l.tokColumn = l.line, l.column
 
 	var tok token.Token
 
@@ -93,10 +123,18 @@ func (l *Lexer) NextToken() token.Token {
 		}
 	case '/':
 		if l.peekChar() == '/' {
+			start := l.position
 			l.skipComment()
+			if l.raw {
+				return l.newToken(token.COMMENT, l.sliceFrom(start))
+			}
 			return l.NextToken()
 		} else if l.peekChar() == '*' {
+			start := l.position
 			l.skipMultiLineComment()
+			if l.raw {
+				return l.newToken(token.COMMENT, l.sliceFrom(start))
+			}
 			return l.NextToken()
 		} else if l.peekChar() == '=' {
 			ch := l.ch
@@ -174,7 +212,21 @@ func (l *Lexer) NextToken() token.Token {
 	case ':':
 		tok = l.newToken(token.COLON, string(l.ch))
 	case '.':
-		tok = l.newToken(token.DOT, string(l.ch))
+		if l.peekChar() == '.' {
+			l.readChar()
+			switch l.peekChar() {
+			case '.':
+				l.readChar()
+				tok = l.newToken(token.ELLIPSIS, "...")
+			case '=':
+				l.readChar()
+				tok = l.newToken(token.RANGE_INCLUSIVE, "..=")
+			default:
+				tok = l.newToken(token.RANGE, "..")
+			}
+		} else {
+			tok = l.newToken(token.DOT, string(l.ch))
+		}
 	case '?':
 		if l.peekChar() == '?' {
 			ch := l.ch
@@ -5,7 +5,7 @@ func (l *Lexer) readIdentifier() string {
 	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[start:l.position]
+	return l.sliceFrom(start)
 }
 
 func (l *Lexer) readNumber() string {
@@ -22,10 +22,11 @@ func (l *Lexer) readNumber() string {
 		}
 	}
 
-	return l.input[start:l.position]
+	return l.sliceFrom(start)
 }
 
 func (l *Lexer) readString() string {
+	start := l.position + 1
 	var result []byte
 
 	for {
@@ -56,39 +57,60 @@ func (l *Lexer) readString() string {
 		}
 	}
 
+	if l.raw {
+		return l.sliceFrom(start)
+	}
 	return string(result)
 }
 
+// readTemplateLiteral returns the raw source between the backticks. Escape
+// sequences are left in place so the parser can map interpolated expressions
+// back to their exact position in the file.
 func (l *Lexer) readTemplateLiteral() string {
-	var result []byte
+	start := l.position + 1
+	l.skipTemplateBody()
+	return l.sliceFrom(start)
+}
 
+// skipTemplateBody advances to the closing backtick of the current template,
+// stepping over nested interpolations (which may themselves contain strings
+// and template literals).
+func (l *Lexer) skipTemplateBody() {
 	for {
 		l.readChar()
-		if l.ch == '`' || l.ch == 0 {
-			break
-		}
-
-		if l.ch == '\\' {
+		switch {
+		case l.ch == '`' || l.ch == 0:
+			return
+		case l.ch == '\\' && l.peekChar() != 0:
 			l.readChar()
-			switch l.ch {
-			case 'n':
-				result = append(result, '\n')
-			case 't':
-				result = append(result, '\t')
-			case 'r':
-				result = append(result, '\r')
-			case '\\':
-				result = append(result, '\\')
-			case '`':
-				result = append(result, '`')
-			default:
-				result = append(result, '\\')
-				result = append(result, l.ch)
+		case l.ch == '$' && l.peekChar() == '{':
+			l.readChar()
+			l.skipInterpolation()
+			if l.ch == 0 {
+				return
 			}
-		} else {
-			result = append(result, l.ch)
 		}
 	}
+}
 
-	return string(result)
+func (l *Lexer) skipInterpolation() {
+	depth := 1
+	for depth > 0 {
+		l.readChar()
+		switch l.ch {
+		case 0:
+			return
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '"':
+			l.readString()
+		case '`':
+			l.skipTemplateBody()
+		}
+		if l.ch == 0 {
+			return
+		}
+	}
 }
@@ -84,6 +84,13 @@ func FindPathToNode(node ast.Node, line, col int) []ast.Node {
 			return []ast.Node{node}
 		}
 
+	case *ast.TemplateLiteral:
+		for _, expr := range n.Expressions {
+			if childPath = FindPathToNode(expr, line, col); childPath != nil {
+				return append([]ast.Node{node}, childPath...)
+			}
+		}
+
 	case *ast.CallExpression:
 		if childPath = FindPathToNode(n.Function, line, col); childPath != nil {
 			return append([]ast.Node{node}, childPath...)
@@ -94,6 +101,19 @@ func FindPathToNode(node ast.Node, line, col int) []ast.Node {
 			}
 		}
 
+	case *ast.SpreadExpression:
+		if childPath = FindPathToNode(n.Value, line, col); childPath != nil {
+			return append([]ast.Node{node}, childPath...)
+		}
+
+	case *ast.NamedArgument:
+		if isTokenAt(n.Token, line, col) {
+			return []ast.Node{node}
+		}
+		if childPath = FindPathToNode(n.Value, line, col); childPath != nil {
+			return append([]ast.Node{node}, childPath...)
+		}
+
 	case *ast.FunctionLiteral:
 		if isTokenAt(n.Token, line, col) {
 			return []ast.Node{node}
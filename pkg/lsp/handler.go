@@ -38,6 +38,9 @@ func NewServer() *server.Server {
 		TextDocumentSignatureHelp:  withRecovery("TextDocumentSignatureHelp", textDocumentSignatureHelp),
 		TextDocumentReferences:     withRecovery("TextDocumentReferences", textDocumentReferences),
 		TextDocumentRename:         withRecovery("TextDocumentRename", textDocumentRename),
+
+		TextDocumentFormatting:      withRecovery("TextDocumentFormatting", textDocumentFormatting),
+		TextDocumentRangeFormatting: withRecovery("TextDocumentRangeFormatting", textDocumentRangeFormatting),
 	}
 
 	return server.NewServer(&handler, lsName, true)
@@ -93,6 +96,8 @@ func initialize(context *glsp.Context, params *protocol.InitializeParams) (any,
 	}
 	capabilities.ReferencesProvider = true
 	capabilities.RenameProvider = true
+	capabilities.DocumentFormattingProvider = true
+	capabilities.DocumentRangeFormattingProvider = true
 
 	return protocol.InitializeResult{
 		Capabilities: capabilities,
@@ -0,0 +1,91 @@
+package lsp
+
+import (
+	"strings"
+	"unicode/utf16"
+
+	"jabline/pkg/format"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func documentContent(uri string) (string, bool) {
+	workspaceStore.Mutex.RLock()
+	docInfo, ok := workspaceStore.Documents[uri]
+	workspaceStore.Mutex.RUnlock()
+
+	if !ok || docInfo == nil {
+		return "", false
+	}
+	return docInfo.Content, true
+}
+
+func textDocumentFormatting(context *glsp.Context, params *protocol.DocumentFormattingParams) ([]protocol.TextEdit, error) {
+	content, ok := documentContent(params.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+
+	// Documents that do not parse are left alone; the parser errors are
+	// already reported as diagnostics.
+	formatted, err := format.Source(content)
+	if err != nil || formatted == content {
+		return nil, nil
+	}
+
+	return []protocol.TextEdit{{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 0, Character: 0},
+			End:   endPosition(content),
+		},
+		NewText: formatted,
+	}}, nil
+}
+
+func textDocumentRangeFormatting(context *glsp.Context, params *protocol.DocumentRangeFormattingParams) ([]protocol.TextEdit, error) {
+	content, ok := documentContent(params.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+
+	// Whole lines are replaced. A selection ending at the start of a line
+	// does not include that line.
+	startLine := params.Range.Start.Line
+	endLine := params.Range.End.Line
+	if endLine > startLine && params.Range.End.Character == 0 {
+		endLine--
+	}
+
+	formatted, err := format.Range(content, int(startLine)+1, int(endLine)+1)
+	if err != nil {
+		return nil, nil
+	}
+
+	lines := strings.Split(content, "\n")
+	end := protocol.Position{Line: endLine + 1, Character: 0}
+	if int(endLine)+1 >= len(lines) {
+		// The range reaches the last line, which has no newline to replace.
+		end = endPosition(content)
+		formatted = strings.TrimSuffix(formatted, "\n")
+	}
+
+	return []protocol.TextEdit{{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: startLine, Character: 0},
+			End:   end,
+		},
+		NewText: formatted,
+	}}, nil
+}
+
+// endPosition returns the position just past the last character of content,
+// with the column counted in UTF-16 code units as LSP requires.
+func endPosition(content string) protocol.Position {
+	lines := strings.Split(content, "\n")
+	last := lines[len(lines)-1]
+	return protocol.Position{
+		Line:      uint32(len(lines) - 1),
+		Character: uint32(len(utf16.Encode([]rune(last)))),
+	}
+}
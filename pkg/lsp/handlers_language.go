@@ -222,7 +222,7 @@ func textDocumentCompletion(context *glsp.Context, params *protocol.CompletionPa
 
 	keywords := []string{
 		"fn", "let", "const", "return", "if", "else", "true", "false", "for", "while",
-		"struct", "import", "export", "null", "async", "await", "try", "catch", "throw",
+		"struct", "import", "export", "null", "async", "await", "yield", "try", "catch", "finally", "throw", "defer", "match",
 	}
 
 	var items []protocol.CompletionItem
@@ -332,59 +332,62 @@ func textDocumentSignatureHelp(context *glsp.Context, params *protocol.Signature
 		return nil, nil
 	}
 
-	ident, ok := callExpr.Function.(*ast.Identifier)
-	if !ok {
-		return nil, nil
-	}
-	
-	symbol := docInfo.SymbolTable.RootScope.Get(ident.Value)
-	if symbol == nil || symbol.Definition == nil {
-		return nil, nil
-	}
-
+	var nameTok token.Token
 	var label string
-	var paramsInfo []protocol.ParameterInformation
-	
-	switch f := symbol.Definition.(type) {
-	case *ast.FunctionStatement:
-		label = "fn " + f.Name.Value + "("
-		for i, p := range f.Parameters {
-			if i > 0 {
-				label += ", "
-			}
-			label += p.Value
-			paramsInfo = append(paramsInfo, protocol.ParameterInformation{
-				Label: p.Value,
-			})
-		}
-		label += ")"
-	case *ast.FunctionLiteral:
+	var parameters []*ast.Identifier
 
-		label = "fn("
-		for i, p := range f.Parameters {
-			if i > 0 {
-				label += ", "
-			}
-			label += p.Value
-			paramsInfo = append(paramsInfo, protocol.ParameterInformation{
-				Label: p.Value,
-			})
+	switch callee := callExpr.Function.(type) {
+	case *ast.Identifier:
+		nameTok = callee.Token
+		symbol := docInfo.SymbolTable.RootScope.Get(callee.Value)
+		if symbol == nil || symbol.Definition == nil {
+			return nil, nil
 		}
-		label += ")"
-	
+		switch f := symbol.Definition.(type) {
+		case *ast.FunctionStatement:
+			label, parameters = "fn "+f.Name.Value, f.Parameters
+		case *ast.FunctionLiteral:
+			label, parameters = "fn", f.Parameters
+		default:
+			return nil, nil
+		}
+	case *ast.IndexExpression:
+		// A method call: the method is found by name among those declared
+		// in the file, as long as only one struct has a method so named.
+		name, ok := callee.Index.(*ast.StringLiteral)
+		if !ok {
+			return nil, nil
+		}
+		nameTok = name.Token
+		method := findMethod(docInfo.Program, name.Value)
+		if method == nil {
+			return nil, nil
+		}
+		label = "fn (" + method.ReceiverName.Value + " " + method.ReceiverType.Value + ") " + method.Name.Value
+		parameters = method.Parameters
 	default:
 		return nil, nil
 	}
 
+	var paramsInfo []protocol.ParameterInformation
+	paramLabels := make([]string, len(parameters))
+	for i, p := range parameters {
+		paramLabels[i] = p.String()
+		paramsInfo = append(paramsInfo, protocol.ParameterInformation{
+			Label: paramLabels[i],
+		})
+	}
+	label += "(" + strings.Join(paramLabels, ", ") + ")"
+
 	activeParameter := uint32(0)
 	
-	funcIdentifierStartOffset := getTokenByteOffset(funcContent, ident.Token.Line, ident.Token.Column)
+	funcIdentifierStartOffset := getTokenByteOffset(funcContent, nameTok.Line, nameTok.Column)
 	if funcIdentifierStartOffset == -1 {
 		return nil, nil
 	}
 
 	openParenIdx := -1
-	for i := funcIdentifierStartOffset + len(ident.Token.Literal); i < len(funcContent); i++ {
+	for i := funcIdentifierStartOffset + len(nameTok.Literal); i < len(funcContent); i++ {
 		if funcContent[i] == '(' {
 			openParenIdx = i
 			break
@@ -403,6 +406,20 @@ func textDocumentSignatureHelp(context *glsp.Context, params *protocol.Signature
 		} else {
 			argSegment := funcContent[openParenIdx:cursorByteOffset]
 			activeParameter = uint32(strings.Count(argSegment, ","))
+
+			// A named argument is for the parameter it names, and the
+			// arguments past the last parameter for a rest parameter.
+			current := argSegment[strings.LastIndex(argSegment, ",")+1:]
+			if name, _, named := strings.Cut(strings.TrimPrefix(current, "("), ":"); named {
+				for i, p := range parameters {
+					if p.Value == strings.TrimSpace(name) {
+						activeParameter = uint32(i)
+					}
+				}
+			}
+			if n := len(parameters); n > 0 && parameters[n-1].Variadic && int(activeParameter) >= n {
+				activeParameter = uint32(n - 1)
+			}
 		}
 	}
 
@@ -423,6 +440,26 @@ func textDocumentSignatureHelp(context *glsp.Context, params *protocol.Signature
 	return nil, nil
 }
 
+// findMethod returns the declaration of the method called name, if a
+// single struct of the program declares one.
+func findMethod(program *ast.Program, name string) *ast.FunctionStatement {
+	var found *ast.FunctionStatement
+	for _, stmt := range program.Statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok && export.Statement != nil {
+			stmt = export.Statement
+		}
+		fn, ok := stmt.(*ast.FunctionStatement)
+		if !ok || fn.ReceiverName == nil || fn.Name.Value != name {
+			continue
+		}
+		if found != nil {
+			return nil
+		}
+		found = fn
+	}
+	return found
+}
+
 func textDocumentReferences(context *glsp.Context, params *protocol.ReferenceParams) ([]protocol.Location, error) {
 	workspaceStore.Mutex.RLock()
 	docInfo, ok := workspaceStore.Documents[params.TextDocument.URI]
@@ -123,7 +123,7 @@ func (sa *SemanticAnalyzer) walk(node ast.Node) {
 		sa.currentScope = oldScope // Exit scope
 
 	case *ast.LetStatement:
-		sa.declareSymbol(n.Name.Value, protocol.SymbolKindVariable, "any", n.Name.Token, n.Name)
+		sa.declareVariable(n.Name)
 		if n.Value != nil {
 			sa.walk(n.Value)
 		}
@@ -143,7 +143,7 @@ func (sa *SemanticAnalyzer) walk(node ast.Node) {
 		sa.currentScope = newScope
 
 		for _, param := range n.Parameters {
-			sa.declareSymbol(param.Value, protocol.SymbolKindVariable, "any", param.Token, param)
+			sa.declareVariable(param)
 		}
 		sa.walk(n.Body)
 
@@ -169,11 +169,23 @@ func (sa *SemanticAnalyzer) walk(node ast.Node) {
 		sa.walk(n.Right)
 	case *ast.PrefixExpression:
 		sa.walk(n.Right)
+	case *ast.YieldExpression:
+		if n.Value != nil {
+			sa.walk(n.Value)
+		}
+	case *ast.TemplateLiteral:
+		for _, expr := range n.Expressions {
+			sa.walk(expr)
+		}
 	case *ast.CallExpression:
 		sa.walk(n.Function)
 		for _, arg := range n.Arguments {
 			sa.walk(arg)
 		}
+	case *ast.SpreadExpression:
+		sa.walk(n.Value)
+	case *ast.NamedArgument:
+		sa.walk(n.Value)
 	case *ast.FunctionLiteral:
 
 		oldScope := sa.currentScope
@@ -182,7 +194,7 @@ func (sa *SemanticAnalyzer) walk(node ast.Node) {
 		sa.currentScope = newScope
 
 		for _, param := range n.Parameters {
-			sa.declareSymbol(param.Value, protocol.SymbolKindVariable, "any", param.Token, param)
+			sa.declareVariable(param)
 		}
 		sa.walk(n.Body)
 
@@ -219,7 +231,10 @@ func (sa *SemanticAnalyzer) walk(node ast.Node) {
 		newScope := NewScope(oldScope, n)
 		oldScope.Children = append(oldScope.Children, newScope)
 		sa.currentScope = newScope
-		sa.declareSymbol(n.Variable.Value, protocol.SymbolKindVariable, "any", n.Variable.Token, n.Variable)
+		if n.Key != nil {
+			sa.declareSymbol(n.Key.Value, protocol.SymbolKindVariable, "any", n.Key.Token, n.Key)
+		}
+		sa.declareVariable(n.Variable)
 		sa.walk(n.Body)
 					sa.currentScope = oldScope
 			case *ast.ImportStatement:
@@ -430,6 +445,22 @@ func (sa *SemanticAnalyzer) integrateImportedSymbols(importStmt *ast.ImportState
 	}
 }
 
+// declareVariable declares a variable, or each name of the pattern it is
+// destructured with. The default of a parameter is in the scope of the
+// parameters before it.
+func (sa *SemanticAnalyzer) declareVariable(ident *ast.Identifier) {
+	if ident.Default != nil {
+		sa.walk(ident.Default)
+	}
+	if ident.Pattern == nil {
+		sa.declareSymbol(ident.Value, protocol.SymbolKindVariable, "any", ident.Token, ident)
+		return
+	}
+	for _, name := range ast.PatternBindings(ident.Pattern) {
+		sa.declareSymbol(name.Value, protocol.SymbolKindVariable, "any", name.Token, name)
+	}
+}
+
 func (sa *SemanticAnalyzer) declareSymbol(name string, kind SymbolKind, typ string, tok token.Token, definition ast.Node) {
 	startLine := uint32(tok.Line - 1)
 	startCol := uint32(tok.Column - 1)
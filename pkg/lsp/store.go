@@ -2,6 +2,7 @@ package lsp
 
 import (
 	"jabline/pkg/ast"
+	"jabline/pkg/checker"
 	"jabline/pkg/lexer"
 	"jabline/pkg/parser"
 	"sync"
@@ -18,6 +19,7 @@ type DocumentSemanticInfo struct {
 	Program     *ast.Program
 	SymbolTable *SymbolTable
 	URI         string
+	Content     string
 }
 
 type WorkspaceSymbolStore struct {
@@ -66,6 +68,7 @@ func (ws *WorkspaceSymbolStore) UpdateDocument(uri string, content string, conte
 		Program:     program,
 		SymbolTable: sa.Symbols,
 		URI:         uri,
+		Content:     content,
 	}
 	ws.Mutex.Unlock()
 
@@ -139,6 +142,24 @@ func (ws *WorkspaceSymbolStore) UpdateDocument(uri string, content string, conte
 		}
 	}
 
+	// Type errors are only meaningful for a program that parsed.
+	if len(p.Errors()) == 0 {
+		for _, typeErr := range checker.Check(program) {
+			lineIndex := uint32(typeErr.Line - 1)
+			colIndex := uint32(typeErr.Column - 1)
+
+			diagnostics = append(diagnostics, protocol.Diagnostic{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: lineIndex, Character: colIndex},
+					End:   protocol.Position{Line: lineIndex, Character: colIndex + 1},
+				},
+				Severity: ptr(protocol.DiagnosticSeverityError),
+				Source:   ptr(lsName),
+				Message:  typeErr.Message,
+			})
+		}
+	}
+
 	go context.Notify("textDocument/publishDiagnostics", protocol.PublishDiagnosticsParams{
 		URI:         uri,
 		Diagnostics: diagnostics,
@@ -1,10 +1,98 @@
 package object
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
 
+// The errors of using a closed channel, which would panic in Go.
+var (
+	ErrSendOnClosed  = errors.New("send on closed channel")
+	ErrCloseOfClosed = errors.New("close of closed channel")
+)
+
+// Channel is a channel of values. Sends go through Send, or hold SendLock
+// while sending to Value, so that closing the channel cannot race them.
 type Channel struct {
 	Value chan Object
+
+	// sending is held for reading by the sends in progress and for
+	// writing while Value is closed.
+	sending sync.RWMutex
+	once    sync.Once
+	done    chan struct{}
+	closed  atomic.Bool
 }
 
 func (c *Channel) Type() ObjectType { return CHANNEL_OBJ }
 func (c *Channel) Inspect() string  { return fmt.Sprintf("Channel[%p]", c.Value) }
+
+// Done is closed as soon as Close is called, which ends the sends waiting
+// on the channel.
+func (c *Channel) Done() <-chan struct{} {
+	c.once.Do(func() { c.done = make(chan struct{}) })
+	return c.done
+}
+
+// SendLock keeps the channel from being closed until the returned
+// function is called. A send to Value under it must also wait on Done.
+func (c *Channel) SendLock() (unlock func()) {
+	c.sending.RLock()
+	return c.sending.RUnlock
+}
+
+// Send waits until v is received or buffered. It fails with
+// ErrSendOnClosed once the channel is closed.
+func (c *Channel) Send(v Object) error {
+	return c.SendContext(context.Background(), v)
+}
+
+// SendContext is Send, giving up with ctx.Err() once ctx is done.
+func (c *Channel) SendContext(ctx context.Context, v Object) error {
+	defer c.SendLock()()
+	if c.closed.Load() {
+		return ErrSendOnClosed
+	}
+	select {
+	case c.Value <- v:
+		return nil
+	case <-c.Done():
+		return ErrSendOnClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Receive waits for a value. ok is false once the channel is closed and
+// the values buffered before are all received.
+func (c *Channel) Receive() (value Object, ok bool) {
+	value, ok = <-c.Value
+	return value, ok
+}
+
+// ReceiveContext is Receive, giving up with ctx.Err() once ctx is done.
+func (c *Channel) ReceiveContext(ctx context.Context) (value Object, ok bool, err error) {
+	select {
+	case value, ok = <-c.Value:
+		return value, ok, nil
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+// Close closes the channel, failing with ErrCloseOfClosed if it already
+// is. It waits for the sends in progress to give up first.
+func (c *Channel) Close() error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return ErrCloseOfClosed
+	}
+	c.Done()
+	close(c.done)
+	c.sending.Lock()
+	defer c.sending.Unlock()
+	close(c.Value)
+	return nil
+}
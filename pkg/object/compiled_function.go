@@ -10,9 +10,24 @@ type CompiledFunction struct {
 	NumLocals      int
 	NumParameters  int
 	SourceMap      code.SourceMap
+	File           string // Set for functions compiled from an imported module
 	IsAsync        bool
+	IsGenerator    bool // calling it returns a Generator over its body
 	Name           string
 	TypeParameters []string
+	// LocalNames and FreeNames name the local slots and captured variables
+	// for debuggers.
+	LocalNames []string
+	FreeNames  []string
+	// ParameterNames name the parameters, receiver included, for the
+	// arguments passed by name.
+	ParameterNames []string
+	// NumOptional counts the parameters with a default, which come last
+	// and which a call may leave out.
+	NumOptional int
+	// Variadic is set when the last parameter collects the remaining
+	// arguments in an array.
+	Variadic bool
 }
 
 func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
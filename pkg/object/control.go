@@ -1,11 +1,26 @@
 package object
 
+// Error is a failure, either returned by a builtin or raised as an
+// exception. Kind is one of the error kinds in errors.go, empty for a plain
+// Error. Cause is the error that led to this one, if any, and Stack the
+// calls active when it was thrown, outermost first. A builtin returning an
+// Error with Raise set fails with it: the VM throws it instead of handing
+// it to the caller.
 type Error struct {
 	Message string
+	Kind    string
+	Cause   Object
+	Stack   []StackFrame
+	Raise   bool
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+	if e.Kind == "" {
+		return "ERROR: " + e.Message
+	}
+	return e.Kind + ": " + e.Message
+}
 
 type ReturnValue struct {
 	Value Object
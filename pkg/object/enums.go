@@ -0,0 +1,49 @@
+package object
+
+import "strings"
+
+// EnumVariant is a variant of an algebraic enum that carries fields.
+// Calling it with the values of the fields makes an EnumValue.
+type EnumVariant struct {
+	Enum   string
+	Name   string
+	Fields []string
+	// Types holds the annotation of each field, "" when it has none.
+	Types []string
+}
+
+func (v *EnumVariant) Type() ObjectType { return ENUM_VARIANT_OBJ }
+func (v *EnumVariant) Inspect() string {
+	return "variant " + v.Enum + "." + v.Name + "(" + strings.Join(v.Fields, ", ") + ")"
+}
+
+// EnumValue is a value of an algebraic enum: one of its variants with the
+// values of the variant's fields, in order. The variants without fields
+// are values themselves.
+type EnumValue struct {
+	Variant *EnumVariant
+	Values  []Object
+}
+
+func (v *EnumValue) Type() ObjectType { return ENUM_VALUE_OBJ }
+func (v *EnumValue) Inspect() string {
+	name := v.Variant.Enum + "." + v.Variant.Name
+	if len(v.Variant.Fields) == 0 {
+		return name
+	}
+	values := make([]string, len(v.Values))
+	for i, value := range v.Values {
+		values[i] = value.Inspect()
+	}
+	return name + "(" + strings.Join(values, ", ") + ")"
+}
+
+// Field returns the value of the field called name.
+func (v *EnumValue) Field(name string) (Object, bool) {
+	for i, field := range v.Variant.Fields {
+		if field == name {
+			return v.Values[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,95 @@
+package object
+
+import "fmt"
+
+// The built-in error kinds. Each one is also an Error, and TimeoutError is
+// an IOError. Only the VM raises a LimitError, of the kind naming the limit
+// a program ran out of, and CancelledError, once it is cancelled.
+const (
+	ErrorKind           = "Error"
+	TypeErrorKind       = "TypeError"
+	IndexErrorKind      = "IndexError"
+	KeyErrorKind        = "KeyError"
+	ValueErrorKind      = "ValueError"
+	ArithmeticErrorKind = "ArithmeticError"
+	IOErrorKind         = "IOError"
+	TimeoutErrorKind    = "TimeoutError"
+	LimitErrorKind      = "LimitError"
+	CancelledErrorKind  = "CancelledError"
+
+	InstructionLimitErrorKind = "InstructionLimitError"
+	StackOverflowErrorKind    = "StackOverflowError"
+	CallDepthErrorKind        = "CallDepthError"
+	AllocationLimitErrorKind  = "AllocationLimitError"
+)
+
+// ErrorKinds lists the built-in error kinds, each after its parent.
+var ErrorKinds = []string{
+	ErrorKind,
+	TypeErrorKind,
+	IndexErrorKind,
+	KeyErrorKind,
+	ValueErrorKind,
+	ArithmeticErrorKind,
+	IOErrorKind,
+	TimeoutErrorKind,
+	LimitErrorKind,
+	CancelledErrorKind,
+	InstructionLimitErrorKind,
+	StackOverflowErrorKind,
+	CallDepthErrorKind,
+	AllocationLimitErrorKind,
+}
+
+var errorParents = map[string]string{
+	TypeErrorKind:       ErrorKind,
+	IndexErrorKind:      ErrorKind,
+	KeyErrorKind:        ErrorKind,
+	ValueErrorKind:      ErrorKind,
+	ArithmeticErrorKind: ErrorKind,
+	IOErrorKind:         ErrorKind,
+	TimeoutErrorKind:    IOErrorKind,
+	LimitErrorKind:      ErrorKind,
+	CancelledErrorKind:  ErrorKind,
+
+	InstructionLimitErrorKind: LimitErrorKind,
+	StackOverflowErrorKind:    LimitErrorKind,
+	CallDepthErrorKind:        LimitErrorKind,
+	AllocationLimitErrorKind:  LimitErrorKind,
+}
+
+// IsErrorKind reports whether name is a built-in error kind.
+func IsErrorKind(name string) bool {
+	_, ok := errorParents[name]
+	return ok || name == ErrorKind
+}
+
+// KindName is the kind of e, Error if it has none.
+func (e *Error) KindName() string {
+	if e.Kind == "" {
+		return ErrorKind
+	}
+	return e.Kind
+}
+
+// Is reports whether e is of the given kind or of one derived from it.
+func (e *Error) Is(kind string) bool {
+	for k := e.KindName(); k != ""; k = errorParents[k] {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// StackFrame is one call of a captured stack trace.
+type StackFrame struct {
+	Function string
+	File     string
+	Line     int
+	Column   int
+}
+
+func (f StackFrame) String() string {
+	return fmt.Sprintf("%s (%s:%d:%d)", f.Function, f.File, f.Line, f.Column)
+}
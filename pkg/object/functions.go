@@ -1,6 +1,7 @@
 package object
 
 import (
+	"context"
 	"jabline/pkg/ast"
 	"strings"
 )
@@ -67,8 +68,18 @@ func (f *Function) UpdateCapturedVar(name string, value Object) {
 
 type BuiltinFunction func(args ...Object) Object
 
+// ContextBuiltinFunction is a builtin that blocks, such as sleep or recv,
+// and returns early once ctx is done.
+type ContextBuiltinFunction func(ctx context.Context, args ...Object) Object
+
 type Builtin struct {
 	Fn BuiltinFunction
+	// FnContext, if set, is called instead of Fn by a VM that runs with a
+	// context, so that cancelling the task ends the call.
+	FnContext ContextBuiltinFunction
+	// Writes is set for builtins that change their arguments, which a VM
+	// running tasks calls under the lock the tasks share.
+	Writes bool
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
@@ -342,6 +353,18 @@ func (bm *BoundMethod) Inspect() string {
 	return "BoundMethod(" + bm.Function.Inspect() + ")"
 }
 
+// NativeMethod is a method the VM implements for a value of the sync
+// module or a task group, bound to that value.
+type NativeMethod struct {
+	Receiver Object
+	Name     string
+}
+
+func (m *NativeMethod) Type() ObjectType { return BUILTIN_OBJ }
+func (m *NativeMethod) Inspect() string {
+	return "builtin method " + string(m.Receiver.Type()) + "." + m.Name
+}
+
 type InstantiatedFunction struct {
 	Closure      *Closure
 	TypeArgs     map[string]string
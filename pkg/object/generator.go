@@ -0,0 +1,83 @@
+package object
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Generator is the suspended state of a call to a generator function. The
+// VM resumes it in a frame of its own each time a value is requested, and
+// saves the frame back into it when the body yields.
+type Generator struct {
+	Closure   *Closure
+	TypeArgs  map[string]string
+	Globals   []Object
+	Constants []Object
+
+	// IP is the instruction the body is suspended at, -1 before the first
+	// resumption.
+	IP int
+	// Stack holds the locals and operands of the suspended frame.
+	Stack []Object
+	// Handlers are the try blocks open at the yield, relative to the frame.
+	Handlers []GeneratorHandler
+	// Defers are the calls deferred by the body so far, each a callee
+	// followed by its arguments.
+	Defers [][]Object
+	// Done is set once the body has returned or thrown.
+	Done bool
+
+	mu      sync.Mutex
+	running bool
+}
+
+// GeneratorHandler is a try block saved across a yield.
+type GeneratorHandler struct {
+	CatchIP     int
+	StackOffset int
+}
+
+// NewGenerator suspends a call to cl with args before its first
+// instruction.
+func NewGenerator(cl *Closure, args []Object, typeArgs map[string]string) *Generator {
+	stack := make([]Object, cl.Fn.NumLocals)
+	copy(stack, args)
+	return &Generator{Closure: cl, TypeArgs: typeArgs, IP: -1, Stack: stack}
+}
+
+func (g *Generator) Type() ObjectType { return GENERATOR_OBJ }
+func (g *Generator) Inspect() string {
+	name := g.Closure.Fn.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	return fmt.Sprintf("Generator[%p, %s]", g, name)
+}
+
+// Acquire claims the generator for a resumption. It fails while another
+// frame, possibly on another goroutine, is running the body.
+func (g *Generator) Acquire() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.running {
+		return false
+	}
+	g.running = true
+	return true
+}
+
+// Release ends a resumption.
+func (g *Generator) Release() {
+	g.mu.Lock()
+	g.running = false
+	g.mu.Unlock()
+}
+
+// Finish marks the generator as exhausted and releases it.
+func (g *Generator) Finish() {
+	g.Done = true
+	g.Stack = nil
+	g.Handlers = nil
+	g.Defers = nil
+	g.Release()
+}
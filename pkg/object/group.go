@@ -0,0 +1,74 @@
+package object
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// TaskGroup is the scope of a group statement. The tasks spawned in it
+// run with its context, and the group waits for all of them. The first to
+// fail cancels the others.
+type TaskGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	tasks  sync.WaitGroup
+
+	mu sync.Mutex
+	// failed is set by the first failure, and err is the exception of
+	// that failure when it was a task's.
+	failed bool
+	err    Object
+}
+
+// NewTaskGroup returns a group whose context is cancelled with parent.
+func NewTaskGroup(parent context.Context) *TaskGroup {
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	return &TaskGroup{ctx: ctx, cancel: cancel}
+}
+
+func (g *TaskGroup) Type() ObjectType { return TASK_GROUP_OBJ }
+func (g *TaskGroup) Inspect() string  { return fmt.Sprintf("TaskGroup[%p]", g) }
+
+// Context is the context of the tasks of the group, done once the group
+// is cancelled.
+func (g *TaskGroup) Context() context.Context { return g.ctx }
+
+// Go counts a task started in the group; it must call Done when it ends.
+func (g *TaskGroup) Go() { g.tasks.Add(1) }
+
+// Done ends a task counted by Go.
+func (g *TaskGroup) Done() { g.tasks.Done() }
+
+// Fail records the failure of a task, or of the body of the group if
+// exception is nil, and cancels the group. Only the first failure counts;
+// Fail reports whether it was this one.
+func (g *TaskGroup) Fail(exception Object) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.failed {
+		return false
+	}
+	g.failed, g.err = true, exception
+	g.cancel()
+	return true
+}
+
+// Cancel cancels the tasks of the group.
+func (g *TaskGroup) Cancel() { g.cancel() }
+
+// Cancelled reports whether the group was cancelled.
+func (g *TaskGroup) Cancelled() bool { return g.ctx.Err() != nil }
+
+// Wait waits for the tasks of the group to end and returns the exception
+// of the first that failed, if it failed before the body of the group.
+func (g *TaskGroup) Wait() Object {
+	g.tasks.Wait()
+	g.cancel()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
@@ -0,0 +1,140 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// Iterator is a value a for-in loop steps through without running code in
+// the VM. Next returns the following item, or false once there is none.
+type Iterator interface {
+	Object
+	Next() (Object, bool)
+}
+
+// PairIterator is an Iterator that can also be stepped through with two
+// loop variables, as in for (k, v in h). NextPair advances like Next.
+type PairIterator interface {
+	Iterator
+	NextPair() (key, value Object, ok bool)
+}
+
+// ArrayIterator walks the elements of an array by index, so elements
+// appended during the loop are visited too. Its keys are the indices.
+type ArrayIterator struct {
+	Array *Array
+	pos   int
+}
+
+func (it *ArrayIterator) Type() ObjectType { return ITERATOR_OBJ }
+func (it *ArrayIterator) Inspect() string  { return fmt.Sprintf("ArrayIterator[%p]", it) }
+
+func (it *ArrayIterator) Next() (Object, bool) {
+	_, value, ok := it.NextPair()
+	return value, ok
+}
+
+func (it *ArrayIterator) NextPair() (Object, Object, bool) {
+	if it.pos >= len(it.Array.Elements) {
+		return nil, nil, false
+	}
+	it.pos++
+	return &Integer{Value: int64(it.pos - 1)}, it.Array.Elements[it.pos-1], true
+}
+
+// StringIterator walks the runes of a string, each as a string of its own.
+// Its keys are the byte offsets the runes start at, which is what indexing
+// a string expects.
+type StringIterator struct {
+	Value string
+	pos   int
+}
+
+func (it *StringIterator) Type() ObjectType { return ITERATOR_OBJ }
+func (it *StringIterator) Inspect() string  { return fmt.Sprintf("StringIterator[%p]", it) }
+
+func (it *StringIterator) Next() (Object, bool) {
+	_, value, ok := it.NextPair()
+	return value, ok
+}
+
+func (it *StringIterator) NextPair() (Object, Object, bool) {
+	if it.pos >= len(it.Value) {
+		return nil, nil, false
+	}
+	start := it.pos
+	_, size := utf8.DecodeRuneInString(it.Value[start:])
+	it.pos += size
+	return &Integer{Value: int64(start)}, &String{Value: it.Value[start:it.pos]}, true
+}
+
+// HashIterator walks the pairs a hash had when the loop started, in no
+// particular order. With one loop variable it yields the keys.
+type HashIterator struct {
+	pairs []HashPair
+	pos   int
+}
+
+func NewHashIterator(h *Hash) *HashIterator {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair)
+	}
+	return &HashIterator{pairs: pairs}
+}
+
+func (it *HashIterator) Type() ObjectType { return ITERATOR_OBJ }
+func (it *HashIterator) Inspect() string  { return fmt.Sprintf("HashIterator[%p]", it) }
+
+func (it *HashIterator) Next() (Object, bool) {
+	key, _, ok := it.NextPair()
+	return key, ok
+}
+
+func (it *HashIterator) NextPair() (Object, Object, bool) {
+	if it.pos >= len(it.pairs) {
+		return nil, nil, false
+	}
+	it.pos++
+	pair := it.pairs[it.pos-1]
+	return pair.Key, pair.Value, true
+}
+
+// ChannelIterator receives from a channel until it is closed.
+type ChannelIterator struct {
+	Channel *Channel
+}
+
+func (it *ChannelIterator) Type() ObjectType { return ITERATOR_OBJ }
+func (it *ChannelIterator) Inspect() string  { return fmt.Sprintf("ChannelIterator[%p]", it) }
+
+func (it *ChannelIterator) Next() (Object, bool) {
+	value, ok := <-it.Channel.Value
+	return value, ok
+}
+
+// RemoteChannelIterator receives from a remote channel until the peer
+// closes the connection. Any other receive error also ends the loop and is
+// kept in Err.
+type RemoteChannelIterator struct {
+	Channel *RemoteChannel
+	Err     error
+}
+
+func (it *RemoteChannelIterator) Type() ObjectType { return ITERATOR_OBJ }
+func (it *RemoteChannelIterator) Inspect() string {
+	return fmt.Sprintf("RemoteChannelIterator[%p]", it)
+}
+
+func (it *RemoteChannelIterator) Next() (Object, bool) {
+	value, err := it.Channel.Receive()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			it.Err = err
+		}
+		return nil, false
+	}
+	return value, true
+}
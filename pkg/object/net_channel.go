@@ -1,15 +1,71 @@
 package object
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"sync"
 )
 
 type RemoteChannel struct {
 	Conn net.Conn
 	Encoder *json.Encoder
 	Decoder *json.Decoder
+
+	once     sync.Once
+	incoming chan RemoteMessage
+	err      error
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// endOfValues is the byte a remote channel writes once it is closed, so
+// that the receives of the peer end as on a closed local channel. Values
+// travel as bare JSON, one per line, and no JSON text holds this byte
+// unescaped, so it cannot pass for a value.
+const endOfValues = 0x04
+
+// NewRemoteChannel returns a remote channel sending and receiving over
+// conn.
+func NewRemoteChannel(conn net.Conn) *RemoteChannel {
+	return &RemoteChannel{
+		Conn:    conn,
+		Encoder: json.NewEncoder(conn),
+		Decoder: json.NewDecoder(&valueReader{r: conn}),
+	}
+}
+
+// valueReader reads the values a peer sends up to endOfValues, which it
+// reports as the end of the stream.
+type valueReader struct {
+	r    io.Reader
+	done bool
+}
+
+func (vr *valueReader) Read(p []byte) (int, error) {
+	if vr.done {
+		return 0, io.EOF
+	}
+	n, err := vr.r.Read(p)
+	if i := bytes.IndexByte(p[:n], endOfValues); i >= 0 {
+		vr.done = true
+		if i == 0 {
+			return 0, io.EOF
+		}
+		return i, nil
+	}
+	return n, err
+}
+
+// RemoteMessage is a value received from a remote channel, or the error
+// that ended the connection.
+type RemoteMessage struct {
+	Value Object
+	Err   error
 }
 
 func (rc *RemoteChannel) Type() ObjectType { return "REMOTE_CHANNEL" }
@@ -21,16 +77,83 @@ func (rc *RemoteChannel) Send(obj Object) error {
 	// Protocol: JSON Line
 	// Serialize object to native map/type
 	native := ObjectToNative(obj)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.closed {
+		return ErrSendOnClosed
+	}
 	return rc.Encoder.Encode(native)
 }
 
+// Close tells the peer that no more values follow, then shuts down the
+// writing half of the connection when it can. Values from the peer can
+// still be received.
+func (rc *RemoteChannel) Close() error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.closed {
+		return ErrCloseOfClosed
+	}
+	rc.closed = true
+	if _, err := rc.Conn.Write([]byte{endOfValues}); err != nil {
+		return err
+	}
+	if conn, ok := rc.Conn.(interface{ CloseWrite() error }); ok {
+		return conn.CloseWrite()
+	}
+	return nil
+}
+
+// Receive waits for the next value from the peer. It returns io.EOF once
+// the peer has closed the channel or the connection.
 func (rc *RemoteChannel) Receive() (Object, error) {
-	var native interface{}
-	err := rc.Decoder.Decode(&native)
-	if err != nil {
-		return nil, err
+	msg, ok := <-rc.Incoming()
+	if !ok {
+		return nil, rc.err
+	}
+	return msg.Value, msg.Err
+}
+
+// Incoming returns the channel the values the peer sends arrive on, so
+// that a select can wait for them along with other channels. A single
+// reader decodes them until the peer closes the channel, or hands the error
+// ending the connection to a receive waiting for it; then the channel is
+// closed and Receive keeps returning io.EOF, or that error.
+func (rc *RemoteChannel) Incoming() <-chan RemoteMessage {
+	rc.once.Do(func() {
+		rc.incoming = make(chan RemoteMessage)
+		go func() {
+			defer close(rc.incoming)
+			for {
+				var native interface{}
+				err := rc.Decoder.Decode(&native)
+				if errors.Is(err, io.EOF) {
+					rc.err = io.EOF
+					return
+				}
+				if err != nil {
+					rc.err = err
+					// Receives after this one find the error in Err.
+					select {
+					case rc.incoming <- RemoteMessage{Err: err}:
+					default:
+					}
+					return
+				}
+				rc.incoming <- RemoteMessage{Value: NativeToObject(native)}
+			}
+		}()
+	})
+	return rc.incoming
+}
+
+// Err returns the error that ended the connection once Incoming is closed,
+// or nil if the peer closed the channel.
+func (rc *RemoteChannel) Err() error {
+	if errors.Is(rc.err, io.EOF) {
+		return nil
 	}
-	return NativeToObject(native), nil
+	return rc.err
 }
 
 // Helpers for serialization (Should act as bridge between Object and Go types)
@@ -0,0 +1,178 @@
+package object
+
+import "math"
+
+// The sized numeric types follow Go's rules: arithmetic on two values of
+// the same sized type wraps around at the type's width, and values of two
+// different sized types never mix without an explicit conversion. Plain
+// Integer and Float values, which have no fixed type, adopt the type of the
+// sized operand, as long as the value fits in it.
+
+type integerKind struct {
+	bits   uint
+	signed bool
+}
+
+var integerKinds = map[ObjectType]integerKind{
+	INT8_OBJ:   {8, true},
+	INT16_OBJ:  {16, true},
+	INT32_OBJ:  {32, true},
+	INT64_OBJ:  {64, true},
+	UINT8_OBJ:  {8, false},
+	UINT16_OBJ: {16, false},
+	UINT32_OBJ: {32, false},
+	UINT64_OBJ: {64, false},
+}
+
+// IntegerKind reports the width in bits and the signedness of the integer
+// type t. Integer is a signed 64-bit type.
+func IntegerKind(t ObjectType) (bits uint, signed bool, ok bool) {
+	if t == INTEGER_OBJ {
+		return 64, true, true
+	}
+	k, ok := integerKinds[t]
+	return k.bits, k.signed, ok
+}
+
+// IsSizedType reports whether t is one of the sized integer or float types.
+func IsSizedType(t ObjectType) bool {
+	_, ok := integerKinds[t]
+	return ok || t == FLOAT32_OBJ || t == FLOAT64_OBJ
+}
+
+// IsSizedNumber reports whether obj has one of the sized numeric types.
+func IsSizedNumber(obj Object) bool {
+	return IsSizedType(obj.Type())
+}
+
+// IsFloatType reports whether t is Float, Float32 or Float64.
+func IsFloatType(t ObjectType) bool {
+	return t == FLOAT_OBJ || t == FLOAT32_OBJ || t == FLOAT64_OBJ
+}
+
+// IntegerBits returns the value of an integer of any type as a 64-bit
+// pattern, sign-extended for signed types and zero-extended for unsigned
+// ones.
+func IntegerBits(obj Object) (uint64, bool) {
+	switch o := obj.(type) {
+	case *Integer:
+		return uint64(o.Value), true
+	case *Int8:
+		return uint64(o.Value), true
+	case *Int16:
+		return uint64(o.Value), true
+	case *Int32:
+		return uint64(o.Value), true
+	case *Int64:
+		return uint64(o.Value), true
+	case *UInt8:
+		return uint64(o.Value), true
+	case *UInt16:
+		return uint64(o.Value), true
+	case *UInt32:
+		return uint64(o.Value), true
+	case *UInt64:
+		return o.Value, true
+	}
+	return 0, false
+}
+
+// FloatValue returns the value of a float of any type.
+func FloatValue(obj Object) (float64, bool) {
+	switch o := obj.(type) {
+	case *Float:
+		return o.Value, true
+	case *Float32:
+		return float64(o.Value), true
+	case *Float64:
+		return o.Value, true
+	}
+	return 0, false
+}
+
+// NewInteger returns the integer of type t whose low bits are v, truncating
+// v to the width of t like a Go conversion does.
+func NewInteger(t ObjectType, v uint64) Object {
+	switch t {
+	case INT8_OBJ:
+		return &Int8{Value: int8(v)}
+	case INT16_OBJ:
+		return &Int16{Value: int16(v)}
+	case INT32_OBJ:
+		return &Int32{Value: int32(v)}
+	case INT64_OBJ:
+		return &Int64{Value: int64(v)}
+	case UINT8_OBJ:
+		return &UInt8{Value: uint8(v)}
+	case UINT16_OBJ:
+		return &UInt16{Value: uint16(v)}
+	case UINT32_OBJ:
+		return &UInt32{Value: uint32(v)}
+	case UINT64_OBJ:
+		return &UInt64{Value: v}
+	}
+	return &Integer{Value: int64(v)}
+}
+
+// NewFloat returns v as a float of type t, rounded to float32 precision for
+// Float32.
+func NewFloat(t ObjectType, v float64) Object {
+	switch t {
+	case FLOAT32_OBJ:
+		return &Float32{Value: float32(v)}
+	case FLOAT64_OBJ:
+		return &Float64{Value: v}
+	}
+	return &Float{Value: v}
+}
+
+// FitsInteger reports whether the signed value v is representable in the
+// integer type t without wrapping around.
+func FitsInteger(t ObjectType, v int64) bool {
+	bits, signed, ok := IntegerKind(t)
+	if !ok {
+		return false
+	}
+	if signed {
+		return bits == 64 || v >= -1<<(bits-1) && v < 1<<(bits-1)
+	}
+	return v >= 0 && (bits == 64 || v < 1<<bits)
+}
+
+// FitsFloat reports whether v is within the range of the float type t.
+func FitsFloat(t ObjectType, v float64) bool {
+	if t != FLOAT32_OBJ || math.IsInf(v, 0) || math.IsNaN(v) {
+		return true
+	}
+	return math.Abs(v) <= math.MaxFloat32
+}
+
+// ConvertNumber converts a number of any type to type t the way a Go
+// conversion does: integers are truncated to the width of t and floats are
+// truncated toward zero. ok is false when obj is not a number.
+func ConvertNumber(obj Object, t ObjectType) (result Object, ok bool) {
+	if _, _, isInt := IntegerKind(t); isInt {
+		if bits, ok := IntegerBits(obj); ok {
+			return NewInteger(t, bits), true
+		}
+		if f, ok := FloatValue(obj); ok {
+			if f >= 1<<63 {
+				return NewInteger(t, uint64(f)), true
+			}
+			return NewInteger(t, uint64(int64(f))), true
+		}
+		return nil, false
+	}
+	if IsFloatType(t) {
+		if f, ok := FloatValue(obj); ok {
+			return NewFloat(t, f), true
+		}
+		if bits, ok := IntegerBits(obj); ok {
+			if _, signed, _ := IntegerKind(obj.Type()); signed {
+				return NewFloat(t, float64(int64(bits))), true
+			}
+			return NewFloat(t, float64(bits)), true
+		}
+	}
+	return nil, false
+}
@@ -23,7 +23,15 @@ type Float struct {
 func (f *Float) Type() ObjectType { return FLOAT_OBJ }
 func (f *Float) Inspect() string  { return fmt.Sprintf("%g", f.Value) }
 func (f *Float) HashKey() HashKey {
-	return HashKey{Type: f.Type(), Value: uint64(f.Value)}
+	return HashKey{Type: f.Type(), Value: floatHash(f.Value)}
+}
+
+// floatHash keys a float by its bits, with 0 and -0 as the same key.
+func floatHash(v float64) uint64 {
+	if v == 0 {
+		return 0
+	}
+	return math.Float64bits(v)
 }
 
 type Boolean struct {
@@ -56,6 +64,14 @@ func (s *String) HashKey() HashKey {
 
 type Null struct{}
 
+// The VM uses a single object for each of true, false and null, and decoded
+// bytecode shares them so that constants stay identical to them.
+var (
+	TrueValue  = &Boolean{Value: true}
+	FalseValue = &Boolean{Value: false}
+	NullValue  = &Null{}
+)
+
 func (n *Null) Type() ObjectType { return NULL_OBJ }
 func (n *Null) Inspect() string  { return "null" }
 
@@ -127,7 +143,7 @@ type Float32 struct {
 func (f *Float32) Type() ObjectType { return FLOAT32_OBJ }
 func (f *Float32) Inspect() string  { return fmt.Sprintf("%g", f.Value) }
 func (f *Float32) HashKey() HashKey {
-	return HashKey{Type: f.Type(), Value: uint64(math.Float32bits(f.Value))}
+	return HashKey{Type: f.Type(), Value: floatHash(float64(f.Value))}
 }
 
 type Float64 struct {
@@ -136,5 +152,5 @@ type Float64 struct {
 func (f *Float64) Type() ObjectType { return FLOAT64_OBJ }
 func (f *Float64) Inspect() string  { return fmt.Sprintf("%g", f.Value) }
 func (f *Float64) HashKey() HashKey {
-	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+	return HashKey{Type: f.Type(), Value: floatHash(f.Value)}
 }
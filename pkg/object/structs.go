@@ -29,6 +29,8 @@ func (is *InstantiatedStruct) Inspect() string {
 type Instance struct {
 	StructName string // Esto ahora almacenará el nombre completo instanciado si aplica
 	Fields     map[string]Object
+	// Stack is where the instance was first thrown, if it was.
+	Stack []StackFrame
 }
 
 func (i *Instance) Type() ObjectType { return INSTANCE_OBJ }
@@ -0,0 +1,214 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrUnlockOfUnlocked is the error of unlocking a lock that is not held,
+// which would crash a Go program.
+var ErrUnlockOfUnlocked = errors.New("unlock of unlocked mutex")
+
+// Mutex is a lock of the sync module, shared by the tasks of a program.
+type Mutex struct {
+	mu     sync.Mutex
+	locked atomic.Bool
+}
+
+func (m *Mutex) Type() ObjectType { return MUTEX_OBJ }
+func (m *Mutex) Inspect() string  { return fmt.Sprintf("Mutex[%p]", m) }
+
+// Lock waits until the mutex is free and locks it.
+func (m *Mutex) Lock() {
+	m.mu.Lock()
+	m.locked.Store(true)
+}
+
+// TryLock locks the mutex if it is free and reports whether it did.
+func (m *Mutex) TryLock() bool {
+	if !m.mu.TryLock() {
+		return false
+	}
+	m.locked.Store(true)
+	return true
+}
+
+// Unlock frees the mutex, failing with ErrUnlockOfUnlocked if it is not
+// locked.
+func (m *Mutex) Unlock() error {
+	if !m.locked.CompareAndSwap(true, false) {
+		return ErrUnlockOfUnlocked
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// RWMutex is a lock held by any number of readers or by one writer.
+type RWMutex struct {
+	mu      sync.RWMutex
+	writer  atomic.Bool
+	readers atomic.Int64
+}
+
+func (m *RWMutex) Type() ObjectType { return RWMUTEX_OBJ }
+func (m *RWMutex) Inspect() string  { return fmt.Sprintf("RWMutex[%p]", m) }
+
+// Lock waits until the mutex is free and locks it for writing.
+func (m *RWMutex) Lock() {
+	m.mu.Lock()
+	m.writer.Store(true)
+}
+
+// TryLock locks the mutex for writing if it is free and reports whether
+// it did.
+func (m *RWMutex) TryLock() bool {
+	if !m.mu.TryLock() {
+		return false
+	}
+	m.writer.Store(true)
+	return true
+}
+
+// Unlock ends the lock for writing.
+func (m *RWMutex) Unlock() error {
+	if !m.writer.CompareAndSwap(true, false) {
+		return ErrUnlockOfUnlocked
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// RLock waits until no writer holds the mutex and locks it for reading.
+func (m *RWMutex) RLock() {
+	m.mu.RLock()
+	m.readers.Add(1)
+}
+
+// RUnlock ends one lock for reading.
+func (m *RWMutex) RUnlock() error {
+	for {
+		n := m.readers.Load()
+		if n <= 0 {
+			return ErrUnlockOfUnlocked
+		}
+		if m.readers.CompareAndSwap(n, n-1) {
+			break
+		}
+	}
+	m.mu.RUnlock()
+	return nil
+}
+
+// Atomic holds a value that tasks read and replace as a whole.
+type Atomic struct {
+	mu    sync.Mutex
+	value Object
+}
+
+// NewAtomic returns an Atomic holding value.
+func NewAtomic(value Object) *Atomic {
+	return &Atomic{value: value}
+}
+
+func (a *Atomic) Type() ObjectType { return ATOMIC_OBJ }
+func (a *Atomic) Inspect() string  { return "Atomic(" + a.Load().Inspect() + ")" }
+
+// Load returns the value held.
+func (a *Atomic) Load() Object {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.value
+}
+
+// Update replaces the value held with what f returns for it, with no
+// other update in between, and returns the previous value.
+func (a *Atomic) Update(f func(Object) (Object, error)) (Object, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	next, err := f(a.value)
+	if err != nil {
+		return nil, err
+	}
+	old := a.value
+	a.value = next
+	return old, nil
+}
+
+// SharedMap is a hash that tasks can read and write at the same time.
+type SharedMap struct {
+	mu    sync.RWMutex
+	pairs map[HashKey]HashPair
+}
+
+// NewSharedMap returns a SharedMap holding the pairs of h, or no pairs
+// when h is nil.
+func NewSharedMap(h *Hash) *SharedMap {
+	m := &SharedMap{pairs: make(map[HashKey]HashPair)}
+	if h != nil {
+		for k, pair := range h.Pairs {
+			m.pairs[k] = pair
+		}
+	}
+	return m
+}
+
+func (m *SharedMap) Type() ObjectType { return SHARED_MAP_OBJ }
+func (m *SharedMap) Inspect() string  { return "SharedMap" + m.Snapshot().Inspect() }
+
+// Get returns the value stored under key.
+func (m *SharedMap) Get(key Hashable) (Object, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	pair, ok := m.pairs[key.HashKey()]
+	return pair.Value, ok
+}
+
+// Set stores value under key.
+func (m *SharedMap) Set(key Hashable, value Object) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pairs[key.HashKey()] = HashPair{Key: key.(Object), Value: value}
+}
+
+// Delete removes key and reports whether it was there.
+func (m *SharedMap) Delete(key Hashable) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.pairs[key.HashKey()]
+	delete(m.pairs, key.HashKey())
+	return ok
+}
+
+// Len returns the number of keys.
+func (m *SharedMap) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.pairs)
+}
+
+// Update stores under key what f returns for the value there, with no
+// other write in between, and returns it. f is told whether key was set.
+func (m *SharedMap) Update(key Hashable, f func(old Object, ok bool) (Object, error)) (Object, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pair, ok := m.pairs[key.HashKey()]
+	next, err := f(pair.Value, ok)
+	if err != nil {
+		return nil, err
+	}
+	m.pairs[key.HashKey()] = HashPair{Key: key.(Object), Value: next}
+	return next, nil
+}
+
+// Snapshot copies the pairs into a Hash.
+func (m *SharedMap) Snapshot() *Hash {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	pairs := make(map[HashKey]HashPair, len(m.pairs))
+	for k, pair := range m.pairs {
+		pairs[k] = pair
+	}
+	return &Hash{Pairs: pairs}
+}
@@ -1,5 +1,7 @@
 package object
 
+import "context"
+
 type ObjectType string
 
 const (
@@ -42,10 +44,19 @@ const (
 	INSTANTIATED_STRUCT_OBJ   = "INSTANTIATED_STRUCT"
 	INSTANTIATED_FUNCTION_OBJ = "INSTANTIATED_FUNCTION"
 	INSTANCE_OBJ              = "INSTANCE"
+	ENUM_VARIANT_OBJ          = "ENUM_VARIANT"
+	ENUM_VALUE_OBJ            = "ENUM_VALUE"
 	ARRAY_OBJ                 = "ARRAY"
 	HASH_OBJ                  = "HASH"
 	PROMISE_OBJ               = "PROMISE"
 	CHANNEL_OBJ               = "CHANNEL"
+	GENERATOR_OBJ             = "GENERATOR"
+	ITERATOR_OBJ              = "ITERATOR"
+	MUTEX_OBJ                 = "MUTEX"
+	RWMUTEX_OBJ               = "RWMUTEX"
+	ATOMIC_OBJ                = "ATOMIC"
+	SHARED_MAP_OBJ            = "SHARED_MAP"
+	TASK_GROUP_OBJ            = "TASK_GROUP"
 )
 
 type Object interface {
@@ -58,5 +69,6 @@ type Hashable interface {
 }
 
 // VMExecutor is a function hook to execute a closure in a new VM/Context.
-// Used to break dependency cycles between stdlib and vm.
-type VMExecutor func(closure Object, args []Object) Object
+// Used to break dependency cycles between stdlib and vm. The closure stops
+// with an error once ctx is done.
+type VMExecutor func(ctx context.Context, closure Object, args []Object) Object
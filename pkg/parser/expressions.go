@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"jabline/pkg/ast"
 	"jabline/pkg/lexer"
@@ -19,6 +20,9 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	leftExp := prefix()
 
 	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+		if p.startsDestructuring() {
+			return leftExp
+		}
 		infix := p.infixParseFns[p.peekTok.Type]
 		if infix == nil {
 			return leftExp
@@ -97,64 +101,160 @@ func (p *Parser) parseTemplateLiteral() ast.Expression {
 	return lit
 }
 
+// parseTemplateContent splits the raw template source into literal parts and
+// interpolated expressions. Each expression is parsed by a sub-parser whose
+// lexer starts at the expression's real position in the file, so the
+// resulting nodes (and any errors) carry accurate line and column info.
 func (p *Parser) parseTemplateContent(content string) ([]string, []ast.Expression) {
 	parts := []string{}
 	expressions := []ast.Expression{}
 
-	currentPart := ""
+	var currentPart strings.Builder
+	line, column := p.curTok.Line, p.curTok.Column+1
 	i := 0
 
+	advance := func(n int) {
+		for ; n > 0 && i < len(content); n-- {
+			if content[i] == '\n' {
+				line++
+				column = 1
+			} else {
+				column++
+			}
+			i++
+		}
+	}
+
 	for i < len(content) {
-		if i < len(content)-1 && content[i] == '$' && content[i+1] == '{' {
+		if content[i] == '\\' && i+1 < len(content) {
+			currentPart.WriteString(unescapeTemplateChar(content[i+1]))
+			advance(2)
+			continue
+		}
 
-			parts = append(parts, currentPart)
-			currentPart = ""
-			i += 2
+		if i < len(content)-1 && content[i] == '$' && content[i+1] == '{' {
+			advance(2)
 
-			braceCount := 1
 			exprStart := i
-
-			for i < len(content) && braceCount > 0 {
-				if content[i] == '{' {
-					braceCount++
-				} else if content[i] == '}' {
-					braceCount--
-				}
-				if braceCount > 0 {
-					i++
-				}
+			exprLine, exprColumn := line, column
+
+			end := templateInterpolationEnd(content, i)
+			if end < 0 {
+				p.addError("unterminated interpolation in template literal")
+				currentPart.WriteString("${")
+				currentPart.WriteString(content[exprStart:])
+				advance(len(content) - i)
+				break
 			}
+			advance(end - i)
 
-			if braceCount == 0 {
-				exprContent := content[exprStart:i]
-
-				exprLexer := lexer.New(exprContent)
-				exprParser := New(exprLexer)
-				expr := exprParser.parseExpression(1)
-
-				if expr != nil {
-					expressions = append(expressions, expr)
-				} else {
-					expressions = append(expressions, &ast.StringLiteral{
-						Token: token.Token{Type: token.STRING, Literal: exprContent},
-						Value: exprContent,
-					})
-				}
-				i++
-			} else {
-				currentPart += "${"
-			}
-		} else {
-			currentPart += string(content[i])
-			i++
+			exprContent := content[exprStart:i]
+			advance(1)
+
+			parts = append(parts, currentPart.String())
+			currentPart.Reset()
+			expressions = append(expressions, p.parseTemplateExpression(exprContent, exprLine, exprColumn))
+			continue
 		}
+
+		currentPart.WriteByte(content[i])
+		advance(1)
 	}
 
-	parts = append(parts, currentPart)
+	parts = append(parts, currentPart.String())
 
 	return parts, expressions
 }
 
+func (p *Parser) parseTemplateExpression(source string, line, column int) ast.Expression {
+	exprParser := New(lexer.NewAt(source, line, column))
+	exprParser.yields = p.yields
+
+	if exprParser.curTokenIs(token.EOF) {
+		p.errors = append(p.errors, fmt.Sprintf("line %d, column %d: empty interpolation in template literal", line, column))
+		return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Line: line, Column: column}}
+	}
+
+	expr := exprParser.parseExpression(LOWEST)
+	if expr != nil && !exprParser.peekTokenIs(token.EOF) {
+		exprParser.nextToken()
+		exprParser.addError("unexpected %s in template interpolation", exprParser.curTok.Literal)
+	}
+	p.errors = append(p.errors, exprParser.Errors()...)
+
+	if expr == nil {
+		return &ast.StringLiteral{
+			Token: token.Token{Type: token.STRING, Literal: source, Line: line, Column: column},
+			Value: source,
+		}
+	}
+
+	return expr
+}
+
+// templateInterpolationEnd returns the index of the '}' closing the
+// interpolation that starts at start, or -1 if it is never closed. Braces
+// inside string and nested template literals are not counted.
+func templateInterpolationEnd(content string, start int) int {
+	depth := 1
+	for i := start; i < len(content); i++ {
+		switch content[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case '"', '`':
+			i = skipQuoted(content, i)
+			if i < 0 {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+// skipQuoted returns the index of the quote closing the string or template
+// literal opened at start, or -1 if it is unterminated.
+func skipQuoted(content string, start int) int {
+	quote := content[start]
+	for i := start + 1; i < len(content); i++ {
+		switch {
+		case content[i] == '\\':
+			i++
+		case content[i] == quote:
+			return i
+		case quote == '`' && content[i] == '$' && i+1 < len(content) && content[i+1] == '{':
+			i = templateInterpolationEnd(content, i+2)
+			if i < 0 {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+func unescapeTemplateChar(ch byte) string {
+	switch ch {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	case '\\':
+		return "\\"
+	case '`':
+		return "`"
+	case '$':
+		return "$"
+	default:
+		return "\\" + string(ch)
+	}
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curTok, Value: p.curTokenIs(token.TRUE)}
 }
@@ -201,9 +301,14 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 func (p *Parser) parseGroupedOrArrowFunction() ast.Expression {
 	lParenToken := p.curTok
 
-	// Peak ahead to see if it's an arrow function or empty param list
-	if p.peekTokenIs(token.RPAREN) {
-		p.nextToken() // curTok = RPAREN
+	// Peak ahead to see if it's an arrow function or empty param list.
+	// Parameters followed by => are read as such, so that they can be
+	// destructuring patterns: ([k, v]) => ...
+	if p.peekTokenIs(token.RPAREN) || !p.matchGuard && p.tokenAfterGroup().Type == token.ARROW {
+		params := p.parseFunctionParameters() // curTok = RPAREN
+		if params == nil {
+			return nil
+		}
 		// Must be an arrow function: () => ... OR (): type => ...
 		var returnType *ast.TypeExpression
 		if p.peekTokenIs(token.COLON) {
@@ -219,10 +324,10 @@ func (p *Parser) parseGroupedOrArrowFunction() ast.Expression {
 		p.nextToken() // move to body
 		arrow := &ast.ArrowFunction{
 			Token:      lParenToken,
-			Parameters: []*ast.Identifier{},
+			Parameters: params,
 			ReturnType: returnType,
 		}
-		arrow.Body = p.parseExpression(LOWEST)
+		arrow.Body = p.parseArrowBody()
 		return arrow
 	}
 
@@ -282,7 +387,7 @@ func (p *Parser) parseGroupedOrArrowFunction() ast.Expression {
 		returnType = p.parseTypeExpression()
 	}
 
-	if p.peekTokenIs(token.ARROW) || isArrow {
+	if (p.peekTokenIs(token.ARROW) && !p.matchGuard) || isArrow {
 		if !p.expectPeek(token.ARROW) {
 			return nil
 		}
@@ -293,7 +398,7 @@ func (p *Parser) parseGroupedOrArrowFunction() ast.Expression {
 			Parameters: idents,
 			ReturnType: returnType,
 		}
-		arrow.Body = p.parseExpression(LOWEST)
+		arrow.Body = p.parseArrowBody()
 		return arrow
 	}
 
@@ -409,13 +514,74 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 
 func (p *Parser) parseCallExpression(fn ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curTok, Function: fn}
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Arguments = p.parseCallArguments()
 	return exp
 }
 
+// parseCallArguments parses the arguments of a call. Besides expressions
+// these may be spread arrays, ...args, and arguments named after their
+// parameter, name: value, which come after all the others.
+func (p *Parser) parseCallArguments() []ast.Expression {
+	args := []ast.Expression{}
+
+	guard := p.matchGuard
+	p.matchGuard = false
+	defer func() { p.matchGuard = guard }()
+
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return args
+	}
+
+	named := map[string]bool{}
+	for {
+		p.nextToken()
+		var arg ast.Expression
+		switch {
+		case p.curTokenIs(token.ELLIPSIS):
+			spread := &ast.SpreadExpression{Token: p.curTok}
+			p.nextToken()
+			spread.Value = p.parseExpression(LOWEST)
+			arg = spread
+		case p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON):
+			na := &ast.NamedArgument{Token: p.curTok, Name: &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}}
+			if named[na.Name.Value] {
+				p.addError("argument %s is given twice", na.Name.Value)
+			}
+			named[na.Name.Value] = true
+			p.nextToken()
+			p.nextToken()
+			na.Value = p.parseExpression(LOWEST)
+			arg = na
+		default:
+			arg = p.parseExpression(LOWEST)
+		}
+		if _, isNamed := arg.(*ast.NamedArgument); !isNamed && len(named) > 0 {
+			p.addError("positional argument %s follows a named argument", arg)
+		}
+		args = append(args, arg)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return args
+}
+
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	args := []ast.Expression{}
 
+	// Arrow functions may be passed to a call in a match guard
+	guard := p.matchGuard
+	p.matchGuard = false
+	defer func() { p.matchGuard = guard }()
+
 	if p.peekTokenIs(end) {
 		p.nextToken()
 		return args
@@ -572,7 +738,7 @@ func (p *Parser) parseAsyncFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Body = p.parseBlockStatement()
+	lit.Body = p.parseAsyncBody()
 
 	return lit
 }
@@ -587,6 +753,26 @@ func (p *Parser) parseAwaitExpression() ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseYieldExpression() ast.Expression {
+	expression := &ast.YieldExpression{Token: p.curTok}
+
+	if len(p.yields) == 0 {
+		p.addError("yield outside of a function")
+	} else {
+		p.yields[len(p.yields)-1] = true
+	}
+
+	switch p.peekTok.Type {
+	case token.SEMICOLON, token.RBRACE, token.RPAREN, token.RBRACKET, token.COMMA, token.EOF:
+		return expression
+	}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(LOWEST)
+
+	return expression
+}
+
 func (p *Parser) parseTypeCastExpression() ast.Expression {
 	tok := p.curTok // Current token is INT8_TYPE, UINT16_TYPE, etc.
 
@@ -9,6 +9,11 @@ import (
 func (p *Parser) parseFunctionStatement() ast.Statement {
 	stmt := &ast.FunctionStatement{Token: p.curTok}
 
+	if p.peekTokenIs(token.ASTERISK) {
+		p.nextToken()
+		stmt.Generator = true
+	}
+
 	// Check for Method Receiver: fn (receiver Type) name
 	if p.peekTokenIs(token.LPAREN) {
 		p.nextToken() // Move to (
@@ -58,7 +63,9 @@ func (p *Parser) parseFunctionStatement() ast.Statement {
 		return nil
 	}
 
-	stmt.Body = p.parseBlockStatement()
+	var yields bool
+	stmt.Body, yields = p.parseFunctionBody()
+	stmt.Generator = stmt.Generator || yields
 
 	return stmt
 }
@@ -66,6 +73,11 @@ func (p *Parser) parseFunctionStatement() ast.Statement {
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curTok}
 
+	if p.peekTokenIs(token.ASTERISK) {
+		p.nextToken()
+		lit.Generator = true
+	}
+
 	if p.peekTokenIs(token.LBRACKET) {
 		p.nextToken()
 		lit.TypeParameters = p.parseTypeParameters()
@@ -88,11 +100,35 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Body = p.parseBlockStatement()
+	var yields bool
+	lit.Body, yields = p.parseFunctionBody()
+	lit.Generator = lit.Generator || yields
 
 	return lit
 }
 
+// parseFunctionBody parses the block of a function and reports whether it
+// yields, which makes the function a generator. Yields inside nested
+// functions belong to those.
+func (p *Parser) parseFunctionBody() (*ast.BlockStatement, bool) {
+	p.yields = append(p.yields, false)
+	body := p.parseBlockStatement()
+	yields := p.yields[len(p.yields)-1]
+	p.yields = p.yields[:len(p.yields)-1]
+	return body, yields
+}
+
+// parseAsyncBody parses the block of an async function, which may not
+// yield.
+func (p *Parser) parseAsyncBody() *ast.BlockStatement {
+	tok := p.curTok
+	body, yields := p.parseFunctionBody()
+	if yields {
+		p.errors = append(p.errors, fmt.Sprintf("line %d, column %d: async functions cannot yield", tok.Line, tok.Column))
+	}
+	return body
+}
+
 func (p *Parser) parseArrowFunction() ast.Expression {
 	arrowFn := &ast.ArrowFunction{Token: p.curTok}
 
@@ -109,7 +145,7 @@ func (p *Parser) parseArrowFunction() ast.Expression {
 		p.nextToken()
 		p.nextToken()
 
-		arrowFn.Body = p.parseExpression(1)
+		arrowFn.Body = p.parseArrowBody()
 		return arrowFn
 	}
 
@@ -117,8 +153,11 @@ func (p *Parser) parseArrowFunction() ast.Expression {
 }
 
 func (p *Parser) parseArrowFunctionFromIdent() ast.Expression {
+	if p.curTok.Literal == "match" && p.peekTokenIs(token.LPAREN) {
+		return p.parseMatchOrCall()
+	}
 
-	if p.peekTok.Type == token.ARROW {
+	if p.peekTok.Type == token.ARROW && !p.matchGuard {
 		arrowFn := &ast.ArrowFunction{Token: p.curTok}
 
 		param := &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
@@ -127,13 +166,25 @@ func (p *Parser) parseArrowFunctionFromIdent() ast.Expression {
 		p.nextToken()
 		p.nextToken()
 
-		arrowFn.Body = p.parseExpression(1)
+		arrowFn.Body = p.parseArrowBody()
 		return arrowFn
 	}
 
 	return &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
 }
 
+// parseArrowBody parses the expression body of an arrow function, which
+// may not yield.
+func (p *Parser) parseArrowBody() ast.Expression {
+	p.yields = append(p.yields, false)
+	body := p.parseExpression(LOWEST)
+	if p.yields[len(p.yields)-1] {
+		p.addError("arrow functions cannot yield")
+	}
+	p.yields = p.yields[:len(p.yields)-1]
+	return body
+}
+
 func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	identifiers := []*ast.Identifier{}
 
@@ -144,30 +195,26 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 
 	p.nextToken()
 
-	ident := &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
-
-	// Optional type annotation: `param: int`
-	if p.peekTokenIs(token.COLON) {
-		p.nextToken() // consume COLON
-		p.nextToken() // move to type token
-		ident.Type = p.parseTypeExpression()
+	ident := p.parseParameter(len(identifiers))
+	if ident == nil {
+		return nil
 	}
-
 	identifiers = append(identifiers, ident)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken() // consume COMMA
 		p.nextToken() // move to next parameter
 
-		ident := &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
-
-		// Optional type annotation: `, param: int`
-		if p.peekTokenIs(token.COLON) {
-			p.nextToken() // consume COLON
-			p.nextToken() // move to type token
-			ident.Type = p.parseTypeExpression()
+		if last := identifiers[len(identifiers)-1]; last.Variadic {
+			p.addError("the rest parameter %s must come last", last.Value)
+		}
+		ident := p.parseParameter(len(identifiers))
+		if ident == nil {
+			return nil
+		}
+		if ident.Default == nil && !ident.Variadic && identifiers[len(identifiers)-1].Default != nil {
+			p.addError("parameter %s needs a default, as it follows one that has a default", ident)
 		}
-
 		identifiers = append(identifiers, ident)
 	}
 
@@ -178,6 +225,49 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	return identifiers
 }
 
+// parseParameter parses the parameter at index i: a name or a pattern,
+// `...name` for the rest of the arguments, then an optional type
+// annotation and default.
+func (p *Parser) parseParameter(i int) *ast.Identifier {
+	variadic := p.curTokenIs(token.ELLIPSIS)
+	if variadic && !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	ident := p.parseParameterName(i)
+	if ident == nil {
+		return nil
+	}
+	ident.Variadic = variadic
+
+	// Optional type annotation: `param: int`
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken() // consume COLON
+		p.nextToken() // move to type token
+		ident.Type = p.parseTypeExpression()
+	}
+
+	// Optional default: `param = 10`
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		if variadic {
+			p.addError("the rest parameter %s cannot have a default", ident.Value)
+		}
+		p.nextToken()
+		ident.Default = p.parseExpression(LOWEST)
+	}
+	return ident
+}
+
+// parseParameterName parses the name of the parameter at index i, or a
+// pattern destructuring the argument.
+func (p *Parser) parseParameterName(i int) *ast.Identifier {
+	if p.curTokenIs(token.LBRACKET) || p.curTokenIs(token.LBRACE) {
+		return p.parseDeclaredName(fmt.Sprintf("$$param%d$$", i))
+	}
+	return &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+}
+
 func (p *Parser) parseStructStatement() ast.Statement {
 	stmt := &ast.StructStatement{Token: p.curTok}
 
@@ -319,8 +409,42 @@ func (p *Parser) parseStructLiteralFields() map[string]ast.Expression {
 	return fields
 }
 
+// parseTypeExpression parses a type annotation: a named type, optionally
+// generic (Box[int]) or nullable (int?), or a union of them (int | string).
 func (p *Parser) parseTypeExpression() *ast.TypeExpression {
+	te := p.parseNullableType()
+	if te == nil || !p.peekTokenIs(token.BIT_OR) {
+		return te
+	}
+
+	union := &ast.TypeExpression{Token: te.Token, Union: []*ast.TypeExpression{te}}
+	for p.peekTokenIs(token.BIT_OR) {
+		p.nextToken() // consume |
+		p.nextToken() // move to type token
+		alt := p.parseNullableType()
+		if alt == nil {
+			return nil
+		}
+		union.Union = append(union.Union, alt)
+	}
+	return union
+}
+
+func (p *Parser) parseNullableType() *ast.TypeExpression {
+	te := p.parseNamedType()
+	if te != nil && p.peekTokenIs(token.QUESTION) {
+		p.nextToken()
+		te.Nullable = true
+	}
+	return te
+}
+
+func (p *Parser) parseNamedType() *ast.TypeExpression {
 	switch p.curTok.Type {
+	case token.NULL:
+		return &ast.TypeExpression{Token: p.curTok, Value: "null"}
+	case token.FUNCTION:
+		return &ast.TypeExpression{Token: p.curTok, Value: "fn"}
 	case token.STRING_TYPE:
 		return &ast.TypeExpression{Token: p.curTok, Value: "string"}
 	case token.INT_TYPE:
@@ -14,6 +14,13 @@ type Parser struct {
 
 	errors []string
 
+	// yields has an entry per function being parsed, set once its body
+	// contains a yield.
+	yields []bool
+	// matchGuard is set while parsing the guard of a match arm, where =>
+	// ends the guard instead of starting an arrow function.
+	matchGuard bool
+
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
@@ -61,6 +68,7 @@ func (p *Parser) registerPrefixFunctions() {
 	p.registerPrefix(token.ASYNC, p.parseAsyncFunctionLiteral)
 	p.registerPrefix(token.AWAIT, p.parseAwaitExpression)
 	p.registerPrefix(token.SPAWN, p.parseSpawnExpression)
+	p.registerPrefix(token.YIELD, p.parseYieldExpression)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
@@ -3,6 +3,7 @@ package parser
 import (
 	"jabline/pkg/ast"
 	"jabline/pkg/lexer"
+	"strings"
 	"testing"
 )
 
@@ -73,6 +74,495 @@ func TestGroupedExpressionParsing(t *testing.T) {
 	}
 }
 
+func TestTypeAnnotationParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a: int = 1;", "int"},
+		{"let a: int? = null;", "int?"},
+		{"let a: int | string = 1;", "int | string"},
+		{"let a: Box[int]? | null = null;", "Box[int]? | null"},
+		{"let a: Hash[string, Array[int]] = {};", "Hash[string, Array[int]]"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.LetStatement. got=%T",
+				program.Statements[0])
+		}
+		if stmt.Type.String() != tt.expected {
+			t.Errorf("type is not %q. got=%q", tt.expected, stmt.Type.String())
+		}
+	}
+}
+
+func TestGeneratorParsing(t *testing.T) {
+	tests := []struct {
+		input     string
+		generator bool
+		expected  string
+	}{
+		{"fn* g() { }", true, "fn* g() "},
+		{"fn g(n) { yield n + 1; }", true, "fn* g(n) yield (n + 1)"},
+		{"fn g() { let x = yield; }", true, "fn* g() x = yield;"},
+		{"fn g() { let f = fn() { yield 1; }; }", false, "fn g() f = fn*() yield 1;"},
+		{"fn g() { return 1; }", false, "fn g() return 1;"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.FunctionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.FunctionStatement. got=%T",
+				program.Statements[0])
+		}
+		if stmt.Generator != tt.generator {
+			t.Errorf("%s: Generator is %v", tt.input, stmt.Generator)
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, stmt.String())
+		}
+	}
+
+	invalid := map[string]string{
+		"yield 1;":                           "yield outside of a function",
+		"async fn f() { yield 1; }":          "async functions cannot yield",
+		"fn f() { let g = (x) => yield x; }": "arrow functions cannot yield",
+	}
+	for input, want := range invalid {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if errs := p.Errors(); len(errs) == 0 || !strings.Contains(errs[0], want) {
+			t.Errorf("%s: expected error %q, got %v", input, want, errs)
+		}
+	}
+}
+
+func TestForEachParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		key      string
+		variable string
+	}{
+		{"for (x in xs) { }", "", "x"},
+		{"for (k, v in h) { }", "k", "v"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ForEachStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ForEachStatement. got=%T",
+				program.Statements[0])
+		}
+		key := ""
+		if stmt.Key != nil {
+			key = stmt.Key.Value
+		}
+		if key != tt.key || stmt.Variable.Value != tt.variable {
+			t.Errorf("%s: got variables %q, %q", tt.input, key, stmt.Variable.Value)
+		}
+	}
+}
+
+func TestFinallyAndDeferParsing(t *testing.T) {
+	input := "fn f() { defer close(x); try { a(); } catch (e) { b(); } finally { c(); } }"
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	body := program.Statements[0].(*ast.FunctionStatement).Body.Statements
+	if _, ok := body[0].(*ast.DeferStatement); !ok {
+		t.Fatalf("body[0] is not ast.DeferStatement. got=%T", body[0])
+	}
+	try, ok := body[1].(*ast.TryStatement)
+	if !ok {
+		t.Fatalf("body[1] is not ast.TryStatement. got=%T", body[1])
+	}
+	if try.FinallyBlock == nil || try.FinallyBlock.String() != "c()" {
+		t.Errorf("wrong finally block: %v", try.FinallyBlock)
+	}
+
+	invalid := map[string]string{
+		"defer close(x);":         "defer outside of a function",
+		"fn f() { defer x + 1; }": "defer requires a function call",
+	}
+	for input, want := range invalid {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if errs := p.Errors(); len(errs) == 0 || !strings.Contains(errs[0], want) {
+			t.Errorf("%s: expected error %q, got %v", input, want, errs)
+		}
+	}
+}
+
+func TestCatchClauseParsing(t *testing.T) {
+	input := "try { a(); } catch (e: IOError | TimeoutError) { b(); } catch (e: NotFound) { c(); } catch { d(); }"
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	try, ok := program.Statements[0].(*ast.TryStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.TryStatement. got=%T", program.Statements[0])
+	}
+	if len(try.Catches) != 3 {
+		t.Fatalf("expected 3 catch clauses, got %d", len(try.Catches))
+	}
+	if got := try.Catches[0].Param.String(); got != "e: IOError | TimeoutError" {
+		t.Errorf("wrong first catch parameter: %s", got)
+	}
+	if try.Catches[2].Param != nil {
+		t.Errorf("last catch clause should have no parameter, got %s", try.Catches[2].Param)
+	}
+
+	p = New(lexer.New("try { a(); } catch (e) { b(); } catch (e: IOError) { c(); }"))
+	p.ParseProgram()
+	if errs := p.Errors(); len(errs) == 0 || !strings.Contains(errs[0], "catches every exception") {
+		t.Errorf("expected unreachable catch clause error, got %v", errs)
+	}
+}
+
+func TestMatchParsing(t *testing.T) {
+	input := `let r = match (v) {
+		0 => "zero",
+		1..=9 => "digit",
+		[first, ...rest] if first > 0 => rest,
+		{ "status": 200, "body": b } => b,
+		User { name, age: 18..65 } => { name }
+		Color.Red => "red",
+		_ => null
+	};`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.LetStatement)
+	match, ok := stmt.Value.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.MatchExpression. got=%T", stmt.Value)
+	}
+	expected := []string{
+		`0 => "zero"`,
+		`1..=9 => "digit"`,
+		`[first, ...rest] if (first > 0) => rest`,
+		`{"status": 200, "body": b} => b`,
+		`User { name: name, age: 18..65 } => name`,
+		`Color.Red => "red"`,
+		`_ => null`,
+	}
+	if len(match.Arms) != len(expected) {
+		t.Fatalf("expected %d arms, got %d", len(expected), len(match.Arms))
+	}
+	for i, want := range expected {
+		if got := match.Arms[i].String(); got != want {
+			t.Errorf("arm %d: want %s, got %s", i, want, got)
+		}
+	}
+
+	// match is not a keyword: without a block it is an ordinary call.
+	p = New(lexer.New(`let match = fn(x) { x }; match(1);`))
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+	if _, ok := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression); !ok {
+		t.Errorf("match(1) should parse as a call")
+	}
+
+	p = New(lexer.New(`match (v) { [...rest, last] => 1 }`))
+	p.ParseProgram()
+	if errs := p.Errors(); len(errs) == 0 || !strings.Contains(errs[0], "rest element must come last") {
+		t.Errorf("expected rest element error, got %v", errs)
+	}
+}
+
+func TestDestructuringParsing(t *testing.T) {
+	input := `let [first, _, ...rest] = items;
+let { name, age: years = 0, "full name": full } = user;
+[a, b] = [b, a];
+fn show({ name }, [x, y]) { name }
+for ([k, v] in pairs) { k }`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := []string{
+		`[first, _, ...rest] = items;`,
+		`{ name: name, age: years = 0, full name: full } = user;`,
+		`[a, b] = [b, a];`,
+	}
+	for i, want := range expected {
+		if got := program.Statements[i].String(); got != want {
+			t.Errorf("statement %d: want %s, got %s", i, want, got)
+		}
+	}
+
+	fn := program.Statements[3].(*ast.FunctionStatement)
+	if len(fn.Parameters) != 2 || fn.Parameters[0].Pattern == nil || fn.Parameters[0].Value != "$$param0$$" {
+		t.Fatalf("expected a destructured first parameter, got %v", fn.Parameters)
+	}
+	loop := program.Statements[4].(*ast.ForEachStatement)
+	if got := ast.PatternBindings(loop.Variable.Pattern); len(got) != 2 || got[1].Value != "v" {
+		t.Errorf("expected the loop to bind k and v, got %v", got)
+	}
+
+	for input, want := range map[string]string{
+		`let [...rest, last] = xs;`: "rest element must come last",
+		`for ([a, b], v in xs) {}`:  "the key of a for-in loop cannot be destructured",
+	} {
+		p = New(lexer.New(input))
+		p.ParseProgram()
+		if errs := p.Errors(); len(errs) == 0 || !strings.Contains(strings.Join(errs, "\n"), want) {
+			t.Errorf("%s: expected %q, got %v", input, want, errs)
+		}
+	}
+}
+
+func TestNewlineSeparatedDestructuring(t *testing.T) {
+	input := `let { name, age: years = 0 } = user
+[a, b] = [b, a]
+let c = rows
+[0]
+let d = cells
+[i] = 1`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	// A bracket starting a line only begins a statement when it is
+	// assigned to; otherwise it still indexes the line before.
+	expected := []string{
+		`{ name: name, age: years = 0 } = user;`,
+		`[a, b] = [b, a];`,
+		`c = (rows[0]);`,
+		`d = cells;`,
+		`[i] = 1;`,
+	}
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("want %d statements, got %d: %v", len(expected), len(program.Statements), program.Statements)
+	}
+	for i, want := range expected {
+		if got := program.Statements[i].String(); got != want {
+			t.Errorf("statement %d: want %s, got %s", i, want, got)
+		}
+	}
+}
+
+func TestParameterAndArgumentParsing(t *testing.T) {
+	input := `fn connect(url: string, timeout = 1000, ...rest) { url }
+connect(url, ...extra, timeout: 5000, retries: 3);`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	fn := program.Statements[0].(*ast.FunctionStatement)
+	if required, variadic := ast.ParameterArity(fn.Parameters); required != 1 || !variadic {
+		t.Errorf("expected 1 required parameter and a rest one, got %d, %t", required, variadic)
+	}
+	if got := fn.Parameters[1].String() + ", " + fn.Parameters[2].String(); got != "timeout = 1000, ...rest" {
+		t.Errorf("wrong parameters: %s", got)
+	}
+
+	call := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	if _, ok := call.Arguments[1].(*ast.SpreadExpression); !ok {
+		t.Errorf("argument 1 is not ast.SpreadExpression. got=%T", call.Arguments[1])
+	}
+	if got := call.String(); got != "connect(url, ...extra, timeout: 5000, retries: 3)" {
+		t.Errorf("wrong call: %s", got)
+	}
+
+	for input, want := range map[string]string{
+		`fn f(...rest, last) {}`:     "the rest parameter rest must come last",
+		`fn f(...rest = []) {}`:      "the rest parameter rest cannot have a default",
+		`fn f(a = 1, b) {}`:          "parameter b needs a default",
+		`f(timeout: 1, url);`:        "positional argument url follows a named argument",
+		`f(timeout: 1, timeout: 2);`: "argument timeout is given twice",
+	} {
+		p = New(lexer.New(input))
+		p.ParseProgram()
+		if errs := p.Errors(); len(errs) == 0 || !strings.Contains(strings.Join(errs, "\n"), want) {
+			t.Errorf("%s: expected %q, got %v", input, want, errs)
+		}
+	}
+}
+
+func TestAlgebraicEnumParsing(t *testing.T) {
+	input := `enum Shape { Circle(radius: float), Rect(w, h), Empty }
+match (s) { Shape.Circle(r) => r, Shape.Rect(w, _) if w > 1 => w, Shape.Empty => 0 }`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	enum := program.Statements[0].(*ast.EnumStatement)
+	if want := "enum Shape { Circle(radius: float), Rect(w, h), Empty }"; enum.String() != want {
+		t.Errorf("want %s, got %s", want, enum.String())
+	}
+	if !enum.Algebraic() || enum.VariantFields(2) != nil {
+		t.Errorf("expected an algebraic enum whose last variant has no fields")
+	}
+
+	match := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+	expected := []string{"Shape.Circle(r) => r", "Shape.Rect(w, _) if (w > 1) => w", "Shape.Empty => 0"}
+	for i, want := range expected {
+		if got := match.Arms[i].String(); got != want {
+			t.Errorf("arm %d: want %s, got %s", i, want, got)
+		}
+	}
+	if _, ok := match.Arms[2].Pattern.(*ast.ValuePattern); !ok {
+		t.Errorf("expected a variant without parentheses to be a value pattern, got %T", match.Arms[2].Pattern)
+	}
+
+	for input, want := range map[string]string{
+		`enum E { A() }`:     "variant A of E needs a field",
+		`enum E { A(x, x) }`: "variant A of E has two fields named x",
+	} {
+		p = New(lexer.New(input))
+		p.ParseProgram()
+		if errs := p.Errors(); len(errs) == 0 || !strings.Contains(strings.Join(errs, "\n"), want) {
+			t.Errorf("%s: expected %q, got %v", input, want, errs)
+		}
+	}
+}
+
+func TestGroupStatementParsing(t *testing.T) {
+	tests := []struct {
+		input string
+		name  string
+		body  string
+	}{
+		{`group { spawn a(); spawn b(); }`, "", "spawn a()spawn b()"},
+		{`group g { g.cancel(); }`, "g", `(g."cancel")()`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.GroupStatement)
+		if !ok {
+			t.Fatalf("%s: expected *ast.GroupStatement, got %T", tt.input, program.Statements[0])
+		}
+		name := ""
+		if stmt.Name != nil {
+			name = stmt.Name.Value
+		}
+		if name != tt.name {
+			t.Errorf("%s: want name %q, got %q", tt.input, tt.name, name)
+		}
+		if got := stmt.Body.String(); got != tt.body {
+			t.Errorf("%s: want body %s, got %s", tt.input, tt.body, got)
+		}
+	}
+
+	// group stays usable as a name.
+	p := New(lexer.New(`let group = 1; group + 1;`))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 2 {
+		t.Errorf("expected 2 statements, got %d", len(program.Statements))
+	}
+}
+
+func TestSelectStatementParsing(t *testing.T) {
+	input := `select { case v = <-a: echo(v); case <-b: case c <- 1: case timeout(10): default: echo(0); }`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.SelectStatement)
+	if !ok {
+		t.Fatalf("expected *ast.SelectStatement, got %T", program.Statements[0])
+	}
+	expected := []string{"case v = <-a:echo(v);", "case <-b:", "case c <- 1:", "case timeout(10):"}
+	if len(stmt.Cases) != len(expected) || stmt.DefaultCase == nil {
+		t.Fatalf("expected %d cases and a default, got %s", len(expected), stmt.String())
+	}
+	for i, want := range expected {
+		if got := stmt.Cases[i].String(); got != want {
+			t.Errorf("case %d: want %s, got %s", i, want, got)
+		}
+	}
+
+	for input, want := range map[string]string{
+		`select { case f(): }`:         "select case must receive from a channel, send to one or be timeout(ms)",
+		`select { default: default: }`: "multiple default clauses in select statement",
+		`select { echo(1); }`:          "expected 'case' or 'default' in select body",
+	} {
+		p = New(lexer.New(input))
+		p.ParseProgram()
+		if errs := p.Errors(); len(errs) == 0 || !strings.Contains(strings.Join(errs, "\n"), want) {
+			t.Errorf("%s: expected %q, got %v", input, want, errs)
+		}
+	}
+}
+
+func TestTemplateLiteralParsing(t *testing.T) {
+	input := "let s = `a\\${b} ${name}:\\n${add(x, 1)}`"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.LetStatement. got=%T",
+			program.Statements[0])
+	}
+
+	tmpl, ok := stmt.Value.(*ast.TemplateLiteral)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.TemplateLiteral. got=%T", stmt.Value)
+	}
+
+	expectedParts := []string{"a${b} ", ":\n", ""}
+	if len(tmpl.Parts) != len(expectedParts) {
+		t.Fatalf("wrong number of parts. want=%d, got=%d", len(expectedParts), len(tmpl.Parts))
+	}
+	for i, part := range expectedParts {
+		if tmpl.Parts[i] != part {
+			t.Errorf("part %d is not %q. got=%q", i, part, tmpl.Parts[i])
+		}
+	}
+
+	if len(tmpl.Expressions) != 2 {
+		t.Fatalf("wrong number of expressions. want=2, got=%d", len(tmpl.Expressions))
+	}
+
+	ident, ok := tmpl.Expressions[0].(*ast.Identifier)
+	if !ok {
+		t.Fatalf("expression 0 is not ast.Identifier. got=%T", tmpl.Expressions[0])
+	}
+	if ident.Token.Line != 1 || ident.Token.Column != 19 {
+		t.Errorf("identifier has wrong position. want=1:19, got=%d:%d",
+			ident.Token.Line, ident.Token.Column)
+	}
+
+	call, ok := tmpl.Expressions[1].(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("expression 1 is not ast.CallExpression. got=%T", tmpl.Expressions[1])
+	}
+	if call.String() != "add(x, 1)" {
+		t.Errorf("call is not %q. got=%q", "add(x, 1)", call.String())
+	}
+	arg := call.Arguments[0].(*ast.Identifier)
+	if arg.Token.Line != 1 || arg.Token.Column != 33 {
+		t.Errorf("argument has wrong position. want=1:33, got=%d:%d",
+			arg.Token.Line, arg.Token.Column)
+	}
+}
+
 func checkParserErrors(t *testing.T, p *Parser) {
 	errors := p.Errors()
 	if len(errors) == 0 {
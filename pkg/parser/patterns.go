@@ -0,0 +1,483 @@
+package parser
+
+import (
+	"jabline/pkg/ast"
+	"jabline/pkg/token"
+)
+
+// parseMatchOrCall parses match (subject) { arms }. match is not a keyword,
+// so that existing functions and variables named match keep working: it is
+// a call of one unless a block follows the parenthesis.
+func (p *Parser) parseMatchOrCall() ast.Expression {
+	ident := &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+	p.nextToken()
+
+	call := &ast.CallExpression{Token: p.curTok, Function: ident}
+	call.Arguments = p.parseCallArguments()
+	if call.Arguments == nil || !p.peekTokenIs(token.LBRACE) {
+		return call
+	}
+	if len(call.Arguments) != 1 || !isSubject(call.Arguments[0]) {
+		p.addError("match takes a single subject, got %d", len(call.Arguments))
+		return nil
+	}
+	p.nextToken()
+
+	expr := &ast.MatchExpression{Token: ident.Token, Subject: call.Arguments[0]}
+	for !p.peekTokenIs(token.RBRACE) && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expr.Arms = append(expr.Arms, arm)
+	}
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return expr
+}
+
+// isSubject reports whether a call argument can be the subject of a match.
+func isSubject(arg ast.Expression) bool {
+	switch arg.(type) {
+	case *ast.SpreadExpression, *ast.NamedArgument:
+		return false
+	}
+	return true
+}
+
+// parseMatchArm parses pattern [if guard] => body. The body is a block or
+// an expression; arms with an expression body are separated by commas.
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Token: p.curTok}
+	if arm.Pattern = p.parsePattern(); arm.Pattern == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		p.matchGuard = true
+		arm.Guard = p.parseExpression(LOWEST)
+		p.matchGuard = false
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+	p.nextToken()
+
+	if p.curTokenIs(token.LBRACE) {
+		arm.Body = p.parseBlockStatement()
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		}
+		return arm
+	}
+
+	tok := p.curTok
+	value := p.parseExpression(LOWEST)
+	arm.Body = &ast.BlockStatement{Token: tok, Statements: []ast.Statement{
+		&ast.ExpressionStatement{Token: tok, Expression: value},
+	}}
+	if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+		return nil
+	}
+	return arm
+}
+
+// parsePattern parses a pattern starting at the current token.
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curTok.Type {
+	case token.IDENT:
+		switch {
+		case p.curTok.Literal == "_":
+			return &ast.WildcardPattern{Token: p.curTok}
+		case p.peekTokenIs(token.LBRACE):
+			return p.parseStructPattern()
+		case p.peekTokenIs(token.DOT):
+			return p.parseQualifiedPattern()
+		}
+		return &ast.BindingPattern{Token: p.curTok, Name: &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}}
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE, token.NULL, token.MINUS:
+		return p.parseValuePattern()
+	}
+	p.addError("unexpected %q in pattern", p.curTok.Literal)
+	return nil
+}
+
+// parseValuePattern parses a literal, a qualified name such as Color.Red or
+// a range of numbers.
+func (p *Parser) parseValuePattern() ast.Pattern {
+	tok := p.curTok
+	value := p.parsePatternValue()
+	if value == nil {
+		return nil
+	}
+	return p.parseRangePattern(tok, value)
+}
+
+// parseQualifiedPattern parses a pattern starting with a qualified name:
+// a variant of an algebraic enum with patterns for its fields, such as
+// Result.Ok(value), or else a value or range pattern.
+func (p *Parser) parseQualifiedPattern() ast.Pattern {
+	tok := p.curTok
+	var name ast.Expression = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+	for p.peekTokenIs(token.DOT) {
+		p.nextToken()
+		idx := &ast.IndexExpression{Token: p.curTok, Left: name}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		idx.Index = &ast.StringLiteral{Token: p.curTok, Value: p.curTok.Literal}
+		name = idx
+	}
+	if !p.peekTokenIs(token.LPAREN) {
+		return p.parseRangePattern(tok, name)
+	}
+
+	p.nextToken()
+	pat := &ast.VariantPattern{Token: tok, Variant: name}
+	for !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		arg := p.parsePattern()
+		if arg == nil {
+			return nil
+		}
+		pat.Arguments = append(pat.Arguments, arg)
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	return pat
+}
+
+// parseRangePattern parses the rest of a range pattern whose low bound is
+// value, or makes a value pattern of value when no range follows.
+func (p *Parser) parseRangePattern(tok token.Token, value ast.Expression) ast.Pattern {
+	if !p.peekTokenIs(token.RANGE) && !p.peekTokenIs(token.RANGE_INCLUSIVE) {
+		return &ast.ValuePattern{Token: tok, Value: value}
+	}
+
+	p.nextToken()
+	rng := &ast.RangePattern{Token: tok, Low: value, Inclusive: p.curTokenIs(token.RANGE_INCLUSIVE)}
+	p.nextToken()
+	if rng.High = p.parsePatternValue(); rng.High == nil {
+		return nil
+	}
+	return rng
+}
+
+func (p *Parser) parsePatternValue() ast.Expression {
+	value := p.parseExpression(PREFIX)
+	switch v := value.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean, *ast.Null:
+		return value
+	case *ast.PrefixExpression:
+		switch v.Right.(type) {
+		case *ast.IntegerLiteral, *ast.FloatLiteral:
+			if v.Operator == "-" {
+				return value
+			}
+		}
+	case *ast.IndexExpression:
+		if isQualifiedName(v) {
+			return value
+		}
+	case nil:
+		return nil
+	}
+	p.addError("%s is not a valid pattern", value)
+	return nil
+}
+
+// isQualifiedName reports whether e is a chain of names like Color.Red.
+func isQualifiedName(e ast.Expression) bool {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return true
+	case *ast.IndexExpression:
+		_, ok := e.Index.(*ast.StringLiteral)
+		return ok && isQualifiedName(e.Left)
+	}
+	return false
+}
+
+// parseArrayPattern parses [p1, p2, ...rest].
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pat := &ast.ArrayPattern{Token: p.curTok}
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		if p.curTokenIs(token.ELLIPSIS) {
+			pat.HasRest = true
+			if p.peekTokenIs(token.IDENT) {
+				p.nextToken()
+				pat.Rest = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+			}
+			if !p.peekTokenIs(token.RBRACKET) {
+				p.addError("the rest element must come last")
+				return nil
+			}
+			break
+		}
+
+		el := p.parsePattern()
+		if el == nil {
+			return nil
+		}
+		pat.Elements = append(pat.Elements, el)
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	return pat
+}
+
+// parseHashPattern parses { "key": pattern, ... }. Keys are string or
+// integer literals.
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pat := &ast.HashPattern{Token: p.curTok}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		if !p.curTokenIs(token.STRING) && !p.curTokenIs(token.INT) {
+			p.addError("hash pattern keys must be string or integer literals, got %q", p.curTok.Literal)
+			return nil
+		}
+		key := p.parseExpression(PREFIX)
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		value := p.parsePattern()
+		if value == nil {
+			return nil
+		}
+		pat.Entries = append(pat.Entries, &ast.HashPatternEntry{Key: key, Value: value})
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	return pat
+}
+
+// parseStructPattern parses Name { field, field: pattern, ... }.
+func (p *Parser) parseStructPattern() ast.Pattern {
+	pat := &ast.StructPattern{Token: p.curTok, Name: &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}}
+	p.nextToken()
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		field := &ast.FieldPattern{Name: &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}}
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			if field.Pattern = p.parsePattern(); field.Pattern == nil {
+				return nil
+			}
+		} else {
+			field.Pattern = &ast.BindingPattern{Token: p.curTok, Name: field.Name}
+		}
+		pat.Fields = append(pat.Fields, field)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	return pat
+}
+
+// parseDeclaredName parses a name being declared by let or for-in, or a
+// destructuring pattern declaring several, whose whole value is kept under
+// the hidden name given.
+func (p *Parser) parseDeclaredName(hidden string) *ast.Identifier {
+	switch p.curTok.Type {
+	case token.IDENT:
+		return &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+	case token.LBRACKET, token.LBRACE:
+		tok := p.curTok
+		pattern := p.parseDestructuringPattern()
+		if pattern == nil {
+			return nil
+		}
+		return &ast.Identifier{Token: tok, Value: hidden, Pattern: pattern}
+	}
+	p.addError("expected a name or a destructuring pattern, got %q", p.curTok.Literal)
+	return nil
+}
+
+// parseDestructuringPattern parses the target of a destructuring
+// declaration or assignment: a name, _, [a, b = 1, ...rest] or
+// { name, age: years = 0 }.
+func (p *Parser) parseDestructuringPattern() ast.Pattern {
+	switch p.curTok.Type {
+	case token.IDENT:
+		if p.curTok.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.curTok}
+		}
+		return &ast.BindingPattern{Token: p.curTok, Name: &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}}
+	case token.LBRACKET:
+		return p.parseArrayDestructuring()
+	case token.LBRACE:
+		return p.parseObjectDestructuring()
+	}
+	p.addError("unexpected %q in destructuring pattern", p.curTok.Literal)
+	return nil
+}
+
+// parseDestructuringDefault parses the optional `= default` after a nested
+// pattern.
+func (p *Parser) parseDestructuringDefault(pattern ast.Pattern) ast.Pattern {
+	if pattern == nil || !p.peekTokenIs(token.ASSIGN) {
+		return pattern
+	}
+	p.nextToken()
+	def := &ast.DefaultPattern{Token: p.curTok, Target: pattern}
+	p.nextToken()
+	if def.Default = p.parseExpression(LOWEST); def.Default == nil {
+		return nil
+	}
+	return def
+}
+
+func (p *Parser) parseArrayDestructuring() ast.Pattern {
+	pat := &ast.ArrayPattern{Token: p.curTok}
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pat.HasRest = true
+			pat.Rest = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+			if !p.peekTokenIs(token.RBRACKET) {
+				p.addError("the rest element must come last")
+				return nil
+			}
+			break
+		}
+
+		el := p.parseDestructuringDefault(p.parseDestructuringPattern())
+		if el == nil {
+			return nil
+		}
+		pat.Elements = append(pat.Elements, el)
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	return pat
+}
+
+// parseObjectDestructuring parses { name, age: years = 0, "x-id": id }.
+// Quoted keys need a pattern after them.
+func (p *Parser) parseObjectDestructuring() ast.Pattern {
+	pat := &ast.ObjectPattern{Token: p.curTok}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		if !p.curTokenIs(token.IDENT) && !p.curTokenIs(token.STRING) {
+			p.addError("expected a field name in destructuring pattern, got %q", p.curTok.Literal)
+			return nil
+		}
+		prop := &ast.PropertyPattern{Key: &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}}
+		switch {
+		case p.peekTokenIs(token.COLON):
+			p.nextToken()
+			p.nextToken()
+			prop.Value = p.parseDestructuringPattern()
+		case p.curTokenIs(token.STRING):
+			p.addError("quoted key %q needs a pattern to bind it to", p.curTok.Literal)
+			return nil
+		default:
+			prop.Value = &ast.BindingPattern{Token: p.curTok, Name: prop.Key}
+		}
+		if prop.Value = p.parseDestructuringDefault(prop.Value); prop.Value == nil {
+			return nil
+		}
+
+		pat.Properties = append(pat.Properties, prop)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	return pat
+}
+
+// tokenAfterGroup returns the token after the bracket closing the one at
+// the current token.
+func (p *Parser) tokenAfterGroup() token.Token {
+	return p.tokenAfter(p.peekTok, p.peekTok2)
+}
+
+// startsDestructuring reports whether the peek token begins a destructuring
+// assignment on a line of its own, as in
+//
+//	let { name } = user
+//	[a, b] = [b, a]
+//
+// so that the expression before it ends there instead of being indexed.
+func (p *Parser) startsDestructuring() bool {
+	return p.peekTokenIs(token.LBRACKET) && p.peekTok.Line > p.curTok.Line &&
+		p.tokenAfter(p.peekTok2).Type == token.ASSIGN
+}
+
+// tokenAfter returns the token after the bracket closing the one just
+// before ahead, the tokens the parser has already read past it, reading on
+// from a copy of the lexer.
+func (p *Parser) tokenAfter(ahead ...token.Token) token.Token {
+	lx := *p.l
+	next := func() token.Token {
+		if len(ahead) > 0 {
+			tok := ahead[0]
+			ahead = ahead[1:]
+			return tok
+		}
+		return lx.NextToken()
+	}
+
+	depth := 1
+	for {
+		tok := next()
+		switch tok.Type {
+		case token.LPAREN, token.LBRACKET, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACKET, token.RBRACE:
+			if depth--; depth == 0 {
+				return next()
+			}
+		case token.EOF:
+			return tok
+		}
+	}
+}
+
+// parseDestructuringAssignment parses [a, b] = [b, a] and
+// { name, age } = user, which assign to variables already declared.
+func (p *Parser) parseDestructuringAssignment() *ast.AssignmentStatement {
+	stmt := &ast.AssignmentStatement{Token: p.curTok}
+	target := p.parseDeclaredName("$$destructured$$")
+	if target == nil {
+		return nil
+	}
+	stmt.Left = target
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
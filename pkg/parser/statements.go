@@ -46,6 +46,8 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseServiceStatement()
 	case token.THROW:
 		return p.parseThrowStatement()
+	case token.DEFER:
+		return p.parseDeferStatement()
 	case token.SWITCH:
 		return p.parseSwitchStatement()
 	case token.IMPORT:
@@ -54,7 +56,18 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseExportStatement()
 	case token.ENUM:
 		return p.parseEnumStatement()
+	case token.LBRACKET, token.LBRACE:
+		if p.tokenAfterGroup().Type == token.ASSIGN {
+			return p.parseDestructuringAssignment()
+		}
+		return p.parseExpressionStatement()
 	default:
+		if p.curTok.Literal == "select" && p.peekTokenIs(token.LBRACE) {
+			return p.parseSelectStatement()
+		}
+		if p.curTok.Literal == "group" && (p.peekTokenIs(token.LBRACE) || p.peekTokenIs(token.IDENT) && p.peekToken2Is(token.LBRACE)) {
+			return p.parseGroupStatement()
+		}
 		if p.isAssignmentStatement() {
 			return p.parseFieldAssignmentStatement()
 		}
@@ -68,17 +81,16 @@ func (p *Parser) parseStatement() ast.Statement {
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curTok}
 
-	if !p.expectPeek(token.IDENT) {
+	p.nextToken()
+	if stmt.Name = p.parseDeclaredName("$$destructured$$"); stmt.Name == nil {
 		return nil
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
-
 	// Optional type annotation: `let x: int = 5;`
 	if p.peekTokenIs(token.COLON) {
 		p.nextToken() // consume COLON
 		p.nextToken() // move to type token
-		stmt.Type = &ast.TypeExpression{Token: p.curTok, Value: p.curTok.Literal}
+		stmt.Type = p.parseTypeExpression()
 	}
 
 	if !p.expectPeek(token.ASSIGN) {
@@ -109,7 +121,7 @@ func (p *Parser) parseConstStatement() *ast.ConstStatement {
 	if p.peekTokenIs(token.COLON) {
 		p.nextToken() // consume COLON
 		p.nextToken() // move to type token
-		stmt.Type = &ast.TypeExpression{Token: p.curTok, Value: p.curTok.Literal}
+		stmt.Type = p.parseTypeExpression()
 	}
 
 	if !p.expectPeek(token.ASSIGN) {
@@ -187,11 +199,14 @@ func (p *Parser) parseForStatement() ast.Statement {
 
 	if p.peekTok.Type == token.IDENT {
 		p.nextToken()
-		if p.peekTok.Type == token.IN {
+		if p.peekTok.Type == token.IN || p.peekTok.Type == token.COMMA {
 
 			return p.parseForEachStatement()
 		}
 
+	} else if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		return p.parseForEachStatement()
 	}
 
 	return p.parseTraditionalForStatement()
@@ -253,7 +268,21 @@ func (p *Parser) parseTraditionalForStatement() *ast.ForStatement {
 func (p *Parser) parseForEachStatement() *ast.ForEachStatement {
 	stmt := &ast.ForEachStatement{Token: p.curTok}
 
-	stmt.Variable = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+	if stmt.Variable = p.parseDeclaredName("$$destructured$$"); stmt.Variable == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.COMMA) {
+		if stmt.Variable.Pattern != nil {
+			p.addError("the key of a for-in loop cannot be destructured")
+			return nil
+		}
+		p.nextToken()
+		p.nextToken()
+		stmt.Key = stmt.Variable
+		if stmt.Variable = p.parseDeclaredName("$$destructured$$"); stmt.Variable == nil {
+			return nil
+		}
+	}
 
 	if !p.expectPeek(token.IN) {
 		return nil
@@ -424,7 +453,7 @@ func (p *Parser) parseAsyncFunctionStatement() ast.Statement {
 		return nil
 	}
 
-	stmt.Body = p.parseBlockStatement()
+	stmt.Body = p.parseAsyncBody()
 
 	return stmt
 }
@@ -537,15 +566,24 @@ func (p *Parser) parseTryStatement() *ast.TryStatement {
 
 	stmt.TryBlock = p.parseBlockStatement()
 
-	if p.peekTokenIs(token.CATCH) {
+	for p.peekTokenIs(token.CATCH) {
 		p.nextToken()
+		if n := len(stmt.Catches); n > 0 && (stmt.Catches[n-1].Param == nil || stmt.Catches[n-1].Param.Type == nil) {
+			p.addError("catch clause after one that catches every exception")
+		}
+		clause := &ast.CatchClause{Token: p.curTok}
 
 		if p.peekTokenIs(token.LPAREN) {
 			p.nextToken()
 			if !p.expectPeek(token.IDENT) {
 				return nil
 			}
-			stmt.CatchParam = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+			clause.Param = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+			if p.peekTokenIs(token.COLON) {
+				p.nextToken() // consume COLON
+				p.nextToken() // move to type token
+				clause.Param.Type = p.parseTypeExpression()
+			}
 			if !p.expectPeek(token.RPAREN) {
 				return nil
 			}
@@ -555,7 +593,16 @@ func (p *Parser) parseTryStatement() *ast.TryStatement {
 			return nil
 		}
 
-		stmt.CatchBlock = p.parseBlockStatement()
+		clause.Block = p.parseBlockStatement()
+		stmt.Catches = append(stmt.Catches, clause)
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.FinallyBlock = p.parseBlockStatement()
 	}
 
 	return stmt
@@ -574,6 +621,31 @@ func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
 	return stmt
 }
 
+func (p *Parser) parseDeferStatement() *ast.DeferStatement {
+	stmt := &ast.DeferStatement{Token: p.curTok}
+
+	if len(p.yields) == 0 {
+		p.addError("defer outside of a function")
+	}
+
+	p.nextToken()
+	expr := p.parseExpression(LOWEST)
+	call, ok := expr.(*ast.CallExpression)
+	if !ok {
+		if expr != nil {
+			p.addError("defer requires a function call, got %s", expr.String())
+		}
+		return nil
+	}
+	stmt.Call = call
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseImportStatement() *ast.ImportStatement {
 	stmt := &ast.ImportStatement{Token: p.curTok}
 
@@ -946,6 +1018,105 @@ func (p *Parser) parseCaseClause() *ast.CaseClause {
 	return clause
 }
 
+// parseSelectStatement parses select { case ...: ... default: ... }. select
+// is not a keyword, so that variables named select keep working: it only
+// starts a statement when a block follows it.
+func (p *Parser) parseSelectStatement() *ast.SelectStatement {
+	stmt := &ast.SelectStatement{Token: p.curTok}
+	p.nextToken()
+
+	for !p.peekTokenIs(token.RBRACE) && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+
+		switch p.curTok.Type {
+		case token.CASE:
+			clause := p.parseSelectCase()
+			if clause == nil {
+				return nil
+			}
+			stmt.Cases = append(stmt.Cases, clause)
+		case token.DEFAULT:
+			if stmt.DefaultCase != nil {
+				p.addError("multiple default clauses in select statement")
+				return nil
+			}
+			stmt.DefaultCase = p.parseDefaultClause()
+		default:
+			p.addError("expected 'case' or 'default' in select body, got %q", p.curTok.Literal)
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return stmt
+}
+
+// parseGroupStatement parses group { ... } or group name { ... }. Like
+// select, group is not a keyword.
+func (p *Parser) parseGroupStatement() *ast.GroupStatement {
+	stmt := &ast.GroupStatement{Token: p.curTok}
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		stmt.Name = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+	if stmt.Body == nil {
+		return nil
+	}
+	return stmt
+}
+
+// parseSelectCase parses a case of a select statement: a receive, <-ch or
+// name = <-ch, a send, ch <- value, or timeout(ms).
+func (p *Parser) parseSelectCase() *ast.SelectCase {
+	clause := &ast.SelectCase{Token: p.curTok}
+	p.nextToken()
+
+	if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.ASSIGN) {
+		clause.Name = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+		p.nextToken()
+		p.nextToken()
+	}
+
+	comm := p.parseExpression(LOWEST)
+	switch e := comm.(type) {
+	case *ast.PrefixExpression:
+		if e.Operator == "<-" {
+			clause.Channel = e.Right
+		}
+	case *ast.InfixExpression:
+		if e.Operator == "<-" && clause.Name == nil {
+			clause.Channel, clause.Value = e.Left, e.Right
+		}
+	case *ast.CallExpression:
+		if fn, ok := e.Function.(*ast.Identifier); ok && fn.Value == "timeout" && len(e.Arguments) == 1 && clause.Name == nil {
+			clause.Timeout = e.Arguments[0]
+		}
+	case nil:
+		return nil
+	}
+	if clause.Channel == nil && clause.Timeout == nil {
+		p.addError("select case must receive from a channel, send to one or be timeout(ms), got %s", comm)
+		return nil
+	}
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+	for !p.peekTokenIs(token.CASE) && !p.peekTokenIs(token.DEFAULT) && !p.peekTokenIs(token.RBRACE) && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+		if stmt := p.parseStatement(); stmt != nil {
+			clause.Statements = append(clause.Statements, stmt)
+		}
+	}
+	return clause
+}
+
 func (p *Parser) parseDefaultClause() *ast.DefaultClause {
 	clause := &ast.DefaultClause{Token: p.curTok}
 
@@ -963,7 +1134,7 @@ func (p *Parser) parseDefaultClause() *ast.DefaultClause {
 	return clause
 }
 
-// parseEnumStatement parses: enum Name { Variant1, Variant2, ... }
+// parseEnumStatement parses: enum Name { Variant1, Variant2(field, field: type), ... }
 func (p *Parser) parseEnumStatement() *ast.EnumStatement {
 	stmt := &ast.EnumStatement{Token: p.curTok}
 
@@ -987,6 +1158,15 @@ func (p *Parser) parseEnumStatement() *ast.EnumStatement {
 		variant := &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
 		stmt.Values = append(stmt.Values, variant)
 
+		var fields []*ast.Identifier
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			if fields = p.parseVariantFields(stmt.Name, variant); fields == nil {
+				return nil
+			}
+		}
+		stmt.Fields = append(stmt.Fields, fields)
+
 		if p.peekTokenIs(token.COMMA) {
 			p.nextToken() // consume comma
 		}
@@ -1002,3 +1182,43 @@ func (p *Parser) parseEnumStatement() *ast.EnumStatement {
 
 	return stmt
 }
+
+// parseVariantFields parses the fields of an enum variant, from the
+// opening parenthesis on: (name, name: type).
+func (p *Parser) parseVariantFields(enum, variant *ast.Identifier) []*ast.Identifier {
+	if p.peekTokenIs(token.RPAREN) {
+		p.addError("variant %s of %s needs a field between its parentheses", variant.Value, enum.Value)
+		return nil
+	}
+
+	var fields []*ast.Identifier
+	seen := map[string]bool{}
+	for {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		field := &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+		if seen[field.Value] {
+			p.addError("variant %s of %s has two fields named %s", variant.Value, enum.Value, field.Value)
+			return nil
+		}
+		seen[field.Value] = true
+
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			field.Type = p.parseTypeExpression()
+		}
+		fields = append(fields, field)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return fields
+}
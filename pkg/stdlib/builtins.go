@@ -2,10 +2,15 @@ package stdlib
 
 import (
 	"fmt"
+	"io"
 	"jabline/pkg/object"
+	"os"
 	"strconv"
 )
 
+// Registry holds the global builtins. Compiled bytecode refers to them by
+// index, so the list is append-only: init adds the native modules and each
+// later group of builtins after the existing ones, never in between.
 var Registry = []struct {
 	Name   string
 	Object object.Object
@@ -18,22 +23,22 @@ var Registry = []struct {
 	{"parseFloat", &object.Builtin{Fn: parseFloatFunc}},
 
 	// Numeric Type Constructors
-	{"int8", &object.Builtin{Fn: int8Func}},
-	{"int16", &object.Builtin{Fn: int16Func}},
-	{"int32", &object.Builtin{Fn: int32Func}},
-	{"int64", &object.Builtin{Fn: int64Func}},
-	{"uint8", &object.Builtin{Fn: uint8Func}},
-	{"uint16", &object.Builtin{Fn: uint16Func}},
-	{"uint32", &object.Builtin{Fn: uint32Func}},
-	{"uint64", &object.Builtin{Fn: uint64Func}},
-	{"float32", &object.Builtin{Fn: float32Func}},
-	{"float64", &object.Builtin{Fn: float64Func}},
+	{"int8", &object.Builtin{Fn: convertFunc("int8", object.INT8_OBJ)}},
+	{"int16", &object.Builtin{Fn: convertFunc("int16", object.INT16_OBJ)}},
+	{"int32", &object.Builtin{Fn: convertFunc("int32", object.INT32_OBJ)}},
+	{"int64", &object.Builtin{Fn: convertFunc("int64", object.INT64_OBJ)}},
+	{"uint8", &object.Builtin{Fn: convertFunc("uint8", object.UINT8_OBJ)}},
+	{"uint16", &object.Builtin{Fn: convertFunc("uint16", object.UINT16_OBJ)}},
+	{"uint32", &object.Builtin{Fn: convertFunc("uint32", object.UINT32_OBJ)}},
+	{"uint64", &object.Builtin{Fn: convertFunc("uint64", object.UINT64_OBJ)}},
+	{"float32", &object.Builtin{Fn: convertFunc("float32", object.FLOAT32_OBJ)}},
+	{"float64", &object.Builtin{Fn: convertFunc("float64", object.FLOAT64_OBJ)}},
 
 	{"echo", &object.Builtin{Fn: printlnFunc}},
-	{"set", &object.Builtin{Fn: setFunc}}, // Add set
+	{"set", &object.Builtin{Fn: setFunc, Writes: true}}, // Add set
 
 	{"push", &object.Builtin{Fn: pushFunc}},
-	{"pop", &object.Builtin{Fn: popFunc}},
+	{"pop", &object.Builtin{Fn: popFunc, Writes: true}},
 	{"rest", &object.Builtin{Fn: restFunc}},
 	{"first", &object.Builtin{Fn: firstFunc}},
 	{"last", &object.Builtin{Fn: lastFunc}},
@@ -44,15 +49,7 @@ var Registry = []struct {
 }
 
 func errorFunc(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
-	}
-	switch arg := args[0].(type) {
-	case *object.String:
-		return &object.Error{Message: arg.Value}
-	default:
-		return &object.Error{Message: arg.Inspect()}
-	}
+	return errorConstructor("")(args...)
 }
 
 func isErrorFunc(args ...object.Object) object.Object {
@@ -116,13 +113,23 @@ func init() {
 		}
 	}
 
-	// Register Global Modules (like fs, math, os, etc.) in the global Registry
-	for name, obj := range GlobalModules {
+	// Register Global Modules (like fs, math, os, etc.) in the global Registry.
+	// Builtin indices are baked into compiled bytecode, so the order must not
+	// depend on map iteration.
+	for _, modName := range nativeModules {
+		obj, ok := GlobalModules[modName[1:]]
+		if !ok {
+			continue
+		}
 		Registry = append(Registry, struct {
 			Name   string
 			Object object.Object
-		}{name, obj})
+		}{modName[1:], obj})
 	}
+
+	Registry = append(Registry, GeneratorBuiltins...)
+	Registry = append(Registry, ErrorBuiltins...)
+	Registry = append(Registry, ChannelBuiltins...)
 }
 
 func lenFunc(args ...object.Object) object.Object {
@@ -136,6 +143,12 @@ func lenFunc(args ...object.Object) object.Object {
 		return &object.Integer{Value: int64(len(arg.Value))}
 	case *object.Hash:
 		return &object.Integer{Value: int64(len(arg.Pairs))}
+	case *object.Channel:
+		return &object.Integer{Value: int64(len(arg.Value))}
+	case *object.RemoteChannel:
+		return &object.Integer{Value: 0}
+	case *object.SharedMap:
+		return &object.Integer{Value: int64(arg.Len())}
 	default:
 		return newError("argument to `len` not supported, got %T", args[0])
 	}
@@ -165,7 +178,7 @@ func parseIntFunc(args ...object.Object) object.Object {
 	}
 	val, err := strconv.ParseInt(str.Value, 10, 64)
 	if err != nil {
-		return newError("could not parse int: %s", err)
+		return newValueError("could not parse int: %s", err)
 	}
 	return &object.Integer{Value: val}
 }
@@ -180,7 +193,7 @@ func parseFloatFunc(args ...object.Object) object.Object {
 	}
 	val, err := strconv.ParseFloat(str.Value, 64)
 	if err != nil {
-		return newError("could not parse float: %s", err)
+		return newValueError("could not parse float: %s", err)
 	}
 	return &object.Float{Value: val}
 }
@@ -196,14 +209,20 @@ func printFunc(args ...object.Object) object.Object {
 }
 
 func printlnFunc(args ...object.Object) object.Object {
+	Echo(os.Stdout, args...)
+	return &object.Null{}
+}
+
+// Echo writes the arguments the way the echo builtin does: separated by
+// spaces and followed by a newline.
+func Echo(w io.Writer, args ...object.Object) {
 	for i, arg := range args {
 		if i > 0 {
-			fmt.Print(" ")
+			fmt.Fprint(w, " ")
 		}
-		fmt.Print(arg.Inspect())
+		fmt.Fprint(w, arg.Inspect())
 	}
-	fmt.Println()
-	return &object.Null{}
+	fmt.Fprintln(w)
 }
 
 func pushFunc(args ...object.Object) object.Object {
@@ -294,143 +313,18 @@ func newError(format string, a ...interface{}) *object.Error {
 
 // --- Numeric Type Constructors ---
 
-func int8Func(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong args")
-	}
-	switch arg := args[0].(type) {
-	case *object.Integer:
-		return &object.Int8{Value: int8(arg.Value)}
-	case *object.Float:
-		return &object.Int8{Value: int8(arg.Value)}
-	default:
-		return newError("argument to int8 not supported, got %s", arg.Type())
-	}
-}
-
-func int16Func(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong args")
-	}
-	switch arg := args[0].(type) {
-	case *object.Integer:
-		return &object.Int16{Value: int16(arg.Value)}
-	case *object.Float:
-		return &object.Int16{Value: int16(arg.Value)}
-	default:
-		return newError("argument to int16 not supported, got %s", arg.Type())
-	}
-}
-
-func int32Func(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong args")
-	}
-	switch arg := args[0].(type) {
-	case *object.Integer:
-		return &object.Int32{Value: int32(arg.Value)}
-	case *object.Float:
-		return &object.Int32{Value: int32(arg.Value)}
-	default:
-		return newError("argument to int32 not supported, got %s", arg.Type())
-	}
-}
-
-func int64Func(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong args")
-	}
-	switch arg := args[0].(type) {
-	case *object.Integer:
-		return &object.Int64{Value: int64(arg.Value)}
-	case *object.Float:
-		return &object.Int64{Value: int64(arg.Value)}
-	default:
-		return newError("argument to int64 not supported, got %s", arg.Type())
-	}
-}
-
-func uint8Func(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong args")
-	}
-	switch arg := args[0].(type) {
-	case *object.Integer:
-		return &object.UInt8{Value: uint8(arg.Value)}
-	case *object.Float:
-		return &object.UInt8{Value: uint8(arg.Value)}
-	default:
-		return newError("argument to uint8 not supported, got %s", arg.Type())
-	}
-}
-
-func uint16Func(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong args")
-	}
-	switch arg := args[0].(type) {
-	case *object.Integer:
-		return &object.UInt16{Value: uint16(arg.Value)}
-	case *object.Float:
-		return &object.UInt16{Value: uint16(arg.Value)}
-	default:
-		return newError("argument to uint16 not supported, got %s", arg.Type())
-	}
-}
-
-func uint32Func(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong args")
-	}
-	switch arg := args[0].(type) {
-	case *object.Integer:
-		return &object.UInt32{Value: uint32(arg.Value)}
-	case *object.Float:
-		return &object.UInt32{Value: uint32(arg.Value)}
-	default:
-		return newError("argument to uint32 not supported, got %s", arg.Type())
-	}
-}
-
-func uint64Func(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong args")
-	}
-	switch arg := args[0].(type) {
-	case *object.Integer:
-		return &object.UInt64{Value: uint64(arg.Value)}
-	case *object.Float:
-		return &object.UInt64{Value: uint64(arg.Value)}
-	default:
-		return newError("argument to uint64 not supported, got %s", arg.Type())
-	}
-}
-
-func float32Func(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong args")
-	}
-	switch arg := args[0].(type) {
-	case *object.Integer:
-		return &object.Float32{Value: float32(arg.Value)}
-	case *object.Float:
-		return &object.Float32{Value: float32(arg.Value)}
-	default:
-		return newError("argument to float32 not supported, got %s", arg.Type())
-	}
-}
-
-func float64Func(args ...object.Object) object.Object {
-	if len(args) != 1 {
-		return newError("wrong args")
-	}
-	switch arg := args[0].(type) {
-	case *object.Integer:
-		return &object.Float64{Value: float64(arg.Value)}
-	case *object.Float:
-		return &object.Float64{Value: float64(arg.Value)}
-	default:
-		return newError("argument to float64 not supported, got %s", arg.Type())
+// convertFunc returns the builtin converting any number to type t, with
+// the truncating semantics of object.ConvertNumber.
+func convertFunc(name string, t object.ObjectType) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments. got=%d, want=1", len(args))
+		}
+		result, ok := object.ConvertNumber(args[0], t)
+		if !ok {
+			return newError("argument to %s not supported, got %s", name, args[0].Type())
+		}
+		return result
 	}
 }
 
@@ -0,0 +1,26 @@
+package stdlib
+
+import "testing"
+
+// TestRegistryOrder checks that the global modules take the same indices
+// in every process, as the bytecode built by one refers to them by index.
+func TestRegistryOrder(t *testing.T) {
+	want := []string{"strings", "math", "json", "os", "fs", "http"}
+	index := make(map[string]int)
+	for i, b := range Registry {
+		index[b.Name] = i
+	}
+	first, ok := index[want[0]]
+	if !ok {
+		t.Fatalf("module %s not registered", want[0])
+	}
+	for i, name := range want {
+		got, ok := index[name]
+		if !ok {
+			t.Fatalf("module %s not registered", name)
+		}
+		if got != first+i {
+			t.Errorf("module %s at index %d, want %d", name, got, first+i)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package stdlib
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"jabline/pkg/object"
 	"net"
 	"strings"
@@ -13,18 +15,51 @@ var ConcurrencyBuiltins = []struct {
 	Object object.Object
 }{
 	{"make_chan", &object.Builtin{Fn: makeChan}},
-	{"send", &object.Builtin{Fn: sendChan}},
-	{"recv", &object.Builtin{Fn: recvChan}},
+	{"send", &object.Builtin{Fn: sendChan, FnContext: sendChanContext}},
+	{"recv", &object.Builtin{Fn: recvChan, FnContext: recvChanContext}},
 	{"connect", &object.Builtin{Fn: connectFunc}},
 	{"listen", &object.Builtin{Fn: listenFunc}},
 }
 
+// ChannelBuiltins close and measure channels.
+var ChannelBuiltins = []struct {
+	Name   string
+	Object object.Object
+}{
+	{"close", &object.Builtin{Fn: closeChan}},
+	{"cap", &object.Builtin{Fn: capFunc}},
+}
+
+// defaultChanCapacity is the buffer of a channel made without a capacity.
+const defaultChanCapacity = 10
+
+// makeChan makes a channel buffering the given number of values, 0 for an
+// unbuffered channel whose sends wait for a receiver.
 func makeChan(args ...object.Object) object.Object {
-	ch := make(chan object.Object, 10) // Increased buffer size to 10
-	return &object.Channel{Value: ch}
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+	capacity := int64(defaultChanCapacity)
+	if len(args) == 1 {
+		n, ok := args[0].(*object.Integer)
+		if !ok {
+			return newError("argument to `make_chan` must be INTEGER, got %s", args[0].Type())
+		}
+		if n.Value < 0 {
+			return newValueError("negative channel capacity: %d", n.Value)
+		}
+		capacity = n.Value
+	}
+	return &object.Channel{Value: make(chan object.Object, capacity)}
 }
 
 func sendChan(args ...object.Object) object.Object {
+	return sendChanContext(context.Background(), args...)
+}
+
+// sendChanContext sends like sendChan, giving up on a local channel once
+// ctx is done.
+func sendChanContext(ctx context.Context, args ...object.Object) object.Object {
 	if len(args) != 2 {
 		return newError("wrong args")
 	}
@@ -32,11 +67,17 @@ func sendChan(args ...object.Object) object.Object {
 
 	switch ch := args[0].(type) {
 	case *object.Channel:
-		ch.Value <- val
+		if err := ch.SendContext(ctx, val); errors.Is(err, object.ErrSendOnClosed) {
+			return newValueError("%s", err)
+		} else if err != nil {
+			return newError("send interrupted: %s", err)
+		}
 		return val
 	case *object.RemoteChannel:
-		if err := ch.Send(val); err != nil {
-			return newError("remote send failed: %s", err)
+		if err := ch.Send(val); errors.Is(err, object.ErrSendOnClosed) {
+			return newValueError("%s", err)
+		} else if err != nil {
+			return newIOError(err, "remote send failed: %s", err)
 		}
 		return val
 	default:
@@ -45,21 +86,33 @@ func sendChan(args ...object.Object) object.Object {
 }
 
 func recvChan(args ...object.Object) object.Object {
+	return recvChanContext(context.Background(), args...)
+}
+
+// recvChanContext receives like recvChan, giving up on a local channel
+// once ctx is done.
+func recvChanContext(ctx context.Context, args ...object.Object) object.Object {
 	if len(args) != 1 {
 		return newError("wrong args")
 	}
 
 	switch ch := args[0].(type) {
 	case *object.Channel:
-		val, ok := <-ch.Value
+		val, ok, err := ch.ReceiveContext(ctx)
+		if err != nil {
+			return newError("recv interrupted: %s", err)
+		}
 		if !ok {
 			return &object.Null{}
 		}
 		return val
 	case *object.RemoteChannel:
 		val, err := ch.Receive()
+		if errors.Is(err, io.EOF) {
+			return &object.Null{}
+		}
 		if err != nil {
-			return newError("remote recv failed: %s", err)
+			return newIOError(err, "remote recv failed: %s", err)
 		}
 		return val
 	default:
@@ -67,6 +120,46 @@ func recvChan(args ...object.Object) object.Object {
 	}
 }
 
+// closeChan closes a channel: receives drain what it buffers, then get
+// null. Closing a remote channel tells the peer no more values follow.
+func closeChan(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	var err error
+	switch ch := args[0].(type) {
+	case *object.Channel:
+		err = ch.Close()
+	case *object.RemoteChannel:
+		if err = ch.Close(); err != nil && !errors.Is(err, object.ErrCloseOfClosed) {
+			return newIOError(err, "remote close failed: %s", err)
+		}
+	default:
+		return newError("argument to `close` must be CHANNEL, got %s", args[0].Type())
+	}
+	if err != nil {
+		return newValueError("%s", err)
+	}
+	return &object.Null{}
+}
+
+// capFunc returns the number of values a channel buffers. Remote channels
+// do not buffer.
+func capFunc(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	switch ch := args[0].(type) {
+	case *object.Channel:
+		return &object.Integer{Value: int64(cap(ch.Value))}
+	case *object.RemoteChannel:
+		return &object.Integer{Value: 0}
+	default:
+		return newError("argument to `cap` must be CHANNEL, got %s", args[0].Type())
+	}
+}
+
 func connectFunc(args ...object.Object) object.Object {
 	if len(args) != 1 {
 		return newError("connect expects (url)")
@@ -85,14 +178,10 @@ func connectFunc(args ...object.Object) object.Object {
 
 	conn, err := net.Dial(proto, addr)
 	if err != nil {
-		return newError("connect failed: %s", err)
+		return newIOError(err, "connect failed: %s", err)
 	}
 
-	return &object.RemoteChannel{
-		Conn:    conn,
-		Encoder: json.NewEncoder(conn),
-		Decoder: json.NewDecoder(conn),
-	}
+	return object.NewRemoteChannel(conn)
 }
 
 func listenFunc(args ...object.Object) object.Object {
@@ -107,25 +196,27 @@ func listenFunc(args ...object.Object) object.Object {
 	addr := fmt.Sprintf(":%d", portObj.Value)
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		return newError("listen failed: %s", err)
+		return newIOError(err, "listen failed: %s", err)
 	}
 
-	clientChan := make(chan object.Object)
+	clients := &object.Channel{Value: make(chan object.Object)}
 
+	// Closing the channel of clients stops the listener.
 	go func() {
-		defer close(clientChan)
+		defer clients.Close()
+		defer listener.Close()
 		for {
 			conn, err := listener.Accept()
 			if err != nil {
 				return
 			}
-			clientChan <- &object.RemoteChannel{
-				Conn:    conn,
-				Encoder: json.NewEncoder(conn),
-				Decoder: json.NewDecoder(conn),
+			client := object.NewRemoteChannel(conn)
+			if err := clients.Send(client); err != nil {
+				conn.Close()
+				return
 			}
 		}
 	}()
 
-	return &object.Channel{Value: clientChan}
+	return clients
 }
@@ -63,7 +63,7 @@ func cryptoBase64Decode(args ...object.Object) object.Object {
 	}
 	decoded, err := base64.StdEncoding.DecodeString(s.Value)
 	if err != nil {
-		return newError("failed to decode base64: %s", err)
+		return newValueError("failed to decode base64: %s", err)
 	}
 	return &object.String{Value: string(decoded)}
 }
@@ -37,7 +37,7 @@ func encodingBase64Decode(args ...object.Object) object.Object {
 	}
 	decoded, err := base64.StdEncoding.DecodeString(input.Value)
 	if err != nil {
-		return newError("failed to decode base64: %s", err)
+		return newValueError("failed to decode base64: %s", err)
 	}
 	return &object.String{Value: string(decoded)}
 }
@@ -63,7 +63,7 @@ func encodingHexDecode(args ...object.Object) object.Object {
 	}
 	decoded, err := hex.DecodeString(input.Value)
 	if err != nil {
-		return newError("failed to decode hex: %s", err)
+		return newValueError("failed to decode hex: %s", err)
 	}
 	return &object.String{Value: string(decoded)}
 }
@@ -0,0 +1,63 @@
+package stdlib
+
+import (
+	"errors"
+	"jabline/pkg/object"
+	"net"
+	"os"
+)
+
+// ErrorBuiltins construct the built-in error kinds, as in
+// IOError("no config", cause). Error itself is one of the core builtins.
+var ErrorBuiltins = []struct {
+	Name   string
+	Object object.Object
+}{
+	{"TypeError", &object.Builtin{Fn: errorConstructor(object.TypeErrorKind)}},
+	{"IndexError", &object.Builtin{Fn: errorConstructor(object.IndexErrorKind)}},
+	{"KeyError", &object.Builtin{Fn: errorConstructor(object.KeyErrorKind)}},
+	{"ValueError", &object.Builtin{Fn: errorConstructor(object.ValueErrorKind)}},
+	{"ArithmeticError", &object.Builtin{Fn: errorConstructor(object.ArithmeticErrorKind)}},
+	{"IOError", &object.Builtin{Fn: errorConstructor(object.IOErrorKind)}},
+	{"TimeoutError", &object.Builtin{Fn: errorConstructor(object.TimeoutErrorKind)}},
+}
+
+// errorConstructor returns the builtin making an error of the given kind
+// from a message and an optional cause.
+func errorConstructor(kind string) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if len(args) != 1 && len(args) != 2 {
+			return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+		}
+		e := &object.Error{Kind: kind, Message: args[0].Inspect()}
+		if msg, ok := args[0].(*object.String); ok {
+			e.Message = msg.Value
+		}
+		if len(args) == 2 && args[1].Type() != object.NULL_OBJ {
+			e.Cause = args[1]
+		}
+		return e
+	}
+}
+
+// newIOError reports a failed operating system or network call. It is a
+// TimeoutError if err is a timeout.
+func newIOError(err error, format string, a ...interface{}) *object.Error {
+	e := newError(format, a...)
+	e.Kind = object.IOErrorKind
+	e.Raise = true
+	var netErr net.Error
+	if errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		e.Kind = object.TimeoutErrorKind
+	}
+	return e
+}
+
+// newValueError reports an argument of the right type whose value cannot be
+// used, such as malformed JSON.
+func newValueError(format string, a ...interface{}) *object.Error {
+	e := newError(format, a...)
+	e.Kind = object.ValueErrorKind
+	e.Raise = true
+	return e
+}
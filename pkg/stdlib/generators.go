@@ -0,0 +1,44 @@
+package stdlib
+
+import (
+	"context"
+	"jabline/pkg/object"
+)
+
+// GeneratorBuiltins step generators from outside a for-in loop.
+var GeneratorBuiltins = []struct {
+	Name   string
+	Object object.Object
+}{
+	{"next", &object.Builtin{Fn: nextFunc, FnContext: nextContext}},
+}
+
+// Next resumes gen, sending it sent, and returns the value it yields, or
+// null once it is exhausted, which sets gen.Done. It is set by the VM, which resumes the
+// generator itself when it calls next.
+var Next func(ctx context.Context, gen *object.Generator, sent object.Object) object.Object
+
+func nextFunc(args ...object.Object) object.Object {
+	return nextContext(context.Background(), args...)
+}
+
+// nextContext resumes a generator and returns the value it yields, or null
+// once it is exhausted; scripts tell the two apart with gen.done. The optional second argument becomes the value of
+// the yield expression the generator is suspended at.
+func nextContext(ctx context.Context, args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	gen, ok := args[0].(*object.Generator)
+	if !ok {
+		return newError("argument to `next` must be GENERATOR, got %s", args[0].Type())
+	}
+	if Next == nil {
+		return newError("VM Executor not initialized")
+	}
+	var sent object.Object = &object.Null{}
+	if len(args) == 2 {
+		sent = args[1]
+	}
+	return Next(ctx, gen, sent)
+}
@@ -1,6 +1,7 @@
 package stdlib
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"jabline/pkg/object"
@@ -17,7 +18,7 @@ var HTTPBuiltins = []struct {
 }{
 	{"http_get", &object.Builtin{Fn: httpGet}},
 	{"http_post", &object.Builtin{Fn: httpPost}},
-	{"http_serve", &object.Builtin{Fn: httpServe}},
+	{"http_serve", &object.Builtin{Fn: httpServe, FnContext: httpServeContext}},
 }
 
 func httpGet(args ...object.Object) object.Object {
@@ -31,13 +32,13 @@ func httpGet(args ...object.Object) object.Object {
 
 	resp, err := http.Get(url.Value)
 	if err != nil {
-		return newError("http error: %s", err)
+		return newIOError(err, "http error: %s", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return newError("read error: %s", err)
+		return newIOError(err, "read error: %s", err)
 	}
 
 	return &object.String{Value: string(body)}
@@ -63,19 +64,40 @@ func httpPost(args ...object.Object) object.Object {
 
 	resp, err := http.Post(url.Value, contentType, strings.NewReader(bodyInput.Value))
 	if err != nil {
-		return newError("http post error: %s", err)
+		return newIOError(err, "http post error: %s", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return newError("read error: %s", err)
+		return newIOError(err, "read error: %s", err)
 	}
 
 	return &object.String{Value: string(respBody)}
 }
 
 func httpServe(args ...object.Object) object.Object {
+	return httpServeContext(context.Background(), args...)
+}
+
+// requestContext is the context a handler runs in: that of the request,
+// with the values of the context http_serve was called with, which lead the
+// VM to the program serving.
+type requestContext struct {
+	context.Context
+	serve context.Context
+}
+
+func (c requestContext) Value(key any) any {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.serve.Value(key)
+}
+
+// httpServeContext is http_serve(port, handler). The handler runs within
+// the limits and budgets of the program, until its request is done.
+func httpServeContext(ctx context.Context, args ...object.Object) object.Object {
 	if len(args) != 2 {
 		return newError("wrong args. usage: http_serve(port, handler)")
 	}
@@ -114,7 +136,7 @@ func httpServe(args ...object.Object) object.Object {
 
 		// 2. Execute Jabline Handler
 		// We expect the handler to return a Hash: { status: 200, body: "...", headers: {...} }
-		result := Executor(handlerClosure, []object.Object{reqHash})
+		result := Executor(requestContext{r.Context(), ctx}, handlerClosure, []object.Object{reqHash})
 
 		// 3. Process Response
 		if result.Type() == object.ERROR_OBJ {
@@ -161,7 +183,7 @@ func httpServe(args ...object.Object) object.Object {
 	fmt.Printf("Jabline HTTP Server listening on %s\n", port)
 	err := http.ListenAndServe(port, nil)
 	if err != nil {
-		return newError("server error: %s", err)
+		return newIOError(err, "server error: %s", err)
 	}
 
 	return &object.Null{}
@@ -29,7 +29,7 @@ func ioReadFile(args ...object.Object) object.Object {
 
 	content, err := os.ReadFile(filename.Value)
 	if err != nil {
-		return &object.Error{Message: err.Error()}
+		return newIOError(err, "%s", err)
 	}
 	return &object.String{Value: string(content)}
 }
@@ -45,7 +45,7 @@ func ioReadLines(args ...object.Object) object.Object {
 
 	file, err := os.Open(filename.Value)
 	if err != nil {
-		return &object.Error{Message: err.Error()}
+		return newIOError(err, "%s", err)
 	}
 	defer file.Close()
 
@@ -56,7 +56,7 @@ func ioReadLines(args ...object.Object) object.Object {
 	}
 
 	if err := scanner.Err(); err != nil {
-		return &object.Error{Message: err.Error()}
+		return newIOError(err, "%s", err)
 	}
 
 	return &object.Array{Elements: lines}
@@ -74,7 +74,7 @@ func ioWriteFile(args ...object.Object) object.Object {
 
 	err := os.WriteFile(filename.Value, []byte(content.Value), 0644)
 	if err != nil {
-		return &object.Error{Message: err.Error()}
+		return newIOError(err, "%s", err)
 	}
 	return &object.Boolean{Value: true}
 }
@@ -26,7 +26,7 @@ func jsonParse(args ...object.Object) object.Object {
 	var data interface{}
 	err := json.Unmarshal([]byte(s.Value), &data)
 	if err != nil {
-		return newError("json error: %s", err)
+		return newValueError("json error: %s", err)
 	}
 
 	return goToJabline(data)
@@ -40,7 +40,7 @@ func jsonStringify(args ...object.Object) object.Object {
 	data := jablineToGo(args[0])
 	bytes, err := json.Marshal(data)
 	if err != nil {
-		return newError("json error: %s", err)
+		return newValueError("json error: %s", err)
 	}
 
 	return &object.String{Value: string(bytes)}
@@ -54,7 +54,7 @@ func jsonPretty(args ...object.Object) object.Object {
 	data := jablineToGo(args[0])
 	bytes, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
-		return newError("json error: %s", err)
+		return newValueError("json error: %s", err)
 	}
 
 	return &object.String{Value: string(bytes)}
@@ -125,6 +125,26 @@ func jablineToGo(obj object.Object) interface{} {
 			}
 		}
 		return m
+	case *object.EnumValue:
+		// A variant without fields is written as its name, and one with
+		// fields as {"Variant": {"field": value, ...}}.
+		if len(o.Variant.Fields) == 0 {
+			return o.Variant.Name
+		}
+		fields := make(map[string]interface{}, len(o.Values))
+		for i, name := range o.Variant.Fields {
+			fields[name] = jablineToGo(o.Values[i])
+		}
+		return map[string]interface{}{o.Variant.Name: fields}
+	}
+	if f, ok := object.FloatValue(obj); ok {
+		return f
+	}
+	if bits, ok := object.IntegerBits(obj); ok {
+		if _, signed, _ := object.IntegerKind(obj.Type()); !signed {
+			return bits
+		}
+		return int64(bits)
 	}
 	return nil
 }
@@ -45,6 +45,15 @@ func GetNativeModule(name string) *object.Hash {
 	case "_time":
 		builtins = TimeBuiltins
 		prefix = "time_"
+	case "_sync":
+		builtins = SyncBuiltins
+		prefix = "sync_"
+	case "_parallel":
+		builtins = ParallelBuiltins
+		prefix = "parallel_"
+	case "_testing":
+		builtins = TestingBuiltins
+		prefix = "testing_"
 	case "_types":
 		builtins = TypesBuiltins
 		prefix = "to_" // Functions are named toInt8, toUint32, etc.
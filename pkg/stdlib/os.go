@@ -60,7 +60,7 @@ func osSetenv(args ...object.Object) object.Object {
 
 	err := os.Setenv(key.Value, value.Value)
 	if err != nil {
-		return newError("failed to setenv: %s", err)
+		return newIOError(err, "failed to setenv: %s", err)
 	}
 	return &object.Boolean{Value: true}
 }
@@ -72,7 +72,7 @@ func osGetwd(args ...object.Object) object.Object {
 
 	dir, err := os.Getwd()
 	if err != nil {
-		return newError("failed to getwd: %s", err)
+		return newIOError(err, "failed to getwd: %s", err)
 	}
 	return &object.String{Value: dir}
 }
@@ -97,7 +97,7 @@ func osMkdir(args ...object.Object) object.Object {
 
 	err := os.MkdirAll(path.Value, perm)
 	if err != nil {
-		return newError("failed to mkdir: %s", err)
+		return newIOError(err, "failed to mkdir: %s", err)
 	}
 	return &object.Boolean{Value: true}
 }
@@ -113,7 +113,7 @@ func osRemove(args ...object.Object) object.Object {
 
 	err := os.RemoveAll(path.Value)
 	if err != nil {
-		return newError("failed to remove: %s", err)
+		return newIOError(err, "failed to remove: %s", err)
 	}
 	return &object.Boolean{Value: true}
 }
@@ -130,7 +130,7 @@ func osRename(args ...object.Object) object.Object {
 
 	err := os.Rename(oldPath.Value, newPath.Value)
 	if err != nil {
-		return newError("failed to rename: %s", err)
+		return newIOError(err, "failed to rename: %s", err)
 	}
 	return &object.Boolean{Value: true}
 }
@@ -177,7 +177,7 @@ func osChmod(args ...object.Object) object.Object {
 
 	err := os.Chmod(path.Value, os.FileMode(mode.Value))
 	if err != nil {
-		return newError("failed to chmod: %s", err)
+		return newIOError(err, "failed to chmod: %s", err)
 	}
 	return &object.Boolean{Value: true}
 }
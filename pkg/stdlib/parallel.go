@@ -0,0 +1,79 @@
+package stdlib
+
+import (
+	"context"
+	"runtime"
+
+	"jabline/pkg/object"
+)
+
+// ParallelBuiltins call a function with each element of an array on
+// several tasks at once, as the "parallel" module exports them.
+var ParallelBuiltins = []struct {
+	Name   string
+	Object object.Object
+}{
+	{"map", &object.Builtin{Fn: parallelMap, FnContext: parallelMapContext}},
+	{"forEach", &object.Builtin{Fn: parallelForEach, FnContext: parallelForEachContext}},
+}
+
+// Parallel calls fn with each of items on up to workers tasks at once and
+// returns the array of the results, in order. It is set by the VM, which
+// runs the tasks on its scheduler and throws the exception of the first to
+// fail in the caller.
+var Parallel func(ctx context.Context, fn object.Object, items []object.Object, workers int) object.Object
+
+func parallelMap(args ...object.Object) object.Object {
+	return parallelMapContext(context.Background(), args...)
+}
+
+// parallelMapContext is map(array, fn, workers), workers defaulting to the
+// number of CPUs.
+func parallelMapContext(ctx context.Context, args ...object.Object) object.Object {
+	return runParallel(ctx, "map", args)
+}
+
+func parallelForEach(args ...object.Object) object.Object {
+	return parallelForEachContext(context.Background(), args...)
+}
+
+// parallelForEachContext is forEach(array, fn, workers), which runs like
+// map and drops the results.
+func parallelForEachContext(ctx context.Context, args ...object.Object) object.Object {
+	result := runParallel(ctx, "forEach", args)
+	if _, ok := result.(*object.Array); ok {
+		return &object.Null{}
+	}
+	return result
+}
+
+// runParallel checks the arguments of the builtin name and runs it.
+func runParallel(ctx context.Context, name string, args []object.Object) object.Object {
+	if len(args) < 2 || len(args) > 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+	switch args[1].(type) {
+	case *object.Closure, *object.Builtin:
+	default:
+		return newError("argument to `%s` must be a function, got %s", name, args[1].Type())
+	}
+	workers := runtime.NumCPU()
+	if len(args) == 3 {
+		n, ok := args[2].(*object.Integer)
+		if !ok {
+			return newError("argument to `%s` must be INTEGER, got %s", name, args[2].Type())
+		}
+		if n.Value < 1 {
+			return newValueError("invalid number of workers: %d", n.Value)
+		}
+		workers = int(n.Value)
+	}
+	if Parallel == nil {
+		return newError("VM Executor not initialized")
+	}
+	return Parallel(ctx, args[1], arr.Elements, workers)
+}
@@ -171,7 +171,7 @@ func strMatch(args ...object.Object) object.Object {
 
 	matched, err := regexp.MatchString(pattern.Value, text.Value)
 	if err != nil {
-		return newError("regex error: %s", err)
+		return newValueError("regex error: %s", err)
 	}
 	return &object.Boolean{Value: matched}
 }
@@ -189,7 +189,7 @@ func strRegexReplace(args ...object.Object) object.Object {
 
 	re, err := regexp.Compile(pattern.Value)
 	if err != nil {
-		return newError("regex error: %s", err)
+		return newValueError("regex error: %s", err)
 	}
 
 	newText := re.ReplaceAllString(text.Value, repl.Value)
@@ -0,0 +1,58 @@
+package stdlib
+
+import (
+	"jabline/pkg/object"
+)
+
+// SyncBuiltins make the values tasks share safely, as the "sync" module
+// exports them. Their methods are run by the VM.
+var SyncBuiltins = []struct {
+	Name   string
+	Object object.Object
+}{
+	{"Mutex", &object.Builtin{Fn: newMutex}},
+	{"RWMutex", &object.Builtin{Fn: newRWMutex}},
+	{"Atomic", &object.Builtin{Fn: newAtomic}},
+	{"SharedMap", &object.Builtin{Fn: newSharedMap}},
+}
+
+func newMutex(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	return &object.Mutex{}
+}
+
+func newRWMutex(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	return &object.RWMutex{}
+}
+
+// newAtomic makes an Atomic holding its argument, or null.
+func newAtomic(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+	if len(args) == 0 {
+		return object.NewAtomic(&object.Null{})
+	}
+	return object.NewAtomic(args[0])
+}
+
+// newSharedMap makes a SharedMap, holding the pairs of a hash if given
+// one.
+func newSharedMap(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+	if len(args) == 0 {
+		return object.NewSharedMap(nil)
+	}
+	h, ok := args[0].(*object.Hash)
+	if !ok {
+		return newError("argument to `SharedMap` must be HASH, got %s", args[0].Type())
+	}
+	return object.NewSharedMap(h)
+}
@@ -0,0 +1,19 @@
+package stdlib
+
+import (
+	"jabline/pkg/object"
+)
+
+// TestingBuiltins back the testing modules. Outside `jabline test` no test
+// is running; the test runner gives its programs a _testing module of its
+// own that says otherwise.
+var TestingBuiltins = []struct {
+	Name   string
+	Object object.Object
+}{
+	{"testing_active", &object.Builtin{Fn: testingActive}},
+}
+
+func testingActive(args ...object.Object) object.Object {
+	return object.FalseValue
+}
@@ -1,6 +1,7 @@
 package stdlib
 
 import (
+	"context"
 	"jabline/pkg/object"
 	"time"
 )
@@ -11,7 +12,7 @@ var TimeBuiltins = []struct {
 }{
 	{"now", &object.Builtin{Fn: timeNow}},
 	{"unix", &object.Builtin{Fn: timeUnix}},
-	{"sleep", &object.Builtin{Fn: timeSleep}},
+	{"sleep", &object.Builtin{Fn: timeSleep, FnContext: timeSleepContext}},
 }
 
 func timeNow(args ...object.Object) object.Object {
@@ -49,6 +50,11 @@ func timeUnix(args ...object.Object) object.Object {
 }
 
 func timeSleep(args ...object.Object) object.Object {
+	return timeSleepContext(context.Background(), args...)
+}
+
+// timeSleepContext sleeps like timeSleep, waking up early once ctx is done.
+func timeSleepContext(ctx context.Context, args ...object.Object) object.Object {
 	if len(args) != 1 {
 		return newError("wrong number of arguments. got=%d, want=1", len(args))
 	}
@@ -56,6 +62,12 @@ func timeSleep(args ...object.Object) object.Object {
 	if !ok {
 		return newError("argument to `sleep` must be INTEGER (ms), got %s", args[0].Type())
 	}
-	time.Sleep(time.Duration(ms.Value) * time.Millisecond)
-	return &object.Null{}
+	timer := time.NewTimer(time.Duration(ms.Value) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return &object.Null{}
+	case <-ctx.Done():
+		return newError("sleep interrupted: %s", ctx.Err())
+	}
 }
@@ -132,6 +132,24 @@ func (s *SymbolTable) defineFree(original Symbol) Symbol {
 	return symbol
 }
 
+// Lookup returns what name is defined as in this table itself, without
+// looking in the enclosing ones.
+func (s *SymbolTable) Lookup(name string) (Symbol, bool) {
+	sym, ok := s.store[name]
+	return sym, ok
+}
+
+// Restore ends the scope of a name defined for part of a function, such as
+// a match arm, giving it back the symbol Lookup returned before, if any.
+// The slot of the name stays reserved.
+func (s *SymbolTable) Restore(name string, sym Symbol, ok bool) {
+	if ok {
+		s.store[name] = sym
+	} else {
+		delete(s.store, name)
+	}
+}
+
 func (s *SymbolTable) GetStore() map[string]Symbol {
 	return s.store
 }
@@ -139,3 +157,25 @@ func (s *SymbolTable) GetStore() map[string]Symbol {
 func (s *SymbolTable) NumDefinitions() int {
 	return s.numDefinitions
 }
+
+// LocalNames returns the names of the table's own definitions, indexed by
+// their slot.
+func (s *SymbolTable) LocalNames() []string {
+	names := make([]string, s.numDefinitions)
+	for _, sym := range s.store {
+		if (sym.Scope == LocalScope || sym.Scope == GlobalScope) && sym.Index < len(names) {
+			names[sym.Index] = sym.Name
+		}
+	}
+	return names
+}
+
+// FreeNames returns the names of the variables captured from enclosing
+// scopes, in the order their values are stored in a closure.
+func (s *SymbolTable) FreeNames() []string {
+	names := make([]string, len(s.FreeSymbols))
+	for i, sym := range s.FreeSymbols {
+		names[i] = sym.Name
+	}
+	return names
+}
@@ -0,0 +1,105 @@
+package testrunner
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"jabline/pkg/ast"
+	"jabline/pkg/lexer"
+	"jabline/pkg/parser"
+)
+
+const testFileSuffix = "_test.jb"
+const testFuncPrefix = "test"
+
+// TestFile is a parsed *_test.jb file together with the test functions it
+// declares. Test functions are top-level, parameterless functions whose name
+// starts with "test".
+type TestFile struct {
+	Path    string
+	Program *ast.Program
+	Tests   []*ast.FunctionStatement
+}
+
+// Discover expands paths into the list of test files to run. Directories are
+// walked recursively (skipping hidden directories); files are taken as-is.
+func Discover(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			add(root)
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(d.Name(), testFileSuffix) {
+				add(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// Load parses a test file and collects its test functions.
+func Load(path string) (*TestFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.New(string(content)))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return nil, fmt.Errorf("parser errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	file := &TestFile{Path: path, Program: program}
+	for _, stmt := range program.Statements {
+		fn, ok := stmt.(*ast.FunctionStatement)
+		if !ok || fn.ReceiverName != nil || len(fn.Parameters) > 0 {
+			continue
+		}
+		if strings.HasPrefix(fn.Name.Value, testFuncPrefix) {
+			file.Tests = append(file.Tests, fn)
+		}
+	}
+
+	return file, nil
+}
@@ -0,0 +1,191 @@
+package testrunner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Summary counts results by status.
+type Summary struct {
+	Total    int
+	Passed   int
+	Failed   int
+	TimedOut int
+	Duration time.Duration
+}
+
+func Summarize(results []Result) Summary {
+	s := Summary{Total: len(results)}
+	for _, r := range results {
+		switch r.Status {
+		case Passed:
+			s.Passed++
+		case TimedOut:
+			s.TimedOut++
+		default:
+			s.Failed++
+		}
+		s.Duration += r.Duration
+	}
+	return s
+}
+
+func (s Summary) OK() bool {
+	return s.Failed == 0 && s.TimedOut == 0
+}
+
+// FormatTrace renders a result's traceback, most recent call first.
+func FormatTrace(r Result) string {
+	var sb strings.Builder
+	for i := len(r.Trace) - 1; i >= 0; i-- {
+		frame := r.Trace[i]
+		if frame.Line > 0 {
+			fmt.Fprintf(&sb, "at %s (%s:%d:%d)\n", frame.Function, frame.File, frame.Line, frame.Column)
+		} else {
+			fmt.Fprintf(&sb, "at %s (%s)\n", frame.Function, frame.File)
+		}
+	}
+	return sb.String()
+}
+
+// WriteText writes a human readable report.
+func WriteText(w io.Writer, results []Result) {
+	for _, r := range results {
+		fmt.Fprintf(w, "%-7s %s::%s (%s)\n", r.Status, r.File, r.Name, r.Duration.Round(time.Microsecond))
+		if r.Status == Passed {
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimRight(r.Message, "\n"), "\n") {
+			fmt.Fprintf(w, "        %s\n", line)
+		}
+		for _, line := range strings.Split(strings.TrimRight(FormatTrace(r), "\n"), "\n") {
+			if line != "" {
+				fmt.Fprintf(w, "          %s\n", line)
+			}
+		}
+	}
+
+	s := Summarize(results)
+	fmt.Fprintf(w, "\n%d passed, %d failed, %d timed out, %d total\n", s.Passed, s.Failed, s.TimedOut, s.Total)
+}
+
+// WriteTAP writes a TAP version 13 report. Failure details are emitted as a
+// YAML diagnostic block under the failing test point.
+func WriteTAP(w io.Writer, results []Result) {
+	fmt.Fprintln(w, "TAP version 13")
+	fmt.Fprintf(w, "1..%d\n", len(results))
+
+	for i, r := range results {
+		desc := r.File + "::" + r.Name
+		if r.Status == Passed {
+			fmt.Fprintf(w, "ok %d - %s\n", i+1, desc)
+			continue
+		}
+
+		fmt.Fprintf(w, "not ok %d - %s\n", i+1, desc)
+		fmt.Fprintln(w, "  ---")
+		fmt.Fprintf(w, "  status: %s\n", strings.ToLower(r.Status.String()))
+		fmt.Fprintf(w, "  message: %q\n", r.Message)
+		fmt.Fprintf(w, "  duration_ms: %.3f\n", float64(r.Duration)/float64(time.Millisecond))
+		if len(r.Trace) > 0 {
+			fmt.Fprintln(w, "  stack: |")
+			for _, line := range strings.Split(strings.TrimRight(FormatTrace(r), "\n"), "\n") {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+		fmt.Fprintln(w, "  ...")
+	}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes a JUnit XML report with one <testsuite> per test file.
+func WriteJUnit(w io.Writer, results []Result) error {
+	report := junitTestSuites{}
+	suiteIndex := make(map[string]int)
+
+	for _, r := range results {
+		idx, ok := suiteIndex[r.File]
+		if !ok {
+			idx = len(report.Suites)
+			suiteIndex[r.File] = idx
+			report.Suites = append(report.Suites, junitTestSuite{Name: r.File})
+		}
+		suite := &report.Suites[idx]
+
+		tc := junitTestCase{
+			Name:      r.Name,
+			ClassName: r.File,
+			Time:      junitSeconds(r.Duration),
+		}
+		if r.Status != Passed {
+			tc.Failure = &junitFailure{
+				Message: r.Message,
+				Type:    strings.ToLower(r.Status.String()),
+				Body:    r.Message + "\n" + FormatTrace(r),
+			}
+			suite.Failures++
+			report.Failures++
+		}
+
+		suite.Tests++
+		suite.Cases = append(suite.Cases, tc)
+		report.Tests++
+	}
+
+	for i := range report.Suites {
+		var total time.Duration
+		for _, r := range results {
+			if r.File == report.Suites[i].Name {
+				total += r.Duration
+			}
+		}
+		report.Suites[i].Time = junitSeconds(total)
+	}
+	report.Time = junitSeconds(Summarize(results).Duration)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,207 @@
+package testrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"jabline/pkg/ast"
+	"jabline/pkg/compiler"
+	"jabline/pkg/object"
+	"jabline/pkg/token"
+	"jabline/pkg/vm"
+)
+
+type Status int
+
+const (
+	Passed Status = iota
+	Failed
+	TimedOut
+)
+
+func (s Status) String() string {
+	switch s {
+	case Passed:
+		return "PASS"
+	case TimedOut:
+		return "TIMEOUT"
+	default:
+		return "FAIL"
+	}
+}
+
+// Result is the outcome of a single test function. Trace is only set when
+// the test failed with a runtime error.
+type Result struct {
+	File     string
+	Name     string
+	Status   Status
+	Duration time.Duration
+	Message  string
+	Trace    []vm.CallFrame
+}
+
+type Options struct {
+	// Run, when set, selects the tests whose name matches.
+	Run *regexp.Regexp
+	// Parallel is the number of tests executed concurrently.
+	Parallel int
+	// Timeout bounds each test. Zero disables it.
+	Timeout time.Duration
+}
+
+// testingModule stands in for the _testing module of the standard library
+// in the programs the runner runs. Failed assertions of the testing module
+// only throw when it reports a test as active, so that scripts run with
+// `jabline run` keep going past them.
+var testingModule = func() *object.Hash {
+	key := &object.String{Value: "active"}
+	active := &object.Builtin{Fn: func(args ...object.Object) object.Object { return object.TrueValue }}
+	return &object.Hash{Pairs: map[object.HashKey]object.HashPair{key.HashKey(): {Key: key, Value: active}}}
+}()
+
+type job struct {
+	file *TestFile
+	test *ast.FunctionStatement
+}
+
+// Run executes the selected tests of every file and returns their results in
+// file and declaration order. Files that fail to load are reported as a
+// single failed result named after the file.
+func Run(paths []string, opts Options) ([]Result, error) {
+	files, err := Discover(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	var jobs []job
+	slots := make(map[int]int) // job index -> results index
+
+	for _, path := range files {
+		file, err := Load(path)
+		if err != nil {
+			results = append(results, Result{File: path, Name: path, Status: Failed, Message: err.Error()})
+			continue
+		}
+
+		for _, test := range file.Tests {
+			if opts.Run != nil && !opts.Run.MatchString(test.Name.Value) {
+				continue
+			}
+			slots[len(jobs)] = len(results)
+			results = append(results, Result{File: path, Name: test.Name.Value})
+			jobs = append(jobs, job{file: file, test: test})
+		}
+	}
+
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				results[slots[i]] = runTest(jobs[i], opts.Timeout)
+			}
+		}()
+	}
+	for i := range jobs {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	return results, nil
+}
+
+// runTest compiles the test file with a call to the test function appended
+// and runs it in a fresh VM, so every test starts from pristine globals and
+// module state.
+func runTest(j job, timeout time.Duration) Result {
+	result := Result{File: j.file.Path, Name: j.test.Name.Value}
+
+	// The synthetic call is attributed to the test's declaration so the
+	// <main> frame of a traceback points somewhere useful.
+	nameTok := j.test.Name.Token
+	callTok := token.Token{Type: token.LPAREN, Literal: "(", Line: nameTok.Line, Column: nameTok.Column}
+	call := &ast.ExpressionStatement{
+		Token: nameTok,
+		Expression: &ast.CallExpression{
+			Token:    callTok,
+			Function: &ast.Identifier{Token: nameTok, Value: j.test.Name.Value},
+		},
+	}
+	program := &ast.Program{Statements: append(append([]ast.Statement{}, j.file.Program.Statements...), call)}
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		result.Status = Failed
+		result.Message = fmt.Sprintf("compiler error: %s", err)
+		return result
+	}
+
+	bytecode := comp.Bytecode()
+	loader := vm.NewModuleLoader()
+	loader.RegisterNative("_testing", testingModule)
+	machine := vm.NewWithLoader(bytecode.Instructions, bytecode.Constants, j.file.Path, loader)
+	machine.SetSourceMap(bytecode.SourceMap)
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- machine.RunContext(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// The VM stops at its next context check, but a test blocked in
+		// a builtin may never get there, so it is not waited for.
+		err = ctx.Err()
+	}
+	result.Duration = time.Since(start)
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		result.Status = TimedOut
+		result.Message = fmt.Sprintf("test timed out after %s", timeout)
+		return result
+	}
+
+	if err == nil {
+		result.Status = Passed
+		return result
+	}
+
+	result.Status = Failed
+	var rtErr *vm.RuntimeError
+	if errors.As(err, &rtErr) {
+		result.Message = rtErr.Message
+		result.Trace = rtErr.StackTrace
+	} else {
+		result.Message = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,196 @@
+package testrunner
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"jabline/pkg/ast"
+	"jabline/pkg/compiler"
+	"jabline/pkg/lexer"
+	"jabline/pkg/parser"
+	"jabline/pkg/vm"
+)
+
+const sampleTests = `let counter = 0;
+
+fn helper(x) {
+    throw Error("boom " + x);
+}
+
+fn testPasses() {
+    counter = counter + 1;
+    if (counter != 1) { throw Error("state leaked between tests"); }
+}
+
+fn testAlsoPasses() {
+    counter = counter + 1;
+    if (counter != 1) { throw Error("state leaked between tests"); }
+}
+
+fn testFails() {
+    helper(3);
+}
+
+fn notATest() {
+    throw Error("should not run");
+}
+`
+
+func writeSample(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nested", "sample_test.jb"), []byte(sampleTests), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "helper.jb"), []byte("fn testIgnored() {}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRunDiscoversAndIsolatesTests(t *testing.T) {
+	dir := writeSample(t)
+
+	results, err := Run([]string{dir}, Options{Parallel: 2, Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+
+	expected := map[string]Status{
+		"testPasses":     Passed,
+		"testAlsoPasses": Passed,
+		"testFails":      Failed,
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("wrong number of results. want=%d, got=%d (%+v)", len(expected), len(results), results)
+	}
+
+	for _, r := range results {
+		want, ok := expected[r.Name]
+		if !ok {
+			t.Errorf("unexpected test %q was run", r.Name)
+			continue
+		}
+		if r.Status != want {
+			t.Errorf("test %q has wrong status. want=%s, got=%s (%s)", r.Name, want, r.Status, r.Message)
+		}
+	}
+
+	failed := results[2]
+	if !strings.Contains(failed.Message, "boom 3") {
+		t.Errorf("failure message does not mention the error. got=%q", failed.Message)
+	}
+	if len(failed.Trace) < 2 {
+		t.Fatalf("expected a traceback, got %+v", failed.Trace)
+	}
+	top := failed.Trace[len(failed.Trace)-1]
+	if top.Function != "helper" || top.Line != 4 {
+		t.Errorf("innermost frame is wrong. want=helper:4, got=%s:%d", top.Function, top.Line)
+	}
+}
+
+func TestRunFilter(t *testing.T) {
+	dir := writeSample(t)
+
+	results, err := Run([]string{dir}, Options{Run: regexp.MustCompile("Also")})
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	if len(results) != 1 || results[0].Name != "testAlsoPasses" {
+		t.Fatalf("filter selected the wrong tests: %+v", results)
+	}
+}
+
+func TestAssertionsFailTests(t *testing.T) {
+	const src = `import { assertEqual } from "testing/assert";
+
+fn testSum() {
+    assertEqual(1 + 1, 3, "sum");
+}
+
+fn failed() {
+    return assertEqual(1 + 1, 3, "sum");
+}
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "assert_test.jb")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Run([]string{path}, Options{Run: regexp.MustCompile("^testSum$")})
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	if len(results) != 1 || results[0].Status != Failed {
+		t.Fatalf("want testSum to fail, got %+v", results)
+	}
+	if !strings.Contains(results[0].Message, "sum: expected 3, got 2") {
+		t.Errorf("failure message does not describe the assertion. got=%q", results[0].Message)
+	}
+
+	// Outside the runner, even after it ran, a failed assertion returns
+	// false as it always did.
+	file, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comp := compiler.New()
+	if err := comp.Compile(&ast.Program{Statements: append(file.Program.Statements, parseStatement(t, "failed();"))}); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	machine := vm.New(comp.Bytecode().Instructions, comp.Bytecode().Constants, path)
+	machine.SetOutput(io.Discard, io.Discard)
+	if err := machine.Run(); err != nil {
+		t.Fatalf("assertion threw outside the runner: %s", err)
+	}
+	if got := machine.LastPoppedStackElem().Inspect(); got != "false" {
+		t.Errorf("want false from the failed assertion, got %s", got)
+	}
+}
+
+func parseStatement(t *testing.T, src string) ast.Statement {
+	t.Helper()
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program.Statements[0]
+}
+
+func TestReports(t *testing.T) {
+	results := []Result{
+		{File: "a_test.jb", Name: "testOk", Status: Passed},
+		{File: "a_test.jb", Name: "testBad", Status: Failed, Message: "boom"},
+	}
+
+	var tap bytes.Buffer
+	WriteTAP(&tap, results)
+	for _, want := range []string{"TAP version 13\n", "1..2\n", "ok 1 - a_test.jb::testOk\n", "not ok 2 - a_test.jb::testBad\n"} {
+		if !strings.Contains(tap.String(), want) {
+			t.Errorf("TAP output is missing %q:\n%s", want, tap.String())
+		}
+	}
+
+	var junit bytes.Buffer
+	if err := WriteJUnit(&junit, results); err != nil {
+		t.Fatalf("WriteJUnit failed: %s", err)
+	}
+	for _, want := range []string{`<testsuites tests="2" failures="1"`, `<testcase name="testBad" classname="a_test.jb"`, `<failure message="boom" type="fail">`} {
+		if !strings.Contains(junit.String(), want) {
+			t.Errorf("JUnit output is missing %q:\n%s", want, junit.String())
+		}
+	}
+}
@@ -10,5 +10,6 @@ const (
 	COMMA     = ","
 	SEMICOLON = ";"
 	DOT       = "."
+	ELLIPSIS  = "..."
 	COLON     = ":"
 )
@@ -21,6 +21,8 @@ const (
 	TRY          = "TRY"
 	CATCH        = "CATCH"
 	THROW        = "THROW"
+	FINALLY      = "FINALLY"
+	DEFER        = "DEFER"
 	ASYNC        = "ASYNC"
 	AWAIT        = "AWAIT"
 	IMPORT       = "IMPORT"
@@ -47,6 +49,7 @@ const (
 	RETRY        = "RETRY"
 	SERVICE      = "SERVICE"
 	SPAWN        = "SPAWN"
+	YIELD        = "YIELD"
 )
 
 var keywords = map[string]TokenType{
@@ -73,6 +76,8 @@ var keywords = map[string]TokenType{
 	"try":      TRY,
 	"catch":    CATCH,
 	"throw":    THROW,
+	"finally":  FINALLY,
+	"defer":    DEFER,
 	"retry":    RETRY,
 	"async":    ASYNC,
 	"await":    AWAIT,
@@ -80,6 +85,7 @@ var keywords = map[string]TokenType{
 	"export":   EXPORT,
 	"from":     FROM,
 	"spawn":    SPAWN,
+	"yield":    YIELD,
 	"string":   STRING_TYPE,
 	"int":      INT_TYPE,
 	"int8":     INT8_TYPE,
@@ -9,4 +9,7 @@ const (
 	FLOAT            = "FLOAT"
 	STRING           = "STRING"
 	TEMPLATE_LITERAL = "TEMPLATE_LITERAL"
+
+	// COMMENT is only produced by lexers created with lexer.NewRaw.
+	COMMENT = "COMMENT"
 )
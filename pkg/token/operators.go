@@ -38,6 +38,9 @@ const (
 	ARROW      = "=>"
 	ARROW_LEFT = "<-"
 
+	RANGE           = ".."
+	RANGE_INCLUSIVE = "..="
+
 	NULLISH_COALESCING = "??"
 	OPTIONAL_CHAINING  = "?."
 )
@@ -0,0 +1,218 @@
+package vm
+
+import (
+	"fmt"
+
+	"jabline/pkg/code"
+	"jabline/pkg/object"
+)
+
+// namedArgument is an argument passed by the name of its parameter.
+type namedArgument struct {
+	name  string
+	value object.Object
+}
+
+// spreadArguments replaces the values OpCallArgs pushed with the
+// arguments of the call, in parameter order, and returns their number.
+// The shape constant has an entry for each of the values: "" for a
+// positional argument, "..." for an array to spread, or the name of the
+// parameter the value is passed to.
+func (vm *VM) spreadArguments(ins code.Instructions, ip *int) (int, error) {
+	count := int(code.ReadUint16(ins[*ip+1:]))
+	shape := vm.constants[code.ReadUint16(ins[*ip+3:])].(*object.Array)
+	*ip += 4
+
+	args := make([]object.Object, 0, count)
+	var named []namedArgument
+	for i, kind := range shape.Elements {
+		value := vm.stack[vm.sp-count+i]
+		switch name := kind.(*object.String).Value; name {
+		case "":
+			args = append(args, value)
+		case "...":
+			arr, ok := value.(*object.Array)
+			if !ok {
+				return 0, fmt.Errorf("cannot spread %s into arguments", value.Type())
+			}
+			args = append(args, arr.Elements...)
+		default:
+			named = append(named, namedArgument{name, value})
+		}
+	}
+	vm.sp -= count
+
+	if len(named) > 0 {
+		var err error
+		if args, err = placeNamedArguments(vm.stack[vm.sp-1], args, named); err != nil {
+			return 0, err
+		}
+	}
+	if vm.sp+len(args) > len(vm.stack) {
+		return 0, ErrStackOverflow
+	}
+	vm.sp += copy(vm.stack[vm.sp:], args)
+	return len(args), nil
+}
+
+// placeNamedArguments puts the named arguments after the positional ones,
+// at the index of their parameter. The optional parameters they skip are
+// missing, which stands for their default.
+func placeNamedArguments(callee object.Object, args []object.Object, named []namedArgument) ([]object.Object, error) {
+	owner, names, optional, variadic, err := namedParameters(callee)
+	if err != nil {
+		return nil, err
+	}
+	fixed := len(names)
+	if variadic {
+		fixed--
+	}
+
+	placed := args
+	given := len(args)
+	for _, arg := range named {
+		index := -1
+		for i, name := range names[:fixed] {
+			if name == arg.name {
+				index = i
+				break
+			}
+		}
+		switch {
+		case index < 0 && variadic && names[fixed] == arg.name:
+			return nil, fmt.Errorf("the rest parameter %s of %s cannot be passed by name", arg.name, owner)
+		case index < 0:
+			return nil, fmt.Errorf("%s has no parameter named %s", owner, arg.name)
+		case index < given || index < len(placed) && placed[index] != nil:
+			return nil, fmt.Errorf("argument %s of %s is given twice", arg.name, owner)
+		}
+		for len(placed) <= index {
+			placed = append(placed, nil)
+		}
+		placed[index] = arg.value
+	}
+
+	required := fixed - optional
+	for i, arg := range placed {
+		if arg != nil {
+			continue
+		}
+		if i < required {
+			return nil, fmt.Errorf("missing argument %s in call to %s", names[i], owner)
+		}
+		placed[i] = missing
+	}
+	return placed, nil
+}
+
+// namedParameters returns the name of a callee taking named arguments and
+// those of its parameters, with how many of them are optional and whether
+// the last one is a rest parameter. The fields of an enum variant are its
+// parameters.
+func namedParameters(callee object.Object) (string, []string, int, bool, error) {
+	var fn *object.CompiledFunction
+	offset := 0
+	switch c := callee.(type) {
+	case *object.Closure:
+		fn = c.Fn
+	case *object.InstantiatedFunction:
+		fn = c.Closure.Fn
+	case *object.BoundMethod:
+		// The receiver is not among the arguments of the call.
+		fn, offset = c.Function.Fn, 1
+	case *object.EnumVariant:
+		return c.Enum + "." + c.Name, c.Fields, 0, false, nil
+	default:
+		return "", nil, 0, false, fmt.Errorf("%s does not take named arguments", callee.Type())
+	}
+
+	if len(fn.ParameterNames) < offset {
+		return "", nil, 0, false, fmt.Errorf("%s does not take named arguments", functionName(fn))
+	}
+	return functionName(fn), fn.ParameterNames[offset:], fn.NumOptional, fn.Variadic, nil
+}
+
+// bindArguments checks the number of arguments on the stack against the
+// parameters of fn, then marks the optional parameters left out as missing
+// and gathers the rest of the arguments of a variadic function in an
+// array. It returns the new number of arguments, that of the parameters.
+func (vm *VM) bindArguments(fn *object.CompiledFunction, numArgs int) (int, error) {
+	if numArgs == fn.NumParameters && !fn.Variadic {
+		return numArgs, nil
+	}
+	if err := checkArity(fn, numArgs); err != nil {
+		return 0, err
+	}
+
+	fixed := fn.NumParameters
+	if fn.Variadic {
+		fixed--
+	}
+	for ; numArgs < fixed; numArgs++ {
+		if err := vm.push(missing); err != nil {
+			return 0, err
+		}
+	}
+	if fn.Variadic {
+		rest := make([]object.Object, numArgs-fixed)
+		copy(rest, vm.stack[vm.sp-len(rest):vm.sp])
+		vm.sp -= len(rest)
+		if err := vm.pushNew(&object.Array{Elements: rest}); err != nil {
+			return 0, err
+		}
+	}
+	return fn.NumParameters, nil
+}
+
+// bindArgumentList is bindArguments for arguments held in a slice.
+func bindArgumentList(fn *object.CompiledFunction, args []object.Object) ([]object.Object, error) {
+	if len(args) == fn.NumParameters && !fn.Variadic {
+		return args, nil
+	}
+	if err := checkArity(fn, len(args)); err != nil {
+		return nil, err
+	}
+
+	fixed := fn.NumParameters
+	if fn.Variadic {
+		fixed--
+	}
+	given := min(len(args), fixed)
+	bound := make([]object.Object, 0, fn.NumParameters)
+	bound = append(bound, args[:given]...)
+	for len(bound) < fixed {
+		bound = append(bound, missing)
+	}
+	if fn.Variadic {
+		rest := append([]object.Object{}, args[given:]...)
+		bound = append(bound, &object.Array{Elements: rest})
+	}
+	return bound, nil
+}
+
+// checkArity reports a call passing fn fewer arguments than it requires,
+// or more than it has parameters for.
+func checkArity(fn *object.CompiledFunction, numArgs int) error {
+	required := fn.NumParameters - fn.NumOptional
+	if fn.Variadic {
+		required--
+	}
+	if numArgs >= required && (fn.Variadic || numArgs <= fn.NumParameters) {
+		return nil
+	}
+
+	switch {
+	case fn.Variadic:
+		return typeError("wrong number of arguments: want=%d or more, got=%d", required, numArgs)
+	case fn.NumOptional > 0:
+		return typeError("wrong number of arguments: want=%d to %d, got=%d", required, fn.NumParameters, numArgs)
+	}
+	return typeError("wrong number of arguments: want=%d, got=%d", fn.NumParameters, numArgs)
+}
+
+func functionName(fn *object.CompiledFunction) string {
+	if fn.Name == "" {
+		return "function"
+	}
+	return fn.Name
+}
@@ -0,0 +1,46 @@
+package vm
+
+import "testing"
+
+func TestCallArguments(t *testing.T) {
+	const connect = "fn connect(url, timeout = 1000, retries: int = 3) { return `${url} ${timeout} ${retries}`; } "
+	const sum = `fn sum(first, ...rest) { let s = first; for (x in rest) { s = s + x; } return s; } `
+	tests := []vmTestCase{
+		{connect + `connect("a")`, inspected("a 1000 3")},
+		{connect + `connect("a", 5)`, inspected("a 5 3")},
+		{connect + `connect("a", null, 2)`, inspected("a null 2")},
+		{connect + `connect("a", timeout: null)`, inspected("a null 3")},
+		{connect + `connect("a", retries: 9)`, inspected("a 1000 9")},
+		{connect + `connect(retries: 1, url: "b")`, inspected("b 1000 1")},
+		{`fn f(a, b = a * 2) { a + b } f(3)`, inspected("9")},
+		{`let f = (x, y = 10) => x + y; f(1)`, inspected("11")},
+		{sum + `sum(1)`, inspected("1")},
+		{sum + `sum(1, 2, 3)`, inspected("6")},
+		{sum + `let xs = [4, 5, 6]; sum(...xs)`, inspected("15")},
+		{sum + `sum(0, ...[1, 2], ...[3], 4)`, inspected("10")},
+		{`fn f(...all) { all } f()`, inspected("[]")},
+		{`len(...[[1, 2, 3]])`, inspected("3")},
+		{`struct P { n: int } fn (p P) add(k = 1, ...more) { return p.n + k + len(more); } let p = P{ n: 10 }; [p.add(), p.add(k: 5), p.add(1, 2, 3)]`, inspected("[11, 15, 13]")},
+		{`fn* gen(a, b = 2) { yield a; yield b; } let s = 0; for (v in gen(1)) { s = s + v; } s`, inspected("3")},
+		{`fn f(...xs) { len(xs) } let big = []; for (let i = 0; i < 300; i = i + 1) { big = push(big, i); } f(...big)`, inspected("300")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestCallArgumentErrors(t *testing.T) {
+	const connect = `fn connect(url, timeout = 1000) { url } `
+	tests := []vmTestCase{
+		{connect + `connect();`, errorContaining("wrong number of arguments: want=1 to 2, got=0")},
+		{connect + `connect(1, 2, 3);`, errorContaining("wrong number of arguments: want=1 to 2, got=3")},
+		{`fn f(a, ...rest) { a } f();`, errorContaining("wrong number of arguments: want=1 or more, got=0")},
+		{connect + `connect("a", retries: 1);`, errorContaining("connect has no parameter named retries")},
+		{connect + `connect("a", url: "b");`, errorContaining("argument url of connect is given twice")},
+		{connect + `connect(timeout: 1);`, errorContaining("missing argument url in call to connect")},
+		{`fn f(...rest) { rest } f(rest: 1);`, errorContaining("the rest parameter rest of f cannot be passed by name")},
+		{`fn f(a) { a } f(...5);`, errorContaining("cannot spread INTEGER into arguments")},
+		{`len(s: "x");`, errorContaining("does not take named arguments")},
+	}
+
+	runVmTests(t, tests)
+}
@@ -0,0 +1,57 @@
+package vm
+
+import (
+	"jabline/pkg/ast"
+	"jabline/pkg/compiler"
+)
+
+// Bundle resolves the import graph of program and compiles every module it
+// reaches, keyed by import path, so `jabline build` can embed them. Native
+// modules are skipped since every runtime provides them. Only top-level
+// imports are followed; anything else is still resolved at runtime.
+func (ml *ModuleLoader) Bundle(program *ast.Program) (map[string]*compiler.Bytecode, error) {
+	modules := make(map[string]*compiler.Bytecode)
+	byFile := make(map[string]*compiler.Bytecode)
+
+	pending := importPaths(program)
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+
+		if _, ok := modules[name]; ok || nativeModule(name) != nil {
+			continue
+		}
+
+		absPath, err := ml.resolvePath(name)
+		if err != nil {
+			return nil, err
+		}
+
+		// Different import paths naming the same file share one entry so
+		// the module still runs only once.
+		if bytecode, ok := byFile[absPath]; ok {
+			modules[name] = bytecode
+			continue
+		}
+
+		bytecode, prog, err := ml.compileModuleProgram(name, absPath)
+		if err != nil {
+			return nil, err
+		}
+		modules[name] = bytecode
+		byFile[absPath] = bytecode
+		pending = append(pending, importPaths(prog)...)
+	}
+
+	return modules, nil
+}
+
+func importPaths(program *ast.Program) []string {
+	var paths []string
+	for _, stmt := range program.Statements {
+		if imp, ok := stmt.(*ast.ImportStatement); ok && imp.ModuleName != nil {
+			paths = append(paths, imp.ModuleName.Value)
+		}
+	}
+	return paths
+}
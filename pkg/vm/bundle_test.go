@@ -0,0 +1,117 @@
+package vm
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"jabline/pkg/compiler"
+	"jabline/pkg/object"
+)
+
+func TestBundleRunsWithoutSources(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"base.jb": `export let base = 100;`,
+		"util.jb": `import { base } from "base";
+export fn double(x) { return x * 2 + base; }`,
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	program := parse(`import { double } from "util";
+import { base } from "base";
+double(21) + base;`)
+
+	loader := &ModuleLoader{cache: map[string]*object.Hash{}, paths: []string{dir}}
+	modules, err := loader.Bundle(program)
+	if err != nil {
+		t.Fatalf("Bundle failed: %s", err)
+	}
+	if len(modules) != 2 || modules["util"] == nil || modules["base"] == nil {
+		t.Fatalf("wrong modules bundled: %v", modules)
+	}
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := comp.Bytecode()
+	bytecode.Modules = modules
+
+	// The bundle must survive serialization and must not touch the
+	// filesystem once the sources are gone.
+	data, err := compiler.Serialize(bytecode)
+	if err != nil {
+		t.Fatalf("Serialize failed: %s", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	bytecode, err = compiler.Deserialize(data)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %s", err)
+	}
+
+	machine := New(bytecode.Instructions, bytecode.Constants, "main.jb")
+	machine.SetModules(bytecode.Modules)
+	if err := machine.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if err := testIntegerObject(242, machine.LastPoppedStackElem()); err != nil {
+		t.Errorf("wrong result: %s", err)
+	}
+}
+
+func TestBundleRunsAliasedModuleOnce(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "counter.jb"), []byte(`echo("loaded"); export let n = 1;`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	program := parse(`import { n } from "counter";
+import * as again from "counter.jb";
+n + again.n;`)
+
+	loader := &ModuleLoader{cache: map[string]*object.Hash{}, paths: []string{dir}}
+	modules, err := loader.Bundle(program)
+	if err != nil {
+		t.Fatalf("Bundle failed: %s", err)
+	}
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := comp.Bytecode()
+	bytecode.Modules = modules
+
+	data, err := compiler.Serialize(bytecode)
+	if err != nil {
+		t.Fatalf("Serialize failed: %s", err)
+	}
+	bytecode, err = compiler.Deserialize(data)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %s", err)
+	}
+	if bytecode.Modules["counter"] != bytecode.Modules["counter.jb"] {
+		t.Errorf("the two import paths of one file must share its module")
+	}
+
+	var out bytes.Buffer
+	machine := New(bytecode.Instructions, bytecode.Constants, "main.jb")
+	machine.SetModules(bytecode.Modules)
+	machine.SetOutput(&out, nil)
+	if err := machine.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if err := testIntegerObject(2, machine.LastPoppedStackElem()); err != nil {
+		t.Errorf("wrong result: %s", err)
+	}
+	if out.String() != "loaded\n" {
+		t.Errorf("want the module to run once, got output %q", out.String())
+	}
+}
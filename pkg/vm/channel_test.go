@@ -0,0 +1,132 @@
+package vm
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"jabline/pkg/object"
+)
+
+func TestChannelLifecycle(t *testing.T) {
+	tests := []vmTestCase{
+		{`let ch = make_chan(3); ch <- 1; [len(ch), cap(ch), cap(make_chan(0)), cap(make_chan())]`, inspected("[1, 3, 0, 10]")},
+		{`let ch = make_chan(2); ch <- 1; close(ch); let [a, ok] = <-ch; let [b, ok2] = <-ch; [a, ok, b, ok2]`, inspected("[1, true, null, false]")},
+		{`let ch = make_chan(); ch <- [1, 2]; let [a, b, c] = [<-ch, 3, 4]; a`, inspected("[1, 2]")},
+		{`let ch = make_chan(); ch <- 1; ch <- 2; close(ch); let s = 0; for (x in ch) { s = s + x; } s`, inspected("3")},
+		{`let ch = make_chan(0); fn produce(c) { c <- 1; c <- 2; close(c); } spawn produce(ch); let s = 0; for (x in ch) { s = s + x; } s`, inspected("3")},
+		{`let ch = make_chan(); close(ch); let r = null; try { ch <- 1; } catch (e: ValueError) { r = e.message; } r`, inspected("send on closed channel")},
+		{`let ch = make_chan(); close(ch); let r = null; try { close(ch); } catch (e: ValueError) { r = e.message; } r`, inspected("close of closed channel")},
+		{`let ch = make_chan(); close(ch); let r = null; try { send(ch, 1); } catch (e: ValueError) { r = e.message; } r`, inspected("send on closed channel")},
+		{`let ch = make_chan(); close(ch); let v = 0; let ok = true; [v, ok] = <-ch; ok`, inspected("false")},
+		{`let r = null; try { make_chan(-1); } catch (e: ValueError) { r = e.message; } r`, inspected("negative channel capacity: -1")},
+		{`fn f() { return 1; } let t = spawn f(); close(t); <-t`, inspected("null")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestChannelLifecycleErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`let ch = make_chan(); close(ch); ch <- 1;`, errorContaining("send on closed channel")},
+		{`let ch = make_chan(1); close(ch); select { case ch <- 1: }`, errorContaining("send on closed channel")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRemoteChannelClose(t *testing.T) {
+	machine := newTestVM(t, `fn drain(ch) { let [a, ok] = <-ch; let [b, ok2] = <-ch; return [a, ok, b, ok2]; } drain`)
+	if err := machine.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	peer := object.NewRemoteChannel(server)
+	go func() {
+		peer.Send(&object.Integer{Value: 1})
+		peer.Close()
+	}()
+	ch := object.NewRemoteChannel(client)
+
+	got := ExecuteClosureBridge(context.Background(), machine.StackTop(), []object.Object{ch})
+	if got.Inspect() != "[1, true, null, false]" {
+		t.Errorf("want [1, true, null, false], got %s", got.Inspect())
+	}
+	if err := peer.Send(&object.Integer{Value: 2}); err != object.ErrSendOnClosed {
+		t.Errorf("want %v after close, got %v", object.ErrSendOnClosed, err)
+	}
+	if err := peer.Close(); err != object.ErrCloseOfClosed {
+		t.Errorf("want %v on second close, got %v", object.ErrCloseOfClosed, err)
+	}
+}
+
+func TestRemoteChannelFrames(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	peer := object.NewRemoteChannel(server)
+	go func() {
+		key := &object.String{Value: "$close"}
+		peer.Send(&object.Hash{Pairs: map[object.HashKey]object.HashPair{key.HashKey(): {Key: key, Value: True}}})
+		server.Write([]byte("not json\n"))
+		server.Close()
+	}()
+	ch := object.NewRemoteChannel(client)
+
+	got, err := ch.Receive()
+	if err != nil || got.Inspect() != "{$close: true}" {
+		t.Fatalf("want the hash sent, got %v, %v", got, err)
+	}
+	// The error ending the connection stays for every later receive.
+	for range 2 {
+		if _, err := ch.Receive(); err == nil || err == io.EOF {
+			t.Errorf("want the connection error, got %v", err)
+		}
+	}
+	if ch.Err() == nil {
+		t.Errorf("want the connection error from Err")
+	}
+}
+
+func TestRemoteChannelWire(t *testing.T) {
+	// Values go out as bare JSON lines, and the close as a byte of its own.
+	client, server := net.Pipe()
+	defer server.Close()
+	ch := object.NewRemoteChannel(client)
+	key := &object.String{Value: "value"}
+	go func() {
+		ch.Send(&object.Integer{Value: 1})
+		ch.Send(&object.Hash{Pairs: map[object.HashKey]object.HashPair{key.HashKey(): {Key: key, Value: &object.Integer{Value: 2}}}})
+		ch.Close()
+	}()
+	want := "1\n{\"value\":2}\n\x04"
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(server, got); err != nil || string(got) != want {
+		t.Errorf("want %q on the wire, got %q, %v", want, got, err)
+	}
+
+	// A peer writing plain JSON lines is understood as is.
+	client, server = net.Pipe()
+	defer client.Close()
+	go func() {
+		server.Write([]byte("1\n{\"value\":2}\n"))
+		server.Close()
+	}()
+	ch = object.NewRemoteChannel(client)
+	var values []string
+	for {
+		value, err := ch.Receive()
+		if err != nil {
+			if err != io.EOF {
+				t.Errorf("want io.EOF at the end, got %v", err)
+			}
+			break
+		}
+		values = append(values, value.Inspect())
+	}
+	if len(values) != 2 || values[0] != "1" || values[1] != "{value: 2}" {
+		t.Errorf("want 1 and {value: 2}, got %v", values)
+	}
+}
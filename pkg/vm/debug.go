@@ -0,0 +1,150 @@
+package vm
+
+import (
+	"jabline/pkg/object"
+)
+
+// Debugger is notified by a VM as it runs. Its methods are called on the
+// goroutine running the VM, so a debugger pauses the program simply by not
+// returning, and may inspect the VM in the meantime.
+type Debugger interface {
+	// Step is called before each instruction of the program is executed.
+	// Modules and async calls run in VMs of their own and are not traced.
+	Step(vm *VM)
+	// Exception is called when err is raised by a throw statement or by
+	// the VM itself, before the stack is unwound. uncaught reports whether
+	// no try block will handle it.
+	Exception(vm *VM, err error, uncaught bool)
+}
+
+// Variable is a named slot of a paused frame.
+type Variable struct {
+	Name  string
+	Value object.Object
+}
+
+// SetDebugger installs d. It must be called before Run.
+func (vm *VM) SetDebugger(d Debugger) {
+	vm.debugger = d
+}
+
+// Depth is the number of active frames, the top-level program included.
+func (vm *VM) Depth() int {
+	return vm.framesIndex
+}
+
+// CallStack describes the active frames, outermost first, each positioned
+// at the instruction it is executing.
+func (vm *VM) CallStack() []CallFrame {
+	var trace []CallFrame
+	for i := 0; i < vm.framesIndex; i++ {
+		frm := vm.frames[i]
+		fnName := "<main>"
+		file := vm.filename
+		var line, column int
+
+		if frm.cl != nil && frm.cl.Fn != nil {
+			if frm.cl.Fn.SourceMap != nil {
+				pos, _ := frm.cl.Fn.SourceMap.Lookup(frm.ip)
+				line, column = pos.Line, pos.Column
+			}
+			if frm.cl.Fn.File != "" {
+				file = frm.cl.Fn.File
+			}
+			if frm.cl.Fn.Name != "" {
+				fnName = frm.cl.Fn.Name
+			} else if i > 0 {
+				fnName = "<anonymous>"
+			}
+		}
+
+		trace = append(trace, CallFrame{
+			Function: fnName,
+			File:     file,
+			Line:     line,
+			Column:   column,
+		})
+	}
+	return trace
+}
+
+// Position returns the file and line of the instruction about to execute,
+// or a zero line when the compiler recorded none for it.
+func (vm *VM) Position() (file string, line int) {
+	frm := vm.currentFrame()
+	file = vm.filename
+	if frm.cl.Fn.File != "" {
+		file = frm.cl.Fn.File
+	}
+	if pos, ok := frm.cl.Fn.SourceMap[frm.ip]; ok {
+		line = pos.Line
+	}
+	return file, line
+}
+
+// Locals returns the parameters and local variables of the frame at depth
+// (0 is the top-level program, which has none).
+func (vm *VM) Locals(depth int) []Variable {
+	if depth <= 0 || depth >= vm.framesIndex {
+		return nil
+	}
+	frm := vm.frames[depth]
+	fn := frm.cl.Fn
+
+	var vars []Variable
+	for i := 0; i < fn.NumLocals; i++ {
+		slot := frm.basePointer + i
+		if slot >= len(vm.stack) {
+			break
+		}
+		name := ""
+		if i < len(fn.LocalNames) {
+			name = fn.LocalNames[i]
+		}
+		if name == "" {
+			continue
+		}
+		vars = append(vars, Variable{Name: name, Value: orNull(vm.stack[slot])})
+	}
+	return vars
+}
+
+// Free returns the variables the closure of the frame at depth captured
+// from enclosing functions.
+func (vm *VM) Free(depth int) []Variable {
+	if depth <= 0 || depth >= vm.framesIndex {
+		return nil
+	}
+	cl := vm.frames[depth].cl
+
+	var vars []Variable
+	for i, value := range cl.Free {
+		if i < len(cl.Fn.FreeNames) {
+			vars = append(vars, Variable{Name: cl.Fn.FreeNames[i], Value: orNull(value)})
+		}
+	}
+	return vars
+}
+
+// Globals returns the global store of the frame at depth. Functions
+// imported from a module see the module's globals.
+func (vm *VM) Globals(depth int) []object.Object {
+	if depth < 0 || depth >= vm.framesIndex {
+		return nil
+	}
+	// A frame that switched to its closure's globals saved the caller's,
+	// so the store in use at depth is the one the next frame up saved.
+	for i := depth + 1; i < vm.framesIndex; i++ {
+		if saved := vm.frames[i].savedGlobals; saved != nil {
+			return saved
+		}
+	}
+	return vm.globals
+}
+
+func orNull(obj object.Object) object.Object {
+	if obj == nil {
+		return Null
+	}
+	return obj
+}
@@ -0,0 +1,41 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFinallyAndDefer(t *testing.T) {
+	const log = `let log = []; fn say(s) { log = push(log, s); } `
+	tests := []vmTestCase{
+		{log + `try { say("try"); } finally { say("finally"); } log`, inspected("[try, finally]")},
+		{log + `try { throw "x"; } catch (e) { say(e); } finally { say("finally"); } log`, inspected("[x, finally]")},
+		{log + `fn f() { try { throw "x"; } finally { say("finally"); } } try { f(); } catch (e) { say("outer " + e); } log`, inspected("[finally, outer x]")},
+		{log + `fn f() { try { throw "x"; } catch (e) { throw "y"; } finally { say("finally"); } } try { f(); } catch (e) { say(e); } log`, inspected("[finally, y]")},
+		{log + `fn f() { try { return "r"; } finally { say("finally"); } } say(f()); log`, inspected("[finally, r]")},
+		{log + `let i = 0; while (i < 3) { i = i + 1; try { if (i == 1) { continue; } break; } finally { say(i); } } log`, inspected("[1, 2]")},
+		{log + `try { try { throw "x"; } finally { say("inner"); } } catch (e) { say(e); } finally { say("outer"); } log`, inspected("[inner, x, outer]")},
+		{log + `try { 1 / 0; } catch (e) { say("caught"); } finally { say("finally"); } log`, inspected("[caught, finally]")},
+		{log + `fn f() { defer say(1); defer say(2); say("body"); return 3; } say(f()); log`, inspected("[body, 2, 1, 3]")},
+		{log + `fn f(n) { defer say(n); n = n + 1; return n; } say(f(1)); log`, inspected("[1, 2]")},
+		{log + `fn f() { defer say("deferred"); throw "x"; } try { f(); } catch (e) { say(e); } log`, inspected("[deferred, x]")},
+		{log + `fn fail() { throw "late"; } fn f() { defer say("first"); defer fail(); return 1; } try { f(); } catch (e) { say(e); } log`, inspected("[first, late]")},
+		{log + `struct F { name: string } fn (f F) close() { say("close " + f.name); } fn f() { let file = F{ name: "a" }; defer file.close(); say("use"); } f(); log`, inspected("[use, close a]")},
+		{log + `fn* g() { defer say("done"); yield 1; yield 2; } for (x in g()) { say(x); } log`, inspected("[1, 2, done]")},
+		{log + `fn f() { let n = 10; try { throw "x"; } catch (e) { n = n + 1; } return n; } f()`, inspected("11")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestDeferUncaught(t *testing.T) {
+	input := `let log = []; fn say(s) { log = push(log, s); } fn f() { defer say("deferred"); throw "boom"; } f()`
+	machine := newTestVM(t, input)
+	err := machine.Run()
+	if err == nil || !strings.Contains(err.Error(), "uncaught exception: boom") {
+		t.Fatalf("want uncaught exception, got %v", err)
+	}
+	if got := machine.globals[0].Inspect(); got != "[deferred]" {
+		t.Errorf("deferred call did not run before the error: log is %s", got)
+	}
+}
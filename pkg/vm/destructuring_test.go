@@ -0,0 +1,37 @@
+package vm
+
+import "testing"
+
+func TestDestructuring(t *testing.T) {
+	tests := []vmTestCase{
+		{`let [a, b, ...rest] = [1, 2, 3, 4]; a + b + len(rest)`, inspected("5")},
+		{`let [a, ...rest] = [1]; rest`, inspected("[]")},
+		{`let [a, b] = [1]; b`, inspected("null")},
+		{`let [_, second] = ["x", "y"]; second`, inspected("y")},
+		{`let { name, age: years = 0 } = {"name": "ann"}; ` + "`${name} ${years}`", inspected("ann 0")},
+		{`let { age: years = 0 } = {"age": 30}; years`, inspected("30")},
+		{`let { "full name": full } = {"full name": "Ann Lee"}; full`, inspected("Ann Lee")},
+		{`let [x, { y, z: [p, q] }] = [1, {"y": 2, "z": [3, 4]}]; x + y + p + q`, inspected("10")},
+		{`let [[a, b] = [5, 6]] = []; a * b`, inspected("30")},
+		{`struct User { name: string, age: int } let { name, age } = User{ name: "bo", age: 7 }; age`, inspected("7")},
+		{`let a = 1; let b = 2; [a, b] = [b, a]; a * 10 + b`, inspected("21")},
+		{`let n = 0; let m = 0; { n, m } = {"n": 3, "m": 4}; n + m`, inspected("7")},
+		{`fn area([w, h]) { return w * h; } area([3, 4])`, inspected("12")},
+		{`let f = fn({ x, y = 10 }) { x + y }; f({"x": 1})`, inspected("11")},
+		{`let f = ([a, b]) => a - b; f([9, 4])`, inspected("5")},
+		{`let s = 0; for ([a, b] in [[1, 2], [3, 4]]) { s = s + a * b; } s`, inspected("14")},
+		{`let s = ""; for (i, { k } in [{"k": "a"}, {"k": "b"}]) { s = s + k; } s`, inspected("ab")},
+		{`fn adder([n]) { return fn(x) { x + n }; } adder([5])(1)`, inspected("6")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`let [a] = {"a": 1};`, errorContaining("expected type array")},
+		{`let { a } = null;`, errorContaining("index operator not supported: NULL")},
+	}
+
+	runVmTests(t, tests)
+}
@@ -0,0 +1,90 @@
+package vm
+
+import (
+	"jabline/pkg/object"
+)
+
+// executeConstruct calls the variant of an algebraic enum, replacing it
+// and its arguments on the stack with the value they make. Fields with a
+// type only take values of that type.
+func (vm *VM) executeConstruct(variant *object.EnumVariant, numArgs int) error {
+	if numArgs != len(variant.Fields) {
+		return typeError("wrong number of arguments to %s.%s: want=%d, got=%d",
+			variant.Enum, variant.Name, len(variant.Fields), numArgs)
+	}
+
+	values := make([]object.Object, numArgs)
+	copy(values, vm.stack[vm.sp-numArgs:vm.sp])
+	for i, value := range values {
+		if variant.Types[i] == "" {
+			continue
+		}
+		expected := parseTypeSpec(variant.Types[i])
+		converted, ok, err := matchType(value, expected, nil)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return typeError("type error: field %s of %s.%s expects %s, got %s",
+				variant.Fields[i], variant.Enum, variant.Name, expected, describeType(value))
+		}
+		values[i] = converted
+	}
+
+	vm.sp = vm.sp - numArgs - 1
+	return vm.pushNew(&object.EnumValue{Variant: variant, Values: values})
+}
+
+// executeEnumValueIndex reads a field of an enum value, by name or by
+// position, or binds one of the methods declared on its enum.
+func (vm *VM) executeEnumValueIndex(value *object.EnumValue, index object.Object) error {
+	switch index := index.(type) {
+	case *object.Integer:
+		if index.Value < 0 || index.Value >= int64(len(value.Values)) {
+			return vm.push(Null)
+		}
+		return vm.push(value.Values[index.Value])
+
+	case *object.String:
+		if field, ok := value.Field(index.Value); ok {
+			return vm.push(field)
+		}
+		if method, ok := vm.methods[value.Variant.Enum][index.Value]; ok {
+			return vm.push(&object.BoundMethod{Receiver: value, Function: method})
+		}
+		return keyError("field or method '%s' not found in %s.%s", index.Value, value.Variant.Enum, value.Variant.Name)
+	}
+	return typeError("index operator not supported: %s[%s]", value.Type(), index.Type())
+}
+
+// sameVariant reports whether a and b are the same variant of the same
+// enum.
+func sameVariant(a, b *object.EnumVariant) bool {
+	return a == b || a.Enum == b.Enum && a.Name == b.Name
+}
+
+// enumValuesEqual compares two enum values field by field, with the rules
+// of a match pattern.
+func enumValuesEqual(a, b *object.EnumValue) bool {
+	if !sameVariant(a.Variant, b.Variant) || len(a.Values) != len(b.Values) {
+		return false
+	}
+	for i := range a.Values {
+		if !patternEquals(a.Values[i], b.Values[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// describeType names the type of val for an error message, giving the
+// struct of an instance and the enum of an enum value.
+func describeType(val object.Object) string {
+	switch v := val.(type) {
+	case *object.Instance:
+		return "instance of " + v.StructName
+	case *object.EnumValue:
+		return v.Variant.Enum + "." + v.Variant.Name
+	}
+	return string(val.Type())
+}
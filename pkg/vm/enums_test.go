@@ -0,0 +1,41 @@
+package vm
+
+import "testing"
+
+const resultEnum = `enum Result { Ok(value), Err(message: string) }
+enum State { Idle, Running(pid: int) }
+fn (r Result) unwrapOr(d) {
+    return match (r) { Result.Ok(v) => v, Result.Err(_) => d };
+}
+`
+
+func TestAlgebraicEnums(t *testing.T) {
+	tests := []vmTestCase{
+		{resultEnum + `Result.Ok([1, 2])`, inspected("Result.Ok([1, 2])")},
+		{resultEnum + `State.Idle`, inspected("State.Idle")},
+		{resultEnum + `Result.Err(message: "boom").message`, inspected("boom")},
+		{resultEnum + `State.Running(7)[0]`, inspected("7")},
+		{resultEnum + `Result.Ok(1) == Result.Ok(1.0)`, inspected("true")},
+		{resultEnum + `Result.Ok(1) != Result.Err("1")`, inspected("true")},
+		{resultEnum + `State.Running(1) == State.Running(2)`, inspected("false")},
+		{resultEnum + `Result.Ok(3).unwrapOr(0) + Result.Err("x").unwrapOr(4)`, inspected("7")},
+		{resultEnum + `match (State.Running(9)) { State.Idle => 0, State.Running(pid) if pid > 5 => pid * 2, _ => -1 }`, inspected("18")},
+		{resultEnum + `match (Result.Err("x")) { Result.Ok => "ok", Result.Err => "err" }`, inspected("err")},
+		{resultEnum + `fn f(s: State) { return s; } f(State.Idle)`, inspected("State.Idle")},
+		{resultEnum + `let n = 0; switch (State.Running(1)) { case State.Running(1): n = 1; } n`, inspected("1")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestAlgebraicEnumErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{resultEnum + `Result.Ok(1, 2)`, errorContaining("wrong number of arguments to Result.Ok: want=1, got=2")},
+		{resultEnum + `Result.Err(1)`, errorContaining("field message of Result.Err expects string, got INTEGER")},
+		{resultEnum + `Result.Ok(1).nope`, errorContaining("field or method 'nope' not found in Result.Ok")},
+		{resultEnum + `State.Running(pid: 1, id: 2)`, errorContaining("State.Running has no parameter named id")},
+		{resultEnum + `fn f(x: int) { return x; } f(State.Idle)`, errorContaining("expected type int, got State.Idle")},
+	}
+
+	runVmTests(t, tests)
+}
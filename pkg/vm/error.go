@@ -2,19 +2,26 @@ package vm
 
 import (
 	"fmt"
+	"jabline/pkg/object"
 	"strings"
 )
 
-type CallFrame struct {
-	Function string
-	File     string
-	Line     int
-	Column   int
-}
+// CallFrame is one call of a stack trace. Exceptions carry the same frames,
+// so a catch block can inspect where they were thrown.
+type CallFrame = object.StackFrame
 
 type RuntimeError struct {
 	Message    string
 	StackTrace []CallFrame
+	// Cause is the error the message was taken from, such as
+	// ErrInstructionLimit.
+	Cause error
+	// Exception is the value thrown, for an uncaught exception.
+	Exception object.Object
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Cause
 }
 
 func (e *RuntimeError) Error() string {
@@ -0,0 +1,182 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jabline/pkg/code"
+	"jabline/pkg/object"
+)
+
+// raisedError is an error raised by the VM together with the calls active
+// where it was raised, kept while frames make their deferred calls.
+type raisedError struct {
+	err   error
+	stack []CallFrame
+}
+
+func (e *raisedError) Error() string { return e.err.Error() }
+func (e *raisedError) Unwrap() error { return e.err }
+
+// kindError is an error raised by the VM that a catch clause receives as
+// an Error of the given kind.
+type kindError struct {
+	kind string
+	err  error
+}
+
+func (e *kindError) Error() string { return e.err.Error() }
+func (e *kindError) Unwrap() error { return e.err }
+
+func typeError(format string, a ...interface{}) error {
+	return &kindError{kind: object.TypeErrorKind, err: fmt.Errorf(format, a...)}
+}
+
+func indexError(format string, a ...interface{}) error {
+	return &kindError{kind: object.IndexErrorKind, err: fmt.Errorf(format, a...)}
+}
+
+func keyError(format string, a ...interface{}) error {
+	return &kindError{kind: object.KeyErrorKind, err: fmt.Errorf(format, a...)}
+}
+
+func arithmeticError(format string, a ...interface{}) error {
+	return &kindError{kind: object.ArithmeticErrorKind, err: fmt.Errorf(format, a...)}
+}
+
+func ioError(format string, a ...interface{}) error {
+	return &kindError{kind: object.IOErrorKind, err: fmt.Errorf(format, a...)}
+}
+
+// errorKinds classifies the sentinel errors a raised error may wrap.
+var errorKinds = []struct {
+	err  error
+	kind string
+}{
+	{ErrInstructionLimit, object.InstructionLimitErrorKind},
+	{ErrStackOverflow, object.StackOverflowErrorKind},
+	{ErrCallDepth, object.CallDepthErrorKind},
+	{ErrAllocationLimit, object.AllocationLimitErrorKind},
+	{context.DeadlineExceeded, object.TimeoutErrorKind},
+	{context.Canceled, object.CancelledErrorKind},
+	{object.ErrSendOnClosed, object.ValueErrorKind},
+	{object.ErrCloseOfClosed, object.ValueErrorKind},
+	{object.ErrUnlockOfUnlocked, object.ValueErrorKind},
+}
+
+// errorKind is the kind of the Error a catch clause receives for err: that
+// of a kindError, or of a sentinel it wraps. The others are plain Errors.
+func errorKind(err error) string {
+	var ke *kindError
+	if errors.As(err, &ke) {
+		return ke.kind
+	}
+	for _, k := range errorKinds {
+		if errors.Is(err, k.err) {
+			return k.kind
+		}
+	}
+	return ""
+}
+
+// exceptionFor is the exception a catch clause receives for an error
+// raised by the VM: an Error, unless it carries an exception thrown
+// elsewhere, as by a task of the parallel module.
+func exceptionFor(re *raisedError) object.Object {
+	if rtErr, ok := re.err.(*RuntimeError); ok && rtErr.Exception != nil {
+		return rtErr.Exception
+	}
+	return &object.Error{Message: re.err.Error(), Kind: errorKind(re.err), Stack: re.stack}
+}
+
+// captureStack records where exception is thrown, unless it was thrown
+// before and is only being rethrown.
+func (vm *VM) captureStack(exception object.Object) {
+	switch e := exception.(type) {
+	case *object.Error:
+		if e.Stack == nil {
+			e.Stack = vm.CallStack()
+		}
+	case *object.Instance:
+		if e.Stack == nil {
+			e.Stack = vm.CallStack()
+		}
+	}
+}
+
+// uncaught is the error Run returns for an exception no try block caught,
+// traced from where it was thrown.
+func (vm *VM) uncaught(exception object.Object) *RuntimeError {
+	msg := "uncaught exception: " + exception.Inspect()
+	for cause := causeOf(exception); cause != nil; cause = causeOf(cause) {
+		msg += "; caused by " + cause.Inspect()
+	}
+
+	var stack []CallFrame
+	switch e := exception.(type) {
+	case *object.Error:
+		stack = e.Stack
+	case *object.Instance:
+		stack = e.Stack
+	}
+	if stack == nil {
+		stack = vm.CallStack()
+	}
+	return &RuntimeError{Message: msg, StackTrace: stack, Exception: exception}
+}
+
+func causeOf(exception object.Object) object.Object {
+	switch e := exception.(type) {
+	case *object.Error:
+		return e.Cause
+	case *object.Instance:
+		if cause, ok := e.Fields["cause"]; ok && cause.Type() != object.NULL_OBJ {
+			return cause
+		}
+	}
+	return nil
+}
+
+// opIsType replaces the value on top of the stack with whether it has the
+// type named by the operand, as a typed catch clause tests the exception.
+func (vm *VM) opIsType(ins code.Instructions, ip *int) error {
+	typeIdx := int(code.ReadUint16(ins[*ip+1:]))
+	*ip += 2
+
+	frame := vm.currentFrame()
+	expected := parseTypeSpec(vm.constants[typeIdx].(*object.String).Value).resolve(frame.TypeArgs)
+	_, ok, err := matchType(vm.pop(), expected, nil)
+	if err != nil {
+		return err
+	}
+	return vm.push(nativeBoolToBooleanObj(ok))
+}
+
+// executeErrorIndex reads the properties of an error: its message, type,
+// cause and stack.
+func (vm *VM) executeErrorIndex(e *object.Error, name string) error {
+	switch name {
+	case "message":
+		return vm.push(&object.String{Value: e.Message})
+	case "type":
+		return vm.push(&object.String{Value: e.KindName()})
+	case "cause":
+		if e.Cause == nil {
+			return vm.push(Null)
+		}
+		return vm.push(e.Cause)
+	case "stack":
+		return vm.push(stackArray(e.Stack))
+	}
+	return keyError("field or method '%s' not found in %s", name, e.KindName())
+}
+
+// stackArray turns a stack trace into strings, the innermost call first as
+// in the traceback of an uncaught error.
+func stackArray(stack []CallFrame) *object.Array {
+	elements := make([]object.Object, 0, len(stack))
+	for i := len(stack) - 1; i >= 0; i-- {
+		elements = append(elements, &object.String{Value: stack[i].String()})
+	}
+	return &object.Array{Elements: elements}
+}
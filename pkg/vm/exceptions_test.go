@@ -0,0 +1,62 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStructuredExceptions(t *testing.T) {
+	const notFound = `struct NotFound { key: string } `
+	tests := []vmTestCase{
+		{`let r = null; try { throw IOError("disk"); } catch (e: TypeError) { r = "type"; } catch (e: IOError) { r = e.message; } r`, inspected("disk")},
+		{`let r = null; try { throw TimeoutError("slow"); } catch (e: IOError) { r = e.type; } r`, inspected("TimeoutError")},
+		{`let r = null; try { throw TimeoutError("slow"); } catch (e: Error) { r = "error"; } r`, inspected("error")},
+		{`let r = null; try { throw ValueError("v"); } catch (e: IOError | ValueError) { r = e.type; } r`, inspected("ValueError")},
+		{`let r = null; try { 1 / 0; } catch (e: ArithmeticError) { r = e.message; } r`, inspected("division by zero")},
+		{`let r = null; try { -"a"; } catch (e: TypeError) { r = e.type; } r`, inspected("TypeError")},
+		{`let r = null; struct P { x: int } try { P{ x: 1 }.y; } catch (e: KeyError) { r = e.type; } r`, inspected("KeyError")},
+		{`let r = null; let c = make_chan(1); close(c); try { c <- 1; } catch (e: ValueError) { r = e.message; } r`, inspected("send on closed channel")},
+		{`let r = null; try { throw "plain"; } catch (e: Error) { r = "error"; } catch (e) { r = e; } r`, inspected("plain")},
+		{`let r = null; try { throw Error("outer", IOError("inner")); } catch (e) { r = e.cause.type + ": " + e.cause.message; } r`, inspected("IOError: inner")},
+		{`let r = 1; try { throw Error("no cause"); } catch (e) { r = e.cause; } r`, inspected("null")},
+		{notFound + `let r = null; try { throw NotFound{ key: "k" }; } catch (e: IOError) { r = "io"; } catch (e: NotFound) { r = e.key; } r`, inspected("k")},
+		{`let r = null; fn f() { try { throw IOError("x"); } catch (e: TypeError) { return "type"; } } try { f(); } catch (e) { r = e.type; } r`, inspected("IOError")},
+		{`let log = []; fn f() { try { throw IOError("x"); } catch (e: TypeError) { } finally { log = push(log, "finally"); } } try { f(); } catch (e) { log = push(log, e.message); } log`, inspected("[finally, x]")},
+		{`let r = null; fn inner() { throw IOError("x"); } fn outer() { inner(); } try { outer(); } catch (e) { r = len(e.stack); } r`, inspected("3")},
+		{`let r = null; fn inner() { throw IOError("x"); } try { inner(); } catch (e) { r = e.stack[0]; } r`, inspected("inner (test.jb:1:28)")},
+		{`let r = null; fn inner() { 1 / 0; } try { inner(); } catch (e) { r = e.stack[0]; } r`, inspected("inner (test.jb:1:30)")},
+		{notFound + `let r = null; fn f() { throw NotFound{ key: "k" }; } try { f(); } catch (e) { r = len(e.stack); } r`, inspected("2")},
+		{`let r = null; fn f() { throw IOError("x"); } try { try { f(); } catch (e) { throw e; } } catch (e) { r = e.stack[0]; } r`, inspected("f (test.jb:1:24)")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBuiltinFailuresAreRaised(t *testing.T) {
+	const fs = `import * as fs from "_fs"; `
+	tests := []vmTestCase{
+		{fs + `let r = null; try { fs.readFile("/nonexistent/x"); r = "read"; } catch (e: IOError) { r = e.type; } r`, inspected("IOError")},
+		{fs + `let r = null; fn load() { return fs.readFile("/nonexistent/x"); } try { load(); } catch (e) { r = e.stack[0]; } r`, inspected("load (test.jb:1:72)")},
+		{fs + `fs.readFile("/nonexistent/x"); 1`, errorContaining("uncaught exception: IOError")},
+		{`import * as json from "_json"; let r = null; try { json.parse("{"); } catch (e: ValueError) { r = e.type; } r`, inspected("ValueError")},
+		{`let e = IOError("made, not raised"); e.message`, inspected("made, not raised")},
+		{`is_error(len(1, 2))`, inspected("true")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestUncaughtExceptionTrace(t *testing.T) {
+	input := `fn load() { throw IOError("cannot read"); } fn config() { try { load(); } catch (e: IOError) { throw Error("no config", e); } } config();`
+	err := newTestVM(t, input).Run()
+	rtErr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("want a RuntimeError, got %v", err)
+	}
+	if !strings.Contains(rtErr.Message, "no config; caused by IOError: cannot read") {
+		t.Errorf("cause missing from %q", rtErr.Message)
+	}
+	if len(rtErr.StackTrace) != 2 || rtErr.StackTrace[1].Function != "config" {
+		t.Errorf("want the trace of the throw in config, got %v", rtErr.StackTrace)
+	}
+}
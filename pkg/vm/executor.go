@@ -16,10 +16,15 @@ func (vm *VM) executeCall(numArgs int) error {
 
 	switch callee := callee.(type) {
 	case *object.BoundMethod:
-		// Replace the BoundMethod on the stack with its receiver.
-		// The receiver will now be at calleePos, and the arguments follow.
-		// The executeCallClosure will then treat the receiver as the first argument (local 0).
-		vm.stack[calleePos] = callee.Receiver
+		// Insert the receiver before the arguments, so that
+		// executeCallClosure treats it as the first argument (local 0) and
+		// the result replaces the BoundMethod on the stack.
+		if vm.sp >= len(vm.stack) {
+			return ErrStackOverflow
+		}
+		copy(vm.stack[calleePos+2:vm.sp+1], vm.stack[calleePos+1:vm.sp])
+		vm.stack[calleePos+1] = callee.Receiver
+		vm.sp++
 		// The underlying closure expects (Receiver + Args).
 		// So expected params = numArgs + 1
 		return vm.executeCallClosure(callee.Function, numArgs+1, nil)
@@ -27,25 +32,64 @@ func (vm *VM) executeCall(numArgs int) error {
 		return vm.executeCallClosure(callee, numArgs, nil)
 	case *object.InstantiatedFunction:
 		return vm.executeCallClosure(callee.Closure, numArgs, callee.TypeArgs)
+	case *object.EnumVariant:
+		return vm.executeConstruct(callee, numArgs)
+	case *object.NativeMethod:
+		return vm.callNativeMethod(callee, numArgs)
 	case *object.Builtin:
 		args := vm.stack[vm.sp-numArgs : vm.sp]
 
-		result := callee.Fn(args...)
+		vm.raceThroughChannels(args, vm.race.release)
+		var result object.Object
+		var call *nativeCall
+		if callee.FnContext != nil {
+			call = &nativeCall{vm: vm}
+			resume := vm.waiting()
+			result = callee.FnContext(call.context(), args...)
+			resume()
+		} else if callee.Writes {
+			unlock := vm.writeShared()
+			result = callee.Fn(args...)
+			unlock()
+		} else {
+			result = callee.Fn(args...)
+		}
+		vm.raceThroughChannels(args, vm.race.acquire)
 		vm.sp = vm.sp - numArgs - 1
+		if vm.ctx != nil && callee.FnContext != nil && vm.ctx.Err() != nil {
+			return vm.interrupted()
+		}
+		if call != nil && call.exception != nil {
+			return vm.uncaught(call.exception)
+		}
+		if call != nil && call.generator != nil {
+			return vm.next(call.generator, call.sent)
+		}
+
+		if e, ok := result.(*object.Error); ok && e.Raise {
+			// Once thrown, the error is an ordinary value to the catch.
+			e.Raise = false
+			vm.captureStack(e)
+			return vm.uncaught(e)
+		}
 
 		if result != nil {
-			vm.push(result)
-		} else {
-			vm.push(Null)
+			return vm.pushNew(result)
 		}
+		vm.push(Null)
 
 	default:
-		return fmt.Errorf("calling non-function: %T", callee)
+		return typeError("calling non-function: %T", callee)
 	}
 	return nil
 }
 
 func (vm *VM) executeCallClosure(cl *object.Closure, numArgs int, typeArgs map[string]string) error {
+	numArgs, err := vm.bindArguments(cl.Fn, numArgs)
+	if err != nil {
+		return err
+	}
+
 	if cl.Fn.IsAsync {
 		// Async functions return a Channel immediately
 		resultChannel := vm.executeAsyncCall(cl, numArgs)
@@ -53,13 +97,18 @@ func (vm *VM) executeCallClosure(cl *object.Closure, numArgs int, typeArgs map[s
 		return vm.push(resultChannel)
 	}
 
-	if numArgs != cl.Fn.NumParameters {
-		return fmt.Errorf("wrong number of arguments: want=%d, got=%d", cl.Fn.NumParameters, numArgs)
+	if cl.Fn.IsGenerator {
+		return vm.callGenerator(cl, numArgs, typeArgs)
 	}
 
+	if vm.framesIndex >= len(vm.frames) {
+		return fmt.Errorf("%w (%d)", ErrCallDepth, len(vm.frames))
+	}
 	frame := NewFrame(cl, vm.sp-numArgs)
+	if frame.basePointer+cl.Fn.NumLocals > len(vm.stack) {
+		return ErrStackOverflow
+	}
 	if typeArgs != nil {
-		fmt.Printf("DEBUG: executeCallClosure with typeArgs: %+v\n", typeArgs)
 		for k, v := range typeArgs {
 			frame.TypeArgs[k] = v
 		}
@@ -74,14 +123,6 @@ func (vm *VM) executeCallClosure(cl *object.Closure, numArgs int, typeArgs map[s
 	}
 	vm.pushFrame(frame)
 
-	fmt.Printf("DEBUG: New Frame pushed. BP:%d, SP:%d, NumLocals:%d\n", frame.basePointer, vm.sp, cl.Fn.NumLocals)
-	for i := 0; i < 5; i++ {
-		idx := frame.basePointer + i
-		if idx < vm.sp {
-			fmt.Printf("  stack[%d]: %s\n", idx, vm.stack[idx].Inspect())
-		}
-	}
-
 	vm.sp = frame.basePointer + cl.Fn.NumLocals
 	return nil
 }
@@ -90,7 +131,7 @@ func (vm *VM) pushClosure(constIndex, numFree int) error {
 	constant := vm.constants[constIndex]
 	function, ok := constant.(*object.CompiledFunction)
 	if !ok {
-		return fmt.Errorf("not a function: %+v", constant)
+		return typeError("not a function: %+v", constant)
 	}
 
 	free := make([]object.Object, numFree)
@@ -105,7 +146,7 @@ func (vm *VM) pushClosure(constIndex, numFree int) error {
 		Globals:   vm.globals,
 		Constants: vm.constants,
 	}
-	return vm.push(closure)
+	return vm.pushNew(closure)
 }
 
 func (vm *VM) executeIndexExpression(left, index object.Object) error {
@@ -124,7 +165,19 @@ func (vm *VM) executeIndexExpression(left, index object.Object) error {
 	if left.Type() == object.SERVICE_OBJ && index.Type() == object.STRING_OBJ {
 		return vm.executeServiceIndex(left, index)
 	}
-	return fmt.Errorf("index operator not supported: %s", left.Type())
+	if value, ok := left.(*object.EnumValue); ok {
+		return vm.executeEnumValueIndex(value, index)
+	}
+	if _, ok := nativeMethods[left.Type()]; ok && index.Type() == object.STRING_OBJ {
+		return vm.executeNativeIndex(left, index.(*object.String).Value)
+	}
+	if left.Type() == object.ERROR_OBJ && index.Type() == object.STRING_OBJ {
+		return vm.executeErrorIndex(left.(*object.Error), index.(*object.String).Value)
+	}
+	if left.Type() == object.GENERATOR_OBJ && index.Type() == object.STRING_OBJ {
+		return vm.executeGeneratorIndex(left.(*object.Generator), index.(*object.String).Value)
+	}
+	return typeError("index operator not supported: %s", left.Type())
 }
 
 func (vm *VM) executeStringIndex(str, index object.Object) error {
@@ -136,7 +189,7 @@ func (vm *VM) executeStringIndex(str, index object.Object) error {
 		return vm.push(Null)
 	}
 
-	return vm.push(&object.String{Value: string(s[idx])})
+	return vm.pushNew(&object.String{Value: string(s[idx])})
 }
 
 func (vm *VM) executeArrayIndex(array, index object.Object) error {
@@ -155,7 +208,7 @@ func (vm *VM) executeHashIndex(hash, index object.Object) error {
 	hashObject := hash.(*object.Hash)
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return fmt.Errorf("unusable as hash key: %s", index.Type())
+		return typeError("unusable as hash key: %s", index.Type())
 	}
 
 	pair, ok := hashObject.Pairs[key.HashKey()]
@@ -186,7 +239,12 @@ func (vm *VM) executeInstanceIndex(instance, index object.Object) error {
 		}
 	}
 
-	return fmt.Errorf("field or method '%s' not found in instance of '%s'", fieldName, instObj.StructName)
+	// A thrown instance without a field of that name exposes its stack trace
+	if fieldName == "stack" && instObj.Stack != nil {
+		return vm.push(stackArray(instObj.Stack))
+	}
+
+	return keyError("field or method '%s' not found in instance of '%s'", fieldName, instObj.StructName)
 }
 
 func (vm *VM) executeServiceIndex(service, index object.Object) error {
@@ -216,7 +274,7 @@ func (vm *VM) executeServiceIndex(service, index object.Object) error {
 		}
 	}
 
-	return fmt.Errorf("field or method '%s' not found in service '%s'", fieldName, serviceObj.Name)
+	return keyError("field or method '%s' not found in service '%s'", fieldName, serviceObj.Name)
 }
 
 func (vm *VM) buildArray(startIndex, endIndex int) object.Object {
@@ -240,7 +298,7 @@ func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
 
 		hashKey, ok := key.(object.Hashable)
 		if !ok {
-			return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			return nil, typeError("unusable as hash key: %s", key.Type())
 		}
 
 		hashedPairs[hashKey.HashKey()] = pair
@@ -263,7 +321,6 @@ func (vm *VM) opInstantiate(ins code.Instructions, ip *int) error {
 	switch o := obj.(type) {
 	case *object.Struct:
 		typeArgsMap := make(map[string]string)
-		fmt.Printf("DEBUG: opInstantiate Struct %s with typeArgs: %+v\n", o.Name, typeArgs)
 		for i, tp := range o.TypeParameters {
 			if i < len(typeArgs) {
 				typeArgsMap[tp] = typeArgs[i]
@@ -276,7 +333,6 @@ func (vm *VM) opInstantiate(ins code.Instructions, ip *int) error {
 		})
 	case *object.Closure:
 		typeArgsMap := make(map[string]string)
-		fmt.Printf("DEBUG: opInstantiate Closure %s with typeArgs: %+v. Fn.TypeParameters: %+v\n", o.Fn.Name, typeArgs, o.Fn.TypeParameters)
 		for i, tp := range o.Fn.TypeParameters {
 			if i < len(typeArgs) {
 				typeArgsMap[tp] = typeArgs[i]
@@ -292,55 +348,3 @@ func (vm *VM) opInstantiate(ins code.Instructions, ip *int) error {
 		return fmt.Errorf("instantiation on non-struct/func: %s", obj.Inspect())
 	}
 }
-
-func (vm *VM) opCheckType(ins code.Instructions, ip *int) error {
-	typeIdx := int(code.ReadUint16(ins[*ip+1:]))
-	*ip += 2
-
-	// Peek at the value on top of the stack (don't pop it!)
-	val := vm.stack[vm.sp-1]
-
-	expectedTypeStr := vm.constants[typeIdx].(*object.String).Value
-	frame := vm.currentFrame()
-	fmt.Printf("DEBUG: opCheckType expected:%s actual_val:%s BP:%d\n", expectedTypeStr, val.Inspect(), frame.basePointer)
-
-	// Resolver tipo si es un parámetro genérico
-	if frame.TypeArgs != nil {
-		if resolved, ok := frame.TypeArgs[expectedTypeStr]; ok {
-			fmt.Printf("DEBUG: Resolved %s to %s\n", expectedTypeStr, resolved)
-			expectedTypeStr = resolved
-		}
-	}
-
-	actualTypeStr := string(val.Type())
-
-	// Special mapping for standard names vs internal ObjectType
-	if expectedTypeStr == "int" && actualTypeStr == "INTEGER" {
-		return nil
-	}
-	if expectedTypeStr == "string" && actualTypeStr == "STRING" {
-		return nil
-	}
-	if expectedTypeStr == "bool" && actualTypeStr == "BOOLEAN" {
-		return nil
-	}
-	if expectedTypeStr == "float" && actualTypeStr == "FLOAT" {
-		return nil
-	}
-
-	// Complex types like Arrays, Maps, Functions could be checked further.
-	// For basic struct instance checking:
-	if actualTypeStr == "INSTANCE" {
-		inst := val.(*object.Instance)
-		if inst.StructName == expectedTypeStr {
-			return nil
-		}
-		return fmt.Errorf("type error: expected type %s, got instance of %s", expectedTypeStr, inst.StructName)
-	}
-
-	if expectedTypeStr != string(actualTypeStr) {
-		return fmt.Errorf("type error: expected type %s, got %s", expectedTypeStr, actualTypeStr)
-	}
-
-	return nil
-}
@@ -12,6 +12,26 @@ type Frame struct {
 	savedGlobals   []object.Object
 	savedConstants []object.Object
 	TypeArgs       map[string]string
+	// generator is set when the frame runs the body of a generator, which
+	// yields back to the frame below instead of returning to it.
+	generator *object.Generator
+	// iterates is set when the frame runs the iter method of a struct for
+	// a for-in loop, which iterates over the value it returns.
+	iterates bool
+	// nexts is set when the frame runs a generator resumed by next(), which
+	// returns the item it yields, or null once it is exhausted.
+	nexts bool
+
+	// defers are the calls deferred by the frame, each a callee followed by
+	// its arguments, made in reverse order when it returns or an exception
+	// leaves it. Meanwhile result holds the value being returned, or
+	// unwinding the exception.
+	defers    [][]object.Object
+	result    object.Object
+	unwinding *unwinding
+	// deferred is set when the frame makes a deferred call, whose result is
+	// dropped.
+	deferred bool
 }
 
 func NewFrame(cl *object.Closure, basePointer int) *Frame {
@@ -0,0 +1,68 @@
+package vm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"jabline/pkg/object"
+)
+
+func TestGenerators(t *testing.T) {
+	const count = `fn* count(n) { let i = 0; while (i < n) { yield i; i = i + 1; } } `
+	tests := []vmTestCase{
+		{count + `let out = []; for (x in count(4)) { out = push(out, x); } out`, inspected("[0, 1, 2, 3]")},
+		{count + `fn evens(src) { for (x in src) { if (x % 2 == 0) { yield x; } } } let out = []; for (x in evens(count(7))) { out = push(out, x); } out`, inspected("[0, 2, 4, 6]")},
+		{count + `let out = []; for (x in count(100)) { if (x == 3) { break; } out = push(out, x); } out`, inspected("[0, 1, 2]")},
+		{count + `let s = 0; for (x in count(5)) { if (x == 2) { continue; } s = s + x; } s`, inspected("8")},
+		{count + `let g = count(2); [next(g), next(g), next(g), next(g)]`, inspected("[0, 1, null, null]")},
+		{`fn echo2() { let got = yield "ready"; while (true) { got = yield got + "!"; } } let g = echo2(); [next(g), next(g, "a"), next(g, "b")]`, inspected("[ready, a!, b!]")},
+		{`struct P { x: int } fn (p P) dbl() { return p.x * 2; } fn* g() { let p = P{ x: 4 }; yield p.dbl(); } next(g())`, inspected("8")},
+		{`fn* g() { yield 1; throw "boom"; } let it = g(); let r = [next(it)]; try { next(it); } catch (e) { r = push(r, e); } push(r, next(it))`, inspected("[1, boom, null]")},
+		{`fn* g() { yield null; } let it = g(); let a = next(it); let r = [a, it.done]; let b = next(it); push(push(r, b), it.done)`, inspected("[null, false, null, true]")},
+		{`fn* g() { yield 1; yield null; yield 2; } let it = g(); let out = []; while (true) { let v = next(it); if (it.done) { break; } out = push(out, v); } out`, inspected("[1, null, 2]")},
+		{`fn* g() { yield 1; } g().value`, errorContaining("field or method 'value' not found in GENERATOR")},
+		{`fn* empty() {} let n = 0; for (x in empty()) { n = n + 1; } n`, inspected("0")},
+		{`fn safe() { try { yield 1; throw "boom"; } catch (e) { yield 2; } } let out = []; for (x in safe()) { out = push(out, x); } out`, inspected("[1, 2]")},
+		{`let gen = fn*(a, b) { yield a; yield b; }; let out = []; for (x in gen("x", "y")) { out = push(out, x); } out`, inspected("[x, y]")},
+		{count + `fn sum(g) { let s = 0; for (x in g) { s = s + x; } return s; } await spawn sum(count(5))`, inspected("10")},
+		{count + `let g = await spawn count(3); let s = 0; for (x in g) { s = s + x; } s`, inspected("3")},
+		{`let out = ""; for (c in "abc") { out = c + out; } out`, inspected("cba")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`fn bad() { yield 1; throw "fail"; } for (x in bad()) { x; }`, errorContaining("uncaught exception: fail")},
+		{`fn* g() { yield 1; } let it = g(); fn inner() { for (x in it) { x; } } fn outer() { for (x in it) { inner(); } } outer()`, nil},
+		{`for (x in 5) { x; }`, errorContaining("cannot iterate over INTEGER")},
+		{`fn* g() { yield 1; } g(1)`, errorContaining("wrong number of arguments: want=0, got=1")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestGeneratorBridge(t *testing.T) {
+	machine := newTestVM(t, `fn* count(n) { let i = 0; while (i < n) { yield i; i = i + 1; } } count`)
+	if err := machine.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	gen, ok := ExecuteClosureBridge(context.Background(), machine.StackTop(), []object.Object{&object.Integer{Value: 2}}).(*object.Generator)
+	if !ok {
+		t.Fatalf("calling a generator function did not return a generator")
+	}
+	var got []string
+	for {
+		value := ExecuteClosureBridge(context.Background(), gen, nil)
+		if gen.Done {
+			break
+		}
+		got = append(got, value.Inspect())
+	}
+	if strings.Join(got, ",") != "0,1" {
+		t.Errorf("want 0,1, got %v", got)
+	}
+}
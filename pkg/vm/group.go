@@ -0,0 +1,111 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"jabline/pkg/object"
+)
+
+// groupScope is a group statement being run. The tasks spawned in it run
+// with the context of the group, which the VM itself runs the body with,
+// and ctx is the context to go back to once the group ends.
+type groupScope struct {
+	group *object.TaskGroup
+	ctx   context.Context
+}
+
+// group is the task group new tasks join, if any.
+func (vm *VM) group() *object.TaskGroup {
+	if len(vm.groups) == 0 {
+		return nil
+	}
+	return vm.groups[len(vm.groups)-1].group
+}
+
+// opGroup starts a group statement, pushing its TaskGroup.
+func (vm *VM) opGroup() error {
+	g := object.NewTaskGroup(vm.ctx)
+	vm.groups = append(vm.groups, groupScope{group: g, ctx: vm.ctx})
+	vm.ctx = g.Context()
+	return vm.push(g)
+}
+
+// opGroupEnd ends a group statement once its tasks have ended. It replaces
+// the exception the body threw, or null, with the exception to throw: that
+// of the first task to fail, if it failed first, else that of the body. A
+// body that stopped only because the group was cancelled ends quietly.
+func (vm *VM) opGroupEnd() error {
+	scope := vm.groups[len(vm.groups)-1]
+	vm.groups = vm.groups[:len(vm.groups)-1]
+	vm.ctx = scope.ctx
+
+	exception := vm.pop()
+	if exception != Null {
+		scope.group.Fail(nil)
+	}
+	resume := vm.waiting()
+	failure := scope.group.Wait()
+	resume()
+
+	// The cancellation of the group stops at the group.
+	outerDone := scope.ctx != nil && scope.ctx.Err() != nil
+	if vm.exceeded != nil && errors.Is(vm.exceeded, context.Canceled) && !outerDone {
+		vm.exceeded = nil
+		if e, ok := exception.(*object.Error); ok && strings.HasPrefix(e.Message, "execution interrupted") {
+			exception = Null
+		}
+	}
+
+	if failure != nil {
+		return vm.push(failure)
+	}
+	return vm.push(exception)
+}
+
+// startTask counts a task started by the VM in its group, returning the
+// function to call with the error the task ends with, if any.
+func (vm *VM) startTask() (end func(err error)) {
+	g := vm.group()
+	if g == nil {
+		return func(error) {}
+	}
+	g.Go()
+	return func(err error) {
+		defer g.Done()
+		// Tasks ending after the group is cancelled were most likely
+		// stopped by it; the failure that cancelled it is kept.
+		if err != nil && !g.Cancelled() {
+			g.Fail(taskException(err))
+		}
+	}
+}
+
+// taskException is the exception a task that failed with err threw, with
+// the stack trace of the task.
+func taskException(err error) object.Object {
+	var rtErr *RuntimeError
+	if errors.As(err, &rtErr) {
+		if rtErr.Exception != nil {
+			return rtErr.Exception
+		}
+		e := &object.Error{Message: rtErr.Message, Stack: rtErr.StackTrace}
+		if rtErr.Cause != nil {
+			e.Kind = errorKind(rtErr.Cause)
+		}
+		return e
+	}
+	return &object.Error{Message: err.Error(), Kind: errorKind(err)}
+}
+
+// callTaskGroupMethod runs a method of a task group.
+func (vm *VM) callTaskGroupMethod(g *object.TaskGroup, name string) (object.Object, error) {
+	switch name {
+	case "cancel":
+		g.Cancel()
+	case "cancelled":
+		return nativeBoolToBooleanObj(g.Cancelled()), nil
+	}
+	return Null, nil
+}
@@ -0,0 +1,48 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTaskGroups(t *testing.T) {
+	tests := []vmTestCase{
+		{`let n = make_chan(); fn put(v) { n <- v; } group { spawn put(1); spawn put(2); } len(n)`, inspected("2")},
+		{`fn f() { throw ValueError("boom"); } let r = null; try { group { spawn f(); } } catch (e: ValueError) { r = e.message; } r`, inspected("boom")},
+		{`fn f() { return 1 / 0; } let r = null; try { group { spawn f(); } } catch (e) { r = [e.type, len(e.stack)]; } r`, inspected("[ArithmeticError, 2]")},
+		{`fn f() { throw "first"; } fn g() { recv(make_chan()); } let r = null; try { group { spawn g(); spawn f(); } } catch (e) { r = e; } r`, inspected("first")},
+		{`let ch = make_chan(); let ready = make_chan(); fn worker() { ready <- 1; for (v in ch) { v; } } fn failer() { <-ready; throw "failed"; } let r = null; try { group { spawn worker(); spawn failer(); } } catch (e) { r = e; } r`, inspected("failed")},
+		{`fn f() { throw "task"; } let r = null; try { group { spawn f(); <-make_chan(); } } catch (e) { r = e; } r`, inspected("task")},
+		{`fn f() { <-make_chan(); } let r = null; try { group { spawn f(); throw "body"; } } catch (e) { r = e; } r`, inspected("body")},
+		{`fn f() { while (true) { } } group g { spawn f(); g.cancel(); } "ended"`, inspected("ended")},
+		{`fn f() { <-make_chan(); } let c = null; group g { spawn f(); g.cancel(); c = g.cancelled(); <-make_chan(); } c`, inspected("true")},
+		{`fn f() { let c = make_chan(); c <- 1; c <- 2; c <- 3; c <- 4; c <- 5; c <- 6; c <- 7; c <- 8; c <- 9; c <- 10; c <- 11; } group g { spawn f(); g.cancel(); } "ended"`, inspected("ended")},
+		{`fn w() { return 1; } fn f() { group { spawn w(); return "left"; } } f()`, inspected("left")},
+		{`fn w() { throw "late"; } fn f() { for (let i = 0; i < 3; i = i + 1) { group { spawn w(); break; } } } let r = null; try { f(); } catch (e) { r = e; } r`, inspected("late")},
+		{`fn f() { throw "inner"; } let r = null; try { group { group { spawn f(); } } } catch (e) { r = e; } r`, inspected("inner")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestTaskGroupUncaught(t *testing.T) {
+	machine := newTestVM(t, "fn inner() { return 1 / 0; }\nfn task() { return inner(); }\ngroup {\nspawn task();\n}")
+	err := machine.Run()
+	if err == nil {
+		t.Fatal("expected the error of the task")
+	}
+	rtErr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("expected a RuntimeError, got %T", err)
+	}
+	if !strings.Contains(rtErr.Message, "division by zero") {
+		t.Errorf("unexpected message %q", rtErr.Message)
+	}
+	var functions []string
+	for _, frame := range rtErr.StackTrace {
+		functions = append(functions, frame.Function)
+	}
+	if got := strings.Join(functions, " "); got != "<main> task inner" {
+		t.Errorf("expected the trace of the task, got %s", got)
+	}
+}
@@ -2,52 +2,117 @@ package vm
 
 import (
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"jabline/modules"
+	"jabline/pkg/ast"
 	"jabline/pkg/compiler"
 	"jabline/pkg/lexer"
 	"jabline/pkg/object"
 	"jabline/pkg/parser"
-	"jabline/pkg/stdlib" // Add this import
-	"jabline/pkg/symbol"
+	"jabline/pkg/stdlib"
 )
 
+// StdlibPrefix marks module paths served from the embedded standard library
+// rather than the filesystem.
+const StdlibPrefix = "<stdlib>/"
+
+// ModulePaths are extra search roots set with --module-path. They are tried
+// after the working directory and before JABLINE_PATH.
+var ModulePaths []string
+
+// ModuleLoader is shared by a program and every module it imports, so it
+// also carries what they have in common: Go-provided modules and the
+// writers program output goes to.
 type ModuleLoader struct {
-	cache map[string]*object.Hash
-	paths []string
+	cache   map[string]*object.Hash
+	paths   []string
+	stdlib  fs.FS
+	bundle  map[string]*compiler.Bytecode
+	natives map[string]*object.Hash
+
+	stdout io.Writer
+	stderr io.Writer
 }
 
+// NewModuleLoader searches, in order: the working directory and its modules
+// and lib subdirectories, ModulePaths, the JABLINE_PATH entries and finally
+// the standard library embedded in the binary.
 func NewModuleLoader() *ModuleLoader {
 	cwd, _ := os.Getwd()
+	paths := []string{
+		cwd,
+		filepath.Join(cwd, "modules"),
+		filepath.Join(cwd, "lib"),
+	}
+	paths = append(paths, ModulePaths...)
+	for _, path := range filepath.SplitList(os.Getenv("JABLINE_PATH")) {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+
 	return &ModuleLoader{
-		cache: make(map[string]*object.Hash),
-		paths: []string{
-			cwd,
-			filepath.Join(cwd, "modules"),
-			filepath.Join(cwd, "lib"),
-		},
+		cache:   make(map[string]*object.Hash),
+		paths:   paths,
+		stdlib:  modules.FS,
+		natives: make(map[string]*object.Hash),
+		stdout:  os.Stdout,
+		stderr:  os.Stderr,
+	}
+}
+
+// AddPath appends a search root. It is tried after the ones configured
+// through --module-path and JABLINE_PATH.
+func (ml *ModuleLoader) AddPath(path string) {
+	ml.paths = append(ml.paths, path)
+}
+
+// RegisterNative makes module importable under name. It takes precedence
+// over every other way of resolving that name.
+func (ml *ModuleLoader) RegisterNative(name string, module *object.Hash) {
+	if ml.natives == nil {
+		ml.natives = make(map[string]*object.Hash)
 	}
+	ml.natives[name] = module
 }
 
+// SetBundle registers modules compiled ahead of time by `jabline build`.
+// They are looked up by import path before the filesystem is searched.
+func (ml *ModuleLoader) SetBundle(modules map[string]*compiler.Bytecode) {
+	ml.bundle = modules
+}
+
+// Load imports the module name, running it the first time.
 func (ml *ModuleLoader) Load(name string) (*object.Hash, error) {
-	// Check for native modules mapping (e.g. "modules/strings" -> "_strings")
-	var nativeName string
-	if strings.HasPrefix(name, "modules/") {
-		nativeName = "_" + strings.TrimPrefix(name, "modules/")
-	} else if strings.HasPrefix(name, "_") {
-		// Allow direct access to "_" prefixed native modules if needed
-		nativeName = name
+	return ml.load(name, nil)
+}
+
+// load imports name for importer, whose context and limits also apply to
+// running the module.
+func (ml *ModuleLoader) load(name string, importer *VM) (*object.Hash, error) {
+	if module, ok := ml.natives[name]; ok {
+		return module, nil
+	}
+	if nativeMod := nativeModule(name); nativeMod != nil {
+		return nativeMod, nil
 	}
 
-	if nativeName != "" {
-		if nativeMod := stdlib.GetNativeModule(nativeName); nativeMod != nil {
-			return nativeMod, nil
+	if bytecode, ok := ml.bundle[name]; ok {
+		if module, ok := ml.cache[bytecode.File]; ok {
+			return module, nil
 		}
-		// If it has modules/ prefix but not found in stdlib, it might be an error or a physical folder
-		// but typically we expect them in stdlib.
+		module, err := ml.instantiate(name, bytecode, importer)
+		if err != nil {
+			return nil, err
+		}
+		ml.cache[bytecode.File] = module
+		return module, nil
 	}
 
 	absPath, err := ml.resolvePath(name)
@@ -59,51 +124,101 @@ func (ml *ModuleLoader) Load(name string) (*object.Hash, error) {
 		return module, nil
 	}
 
-	content, err := ioutil.ReadFile(absPath)
+	bytecode, err := ml.compileModule(name, absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	module, err := ml.instantiate(name, bytecode, importer)
+	if err != nil {
+		return nil, err
+	}
+
+	ml.cache[absPath] = module
+
+	return module, nil
+}
+
+// nativeModule maps import paths such as "modules/strings" or "_strings" to
+// the Go implemented module, if there is one.
+func nativeModule(name string) *object.Hash {
+	var nativeName string
+	if strings.HasPrefix(name, "modules/") {
+		nativeName = "_" + strings.TrimPrefix(name, "modules/")
+	} else if strings.HasPrefix(name, "_") {
+		// Allow direct access to "_" prefixed native modules if needed
+		nativeName = name
+	}
+
+	if nativeName == "" {
+		return nil
+	}
+	// If it has modules/ prefix but not found in stdlib, it might be a
+	// physical folder instead.
+	return stdlib.GetNativeModule(nativeName)
+}
+
+// compileModule parses and compiles the module at absPath. Its functions
+// are attributed to that file in tracebacks.
+func (ml *ModuleLoader) compileModule(name, absPath string) (*compiler.Bytecode, error) {
+	bytecode, _, err := ml.compileModuleProgram(name, absPath)
+	return bytecode, err
+}
+
+func (ml *ModuleLoader) compileModuleProgram(name, absPath string) (*compiler.Bytecode, *ast.Program, error) {
+	content, err := ml.readModule(absPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read module '%s': %s", absPath, err)
+		return nil, nil, fmt.Errorf("failed to read module '%s': %s", absPath, err)
 	}
 
 	l := lexer.New(string(content))
 	p := parser.New(l)
 	prog := p.ParseProgram()
 	if len(p.Errors()) > 0 {
-		return nil, fmt.Errorf("parse errors in module '%s': %v", name, p.Errors())
+		return nil, nil, fmt.Errorf("parse errors in module '%s': %v", name, p.Errors())
 	}
 
 	comp := compiler.New()
 	err = comp.Compile(prog)
 	if err != nil {
-		return nil, fmt.Errorf("compilation error in module '%s': %s", name, err)
+		return nil, nil, fmt.Errorf("compilation error in module '%s': %s", name, err)
 	}
 
 	bytecode := comp.Bytecode()
+	bytecode.File = absPath
+	for _, c := range bytecode.Constants {
+		if fn, ok := c.(*object.CompiledFunction); ok {
+			fn.File = absPath
+		}
+	}
+	return bytecode, prog, nil
+}
 
-	moduleVM := NewWithLoader(bytecode.Instructions, bytecode.Constants, absPath, ml)
+// instantiate runs a compiled module and collects its exported globals.
+func (ml *ModuleLoader) instantiate(name string, bytecode *compiler.Bytecode, importer *VM) (*object.Hash, error) {
+	moduleVM := NewWithLoader(bytecode.Instructions, bytecode.Constants, bytecode.File, ml)
+	moduleVM.SetSourceMap(bytecode.SourceMap)
+	if importer != nil {
+		importer.inherit(moduleVM)
+	}
 
-	err = moduleVM.Run()
+	err := moduleVM.Run()
 	if err != nil {
-		return nil, fmt.Errorf("runtime error in module '%s': %s", name, err)
+		return nil, fmt.Errorf("runtime error in module '%s': %w", name, err)
 	}
 
 	exports := make(map[object.HashKey]object.HashPair)
-	for name, sym := range bytecode.SymbolTable.GetStore() { // Renamed 'symbol' to 'sym'
-		if sym.Scope == symbol.GlobalScope && sym.IsExported { // Only export marked symbols
-			if sym.Index < len(moduleVM.globals) {
-				val := moduleVM.globals[sym.Index]
-				if val != nil {
-					key := &object.String{Value: name}
-					exports[key.HashKey()] = object.HashPair{Key: key, Value: val}
-				}
+	for exportName, index := range bytecode.Exports {
+		if index < len(moduleVM.globals) {
+			val := moduleVM.globals[index]
+			if val != nil {
+				key := &object.String{Value: exportName}
+				exports[key.HashKey()] = object.HashPair{Key: key, Value: val}
 			}
 		}
 	}
 
-	moduleHash := &object.Hash{Pairs: exports}
-
-	ml.cache[absPath] = moduleHash
-
-	return moduleHash, nil
+	return &object.Hash{Pairs: exports}, nil
 }
 
 func (ml *ModuleLoader) resolvePath(name string) (string, error) {
@@ -130,5 +245,21 @@ func (ml *ModuleLoader) resolvePath(name string) (string, error) {
 		}
 	}
 
-	return "", fmt.Errorf("module '%s' not found in paths %v", name, ml.paths)
+	if ml.stdlib != nil {
+		// "modules/testing/assert" names the same file as "testing/assert".
+		stdPath := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(filename)), "modules/")
+		if _, err := fs.Stat(ml.stdlib, stdPath); err == nil {
+			return StdlibPrefix + stdPath, nil
+		}
+	}
+
+	return "", fmt.Errorf("module '%s' not found in paths %v or the standard library", name, ml.paths)
+}
+
+// readModule reads a path returned by resolvePath.
+func (ml *ModuleLoader) readModule(path string) ([]byte, error) {
+	if strings.HasPrefix(path, StdlibPrefix) && ml.stdlib != nil {
+		return fs.ReadFile(ml.stdlib, strings.TrimPrefix(path, StdlibPrefix))
+	}
+	return ioutil.ReadFile(path)
 }
@@ -0,0 +1,48 @@
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jabline/pkg/object"
+)
+
+func TestModuleLoaderSearchPaths(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "envmod.jb"), []byte(`export let answer = 42;`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("JABLINE_PATH", dir)
+
+	loader := NewModuleLoader()
+
+	module, err := loader.Load("envmod")
+	if err != nil {
+		t.Fatalf("module on JABLINE_PATH was not found: %s", err)
+	}
+	key := &object.String{Value: "answer"}
+	if err := testIntegerObject(42, module.Pairs[key.HashKey()].Value); err != nil {
+		t.Errorf("wrong export: %s", err)
+	}
+
+	// The package directory has no modules/ folder, so these can only come
+	// from the embedded standard library.
+	for _, name := range []string{"net/http/status", "modules/net/http/status"} {
+		path, err := loader.resolvePath(name)
+		if err != nil {
+			t.Fatalf("resolvePath(%q) failed: %s", name, err)
+		}
+		if path != StdlibPrefix+"net/http/status.jb" {
+			t.Errorf("resolvePath(%q) = %q, want the embedded file", name, path)
+		}
+	}
+	if _, err := loader.Load("net/http/status"); err != nil {
+		t.Fatalf("embedded module failed to load: %s", err)
+	}
+
+	if _, err := loader.resolvePath("does/not/exist"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
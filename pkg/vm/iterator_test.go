@@ -0,0 +1,61 @@
+package vm
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"jabline/pkg/object"
+)
+
+func TestForInIterators(t *testing.T) {
+	const bag = `struct Bag { items: Array } fn (b Bag) iter() { return b.items; } `
+	const span = `struct Span { lo: int, hi: int } fn* (s Span) iter() { let n = s.lo; while (n < s.hi) { yield n; n = n + 1; } } `
+	tests := []vmTestCase{
+		{`let out = []; for (k, v in {"a": 1}) { out = push(out, k); out = push(out, v); } out`, inspected("[a, 1]")},
+		{`let s = 0; for (k in {1: "x", 2: "y"}) { s = s + k; } s`, inspected("3")},
+		{`let s = 0; for (k, v in {"a": 1, "b": 2, "c": 3}) { s = s + v; } s`, inspected("6")},
+		{`let out = []; for (i, x in ["a", "b"]) { out = push(out, i); out = push(out, x); } out`, inspected("[0, a, 1, b]")},
+		{`let out = []; for (c in "héllo") { out = push(out, c); } out`, inspected("[h, é, l, l, o]")},
+		{`let out = []; for (i, c in "é!") { out = push(out, i); } out`, inspected("[0, 2]")},
+		{bag + `let out = []; for (x in Bag{ items: [1, 2] }) { out = push(out, x); } out`, inspected("[1, 2]")},
+		{bag + `let s = 0; for (i, x in Bag{ items: [5, 6] }) { s = s + i * x; } s`, inspected("6")},
+		{span + `let out = []; for (n in Span{ lo: 2, hi: 5 }) { out = push(out, n); } out`, inspected("[2, 3, 4]")},
+		{span + `fn total(s) { let t = 0; for (n in s) { t = t + n; } return t; } total(Span{ lo: 0, hi: 4 })`, inspected("6")},
+		{`async fn work() { return 42; } let out = []; for (v in work()) { out = push(out, v); } out`, inspected("[42]")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestForInIteratorErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`struct P { x: int } for (x in P{ x: 1 }) { x; }`, errorContaining("cannot iterate over P: it has no iter method")},
+		{`fn* g() { yield 1; } for (k, v in g()) { k; }`, errorContaining("cannot iterate over GENERATOR with two variables")},
+		{`struct P { x: int } fn (p P) iter() { return p.x; } for (x in P{ x: 1 }) { x; }`, errorContaining("cannot iterate over INTEGER")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestForInRemoteChannel(t *testing.T) {
+	machine := newTestVM(t, `fn sum(ch) { let s = 0; for (x in ch) { s = s + x; } return s; } sum`)
+	if err := machine.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	client, server := net.Pipe()
+	go func() {
+		peer := object.NewRemoteChannel(server)
+		for i := 1; i <= 3; i++ {
+			peer.Send(&object.Integer{Value: int64(i)})
+		}
+		server.Close()
+	}()
+	ch := object.NewRemoteChannel(client)
+
+	got := ExecuteClosureBridge(context.Background(), machine.StackTop(), []object.Object{ch})
+	if got.Inspect() != "6" {
+		t.Errorf("want 6, got %s", got.Inspect())
+	}
+}
@@ -0,0 +1,175 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"jabline/pkg/object"
+)
+
+// Errors raised when a program runs out of one of its Limits. Scripts
+// catch each as a LimitError of its own kind, such as StackOverflowError;
+// Go callers can tell them apart with errors.Is on the error returned by Run. Cancellation of the context
+// given to RunContext wraps ctx.Err() instead.
+var (
+	ErrInstructionLimit = errors.New("instruction limit exceeded")
+	ErrStackOverflow    = errors.New("stack overflow")
+	ErrCallDepth        = errors.New("maximum call depth exceeded")
+	ErrAllocationLimit  = errors.New("allocation limit exceeded")
+)
+
+// contextCheckInterval is the number of instructions executed between two
+// checks of the context.
+const contextCheckInterval = 1024
+
+// limitGrace is the number of instructions a program may still execute
+// after catching a cancellation or an exhausted instruction budget, enough
+// to run a catch block. The error is then raised again past any handler.
+const limitGrace = 1024
+
+// Limits bounds the resources a program may use. The budgets are shared
+// with the modules it imports and the async calls it starts.
+type Limits struct {
+	// MaxInstructions caps the number of instructions executed. Zero means
+	// no limit.
+	MaxInstructions int64
+	// StackSize and MaxFrames size the value and call stacks. Zero keeps
+	// StackSize and MaxFrames.
+	StackSize int
+	MaxFrames int
+	// MaxAllocation caps, in approximate bytes, the strings, arrays, hashes,
+	// instances and closures the program creates. Memory is counted when
+	// allocated and never given back. Zero means no limit.
+	MaxAllocation int64
+}
+
+// usage is what a VM and the VMs it started have consumed so far.
+type usage struct {
+	instructions atomic.Int64
+	allocated    atomic.Int64
+}
+
+// RunContext runs the program like Run, raising an error that wraps
+// ctx.Err() once ctx is done. Modules and async calls started by the
+// program inherit the context.
+func (vm *VM) RunContext(ctx context.Context) error {
+	vm.ctx = ctx
+	return vm.Run()
+}
+
+// SetLimits bounds the resources the program may use. It must be called
+// before Run.
+func (vm *VM) SetLimits(limits Limits) {
+	vm.limits = limits
+
+	if size := limits.StackSize; size > 0 && size != len(vm.stack) {
+		stack := make([]object.Object, size)
+		copy(stack, vm.stack[:min(vm.sp, size)])
+		vm.stack = stack
+	}
+	if size := limits.MaxFrames; size > 0 && size != len(vm.frames) {
+		frames := make([]*Frame, size)
+		copy(frames, vm.frames[:min(vm.framesIndex, size)])
+		vm.frames = frames
+	}
+}
+
+// inherit hands the context, limits, budgets, shared lock and task group
+// on to a VM started for a module, an async call or a spawned task.
+func (vm *VM) inherit(child *VM) {
+	child.ctx = vm.ctx
+	child.SetLimits(vm.limits)
+	if vm.usage != nil {
+		child.usage = vm.usage
+	}
+	child.shared = vm.shared
+	child.race = vm.race
+	child.scheduled = vm.scheduled
+	if g := vm.group(); g != nil {
+		child.groups = []groupScope{{group: g}}
+	}
+}
+
+// checkLimits runs before every instruction and raises cancellation and
+// instruction budget errors.
+func (vm *VM) checkLimits() error {
+	if vm.exceeded != nil {
+		vm.grace--
+		if vm.grace < 0 {
+			return vm.fatal(vm.exceeded)
+		}
+		return nil
+	}
+
+	var err error
+	if max := vm.limits.MaxInstructions; max > 0 && vm.usage != nil && vm.usage.instructions.Add(1) > max {
+		err = fmt.Errorf("%w (%d)", ErrInstructionLimit, max)
+	} else if vm.ctx != nil {
+		if vm.steps%contextCheckInterval == 0 {
+			if ctxErr := vm.ctx.Err(); ctxErr != nil {
+				err = fmt.Errorf("execution interrupted: %w", ctxErr)
+			}
+		}
+		vm.steps++
+	}
+	if err == nil {
+		return nil
+	}
+
+	vm.exceeded = err
+	vm.grace = limitGrace
+	return vm.raise(err)
+}
+
+// interrupted is the error raised by an instruction that stopped waiting
+// because the context is done. Like a cancellation seen by checkLimits, it
+// is raised again past the handlers after the grace period.
+func (vm *VM) interrupted() error {
+	err := fmt.Errorf("execution interrupted: %w", vm.ctx.Err())
+	vm.exceeded = err
+	vm.grace = limitGrace
+	return err
+}
+
+// allocate charges obj against the allocation limit.
+func (vm *VM) allocate(obj object.Object) error {
+	max := vm.limits.MaxAllocation
+	if max <= 0 || vm.usage == nil {
+		return nil
+	}
+	if vm.usage.allocated.Add(sizeOf(obj)) > max {
+		return fmt.Errorf("%w (%d bytes)", ErrAllocationLimit, max)
+	}
+	return nil
+}
+
+// pushNew pushes an object the VM has just created, charging it against
+// the allocation limit.
+func (vm *VM) pushNew(obj object.Object) error {
+	if err := vm.allocate(obj); err != nil {
+		return err
+	}
+	return vm.push(obj)
+}
+
+// sizeOf estimates the memory held by obj itself, not counting the values
+// it refers to.
+func sizeOf(obj object.Object) int64 {
+	switch o := obj.(type) {
+	case *object.String:
+		return 16 + int64(len(o.Value))
+	case *object.Array:
+		return 24 + 16*int64(len(o.Elements))
+	case *object.Hash:
+		return 48 + 64*int64(len(o.Pairs))
+	case *object.Instance:
+		return 48 + 48*int64(len(o.Fields))
+	case *object.EnumValue:
+		return 32 + 16*int64(len(o.Values))
+	case *object.Closure:
+		return 48 + 16*int64(len(o.Free))
+	}
+	return 0
+}
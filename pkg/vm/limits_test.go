@@ -0,0 +1,132 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"jabline/pkg/object"
+)
+
+func newLimitedVM(t *testing.T, input string, limits Limits) *VM {
+	t.Helper()
+
+	machine := newTestVM(t, input)
+	machine.SetLimits(limits)
+	return machine
+}
+
+func TestLimitErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		limits Limits
+		want   error
+	}{
+		{
+			name:   "instructions",
+			input:  `while (true) {}`,
+			limits: Limits{MaxInstructions: 10000},
+			want:   ErrInstructionLimit,
+		},
+		{
+			name:   "instructions in a generator",
+			input:  `fn* g() { while (true) {} } next(g());`,
+			limits: Limits{MaxInstructions: 10000},
+			want:   ErrInstructionLimit,
+		},
+		{
+			name:   "call depth",
+			input:  `fn down(n) { return down(n + 1); } down(0);`,
+			limits: Limits{MaxFrames: 64},
+			want:   ErrCallDepth,
+		},
+		{
+			name:   "stack",
+			input:  `fn down(n) { return down(n + 1); } down(0);`,
+			limits: Limits{StackSize: 128},
+			want:   ErrStackOverflow,
+		},
+		{
+			name:   "allocation",
+			input:  `let s = ""; while (true) { s = s + "xxxxxxxx"; }`,
+			limits: Limits{MaxAllocation: 1 << 16},
+			want:   ErrAllocationLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		err := newLimitedVM(t, tt.input, tt.limits).Run()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: wrong error. want=%v, got=%v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestRunContextCancels(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := newLimitedVM(t, `while (true) {}`, Limits{}).RunContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wrong error. want=%v, got=%v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestLimitErrorsAreCatchable(t *testing.T) {
+	const down = `fn down(n) { return down(n + 1); } `
+	tests := []struct {
+		input  string
+		limits Limits
+		want   string
+	}{
+		{`try { while (true) {} } catch (err: InstructionLimitError) { caught = err.type; }`, Limits{MaxInstructions: 10000}, "InstructionLimitError"},
+		{down + `try { down(0); } catch (err: StackOverflowError) { caught = err.type; }`, Limits{StackSize: 128}, "StackOverflowError"},
+		{down + `try { down(0); } catch (err: CallDepthError) { caught = err.type; }`, Limits{MaxFrames: 64}, "CallDepthError"},
+		{`let s = ""; try { while (true) { s = s + "xxxxxxxx"; } } catch (err: AllocationLimitError) { caught = err.type; }`, Limits{MaxAllocation: 1 << 16}, "AllocationLimitError"},
+		// Each is a LimitError, and only matches its own kind.
+		{`try { while (true) {} } catch (err: StackOverflowError) { caught = "wrong"; } catch (err: LimitError) { caught = err.type; }`, Limits{MaxInstructions: 10000}, "InstructionLimitError"},
+	}
+
+	for _, tt := range tests {
+		machine := newLimitedVM(t, `let caught = ""; `+tt.input+` caught;`, tt.limits)
+		if err := machine.Run(); err != nil {
+			t.Fatalf("%s: vm error: %s", tt.want, err)
+		}
+		if err := testStringObject(tt.want, machine.StackTop()); err != nil {
+			t.Errorf("%s: %s", tt.want, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	machine := newLimitedVM(t, `let caught = ""; try { while (true) {} } catch (err: CancelledError) { caught = err.type; } caught;`, Limits{})
+	if err := machine.RunContext(ctx); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if err := testStringObject("CancelledError", machine.StackTop()); err != nil {
+		t.Error(err)
+	}
+
+	// Once caught, the program only gets a short grace period.
+	machine = newLimitedVM(t, `try { while (true) {} } catch (err) { while (true) {} }`, Limits{MaxInstructions: 10000})
+	if err := machine.Run(); !errors.Is(err, ErrInstructionLimit) {
+		t.Fatalf("wrong error. want=%v, got=%v", ErrInstructionLimit, err)
+	}
+}
+
+func TestBridgeInheritsLimits(t *testing.T) {
+	machine := newLimitedVM(t, `fn spin() { while (true) {} } spin`, Limits{MaxInstructions: 10000})
+	if err := machine.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	ctx := (&nativeCall{vm: machine}).context()
+	result := ExecuteClosureBridge(ctx, machine.StackTop(), nil)
+	errObj, ok := result.(*object.Error)
+	if !ok || !strings.Contains(errObj.Message, ErrInstructionLimit.Error()) {
+		t.Fatalf("want the instruction limit error, got %s", result.Inspect())
+	}
+}
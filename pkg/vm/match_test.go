@@ -0,0 +1,37 @@
+package vm
+
+import "testing"
+
+func TestMatchExpressions(t *testing.T) {
+	const decls = `enum Color { Red, Green, Blue } struct User { name: string, age: int } `
+	tests := []vmTestCase{
+		{`match (2) { 1 => "one", 2 => "two", _ => "many" }`, inspected("two")},
+		{`match (7) { 1 => "one" }`, inspected("null")},
+		{`match ("b") { "a" => 1, "b" => 2 }`, inspected("2")},
+		{`match (null) { null => "none", _ => "some" }`, inspected("none")},
+		{`match (2.0) { 2 => "int two" }`, inspected("int two")},
+		{`match (int8(3)) { 1..5 => "low", _ => "high" }`, inspected("low")},
+		{`match (5) { 1..5 => "excluded", 1..=5 => "included" }`, inspected("included")},
+		{`match (-3) { -5..0 => "negative" }`, inspected("negative")},
+		{`match ("x") { 1..5 => "number", _ => "other" }`, inspected("other")},
+		{`match ([1, 2, 3]) { [] => "empty", [a] => a, [a, ...rest] => rest }`, inspected("[2, 3]")},
+		{`match ([1]) { [a, ...rest] => rest }`, inspected("[]")},
+		{`match ([1, 2]) { [a] => "one", [a, b] => a + b }`, inspected("3")},
+		{`match ([1, [2, 3]]) { [1, [x, y]] => x * y }`, inspected("6")},
+		{`match ("s") { [] => "array", _ => "other" }`, inspected("other")},
+		{`match ({"status": 200, "body": "ok"}) { {"status": 404} => "missing", {"status": 200, "body": b} => b }`, inspected("ok")},
+		{`match ({"a": 1}) { {"b": x} => x, _ => "no b" }`, inspected("no b")},
+		{decls + `match (User{ name: "ann", age: 20 }) { User { name, age: 0..18 } => "minor", User { name } => name }`, inspected("ann")},
+		{decls + `match (Color.Green) { Color.Red => "red", Color.Green => "green", Color.Blue => "blue" }`, inspected("green")},
+		{`match (15) { n if n > 10 => "big", n => "small" }`, inspected("big")},
+		{`match (5) { n if n > 10 => "big", n => "small" }`, inspected("small")},
+		{`match (3) { n => { let d = n * 2; d + 1 } }`, inspected("7")},
+		{`match (3) { n => { let d = n * 2; } }`, inspected("null")},
+		{`let n = "outer"; let r = match (1) { n => n }; n`, inspected("outer")},
+		{`let n = "outer"; fn f(x) { return match (x) { [n] => n }; } f([1]) + 1`, inspected("2")},
+		{`match (1) { 1 => match ("a") { "a" => "inner" } }`, inspected("inner")},
+		{`fn f(xs) { return match (xs) { [] => 0, [x, ...rest] => x + f(rest) }; } f([1, 2, 3, 4])`, inspected("10")},
+	}
+
+	runVmTests(t, tests)
+}
@@ -0,0 +1,260 @@
+package vm
+
+import (
+	"fmt"
+
+	"jabline/pkg/code"
+	"jabline/pkg/object"
+)
+
+func isNumber(obj object.Object) bool {
+	if _, ok := object.IntegerBits(obj); ok {
+		return true
+	}
+	_, ok := object.FloatValue(obj)
+	return ok
+}
+
+// numericType returns the type an operation on left and right is carried
+// out in, at least one of them being a sized number. A plain Integer or
+// Float takes the type of the other operand; two different sized types do
+// not mix.
+func numericType(left, right object.Object) (object.ObjectType, error) {
+	lt, rt := left.Type(), right.Type()
+	switch {
+	case lt == rt:
+		return lt, nil
+	case lt == object.INTEGER_OBJ:
+		return rt, nil
+	case rt == object.INTEGER_OBJ:
+		return lt, nil
+	case lt == object.FLOAT_OBJ && object.IsFloatType(rt):
+		return rt, nil
+	case rt == object.FLOAT_OBJ && object.IsFloatType(lt):
+		return lt, nil
+	}
+	return "", typeError("mismatched types %s and %s", lt, rt)
+}
+
+// integerOperand returns obj as an operand of the integer type t. A plain
+// Integer must fit in t.
+func integerOperand(obj object.Object, t object.ObjectType) (uint64, error) {
+	bits, ok := object.IntegerBits(obj)
+	if !ok {
+		return 0, typeError("mismatched types %s and %s", obj.Type(), t)
+	}
+	if obj.Type() != t && !object.FitsInteger(t, int64(bits)) {
+		return 0, fmt.Errorf("%d overflows %s", int64(bits), t)
+	}
+	return bits, nil
+}
+
+// floatOperand returns obj as an operand of the float type t. A plain
+// Integer or Float must be within the range of t.
+func floatOperand(obj object.Object, t object.ObjectType) (float64, error) {
+	var v float64
+	if f, ok := object.FloatValue(obj); ok {
+		v = f
+	} else if i, ok := obj.(*object.Integer); ok {
+		v = float64(i.Value)
+	} else {
+		return 0, typeError("mismatched types %s and %s", obj.Type(), t)
+	}
+	if obj.Type() != t && !object.FitsFloat(t, v) {
+		return 0, fmt.Errorf("%g overflows %s", v, t)
+	}
+	return v, nil
+}
+
+func (vm *VM) executeSizedOperation(op code.Opcode, left, right object.Object) error {
+	if op == code.OpShiftLeft || op == code.OpShiftRight {
+		return vm.executeShift(op, left, right)
+	}
+
+	t, err := numericType(left, right)
+	if err != nil {
+		return err
+	}
+
+	if object.IsFloatType(t) {
+		l, err := floatOperand(left, t)
+		if err != nil {
+			return err
+		}
+		r, err := floatOperand(right, t)
+		if err != nil {
+			return err
+		}
+
+		var result float64
+		switch op {
+		case code.OpAdd:
+			result = l + r
+		case code.OpSub:
+			result = l - r
+		case code.OpMul:
+			result = l * r
+		case code.OpDiv:
+			if r == 0 {
+				return arithmeticError("division by zero")
+			}
+			result = l / r
+		default:
+			return fmt.Errorf("unknown %s operator: %d", t, op)
+		}
+		return vm.push(object.NewFloat(t, result))
+	}
+
+	l, err := integerOperand(left, t)
+	if err != nil {
+		return err
+	}
+	r, err := integerOperand(right, t)
+	if err != nil {
+		return err
+	}
+	_, signed, _ := object.IntegerKind(t)
+
+	// The operands are extended to 64 bits, so computing in 64 bits and
+	// truncating the result wraps around at the width of t.
+	var result uint64
+	switch op {
+	case code.OpAdd:
+		result = l + r
+	case code.OpSub:
+		result = l - r
+	case code.OpMul:
+		result = l * r
+	case code.OpDiv, code.OpMod:
+		if r == 0 {
+			return arithmeticError("division by zero")
+		}
+		switch {
+		case !signed && op == code.OpDiv:
+			result = l / r
+		case !signed:
+			result = l % r
+		case op == code.OpDiv:
+			result = uint64(int64(l) / int64(r))
+		default:
+			result = uint64(int64(l) % int64(r))
+		}
+	case code.OpBitAnd:
+		result = l & r
+	case code.OpBitOr:
+		result = l | r
+	case code.OpBitXor:
+		result = l ^ r
+	default:
+		return fmt.Errorf("unknown %s operator: %d", t, op)
+	}
+	return vm.push(object.NewInteger(t, result))
+}
+
+// executeShift shifts an integer of any type by a non-negative count of any
+// integer type. The result has the type of the shifted value.
+func (vm *VM) executeShift(op code.Opcode, left, right object.Object) error {
+	l, ok1 := object.IntegerBits(left)
+	count, ok2 := object.IntegerBits(right)
+	if !ok1 || !ok2 {
+		return typeError("unsupported types for shift: %s %s", left.Type(), right.Type())
+	}
+	if _, signed, _ := object.IntegerKind(right.Type()); signed && int64(count) < 0 {
+		return arithmeticError("negative shift count %d", int64(count))
+	}
+
+	t := left.Type()
+	var result uint64
+	switch _, signed, _ := object.IntegerKind(t); {
+	case op == code.OpShiftLeft:
+		result = l << count
+	case signed:
+		result = uint64(int64(l) >> count)
+	default:
+		result = l >> count
+	}
+	return vm.push(object.NewInteger(t, result))
+}
+
+func (vm *VM) executeSizedComparison(op code.Opcode, left, right object.Object) error {
+	t, err := numericType(left, right)
+	if err != nil {
+		return err
+	}
+
+	var equal, greater bool
+	if object.IsFloatType(t) {
+		l, err := floatOperand(left, t)
+		if err != nil {
+			return err
+		}
+		r, err := floatOperand(right, t)
+		if err != nil {
+			return err
+		}
+		equal, greater = l == r, l > r
+	} else {
+		l, err := integerOperand(left, t)
+		if err != nil {
+			return err
+		}
+		r, err := integerOperand(right, t)
+		if err != nil {
+			return err
+		}
+		if _, signed, _ := object.IntegerKind(t); signed {
+			equal, greater = l == r, int64(l) > int64(r)
+		} else {
+			equal, greater = l == r, l > r
+		}
+	}
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObj(equal))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObj(!equal))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObj(greater))
+	default:
+		return fmt.Errorf("unknown %s comparison operator: %d", t, op)
+	}
+}
+
+// numbersEqual compares two numbers for a switch case. Numbers of types
+// that cannot be compared are simply not equal.
+func numbersEqual(a, b object.Object) bool {
+	t, err := numericType(a, b)
+	if err != nil {
+		return false
+	}
+	if object.IsFloatType(t) {
+		l, err1 := floatOperand(a, t)
+		r, err2 := floatOperand(b, t)
+		return err1 == nil && err2 == nil && l == r
+	}
+	l, err1 := integerOperand(a, t)
+	r, err2 := integerOperand(b, t)
+	return err1 == nil && err2 == nil && l == r
+}
+
+// coerceNumber converts a plain Integer or Float to the sized type t, for a
+// value declared with that type. Integers must fit in t and floats can only
+// become sized floats.
+func coerceNumber(obj object.Object, t object.ObjectType) (object.Object, error) {
+	if object.IsFloatType(t) {
+		v, err := floatOperand(obj, t)
+		if err != nil {
+			return nil, err
+		}
+		return object.NewFloat(t, v), nil
+	}
+	if obj.Type() != object.INTEGER_OBJ {
+		return nil, typeError("mismatched types %s and %s", obj.Type(), t)
+	}
+	bits, err := integerOperand(obj, t)
+	if err != nil {
+		return nil, err
+	}
+	return object.NewInteger(t, bits), nil
+}
@@ -0,0 +1,60 @@
+package vm
+
+import (
+	"testing"
+
+	"jabline/pkg/object"
+)
+
+func TestSizedArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{`int8(5) + int8(3)`, typed{object.INT8_OBJ, "8"}},
+		{`int8(127) + int8(1)`, typed{object.INT8_OBJ, "-128"}},
+		{`int8(-128) / int8(-1)`, typed{object.INT8_OBJ, "-128"}},
+		{`int8(-7) % int8(3)`, typed{object.INT8_OBJ, "-1"}},
+		{`uint8(3) - uint8(5)`, typed{object.UINT8_OBJ, "254"}},
+		{`uint8(250) + 10`, typed{object.UINT8_OBJ, "4"}},
+		{`2 * int16(20000)`, typed{object.INT16_OBJ, "-25536"}},
+		{`uint32(1) << 31`, typed{object.UINT32_OBJ, "2147483648"}},
+		{`uint32(1) << int8(32)`, typed{object.UINT32_OBJ, "0"}},
+		{`int8(-16) >> 2`, typed{object.INT8_OBJ, "-4"}},
+		{`uint8(240) >> 4`, typed{object.UINT8_OBJ, "15"}},
+		{`uint16(4080) & uint16(255) | uint16(1)`, typed{object.UINT16_OBJ, "241"}},
+		{`~uint8(0)`, typed{object.UINT8_OBJ, "255"}},
+		{`-uint8(1)`, typed{object.UINT8_OBJ, "255"}},
+		{`-int8(-128)`, typed{object.INT8_OBJ, "-128"}},
+		{`uint64(-1) / uint64(2)`, typed{object.UINT64_OBJ, "9223372036854775807"}},
+		{`float32(0.1) + 0.2`, typed{object.FLOAT32_OBJ, "0.3"}},
+		{`float64(1.5) * 2`, typed{object.FLOAT64_OBJ, "3"}},
+		{`int16(int8(-5))`, typed{object.INT16_OBJ, "-5"}},
+		{`uint8(int16(300))`, typed{object.UINT8_OBJ, "44"}},
+		{`int32(3.9)`, typed{object.INT32_OBJ, "3"}},
+		{`float32(uint8(200))`, typed{object.FLOAT32_OBJ, "200"}},
+		{`uint8(255) > uint8(1)`, typed{object.BOOLEAN_OBJ, "true"}},
+		{`uint64(-1) > uint64(1)`, typed{object.BOOLEAN_OBJ, "true"}},
+		{`int64(-1) < 0`, typed{object.BOOLEAN_OBJ, "true"}},
+		{`int8(3) == 3`, typed{object.BOOLEAN_OBJ, "true"}},
+		{`float32(1.5) != 1.5`, typed{object.BOOLEAN_OBJ, "false"}},
+		{`len({int8(1): "a", 1: "b", int8(1): "c"})`, typed{object.INTEGER_OBJ, "2"}},
+		{`len({0.0: "a", -0.0: "b", 0.5: "c"})`, typed{object.INTEGER_OBJ, "2"}},
+		{`let x: uint8 = 200; x + 55`, typed{object.UINT8_OBJ, "255"}},
+		{`fn f(a: int16) { return a; } f(7)`, typed{object.INT16_OBJ, "7"}},
+		{`let x = int8(1); x = 100; x`, typed{object.INT8_OBJ, "100"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestSizedArithmeticErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`let a = [int8(1)]; let b = [int16(2)]; a[0] + b[0]`, errorContaining("mismatched types INT8 and INT16")},
+		{`let a = [int8(1)]; a[0] * 1.5`, errorContaining("mismatched types INT8 and FLOAT")},
+		{`let big = 300; uint8(1) + big`, errorContaining("300 overflows UINT8")},
+		{`let n = -1; uint8(1) == n`, errorContaining("-1 overflows UINT8")},
+		{`uint8(1) / uint8(0)`, errorContaining("division by zero")},
+		{`int8(1) << -1`, errorContaining("negative shift count -1")},
+		{`let x = int8(1); let n = 200; x = n`, errorContaining("type error: 200 overflows INT8")},
+	}
+
+	runVmTests(t, tests)
+}
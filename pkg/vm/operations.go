@@ -18,13 +18,17 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 		return vm.executeBinaryStringOperation(op, left, right)
 	}
 
+	if (object.IsSizedNumber(left) || object.IsSizedNumber(right)) && isNumber(left) && isNumber(right) {
+		return vm.executeSizedOperation(op, left, right)
+	}
+
 	if left.Type() == object.FLOAT_OBJ || right.Type() == object.FLOAT_OBJ ||
 		(left.Type() == object.INTEGER_OBJ && right.Type() == object.FLOAT_OBJ) ||
 		(left.Type() == object.FLOAT_OBJ && right.Type() == object.INTEGER_OBJ) {
 		return vm.executeBinaryFloatOperation(op, left, right)
 	}
 
-	return fmt.Errorf("unsupported types for binary operation: %s %s", left.Type(), right.Type())
+	return typeError("unsupported types for binary operation: %s %s", left.Type(), right.Type())
 }
 
 func (vm *VM) extractFloat64(obj object.Object) (float64, bool) {
@@ -43,7 +47,7 @@ func (vm *VM) executeBinaryFloatOperation(op code.Opcode, left, right object.Obj
 	rightVal, ok2 := vm.extractFloat64(right)
 
 	if !ok1 || !ok2 {
-		return fmt.Errorf("unsupported types for float binary operation: %s %s", left.Type(), right.Type())
+		return typeError("unsupported types for float binary operation: %s %s", left.Type(), right.Type())
 	}
 
 	var result float64
@@ -57,7 +61,7 @@ func (vm *VM) executeBinaryFloatOperation(op code.Opcode, left, right object.Obj
 		result = leftVal * rightVal
 	case code.OpDiv:
 		if rightVal == 0 {
-			return fmt.Errorf("division by zero")
+			return arithmeticError("division by zero")
 		}
 		result = leftVal / rightVal
 	default:
@@ -82,12 +86,12 @@ func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left, right object.O
 		result = leftValue * rightValue
 	case code.OpDiv:
 		if rightValue == 0 {
-			return fmt.Errorf("division by zero")
+			return arithmeticError("division by zero")
 		}
 		result = leftValue / rightValue
 	case code.OpMod:
 		if rightValue == 0 {
-			return fmt.Errorf("division by zero")
+			return arithmeticError("division by zero")
 		}
 		result = leftValue % rightValue
 	case code.OpBitAnd:
@@ -126,7 +130,7 @@ func (vm *VM) executeBinaryStringOperation(op code.Opcode, left, right object.Ob
 		rightVal = right.Inspect()
 	}
 
-	return vm.push(&object.String{Value: leftVal + rightVal})
+	return vm.pushNew(&object.String{Value: leftVal + rightVal})
 }
 
 func (vm *VM) executeComparison(op code.Opcode) error {
@@ -137,6 +141,10 @@ func (vm *VM) executeComparison(op code.Opcode) error {
 		return vm.executeIntegerComparison(op, left, right)
 	}
 
+	if (object.IsSizedNumber(left) || object.IsSizedNumber(right)) && isNumber(left) && isNumber(right) {
+		return vm.executeSizedComparison(op, left, right)
+	}
+
 	if left.Type() == object.FLOAT_OBJ || right.Type() == object.FLOAT_OBJ {
 		return vm.executeFloatComparison(op, left, right)
 	}
@@ -145,6 +153,17 @@ func (vm *VM) executeComparison(op code.Opcode) error {
 		return vm.executeStringComparison(op, left, right)
 	}
 
+	if l, ok := left.(*object.EnumValue); ok {
+		if r, ok := right.(*object.EnumValue); ok {
+			switch op {
+			case code.OpEqual:
+				return vm.push(nativeBoolToBooleanObj(enumValuesEqual(l, r)))
+			case code.OpNotEqual:
+				return vm.push(nativeBoolToBooleanObj(!enumValuesEqual(l, r)))
+			}
+		}
+	}
+
 	switch op {
 	case code.OpEqual:
 		return vm.push(nativeBoolToBooleanObj(right == left))
@@ -160,7 +179,7 @@ func (vm *VM) executeFloatComparison(op code.Opcode, left, right object.Object)
 	rightVal, ok2 := vm.extractFloat64(right)
 
 	if !ok1 || !ok2 {
-		return fmt.Errorf("unsupported types for float comparison: %s %s", left.Type(), right.Type())
+		return typeError("unsupported types for float comparison: %s %s", left.Type(), right.Type())
 	}
 
 	switch op {
@@ -228,21 +247,30 @@ func (vm *VM) executeMinusOperator() error {
 		return vm.push(&object.Integer{Value: -op.Value})
 	case *object.Float:
 		return vm.push(&object.Float{Value: -op.Value})
-	default:
-		return fmt.Errorf("unsupported type for negation: %s", operand.Type())
+	case *object.Float32:
+		return vm.push(&object.Float32{Value: -op.Value})
+	case *object.Float64:
+		return vm.push(&object.Float64{Value: -op.Value})
+	}
+
+	// Negating the most negative value of a signed type, or any non-zero
+	// unsigned value, wraps around.
+	if bits, ok := object.IntegerBits(operand); ok {
+		return vm.push(object.NewInteger(operand.Type(), -bits))
 	}
+	return typeError("unsupported type for negation: %s", operand.Type())
 }
 
 func (vm *VM) executeBitNotOperator() error {
 	operand := vm.pop()
 
-	if operand.Type() != object.INTEGER_OBJ {
-		return fmt.Errorf("unsupported type for bitwise not: %s", operand.Type())
+	bits, ok := object.IntegerBits(operand)
+	if !ok {
+		return typeError("unsupported type for bitwise not: %s", operand.Type())
 	}
 
-	value := operand.(*object.Integer).Value
 	// In Go, ^x is bitwise not (complement).
-	return vm.push(&object.Integer{Value: ^value})
+	return vm.push(object.NewInteger(operand.Type(), ^bits))
 }
 
 func isTruthy(obj object.Object) bool {
@@ -1,16 +1,32 @@
 package vm
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"jabline/pkg/code"
 	"jabline/pkg/object"
 )
 
-func ExecuteClosureBridge(closureObj object.Object, args []object.Object) object.Object {
+func ExecuteClosureBridge(ctx context.Context, closureObj object.Object, args []object.Object) object.Object {
+	if gen, ok := closureObj.(*object.Generator); ok {
+		return resumeGeneratorBridge(ctx, gen, args)
+	}
+
 	callee, ok := closureObj.(*object.Closure)
 	if !ok {
 		return &object.Error{Message: fmt.Sprintf("bridge expected closure, got %s", closureObj.Type())}
 	}
+	args, err := bindArgumentList(callee.Fn, args)
+	if err != nil {
+		return &object.Error{Message: err.Error()}
+	}
+	if callee.Fn.IsGenerator {
+		gen := object.NewGenerator(callee, args, nil)
+		gen.Globals, gen.Constants = callee.Globals, callee.Constants
+		return gen
+	}
 
 	// Create a new VM for this execution (isolated request)
 	// We need constants and globals.
@@ -27,6 +43,19 @@ func ExecuteClosureBridge(closureObj object.Object, args []object.Object) object
 		framesIndex: 0,
 		// Loader and Filename are harder to get, assume defaults or Closure should carry them?
 		// For now, empty filename is fine.
+		ctx:   ctx,
+		usage: &usage{},
+	}
+	if call, ok := ctx.Value(nativeCallKey{}).(*nativeCall); ok {
+		// Called for a builtin of a program, such as the handler of a
+		// request, the closure runs within the limits and budgets of the
+		// program, as a task of its own.
+		caller := call.vm
+		caller.share()
+		caller.inherit(newVM)
+		newVM.ctx = ctx
+		newVM.race = caller.race.fork()
+		newVM.scheduled = false
 	}
 
 	// Push a dummy object at stack[0] so that OpReturnValue has a place to write to
@@ -35,7 +64,7 @@ func ExecuteClosureBridge(closureObj object.Object, args []object.Object) object
 
 	// Push arguments
 	for _, arg := range args {
-		if newVM.sp >= StackSize {
+		if newVM.sp >= len(newVM.stack) {
 			return &object.Error{Message: "stack overflow in bridge"}
 		}
 		newVM.stack[newVM.sp] = arg
@@ -49,8 +78,7 @@ func ExecuteClosureBridge(closureObj object.Object, args []object.Object) object
 	newVM.sp = frame.basePointer + callee.Fn.NumLocals
 
 	// Run
-	err := newVM.Run()
-	if err != nil {
+	if err := newVM.RunContext(ctx); err != nil {
 		return &object.Error{Message: err.Error()}
 	}
 
@@ -59,6 +87,40 @@ func ExecuteClosureBridge(closureObj object.Object, args []object.Object) object
 	return newVM.stack[0]
 }
 
+// resumeGeneratorBridge runs gen up to its next yield in a VM of its own and
+// returns the yielded value. args may hold the value sent back into the
+// generator. Once the generator is exhausted it returns null and gen.Done is
+// set.
+func resumeGeneratorBridge(ctx context.Context, gen *object.Generator, args []object.Object) object.Object {
+	var sent object.Object = Null
+	if len(args) > 0 {
+		sent = args[0]
+	}
+
+	newVM := &VM{
+		constants: gen.Constants,
+		stack:     make([]object.Object, StackSize),
+		globals:   gen.Globals,
+		frames:    make([]*Frame, MaxFrames),
+		ctx:       ctx,
+		usage:     &usage{},
+	}
+	if err := newVM.resumeGenerator(gen, sent); err != nil {
+		return &object.Error{Message: err.Error()}
+	}
+	if newVM.framesIndex > 0 {
+		if err := newVM.RunContext(ctx); err != nil {
+			return &object.Error{Message: err.Error()}
+		}
+	}
+
+	if gen.Done {
+		return Null
+	}
+	// The yield left the item at stack[0].
+	return newVM.stack[0]
+}
+
 func (vm *VM) executeAsyncCall(callee *object.Closure, numArgs int) object.Object {
 	// Args are on stack at vm.sp-numArgs to vm.sp
 	// We need to copy them
@@ -71,64 +133,52 @@ func (vm *VM) executeAsyncCall(callee *object.Closure, numArgs int) object.Objec
 	resultChan := make(chan object.Object, 1)
 	chanObj := &object.Channel{Value: resultChan}
 
-	constants := vm.constants
-	filename := vm.filename
-	loader := vm.loader
-	globals := vm.globals // Capture globals from current VM
+	vm.share()
+	asyncVM := vm.newTask()
+	task := vm.race.fork()
+	asyncVM.race = task
+	end := vm.startTask()
 
-	go func() {
+	tasks.start(func() {
+		var err error
 		defer func() {
 			if r := recover(); r != nil {
-				err, ok := r.(error)
-				if !ok {
+				var ok bool
+				if err, ok = r.(error); !ok {
 					err = fmt.Errorf("async task panicked: %v", r)
 				}
-				resultChan <- &object.Error{Message: err.Error()}
+				chanObj.Send(&object.Error{Message: err.Error()})
 			}
+			end(err)
 		}()
+		asyncVM.load()
+		defer asyncVM.free()
 
-		// Manually set up the new VM for executing the specific closure
-		asyncVM := &VM{
-			constants:   constants,
-			stack:       make([]object.Object, StackSize),
-			sp:          0,
-			globals:     globals, // Use captured globals
-			frames:      make([]*Frame, MaxFrames),
-			framesIndex: 0, // Start with 0 frames, we'll push one
-			filename:    filename,
-			loader:      loader,
-		}
-
-		// Push the arguments onto the asyncVM's stack
-		// The arguments are [id, jobs, results]
+		// stack[0] is the slot OpReturnValue writes the result to, as the
+		// callee slot of an ordinary call; the arguments follow it.
+		asyncVM.stack[0] = Null
 		for i := 0; i < numArgs; i++ {
-			asyncVM.stack[i] = args[i]
+			asyncVM.stack[1+i] = args[i]
 		}
-		asyncVM.sp = numArgs // Stack pointer is now past the arguments
+		asyncVM.sp = 1 + numArgs // Stack pointer is now past the arguments
 
 		// Create a new frame for the closure
-		// basePointer should be 0 because args are already on stack and serve as the base
-		asyncFrame := NewFrame(callee, asyncVM.sp-numArgs) // basePointer for the arguments
-		asyncVM.pushFrame(asyncFrame)                      // Push this new frame
+		asyncFrame := NewFrame(callee, 1) // basePointer for the arguments
+		asyncVM.pushFrame(asyncFrame)     // Push this new frame
 
 		// Update stack pointer for the asyncVM to reflect the new frame and its locals
 		asyncVM.sp = asyncFrame.basePointer + callee.Fn.NumLocals
 
-		err := asyncVM.Run() // Run this specific function in its own VM
+		err = asyncVM.Run() // Run this specific function in its own VM
 		if err != nil {
-			resultChan <- &object.Error{Message: err.Error()}
-			close(resultChan)
+			chanObj.Send(&object.Error{Message: err.Error()})
+			chanObj.Close()
 			return
 		}
 
-		var result object.Object = Null
-		// The result should be at the top of the stack when the function finishes
-		if asyncVM.sp > asyncFrame.basePointer {
-			result = asyncVM.stack[asyncVM.sp-1]
-		}
-		resultChan <- result
-		close(resultChan)
-	}()
+		// OpReturnValue put the result at basePointer-1, which is index 0.
+		deliver(task, chanObj, asyncVM.stack[0])
+	})
 
 	return chanObj
 }
@@ -152,14 +202,17 @@ func (vm *VM) opSpawn(ins code.Instructions, ip *int) error {
 	resultChan := make(chan object.Object, 1)
 	chanObj := &object.Channel{Value: resultChan}
 
-	// Capture constants and loader context
-	constants := vm.constants
-	filename := vm.filename
-	loader := vm.loader
+	vm.share()
+	newVM := vm.newTask()
+	task := vm.race.fork()
+	newVM.race = task
+	end := vm.startTask()
 
-	go func() {
-		// Create new VM
-		newVM := NewWithLoader(code.Instructions{}, constants, filename, loader)
+	tasks.start(func() {
+		var err error
+		defer func() { end(err) }()
+		newVM.load()
+		defer newVM.free()
 
 		// Push callee and args
 		newVM.push(callee)
@@ -169,18 +222,18 @@ func (vm *VM) opSpawn(ins code.Instructions, ip *int) error {
 
 		// Setup call
 		// We manually invoke executeCall to set up the frame
-		err := newVM.executeCall(numArgs)
+		err = newVM.executeCall(numArgs)
 		if err != nil {
-			resultChan <- &object.Error{Message: err.Error()}
-			close(resultChan)
+			chanObj.Send(&object.Error{Message: err.Error()})
+			chanObj.Close()
 			return
 		}
 
 		// Run
 		err = newVM.Run()
 		if err != nil {
-			resultChan <- &object.Error{Message: err.Error()}
-			close(resultChan)
+			chanObj.Send(&object.Error{Message: err.Error()})
+			chanObj.Close()
 			return
 		}
 
@@ -190,33 +243,35 @@ func (vm *VM) opSpawn(ins code.Instructions, ip *int) error {
 			result = newVM.stack[newVM.sp-1]
 		}
 
-		resultChan <- result
-		close(resultChan)
-	}()
+		deliver(task, chanObj, result)
+	})
 
 	return vm.push(chanObj)
 }
 
+// deliver sends the result of a task started by spawn or an async call on
+// the channel returned for it, and closes the channel. The program may have
+// closed it already, which drops the result.
+func deliver(task *raceTask, ch *object.Channel, result object.Object) {
+	task.release(ch)
+	ch.Send(result)
+	ch.Close()
+}
+
 func (vm *VM) opAwait() error {
 	obj := vm.pop()
 
-	switch ch := obj.(type) {
-	case *object.Channel:
-		val, ok := <-ch.Value
-		if !ok {
-			return vm.push(Null) // Channel was closed or empty
-		}
-		return vm.push(val)
-
-	case *object.RemoteChannel:
-		val, err := ch.Receive()
+	switch obj.(type) {
+	case *object.Channel, *object.RemoteChannel:
+		// A closed or empty channel gives null
+		val, _, err := vm.receive(obj)
 		if err != nil {
-			return fmt.Errorf("remote channel receive error: %s", err)
+			return err
 		}
 		return vm.push(val)
 
 	default:
-		return fmt.Errorf("can only await on a channel, got %s", obj.Type())
+		return typeError("can only await on a channel, got %s", obj.Type())
 	}
 }
 
@@ -224,30 +279,87 @@ func (vm *VM) opSendChannel() error {
 	val := vm.pop()
 	chObj := vm.pop()
 
-	ch, ok := chObj.(*object.Channel)
-	if !ok {
-		return fmt.Errorf("send to non-channel type: %T", chObj)
+	var err error
+	switch ch := chObj.(type) {
+	case *object.Channel:
+		vm.race.release(ch)
+		resume := vm.waiting()
+		if vm.ctx == nil {
+			err = ch.Send(val)
+		} else {
+			err = ch.SendContext(vm.ctx, val)
+		}
+		resume()
+		if err != nil && vm.ctx != nil && !errors.Is(err, object.ErrSendOnClosed) {
+			return vm.interrupted()
+		}
+	case *object.RemoteChannel:
+		if err = ch.Send(val); err != nil && !errors.Is(err, object.ErrSendOnClosed) {
+			return fmt.Errorf("remote channel send error: %s", err)
+		}
+	default:
+		return typeError("send to non-channel type: %T", chObj)
+	}
+	if err != nil {
+		return err
 	}
-
-	ch.Value <- val
 
 	// channel expression evaluates to the sent value
 	return vm.push(val)
 }
 
 func (vm *VM) opRecvChannel() error {
-	chObj := vm.pop()
-
-	ch, ok := chObj.(*object.Channel)
-	if !ok {
-		return fmt.Errorf("receive from non-channel type: %T", chObj)
+	val, _, err := vm.receive(vm.pop())
+	if err != nil {
+		return err
 	}
+	return vm.push(val)
+}
 
-	val, ok := <-ch.Value
-	if !ok {
-		// channel is closed, push Null
-		return vm.push(Null)
+// opRecvChannelOk receives like opRecvChannel for `let [v, ok] = <-ch`,
+// pushing the value with whether the channel was still open.
+func (vm *VM) opRecvChannelOk() error {
+	val, ok, err := vm.receive(vm.pop())
+	if err != nil {
+		return err
 	}
+	return vm.pushNew(&object.Array{Elements: []object.Object{val, nativeBoolToBooleanObj(ok)}})
+}
 
-	return vm.push(val)
+// receive waits for a value from a local or remote channel, until the
+// context of the VM is done. A closed channel gives null and false.
+func (vm *VM) receive(chObj object.Object) (object.Object, bool, error) {
+	switch ch := chObj.(type) {
+	case *object.Channel:
+		var val object.Object
+		var ok bool
+		var err error
+		resume := vm.waiting()
+		if vm.ctx == nil {
+			val, ok = ch.Receive()
+		} else {
+			val, ok, err = ch.ReceiveContext(vm.ctx)
+		}
+		resume()
+		if err != nil {
+			return nil, false, vm.interrupted()
+		}
+		vm.race.acquire(ch)
+		if !ok {
+			return Null, false, nil
+		}
+		return val, true, nil
+	case *object.RemoteChannel:
+		resume := vm.waiting()
+		val, err := ch.Receive()
+		resume()
+		if errors.Is(err, io.EOF) {
+			return Null, false, nil
+		}
+		if err != nil {
+			return nil, false, ioError("remote channel receive error: %s", err)
+		}
+		return val, true, nil
+	}
+	return nil, false, typeError("receive from non-channel type: %T", chObj)
 }
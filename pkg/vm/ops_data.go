@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"jabline/pkg/code"
 	"jabline/pkg/object"
+	"strings"
 )
 
 func (vm *VM) opArray(ins code.Instructions, ip *int) error {
@@ -11,7 +12,7 @@ func (vm *VM) opArray(ins code.Instructions, ip *int) error {
 	*ip += 2
 	array := vm.buildArray(vm.sp-numElements, vm.sp)
 	vm.sp = vm.sp - numElements
-	return vm.push(array)
+	return vm.pushNew(array)
 }
 
 func (vm *VM) opHash(ins code.Instructions, ip *int) error {
@@ -22,12 +23,33 @@ func (vm *VM) opHash(ins code.Instructions, ip *int) error {
 		return err
 	}
 	vm.sp = vm.sp - numElements
-	return vm.push(hash)
+	return vm.pushNew(hash)
+}
+
+func (vm *VM) opConcat(ins code.Instructions, ip *int) error {
+	numParts := int(code.ReadUint16(ins[*ip+1:]))
+	*ip += 2
+
+	var out strings.Builder
+	for i := vm.sp - numParts; i < vm.sp; i++ {
+		switch part := vm.stack[i].(type) {
+		case *object.String:
+			out.WriteString(part.Value)
+		case nil:
+			out.WriteString("null")
+		default:
+			out.WriteString(part.Inspect())
+		}
+	}
+	vm.sp = vm.sp - numParts
+
+	return vm.pushNew(&object.String{Value: out.String()})
 }
 
 func (vm *VM) opIndex() error {
 	index := vm.pop()
 	left := vm.pop()
+	defer vm.readShared()()
 	return vm.executeIndexExpression(left, index)
 }
 
@@ -35,12 +57,13 @@ func (vm *VM) opSetProperty() error {
 	val := vm.pop()
 	index := vm.pop()
 	left := vm.pop()
+	defer vm.writeShared()()
 
 	switch obj := left.(type) {
 	case *object.Instance:
 		key, ok := index.(*object.String)
 		if !ok {
-			return fmt.Errorf("property name must be string, got %s", index.Type())
+			return typeError("property name must be string, got %s", index.Type())
 		}
 		obj.Fields[key.Value] = val
 		return nil
@@ -48,7 +71,7 @@ func (vm *VM) opSetProperty() error {
 	case *object.Service:
 		key, ok := index.(*object.String)
 		if !ok {
-			return fmt.Errorf("property name must be string, got %s", index.Type())
+			return typeError("property name must be string, got %s", index.Type())
 		}
 		if _, exists := obj.Config[key.Value]; !exists {
 			// You can decide whether to allow adding new properties or just updating existing ones.
@@ -60,7 +83,7 @@ func (vm *VM) opSetProperty() error {
 	case *object.Hash:
 		key, ok := index.(object.Hashable)
 		if !ok {
-			return fmt.Errorf("unusable as hash key: %s", index.Type())
+			return typeError("unusable as hash key: %s", index.Type())
 		}
 		obj.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: val}
 		return nil
@@ -68,11 +91,11 @@ func (vm *VM) opSetProperty() error {
 	case *object.Array:
 		idxObj, ok := index.(*object.Integer)
 		if !ok {
-			return fmt.Errorf("array index must be integer, got %s", index.Type())
+			return typeError("array index must be integer, got %s", index.Type())
 		}
 		idx := idxObj.Value
 		if idx < 0 || idx >= int64(len(obj.Elements)) {
-			return fmt.Errorf("index out of bounds: %d", idx)
+			return indexError("index out of bounds: %d", idx)
 		}
 		obj.Elements[idx] = val
 		return nil
@@ -129,8 +152,15 @@ func (vm *VM) opCall(ins code.Instructions, ip *int) error {
 func (vm *VM) opReturnValue() error {
 
 	returnValue := vm.pop()
+	if frame := vm.currentFrame(); len(frame.defers) > 0 {
+		frame.result = returnValue
+		return vm.runDefers()
+	}
 
 	frame := vm.popFrame()
+	if frame.generator != nil {
+		return vm.finishGenerator(frame)
+	}
 
 	// Restore globals and constants if this frame had swapped them
 	if frame.savedGlobals != nil {
@@ -141,18 +171,31 @@ func (vm *VM) opReturnValue() error {
 	}
 
 	vm.sp = frame.basePointer - 1
+	if frame.deferred {
+		return vm.runDefers()
+	}
 
 	vm.stack[vm.sp] = returnValue
 
 	vm.sp++
 
+	if frame.iterates {
+		return vm.opGetIterator()
+	}
 	return nil
 
 }
 
 func (vm *VM) opReturn() error {
+	if frame := vm.currentFrame(); len(frame.defers) > 0 {
+		frame.result = Null
+		return vm.runDefers()
+	}
 
 	frame := vm.popFrame()
+	if frame.generator != nil {
+		return vm.finishGenerator(frame)
+	}
 
 	// Restore globals and constants if this frame had swapped them
 	if frame.savedGlobals != nil {
@@ -163,11 +206,17 @@ func (vm *VM) opReturn() error {
 	}
 
 	vm.sp = frame.basePointer - 1
+	if frame.deferred {
+		return vm.runDefers()
+	}
 
 	vm.stack[vm.sp] = Null
 
 	vm.sp++
 
+	if frame.iterates {
+		return vm.opGetIterator()
+	}
 	return nil
 
 }
@@ -200,5 +249,5 @@ func (vm *VM) opInstance(ins code.Instructions, ip *int) error {
 		StructName: structName,
 		Fields:     fields,
 	}
-	return vm.push(instance)
+	return vm.pushNew(instance)
 }
@@ -0,0 +1,93 @@
+package vm
+
+import (
+	"jabline/pkg/code"
+	"jabline/pkg/object"
+)
+
+// unwinding is an exception leaving a frame, kept while the frame makes its
+// deferred calls: either a thrown value or an error raised by the VM.
+type unwinding struct {
+	thrown object.Object
+	err    error
+}
+
+// opDefer takes a callee and its arguments off the stack and saves them to
+// be called when the current frame returns.
+func (vm *VM) opDefer(ins code.Instructions, ip *int) {
+	numArgs := int(ins[*ip+1])
+	*ip += 1
+
+	call := make([]object.Object, numArgs+1)
+	copy(call, vm.stack[vm.sp-numArgs-1:vm.sp])
+	vm.sp -= numArgs + 1
+
+	frame := vm.currentFrame()
+	frame.defers = append(frame.defers, call)
+}
+
+// runDefers makes the most recent deferred call of the current frame. A
+// call that runs in a frame of its own comes back here when it returns.
+// Once none is left, the frame finishes what started the calls: it returns
+// its result, or passes the exception on to the frames below.
+func (vm *VM) runDefers() error {
+	frame := vm.currentFrame()
+	for len(frame.defers) > 0 {
+		call := frame.defers[len(frame.defers)-1]
+		frame.defers = frame.defers[:len(frame.defers)-1]
+
+		vm.sp = frame.basePointer + frame.cl.Fn.NumLocals
+		if vm.sp+len(call) > len(vm.stack) {
+			return ErrStackOverflow
+		}
+		vm.sp += copy(vm.stack[vm.sp:], call)
+
+		depth := vm.framesIndex
+		if err := vm.executeCall(len(call) - 1); err != nil {
+			return err
+		}
+		if vm.framesIndex > depth {
+			vm.currentFrame().deferred = true
+			return nil
+		}
+		vm.sp-- // builtins and generator functions return right away
+	}
+
+	if u := frame.unwinding; u != nil {
+		frame.unwinding = nil
+		if u.thrown == nil {
+			return u.err
+		}
+		vm.push(u.thrown)
+		return vm.opThrow()
+	}
+	vm.push(frame.result)
+	return vm.opReturnValue()
+}
+
+// unwindToDefers prepares an exception caught in the frame below
+// frameIndex, or not caught at all if frameIndex is 0, to leave the frames
+// above it. If one of them deferred calls, the frames above that one are
+// popped and it starts making the calls; the exception continues once they
+// are done. It reports whether that happened.
+func (vm *VM) unwindToDefers(frameIndex int, u *unwinding) bool {
+	for i := vm.framesIndex - 1; i >= frameIndex; i-- {
+		frame := vm.frames[i]
+		if len(frame.defers) == 0 {
+			continue
+		}
+		vm.unwind(i + 1)
+		frame.unwinding = u
+		return true
+	}
+	return false
+}
+
+// handlerFrame is the frameIndex for unwindToDefers: the frames from it
+// upwards are left by an exception thrown now.
+func (vm *VM) handlerFrame() int {
+	if len(vm.handlers) == 0 {
+		return 0
+	}
+	return vm.handlers[len(vm.handlers)-1].FrameIndex
+}
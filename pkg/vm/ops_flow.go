@@ -29,6 +29,16 @@ func (vm *VM) opJumpNotNull(ins code.Instructions, ip *int) {
 	}
 }
 
+// opJumpNotMissing jumps unless the value on top of the stack is an
+// argument the call left out, keeping it there.
+func (vm *VM) opJumpNotMissing(ins code.Instructions, ip *int) {
+	pos := int(code.ReadUint16(ins[*ip+1:]))
+	*ip += 2
+	if _, ok := vm.StackTop().(*missingArgument); !ok {
+		*ip = pos - 1
+	}
+}
+
 func (vm *VM) opJumpNotTruthyKeep(ins code.Instructions, ip *int) {
 	pos := int(code.ReadUint16(ins[*ip+1:]))
 	*ip += 2
@@ -65,6 +75,11 @@ func (vm *VM) opJumpIfEqual(ins code.Instructions, ip *int) {
 		if v, ok := value.(*object.String); ok { match = t.Value == v.Value }
 	case *object.Boolean:
 		if v, ok := value.(*object.Boolean); ok { match = t.Value == v.Value }
+	case *object.EnumValue:
+		if v, ok := value.(*object.EnumValue); ok { match = enumValuesEqual(t, v) }
+	}
+	if object.IsSizedNumber(target) || object.IsSizedNumber(value) {
+		match = isNumber(target) && isNumber(value) && numbersEqual(target, value)
 	}
 	
 	if match {
@@ -0,0 +1,176 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"jabline/pkg/object"
+)
+
+// callGenerator replaces the callee and arguments of a call to a generator
+// function with a Generator suspended before its first instruction.
+func (vm *VM) callGenerator(cl *object.Closure, numArgs int, typeArgs map[string]string) error {
+	gen := object.NewGenerator(cl, vm.stack[vm.sp-numArgs:vm.sp], typeArgs)
+	gen.Globals, gen.Constants = cl.Globals, cl.Constants
+	if gen.Globals == nil {
+		gen.Globals = vm.globals
+	}
+	if gen.Constants == nil {
+		gen.Constants = vm.constants
+	}
+	vm.sp = vm.sp - numArgs - 1
+	return vm.push(gen)
+}
+
+// resumeGenerator continues gen in a new frame on top of the stack. sent
+// becomes the value of the yield expression it is suspended at. Like
+// OpIterNext for other iterators, the next yield pushes the item and true,
+// and the end of the body false.
+func (vm *VM) resumeGenerator(gen *object.Generator, sent object.Object) error {
+	if !gen.Acquire() {
+		return fmt.Errorf("generator is already running")
+	}
+	if gen.Done {
+		gen.Release()
+		return vm.push(False)
+	}
+	if vm.framesIndex >= len(vm.frames) {
+		gen.Release()
+		return fmt.Errorf("%w (%d)", ErrCallDepth, len(vm.frames))
+	}
+	bp := vm.sp
+	if bp+len(gen.Stack)+1 > len(vm.stack) {
+		gen.Release()
+		return ErrStackOverflow
+	}
+
+	frame := NewFrame(gen.Closure, bp)
+	frame.ip = gen.IP
+	frame.generator = gen
+	frame.defers = gen.Defers
+	for k, v := range gen.TypeArgs {
+		frame.TypeArgs[k] = v
+	}
+	frame.savedGlobals, vm.globals = vm.globals, gen.Globals
+	frame.savedConstants, vm.constants = vm.constants, gen.Constants
+	vm.pushFrame(frame)
+
+	vm.sp = bp + copy(vm.stack[bp:], gen.Stack)
+	for _, h := range gen.Handlers {
+		vm.handlers = append(vm.handlers, ExceptionHandler{
+			CatchIP:    h.CatchIP,
+			StackSP:    bp + h.StackOffset,
+			FrameIndex: vm.framesIndex,
+		})
+	}
+
+	if gen.IP >= 0 {
+		return vm.push(sent)
+	}
+	return nil
+}
+
+// resumeNext is next(gen, sent) called by a VM, which has the VM resume gen
+// once the call returns. Called by other code, it runs gen in a VM of its
+// own.
+func resumeNext(ctx context.Context, gen *object.Generator, sent object.Object) object.Object {
+	call, ok := ctx.Value(nativeCallKey{}).(*nativeCall)
+	if !ok {
+		return resumeGeneratorBridge(ctx, gen, []object.Object{sent})
+	}
+	call.generator, call.sent = gen, sent
+	return Null
+}
+
+// next resumes gen in place of a call of next(), in a frame that yields the
+// item alone, as the call returns it.
+func (vm *VM) next(gen *object.Generator, sent object.Object) error {
+	depth := vm.framesIndex
+	if err := vm.resumeGenerator(gen, sent); err != nil {
+		return err
+	}
+	if vm.framesIndex > depth {
+		vm.currentFrame().nexts = true
+		return nil
+	}
+	// An exhausted generator pushed false without running a frame.
+	vm.sp--
+	return vm.push(Null)
+}
+
+// executeGeneratorIndex reads the properties of a generator: done tells
+// whether it is exhausted, so a null returned by next() can be told apart
+// from a yielded null.
+func (vm *VM) executeGeneratorIndex(gen *object.Generator, name string) error {
+	if name == "done" {
+		return vm.push(nativeBoolToBooleanObj(gen.Done))
+	}
+	return keyError("field or method '%s' not found in GENERATOR", name)
+}
+
+// opYield suspends the generator running in the current frame, saving its
+// stack and open try blocks, and hands the yielded value to the frame below.
+func (vm *VM) opYield() error {
+	value := vm.pop()
+	frame := vm.currentFrame()
+	gen := frame.generator
+	if gen == nil {
+		return fmt.Errorf("yield outside of a generator")
+	}
+
+	bp := frame.basePointer
+	gen.IP = frame.ip
+	gen.Stack = append(gen.Stack[:0], vm.stack[bp:vm.sp]...)
+
+	// Try blocks of this frame are on top of the handler stack; any above
+	// them were left open by calls that have already returned.
+	n := len(vm.handlers)
+	for n > 0 && vm.handlers[n-1].FrameIndex >= vm.framesIndex {
+		n--
+	}
+	gen.Handlers = gen.Handlers[:0]
+	for _, h := range vm.handlers[n:] {
+		if h.FrameIndex == vm.framesIndex {
+			gen.Handlers = append(gen.Handlers, object.GeneratorHandler{CatchIP: h.CatchIP, StackOffset: h.StackSP - bp})
+		}
+	}
+	vm.handlers = vm.handlers[:n]
+	gen.Defers = frame.defers
+
+	vm.popFrame()
+	gen.Release()
+
+	vm.sp = bp
+	if frame.nexts {
+		return vm.push(value)
+	}
+	vm.push(value)
+	return vm.push(True)
+}
+
+// finishGenerator ends the generator whose frame has just returned and
+// reports to the frame below that it is exhausted.
+func (vm *VM) finishGenerator(frame *Frame) error {
+	frame.generator.Finish()
+
+	n := len(vm.handlers)
+	for n > 0 && vm.handlers[n-1].FrameIndex > vm.framesIndex {
+		n--
+	}
+	vm.handlers = vm.handlers[:n]
+
+	vm.sp = frame.basePointer
+	if frame.nexts {
+		return vm.push(Null)
+	}
+	return vm.push(False)
+}
+
+// unwind pops the frames above frameIndex for a throw, ending the
+// generators they run.
+func (vm *VM) unwind(frameIndex int) {
+	for vm.framesIndex > frameIndex {
+		if frame := vm.popFrame(); frame.generator != nil {
+			frame.generator.Finish()
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package vm
+
+import (
+	"jabline/pkg/object"
+)
+
+// opGetIterator replaces the iterable on top of the stack with an iterator
+// over it. A struct is iterated through its iter method: the method is
+// called in a frame of its own and whatever it returns is turned into an
+// iterator once it does.
+func (vm *VM) opGetIterator() error {
+	switch obj := vm.pop().(type) {
+	case *object.Array:
+		return vm.push(&object.ArrayIterator{Array: obj})
+	case *object.String:
+		return vm.push(&object.StringIterator{Value: obj.Value})
+	case *object.Hash:
+		return vm.push(object.NewHashIterator(obj))
+	case *object.Channel:
+		return vm.push(&object.ChannelIterator{Channel: obj})
+	case *object.RemoteChannel:
+		return vm.push(&object.RemoteChannelIterator{Channel: obj})
+	case *object.Instance:
+		return vm.callIterMethod(obj)
+	case *object.Generator, object.Iterator:
+		return vm.push(obj)
+	default:
+		return typeError("cannot iterate over %s", obj.Type())
+	}
+}
+
+func (vm *VM) callIterMethod(inst *object.Instance) error {
+	method, ok := vm.methods[inst.StructName]["iter"]
+	if !ok {
+		return typeError("cannot iterate over %s: it has no iter method", inst.StructName)
+	}
+	vm.push(&object.BoundMethod{Receiver: inst, Function: method})
+
+	depth := vm.framesIndex
+	if err := vm.executeCall(0); err != nil {
+		return err
+	}
+	if vm.framesIndex > depth {
+		vm.currentFrame().iterates = true
+		return nil
+	}
+	// A generator method returns without running a frame.
+	return vm.opGetIterator()
+}
+
+// opIterNext replaces the iterator on top of the stack with its next item
+// and true, or with false once it is exhausted. A generator is resumed in a
+// frame of its own, which pushes them when it yields or returns.
+func (vm *VM) opIterNext() error {
+	switch it := vm.pop().(type) {
+	case *object.Generator:
+		return vm.resumeGenerator(it, Null)
+	case *object.ChannelIterator:
+		return vm.channelNext(it.Channel)
+	case *object.RemoteChannelIterator:
+		return vm.channelNext(it.Channel)
+	case object.Iterator:
+		item, ok := it.Next()
+		if !ok {
+			return vm.push(False)
+		}
+		vm.push(item)
+		return vm.push(True)
+	default:
+		return typeError("cannot iterate over %s", it.Type())
+	}
+}
+
+// opIterNextPair is opIterNext for two loop variables: it pushes the key,
+// the value and true, or false once the iterator is exhausted.
+func (vm *VM) opIterNextPair() error {
+	obj := vm.pop()
+	it, ok := obj.(object.PairIterator)
+	if !ok {
+		return typeError("cannot iterate over %s with two variables", obj.Type())
+	}
+	key, value, ok := it.NextPair()
+	if !ok {
+		return vm.push(False)
+	}
+	vm.push(key)
+	vm.push(value)
+	return vm.push(True)
+}
+
+// channelNext steps a channel iterator like opIterNext, receiving from ch
+// the way <-ch does: the task gives its place to another while it waits,
+// and stops waiting once the context of the VM is done.
+func (vm *VM) channelNext(ch object.Object) error {
+	item, ok, err := vm.receive(ch)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return vm.push(False)
+	}
+	vm.push(item)
+	return vm.push(True)
+}
@@ -0,0 +1,167 @@
+package vm
+
+import (
+	"fmt"
+
+	"jabline/pkg/code"
+	"jabline/pkg/object"
+)
+
+// opMatchValue tests the subject against the value of a literal or
+// qualified name pattern.
+func (vm *VM) opMatchValue() error {
+	pattern := vm.pop()
+	subject := vm.pop()
+	return vm.push(nativeBoolToBooleanObj(patternEquals(subject, pattern)))
+}
+
+// patternEquals compares like a switch case, except that numbers of any
+// type are equal when their values are and enum values are equal when
+// their variants and fields are.
+func patternEquals(subject, pattern object.Object) bool {
+	if isNumber(subject) && isNumber(pattern) {
+		return numbersEqual(subject, pattern)
+	}
+	switch p := pattern.(type) {
+	case *object.String:
+		s, ok := subject.(*object.String)
+		return ok && s.Value == p.Value
+	case *object.Boolean:
+		s, ok := subject.(*object.Boolean)
+		return ok && s.Value == p.Value
+	case *object.Null:
+		return subject.Type() == object.NULL_OBJ
+	case *object.EnumValue:
+		s, ok := subject.(*object.EnumValue)
+		return ok && enumValuesEqual(s, p)
+	case *object.EnumVariant:
+		// The constructor of a variant matches any value it makes.
+		s, ok := subject.(*object.EnumValue)
+		return ok && sameVariant(s.Variant, p)
+	}
+	return subject == pattern
+}
+
+// opMatchRange tests whether the subject is a number from the low bound up
+// to the high one, which the operand says is included or not.
+func (vm *VM) opMatchRange(ins code.Instructions, ip *int) error {
+	inclusive := ins[*ip+1] == 1
+	*ip += 1
+
+	high := vm.pop()
+	low := vm.pop()
+	subject := vm.pop()
+	if !isNumber(low) || !isNumber(high) {
+		return fmt.Errorf("range bounds must be numbers, got %s and %s", low.Type(), high.Type())
+	}
+	if !isNumber(subject) {
+		return vm.push(False)
+	}
+
+	fromLow, err := compareNumbers(subject, low)
+	if err != nil {
+		return vm.push(False)
+	}
+	toHigh, err := compareNumbers(subject, high)
+	if err != nil {
+		return vm.push(False)
+	}
+	ok := fromLow >= 0 && (toHigh < 0 || inclusive && toHigh == 0)
+	return vm.push(nativeBoolToBooleanObj(ok))
+}
+
+// compareNumbers returns -1, 0 or 1 as a is less than, equal to or greater
+// than b.
+func compareNumbers(a, b object.Object) (int, error) {
+	t, err := numericType(a, b)
+	if err != nil {
+		return 0, err
+	}
+	if object.IsFloatType(t) {
+		l, err := floatOperand(a, t)
+		if err != nil {
+			return 0, err
+		}
+		r, err := floatOperand(b, t)
+		if err != nil {
+			return 0, err
+		}
+		return compareOrdered(l, r), nil
+	}
+
+	l, err := integerOperand(a, t)
+	if err != nil {
+		return 0, err
+	}
+	r, err := integerOperand(b, t)
+	if err != nil {
+		return 0, err
+	}
+	if _, signed, _ := object.IntegerKind(t); signed {
+		return compareOrdered(int64(l), int64(r)), nil
+	}
+	return compareOrdered(l, r), nil
+}
+
+func compareOrdered[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// opMatchArray tests whether the subject is an array of the pattern's
+// length, or at least that long if the pattern has a rest element.
+func (vm *VM) opMatchArray(ins code.Instructions, ip *int) error {
+	count := int(code.ReadUint16(ins[*ip+1:]))
+	hasRest := ins[*ip+3] == 1
+	*ip += 3
+
+	arr, ok := vm.pop().(*object.Array)
+	ok = ok && (len(arr.Elements) == count || hasRest && len(arr.Elements) > count)
+	return vm.push(nativeBoolToBooleanObj(ok))
+}
+
+// opMatchHash tests whether the subject is a hash holding every key of the
+// pattern.
+func (vm *VM) opMatchHash(ins code.Instructions, ip *int) error {
+	count := int(ins[*ip+1])
+	*ip += 1
+
+	keys := make([]object.Object, count)
+	for i := count - 1; i >= 0; i-- {
+		keys[i] = vm.pop()
+	}
+	hash, ok := vm.pop().(*object.Hash)
+	for _, key := range keys {
+		if !ok {
+			break
+		}
+		hashable, isHashable := key.(object.Hashable)
+		if !isHashable {
+			return typeError("unusable as hash key: %s", key.Type())
+		}
+		_, ok = hash.Pairs[hashable.HashKey()]
+	}
+	return vm.push(nativeBoolToBooleanObj(ok))
+}
+
+// opArrayRest pushes a new array of the elements of an array from the
+// operand's index on.
+func (vm *VM) opArrayRest(ins code.Instructions, ip *int) error {
+	start := int(code.ReadUint16(ins[*ip+1:]))
+	*ip += 2
+
+	arr, ok := vm.pop().(*object.Array)
+	if !ok {
+		return fmt.Errorf("rest element of a non-array")
+	}
+	rest := []object.Object{}
+	if start < len(arr.Elements) {
+		rest = append(rest, arr.Elements[start:]...)
+	}
+	return vm.pushNew(&object.Array{Elements: rest})
+}
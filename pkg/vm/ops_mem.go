@@ -1,20 +1,38 @@
 package vm
 
 import (
+	"os"
+
 	"jabline/pkg/code"
+	"jabline/pkg/object"
 	"jabline/pkg/stdlib"
 )
 
 func (vm *VM) opSetGlobal(ins code.Instructions, ip *int) {
 	globalIndex := int(code.ReadUint16(ins[*ip+1:]))
 	*ip += 2
+	if vm.race != nil {
+		vm.race.write(&vm.globals[globalIndex], vm.position())
+	}
+	if vm.shared == nil {
+		vm.globals[globalIndex] = vm.pop()
+		return
+	}
+	vm.shared.Lock()
 	vm.globals[globalIndex] = vm.pop()
+	vm.shared.Unlock()
 }
 
 func (vm *VM) opGetGlobal(ins code.Instructions, ip *int) error {
 	globalIndex := int(code.ReadUint16(ins[*ip+1:]))
 	*ip += 2
-	return vm.push(vm.globals[globalIndex])
+	if vm.shared == nil {
+		return vm.push(vm.globals[globalIndex])
+	}
+	vm.shared.RLock()
+	value := vm.globals[globalIndex]
+	vm.shared.RUnlock()
+	return vm.push(value)
 }
 
 func (vm *VM) opSetLocal(ins code.Instructions, ip *int) {
@@ -35,6 +53,15 @@ func (vm *VM) opGetBuiltin(ins code.Instructions, ip *int) error {
 	builtinIndex := int(ins[*ip+1])
 	*ip += 1
 	definition := stdlib.Registry[builtinIndex]
+
+	// echo writes to the program's stdout, which an embedding host may
+	// have redirected.
+	if stdout := vm.stdout(); definition.Name == "echo" && stdout != os.Stdout {
+		return vm.push(&object.Builtin{Fn: func(args ...object.Object) object.Object {
+			stdlib.Echo(stdout, args...)
+			return Null
+		}})
+	}
 	return vm.push(definition.Object)
 }
 
@@ -54,12 +81,16 @@ func (vm *VM) opGetFree(ins code.Instructions, ip *int) error {
 	freeIndex := int(ins[*ip+1])
 	*ip += 1
 	currentClosure := vm.currentFrame().cl
-	return vm.push(currentClosure.Free[freeIndex])
+	unlock := vm.readShared()
+	value := currentClosure.Free[freeIndex]
+	unlock()
+	return vm.push(value)
 }
 
 func (vm *VM) opSetFree(ins code.Instructions, ip *int) {
 	freeIndex := int(ins[*ip+1])
 	*ip += 1
 	currentClosure := vm.currentFrame().cl
+	defer vm.writeShared()()
 	currentClosure.Free[freeIndex] = vm.pop()
 }
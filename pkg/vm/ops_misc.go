@@ -17,9 +17,16 @@ func (vm *VM) opEndTry() {
 
 func (vm *VM) opThrow() error {
 	exception := vm.pop()
+	vm.captureStack(exception)
 
+	if vm.debugger != nil {
+		vm.debugger.Exception(vm, fmt.Errorf("%s", exception.Inspect()), len(vm.handlers) == 0)
+	}
+	if vm.unwindToDefers(vm.handlerFrame(), &unwinding{thrown: exception}) {
+		return vm.runDefers()
+	}
 	if len(vm.handlers) == 0 {
-		return fmt.Errorf("uncaught exception: %s", exception.Inspect())
+		return vm.uncaught(exception)
 	}
 
 	handler := vm.handlers[len(vm.handlers)-1]
@@ -27,7 +34,7 @@ func (vm *VM) opThrow() error {
 
 	// Unwind stack
 	vm.sp = handler.StackSP
-	vm.framesIndex = handler.FrameIndex
+	vm.unwind(handler.FrameIndex)
 	vm.stack[vm.sp] = exception // Push exception back for catch block
 	vm.sp++
 
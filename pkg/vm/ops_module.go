@@ -14,9 +14,8 @@ func (vm *VM) opImport(ins code.Instructions, ip *int) error {
 		return fmt.Errorf("import path must be a string. got=%T", pathObj)
 	}
 
-	module, err := vm.loader.Load(pathStr.Value)
+	module, err := vm.loader.load(pathStr.Value, vm)
 	if err != nil {
-		fmt.Println("DEBUG: Import Error:", err) // <--- Debug
 		return err
 	}
 
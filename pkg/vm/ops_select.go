@@ -0,0 +1,178 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"jabline/pkg/code"
+	"jabline/pkg/object"
+)
+
+// Kinds of select case, as the shape constant of OpSelect names them.
+const (
+	selectRecv    = "recv"
+	selectSend    = "send"
+	selectTimeout = "timeout"
+)
+
+// ready is a closed channel, so receiving from it always proceeds. A send
+// to a remote channel waits on it, as writes to the connection do not
+// wait for the peer.
+var ready = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// remoteSend is a send case on a remote channel, made once it is chosen.
+type remoteSend struct {
+	channel *object.RemoteChannel
+	value   object.Object
+}
+
+// opSelect waits until one of the cases of a select can proceed and runs
+// its communication. The shape constant gives the kind of each case, whose
+// operands are on the stack in order: the channel of a receive, the
+// channel and value of a send or the milliseconds of a timeout. It pushes
+// the received value, null for the other cases, then the index of the case
+// chosen, or -1 for the default clause.
+func (vm *VM) opSelect(ins code.Instructions, ip *int) error {
+	shape := vm.constants[code.ReadUint16(ins[*ip+1:])].(*object.Array)
+	hasDefault := ins[*ip+3] == 1
+	*ip += 3
+
+	numOperands := 0
+	for _, kind := range shape.Elements {
+		numOperands++
+		if kind.(*object.String).Value == selectSend {
+			numOperands++
+		}
+	}
+	operands := vm.stack[vm.sp-numOperands : vm.sp]
+
+	cases := make([]reflect.SelectCase, len(shape.Elements), len(shape.Elements)+2)
+	remote := make(map[int]remoteSend)
+	channels := make([]object.Object, len(shape.Elements))
+	var sendTo []*object.Channel
+	var timers []*time.Timer
+	defer func() {
+		for _, t := range timers {
+			t.Stop()
+		}
+	}()
+
+	for i, kind := range shape.Elements {
+		operand := operands[0]
+		operands = operands[1:]
+		c := reflect.SelectCase{Dir: reflect.SelectRecv}
+		switch kind.(*object.String).Value {
+		case selectRecv:
+			channels[i] = operand
+			switch ch := operand.(type) {
+			case *object.Channel:
+				c.Chan = reflect.ValueOf(ch.Value)
+			case *object.RemoteChannel:
+				c.Chan = reflect.ValueOf(ch.Incoming())
+			default:
+				return fmt.Errorf("select case %d: cannot receive from %s", i+1, operand.Type())
+			}
+		case selectSend:
+			value := operands[0]
+			operands = operands[1:]
+			switch ch := operand.(type) {
+			case *object.Channel:
+				select {
+				case <-ch.Done():
+					return object.ErrSendOnClosed
+				default:
+				}
+				if !slices.Contains(sendTo, ch) {
+					sendTo = append(sendTo, ch)
+					defer ch.SendLock()()
+				}
+				c = reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(ch.Value), Send: reflect.ValueOf(value)}
+			case *object.RemoteChannel:
+				c.Chan = reflect.ValueOf(ready)
+				remote[i] = remoteSend{ch, value}
+			default:
+				return fmt.Errorf("select case %d: cannot send to %s", i+1, operand.Type())
+			}
+		case selectTimeout:
+			ms, ok := operand.(*object.Integer)
+			if !ok {
+				return fmt.Errorf("select case %d: timeout must be an integer of milliseconds, got %s", i+1, operand.Type())
+			}
+			timer := time.NewTimer(time.Duration(ms.Value) * time.Millisecond)
+			timers = append(timers, timer)
+			c.Chan = reflect.ValueOf(timer.C)
+		}
+		cases[i] = c
+	}
+	vm.sp -= numOperands
+
+	// The channels sent to stay open during the select; closing one ends
+	// it instead.
+	closing := len(cases)
+	for _, ch := range sendTo {
+		vm.race.release(ch)
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch.Done())})
+	}
+
+	// The select gives up when the program is cancelled.
+	done := -1
+	if vm.ctx != nil {
+		done = len(cases)
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(vm.ctx.Done())})
+	}
+	if hasDefault {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+
+	resume := func() {}
+	if !hasDefault {
+		resume = vm.waiting()
+	}
+	chosen, received, ok := reflect.Select(cases)
+	resume()
+
+	var value object.Object = Null
+	switch {
+	case chosen == done:
+		return fmt.Errorf("execution interrupted: %w", vm.ctx.Err())
+	case chosen >= closing && chosen < closing+len(sendTo):
+		return object.ErrSendOnClosed
+	case chosen >= len(shape.Elements):
+		chosen = -1
+	case shape.Elements[chosen].(*object.String).Value == selectSend:
+		if send, isRemote := remote[chosen]; isRemote {
+			if err := send.channel.Send(send.value); errors.Is(err, object.ErrSendOnClosed) {
+				return err
+			} else if err != nil {
+				return fmt.Errorf("remote channel send error: %s", err)
+			}
+		}
+	case ok:
+		vm.race.acquire(channels[chosen])
+		switch v := received.Interface().(type) {
+		case object.Object:
+			value = v
+		case object.RemoteMessage:
+			if v.Err != nil {
+				return ioError("remote channel receive error: %s", v.Err)
+			}
+			value = v.Value
+		}
+	default:
+		if ch, isRemote := channels[chosen].(*object.RemoteChannel); isRemote && ch.Err() != nil {
+			return ioError("remote channel receive error: %s", ch.Err())
+		}
+	}
+
+	if err := vm.push(value); err != nil {
+		return err
+	}
+	return vm.push(&object.Integer{Value: int64(chosen)})
+}
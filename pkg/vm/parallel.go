@@ -0,0 +1,107 @@
+package vm
+
+import (
+	"context"
+	"sync/atomic"
+
+	"jabline/pkg/object"
+)
+
+// nativeCall is the call of a builtin by a VM, which the builtin finds in
+// the context it is given. The builtins of the parallel module set the
+// exception the VM then throws, and next the generator it then resumes.
+type nativeCall struct {
+	vm        *VM
+	exception object.Object
+	generator *object.Generator
+	sent      object.Object
+}
+
+type nativeCallKey struct{}
+
+// context returns the context of the VM, carrying the call.
+func (c *nativeCall) context() context.Context {
+	ctx := c.vm.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, nativeCallKey{}, c)
+}
+
+// runParallel calls fn with each of items on up to workers tasks of the
+// scheduler, each running the calls it takes in a VM of its own. The
+// tasks run in a task group: the first to fail cancels the others, and the
+// VM calling the builtin throws its exception.
+func runParallel(ctx context.Context, fn object.Object, items []object.Object, workers int) object.Object {
+	call, ok := ctx.Value(nativeCallKey{}).(*nativeCall)
+	if !ok {
+		return &object.Error{Message: "parallel functions can only be called by a program"}
+	}
+	caller := call.vm
+	caller.share()
+
+	g := object.NewTaskGroup(caller.ctx)
+	results := make([]object.Object, len(items))
+	var next atomic.Int64
+	for range min(workers, len(items)) {
+		worker := caller.newTask()
+		worker.ctx = g.Context()
+		worker.groups = []groupScope{{group: g}}
+		worker.race = caller.race.fork()
+		g.Go()
+		tasks.start(func() {
+			defer g.Done()
+			defer worker.race.release(g)
+			worker.load()
+			defer worker.free()
+
+			for !g.Cancelled() {
+				i := int(next.Add(1)) - 1
+				if i >= len(items) {
+					return
+				}
+				result, err := worker.callFunction(fn, items[i])
+				if err != nil {
+					// As in a group statement, failures after the
+					// cancellation were most likely caused by it.
+					if !g.Cancelled() {
+						g.Fail(taskException(err))
+					}
+					return
+				}
+				results[i] = result
+			}
+		})
+	}
+
+	failure := g.Wait()
+	caller.race.acquire(g)
+	if failure != nil {
+		call.exception = failure
+		return Null
+	}
+	return &object.Array{Elements: results}
+}
+
+// callFunction calls fn with args in a VM that is not running anything and
+// returns the result.
+func (vm *VM) callFunction(fn object.Object, args ...object.Object) (object.Object, error) {
+	vm.sp = 0
+	if err := vm.push(fn); err != nil {
+		return nil, err
+	}
+	for _, arg := range args {
+		if err := vm.push(arg); err != nil {
+			return nil, err
+		}
+	}
+	if err := vm.executeCall(len(args)); err != nil {
+		return nil, err
+	}
+	if err := vm.Run(); err != nil {
+		return nil, err
+	}
+	result := vm.stack[vm.sp-1]
+	vm.sp = 0
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package vm
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	s := newScheduler(2)
+	var running, most atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		s.start(func() {
+			defer wg.Done()
+			n := running.Add(1)
+			for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+		})
+	}
+	wg.Wait()
+	if got := most.Load(); got != 2 {
+		t.Errorf("expected 2 tasks at most running at once, got %d", got)
+	}
+}
+
+func TestBoundedTasks(t *testing.T) {
+	SetMaxTasks(1)
+	t.Cleanup(func() { SetMaxTasks(0) })
+
+	tests := []vmTestCase{
+		{`let a = make_chan(0); let b = make_chan(0); fn ping() { a <- 1; return <-b; } fn pong() { let v = <-a; b <- v + 1; return v; } let p = spawn ping(); let q = spawn pong(); [await p, await q]`, inspected("[2, 1]")},
+		{`let c = make_chan(0); fn inner() { c <- "inner"; } fn outer() { group { spawn inner(); } return "outer"; } let o = spawn outer(); [<-c, await o]`, inspected("[inner, outer]")},
+		{`import * as parallel from "_parallel"; fn g(x) { return x + 1; } fn f(x) { return await spawn g(x); } parallel.map([1, 2, 3], f, 3)`, inspected("[2, 3, 4]")},
+		{`let c = make_chan(0); fn cons() { let s = 0; for (v in c) { s = s + v; } return s; } fn prod() { c <- 1; c <- 2; close(c); } let r = spawn cons(); let w = spawn prod(); await w; await r`, inspected("3")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestParallelModule(t *testing.T) {
+	tests := []vmTestCase{
+		{`import * as parallel from "_parallel"; parallel.map([1, 2, 3, 4, 5], fn(x) { return x * x; }, 2)`, inspected("[1, 4, 9, 16, 25]")},
+		{`import * as parallel from "_parallel"; parallel.map(["a", "bb"], len)`, inspected("[1, 2]")},
+		{`import * as parallel from "_parallel"; parallel.map([], len, 4)`, inspected("[]")},
+		{`import * as parallel from "_parallel"; let c = make_chan(); parallel.forEach([1, 2, 3], fn(x) { c <- x; }); len(c)`, inspected("3")},
+		{`import * as parallel from "_parallel"; let r = null; try { parallel.map([1, 2, 3], fn(x) { if (x == 2) { throw ValueError("two"); } return x; }); } catch (e: ValueError) { r = e.message; } r`, inspected("two")},
+		{`import * as parallel from "_parallel"; let r = null; try { parallel.forEach([1], fn(x) { throw x; }); } catch (e) { r = e; } r`, inspected("1")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestParallelUncaught(t *testing.T) {
+	machine := newTestVM(t, "import * as parallel from \"_parallel\";\nfn f(x) {\nreturn 1 / 0;\n}\nparallel.map([1, 2], f);")
+	err := machine.Run()
+	rtErr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("expected a RuntimeError, got %v", err)
+	}
+	if !strings.Contains(rtErr.Message, "division by zero") {
+		t.Errorf("unexpected message %q", rtErr.Message)
+	}
+	if n := len(rtErr.StackTrace); n == 0 || rtErr.StackTrace[n-1].Function != "f" {
+		t.Errorf("expected the trace of the task, got %v", rtErr.StackTrace)
+	}
+}
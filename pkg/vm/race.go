@@ -0,0 +1,190 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"jabline/pkg/object"
+)
+
+// raceDetector reports the writes two tasks make to a global without
+// synchronizing in between, for `jabline run --race`. Each task keeps a
+// vector clock: sending on a channel or unlocking a lock releases the
+// clock of the task into that value, and receiving from it or locking it
+// acquires the clock released there. A write races with the previous one,
+// by another task, when the clock of the writer does not include it.
+type raceDetector struct {
+	mu     sync.Mutex
+	out    io.Writer
+	tasks  int
+	clocks map[any]vectorClock
+	writes map[*object.Object]globalWrite
+	// globals are those of the program, named by names.
+	globals []object.Object
+	names   []string
+	// reported holds the globals already reported, each only once.
+	reported map[*object.Object]bool
+}
+
+// vectorClock maps the id of each task to the last step of that task it
+// has seen.
+type vectorClock map[int]uint64
+
+func (c vectorClock) join(other vectorClock) {
+	for id, step := range other {
+		if step > c[id] {
+			c[id] = step
+		}
+	}
+}
+
+func (c vectorClock) copy() vectorClock {
+	out := make(vectorClock, len(c))
+	out.join(c)
+	return out
+}
+
+// globalWrite is the last write to a global.
+type globalWrite struct {
+	task int
+	step uint64
+	at   CallFrame
+}
+
+// raceTask is a task of a program run with race detection: the program
+// itself, or one it spawned.
+type raceTask struct {
+	detector *raceDetector
+	id       int
+	clock    vectorClock
+}
+
+// DetectRaces makes the program report to w each global that two of its
+// tasks write without synchronizing through a channel, a task result or a
+// lock of the sync module. names holds the names of the globals of the
+// program by index, for the reports.
+func (vm *VM) DetectRaces(w io.Writer, names []string) {
+	d := &raceDetector{
+		out:      w,
+		clocks:   make(map[any]vectorClock),
+		writes:   make(map[*object.Object]globalWrite),
+		globals:  vm.globals,
+		names:    names,
+		reported: make(map[*object.Object]bool),
+	}
+	vm.race = &raceTask{detector: d, clock: vectorClock{0: 1}}
+}
+
+// Races returns the number of globals found written by racing tasks.
+func (vm *VM) Races() int {
+	if vm.race == nil {
+		return 0
+	}
+	d := vm.race.detector
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.reported)
+}
+
+// fork returns the task for one the task starts, which sees everything
+// the task did so far.
+func (t *raceTask) fork() *raceTask {
+	if t == nil {
+		return nil
+	}
+	d := t.detector
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tasks++
+	child := &raceTask{detector: d, id: d.tasks, clock: t.clock.copy()}
+	child.clock[child.id] = 1
+	t.clock[t.id]++
+	return child
+}
+
+// release publishes what the task did so far through via, a channel or a
+// lock, for the task that next acquires it.
+func (t *raceTask) release(via any) {
+	if t == nil {
+		return
+	}
+	d := t.detector
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	clock, ok := d.clocks[via]
+	if !ok {
+		clock = make(vectorClock)
+		d.clocks[via] = clock
+	}
+	clock.join(t.clock)
+	t.clock[t.id]++
+}
+
+// acquire makes the task see what the tasks releasing via did before.
+func (t *raceTask) acquire(via any) {
+	if t == nil {
+		return
+	}
+	d := t.detector
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if clock, ok := d.clocks[via]; ok {
+		t.clock.join(clock)
+	}
+}
+
+// write records a write to the global in slot made at, and reports it if
+// it races with the previous write.
+func (t *raceTask) write(slot *object.Object, at CallFrame) {
+	d := t.detector
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w := globalWrite{task: t.id, step: t.clock[t.id], at: at}
+	if prev, ok := d.writes[slot]; ok && prev.task != t.id && prev.step > t.clock[prev.task] && !d.reported[slot] {
+		d.reported[slot] = true
+		fmt.Fprintf(d.out, "WARNING: data race on %s\n  written by %s at %s\n  previously written by %s at %s\n",
+			d.describe(slot), taskName(w.task), w.at, taskName(prev.task), prev.at)
+	}
+	d.writes[slot] = w
+}
+
+// describe names the global in slot for a report.
+func (d *raceDetector) describe(slot *object.Object) string {
+	for i := range d.globals {
+		if &d.globals[i] != slot {
+			continue
+		}
+		if i < len(d.names) && d.names[i] != "" {
+			return "global " + d.names[i]
+		}
+		return fmt.Sprintf("global #%d", i)
+	}
+	return "a global of a module"
+}
+
+func taskName(id int) string {
+	if id == 0 {
+		return "main"
+	}
+	return fmt.Sprintf("task %d", id)
+}
+
+// position is the innermost frame of the call stack.
+func (vm *VM) position() CallFrame {
+	stack := vm.CallStack()
+	return stack[len(stack)-1]
+}
+
+// raceThroughChannels passes each channel among the arguments of a builtin,
+// such as send or recv, to pass.
+func (vm *VM) raceThroughChannels(args []object.Object, pass func(via any)) {
+	if vm.race == nil {
+		return
+	}
+	for _, arg := range args {
+		if ch, ok := arg.(*object.Channel); ok {
+			pass(ch)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package vm
+
+import (
+	"os"
+	"strconv"
+	"sync"
+
+	"jabline/pkg/object"
+)
+
+// The tasks started with spawn, async calls and the parallel module run on
+// the scheduler of the process. At most MaxTasks of them run at once; the
+// others wait in line to start, before any stack is allocated for them. A
+// task blocked on a channel, a lock, a timer or a task group gives its
+// place to another while it waits. Programs themselves do not hold a
+// place, so a program waiting for its tasks never stops them from running.
+var tasks = newScheduler(maxTasksFromEnv())
+
+// scheduler bounds the number of tasks running at once.
+type scheduler struct {
+	mu      sync.Mutex
+	max     int
+	running int
+	// queue holds the tasks waiting to start, and resuming counts those
+	// waiting to run again after blocking, which go first.
+	queue    []func()
+	resuming int
+	free     *sync.Cond
+}
+
+func newScheduler(max int) *scheduler {
+	s := &scheduler{max: max}
+	s.free = sync.NewCond(&s.mu)
+	return s
+}
+
+// maxTasksFromEnv reads the default bound from JABLINE_MAX_TASKS.
+func maxTasksFromEnv() int {
+	n, err := strconv.Atoi(os.Getenv("JABLINE_MAX_TASKS"))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// SetMaxTasks bounds the number of tasks running at once in the process.
+// Zero or less removes the bound, the default unless JABLINE_MAX_TASKS is
+// set.
+func SetMaxTasks(n int) {
+	tasks.mu.Lock()
+	defer tasks.mu.Unlock()
+	tasks.max = n
+	tasks.next()
+}
+
+// MaxTasks returns the bound set by SetMaxTasks.
+func MaxTasks() int {
+	tasks.mu.Lock()
+	defer tasks.mu.Unlock()
+	return tasks.max
+}
+
+// full reports whether no more task can run. Called with mu held.
+func (s *scheduler) full() bool {
+	return s.max > 0 && s.running >= s.max
+}
+
+// start runs task on a goroutine of its own once a place is free.
+func (s *scheduler) start(task func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.full() || s.resuming > 0 || len(s.queue) > 0 {
+		s.queue = append(s.queue, task)
+		return
+	}
+	s.running++
+	go s.run(task)
+}
+
+func (s *scheduler) run(task func()) {
+	task()
+	s.release()
+}
+
+// release gives the place of a task that ended or blocks to another.
+func (s *scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running--
+	s.next()
+}
+
+// acquire waits for a place for a task that blocked to run again.
+func (s *scheduler) acquire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resuming++
+	for s.full() {
+		s.free.Wait()
+	}
+	s.resuming--
+	s.running++
+}
+
+// next hands the free places to the tasks waiting to resume, or else to
+// those waiting to start. Called with mu held.
+func (s *scheduler) next() {
+	for !s.full() {
+		if s.resuming > 0 {
+			s.free.Broadcast()
+			return
+		}
+		if len(s.queue) == 0 {
+			return
+		}
+		task := s.queue[0]
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+		s.running++
+		go s.run(task)
+	}
+}
+
+// waiting gives the place of the task the VM runs to another before it
+// blocks; resume waits for a place again.
+func (vm *VM) waiting() (resume func()) {
+	if !vm.scheduled {
+		return func() {}
+	}
+	tasks.release()
+	return tasks.acquire
+}
+
+// buffers are the value and call stacks of a task VM, which the next task
+// reuses once it ends.
+type buffers struct {
+	stack  []object.Object
+	frames []*Frame
+}
+
+var bufferPool = sync.Pool{New: func() any {
+	return &buffers{
+		stack:  make([]object.Object, StackSize),
+		frames: make([]*Frame, MaxFrames),
+	}
+}}
+
+// taskMain is the empty function a task VM starts in, returning to it
+// once the function the task calls returns.
+var taskMain = &object.Closure{Fn: &object.CompiledFunction{}}
+
+// newTask returns a VM to run a task the VM starts, sharing its globals
+// and inheriting its context, limits and group. It gets its stacks from
+// load once the task starts.
+func (vm *VM) newTask() *VM {
+	task := &VM{
+		constants: vm.constants,
+		globals:   vm.globals,
+		filename:  vm.filename,
+		loader:    vm.loader,
+		methods:   make(map[string]map[string]*object.Closure),
+		usage:     &usage{},
+	}
+	vm.inherit(task)
+	task.scheduled = true
+	return task
+}
+
+// load gives a VM made by newTask its stacks, reusing those of a task that
+// ended unless limits sized them already.
+func (vm *VM) load() {
+	if vm.stack == nil || vm.frames == nil {
+		b := bufferPool.Get().(*buffers)
+		vm.buffers = b
+		if vm.stack == nil {
+			vm.stack = b.stack
+		}
+		if vm.frames == nil {
+			vm.frames = b.frames
+		}
+	}
+	vm.frames[0] = NewFrame(taskMain, 0)
+	vm.framesIndex = 1
+}
+
+// free gives the stacks of a task that ended back for the next task, once
+// its result was read.
+func (vm *VM) free() {
+	b := vm.buffers
+	if b == nil {
+		return
+	}
+	vm.buffers = nil
+	vm.stack, vm.frames = nil, nil
+	clear(b.stack)
+	clear(b.frames)
+	bufferPool.Put(b)
+}
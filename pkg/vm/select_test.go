@@ -0,0 +1,33 @@
+package vm
+
+import "testing"
+
+func TestSelectStatement(t *testing.T) {
+	tests := []vmTestCase{
+		{`let a = make_chan(); let b = make_chan(); send(b, 2);
+let r = 0; select { case v = <-a: r = v; case v = <-b: r = v * 10; } r`, inspected("20")},
+		{`let a = make_chan(); let r = "";
+select { case <-a: r = "a"; default: r = "default"; } r`, inspected("default")},
+		{`let a = make_chan(); let r = "";
+select { case <-a: r = "a"; case timeout(5): r = "timeout"; } r`, inspected("timeout")},
+		{`let a = make_chan(); select { case a <- 7: } recv(a)`, inspected("7")},
+		{`let v = "outer"; let a = make_chan(); send(a, "inner");
+let r = ""; select { case v = <-a: r = v; } r + v`, inspected("innerouter")},
+		{`fn f() { return 3; } let t = spawn f(); let r = 0;
+select { case v = <-t: r = v; case timeout(1000): r = -1; } r`, inspected("3")},
+		{`let n = 0; let a = make_chan();
+while (n < 5) { n = n + 1; select { case <-a: default: if (n == 3) { break; } } } n`, inspected("3")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestSelectErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`select { case <-1: }`, errorContaining("select case 1: cannot receive from INTEGER")},
+		{`let a = make_chan(); select { case <-a: case "x" <- 1: }`, errorContaining("select case 2: cannot send to STRING")},
+		{`select { case timeout("x"): }`, errorContaining("timeout must be an integer of milliseconds")},
+	}
+
+	runVmTests(t, tests)
+}
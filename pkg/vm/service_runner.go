@@ -14,7 +14,7 @@ func (vm *VM) StartService(service *object.Service) object.Object {
 	}
 	port := fmt.Sprintf("%d", portVal.(*object.Integer).Value)
 
-	fmt.Printf("🚀 Service '%s' listening on port %s...\n", service.Name, port)
+	fmt.Fprintf(vm.stdout(), "🚀 Service '%s' listening on port %s...\n", service.Name, port)
 
 	// Register Handler
 	handler := func(w http.ResponseWriter, r *http.Request) {
@@ -53,7 +53,7 @@ func (vm *VM) StartService(service *object.Service) object.Object {
 		
 		err := reqVM.Run()
 		if err != nil {
-			fmt.Println("Runtime Error:", err)
+			fmt.Fprintln(vm.stderr(), "Runtime Error:", err)
 			http.Error(w, err.Error(), 500)
 			return
 		}
@@ -0,0 +1,36 @@
+package vm
+
+import "sync"
+
+// The tasks of a program share its globals and the arrays, hashes and
+// instances they hold. Once a program starts a task, each read or write of
+// a global, a captured variable, an element or a field takes the shared
+// lock of the program, so that it sees a whole value. Reading then writing
+// is not atomic: tasks update shared values under a lock of the sync
+// module, or keep them in an Atomic or a SharedMap.
+
+// share gives the program the lock its tasks share, before it starts the
+// first one.
+func (vm *VM) share() {
+	if vm.shared == nil {
+		vm.shared = &sync.RWMutex{}
+	}
+}
+
+// readShared takes the shared lock for reading, if the program has tasks.
+func (vm *VM) readShared() (unlock func()) {
+	if vm.shared == nil {
+		return func() {}
+	}
+	vm.shared.RLock()
+	return vm.shared.RUnlock
+}
+
+// writeShared is readShared for writing.
+func (vm *VM) writeShared() (unlock func()) {
+	if vm.shared == nil {
+		return func() {}
+	}
+	vm.shared.Lock()
+	return vm.shared.Unlock
+}
@@ -1,13 +1,12 @@
 package vm
 
 import (
-	"fmt"
 	"jabline/pkg/object"
 )
 
 func (vm *VM) push(o object.Object) error {
-	if vm.sp >= StackSize {
-		return fmt.Errorf("stack overflow")
+	if vm.sp >= len(vm.stack) {
+		return ErrStackOverflow
 	}
 	vm.stack[vm.sp] = o
 	vm.sp++
@@ -0,0 +1,243 @@
+package vm
+
+import (
+	"jabline/pkg/code"
+	"jabline/pkg/object"
+)
+
+// nativeMethods holds the methods of the values of the sync module and of
+// task groups by type, with the number of arguments each takes.
+var nativeMethods = map[object.ObjectType]map[string]int{
+	object.MUTEX_OBJ: {"lock": 0, "unlock": 0, "tryLock": 0},
+	object.RWMUTEX_OBJ: {
+		"lock": 0, "unlock": 0, "tryLock": 0, "rLock": 0, "rUnlock": 0,
+	},
+	object.ATOMIC_OBJ: {
+		"load": 0, "store": 1, "swap": 1, "compareAndSwap": 2, "add": 1,
+	},
+	object.SHARED_MAP_OBJ: {
+		"get": 1, "set": 2, "delete": 1, "has": 1, "len": 0, "keys": 0,
+		"snapshot": 0, "add": 2, "setIfAbsent": 2,
+	},
+	object.TASK_GROUP_OBJ: {"cancel": 0, "cancelled": 0},
+}
+
+// executeNativeIndex pushes the method name of receiver, bound to it.
+func (vm *VM) executeNativeIndex(receiver object.Object, name string) error {
+	if _, ok := nativeMethods[receiver.Type()][name]; !ok {
+		return keyError("field or method '%s' not found in %s", name, receiver.Type())
+	}
+	return vm.push(&object.NativeMethod{Receiver: receiver, Name: name})
+}
+
+// callNativeMethod calls m with the numArgs arguments on the stack and
+// replaces them and the method with its result.
+func (vm *VM) callNativeMethod(m *object.NativeMethod, numArgs int) error {
+	if want := nativeMethods[m.Receiver.Type()][m.Name]; numArgs != want {
+		return typeError("wrong number of arguments to %s.%s: want=%d, got=%d",
+			m.Receiver.Type(), m.Name, want, numArgs)
+	}
+	args := make([]object.Object, numArgs)
+	copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+	vm.sp = vm.sp - numArgs - 1
+
+	var result object.Object
+	var err error
+	switch receiver := m.Receiver.(type) {
+	case *object.Mutex:
+		result, err = vm.callMutexMethod(receiver, m.Name)
+	case *object.RWMutex:
+		result, err = vm.callRWMutexMethod(receiver, m.Name)
+	case *object.Atomic:
+		result, err = vm.callAtomicMethod(receiver, m.Name, args)
+	case *object.SharedMap:
+		result, err = vm.callSharedMapMethod(receiver, m.Name, args)
+	case *object.TaskGroup:
+		result, err = vm.callTaskGroupMethod(receiver, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	return vm.pushNew(result)
+}
+
+// The locks of the sync module order the tasks using them for race
+// detection: unlocking one releases the clock of the task into it, and
+// locking it acquires that clock.
+
+func (vm *VM) callMutexMethod(m *object.Mutex, name string) (object.Object, error) {
+	switch name {
+	case "lock":
+		resume := vm.waiting()
+		m.Lock()
+		resume()
+		vm.race.acquire(m)
+	case "tryLock":
+		if !m.TryLock() {
+			return False, nil
+		}
+		vm.race.acquire(m)
+		return True, nil
+	case "unlock":
+		vm.race.release(m)
+		if err := m.Unlock(); err != nil {
+			return nil, err
+		}
+	}
+	return Null, nil
+}
+
+func (vm *VM) callRWMutexMethod(m *object.RWMutex, name string) (object.Object, error) {
+	switch name {
+	case "lock":
+		resume := vm.waiting()
+		m.Lock()
+		resume()
+		vm.race.acquire(m)
+	case "tryLock":
+		if !m.TryLock() {
+			return False, nil
+		}
+		vm.race.acquire(m)
+		return True, nil
+	case "unlock":
+		vm.race.release(m)
+		if err := m.Unlock(); err != nil {
+			return nil, err
+		}
+	case "rLock":
+		resume := vm.waiting()
+		m.RLock()
+		resume()
+		vm.race.acquire(m)
+	case "rUnlock":
+		if err := m.RUnlock(); err != nil {
+			return nil, err
+		}
+	}
+	return Null, nil
+}
+
+// callAtomicMethod runs a method of an Atomic. Each one that changes the
+// value releases into it, after acquiring what the previous ones did.
+func (vm *VM) callAtomicMethod(a *object.Atomic, name string, args []object.Object) (object.Object, error) {
+	if name == "load" {
+		value := a.Load()
+		vm.race.acquire(a)
+		return value, nil
+	}
+
+	var result object.Object
+	_, err := a.Update(func(old object.Object) (object.Object, error) {
+		vm.race.acquire(a)
+		vm.race.release(a)
+		switch name {
+		case "store":
+			result = Null
+			return args[0], nil
+		case "swap":
+			result = old
+			return args[0], nil
+		case "compareAndSwap":
+			if !patternEquals(old, args[0]) {
+				result = False
+				return old, nil
+			}
+			result = True
+			return args[1], nil
+		default: // add
+			sum, err := vm.addNumbers(old, args[0])
+			result = sum
+			return sum, err
+		}
+	})
+	return result, err
+}
+
+// sharedEntry is an entry of a SharedMap, which orders the tasks using it
+// for race detection like an Atomic.
+type sharedEntry struct {
+	m   *object.SharedMap
+	key object.HashKey
+}
+
+// callSharedMapMethod runs a method of a SharedMap.
+func (vm *VM) callSharedMapMethod(m *object.SharedMap, name string, args []object.Object) (object.Object, error) {
+	switch name {
+	case "len":
+		return &object.Integer{Value: int64(m.Len())}, nil
+	case "keys":
+		snapshot := m.Snapshot()
+		keys := make([]object.Object, 0, len(snapshot.Pairs))
+		for _, pair := range snapshot.Pairs {
+			keys = append(keys, pair.Key)
+		}
+		return &object.Array{Elements: keys}, nil
+	case "snapshot":
+		return m.Snapshot(), nil
+	}
+
+	key, ok := args[0].(object.Hashable)
+	if !ok {
+		return nil, typeError("unusable as hash key: %s", args[0].Type())
+	}
+	entry := sharedEntry{m, key.HashKey()}
+	switch name {
+	case "get", "has":
+		value, ok := m.Get(key)
+		vm.race.acquire(entry)
+		if name == "has" {
+			return nativeBoolToBooleanObj(ok), nil
+		}
+		if !ok {
+			return Null, nil
+		}
+		return value, nil
+	case "delete":
+		vm.race.acquire(entry)
+		vm.race.release(entry)
+		return nativeBoolToBooleanObj(m.Delete(key)), nil
+	}
+
+	var result object.Object
+	_, err := m.Update(key, func(old object.Object, ok bool) (object.Object, error) {
+		vm.race.acquire(entry)
+		vm.race.release(entry)
+		switch name {
+		case "set":
+			result = Null
+			return args[1], nil
+		case "setIfAbsent":
+			result = nativeBoolToBooleanObj(!ok)
+			if ok {
+				return old, nil
+			}
+			return args[1], nil
+		default: // add
+			if !ok {
+				old = &object.Integer{Value: 0}
+			}
+			sum, err := vm.addNumbers(old, args[1])
+			result = sum
+			return sum, err
+		}
+	})
+	return result, err
+}
+
+// addNumbers adds two numbers as the + operator does.
+func (vm *VM) addNumbers(left, right object.Object) (object.Object, error) {
+	if !isNumber(left) || !isNumber(right) {
+		return nil, typeError("unsupported types for add: %s %s", left.Type(), right.Type())
+	}
+	if err := vm.push(left); err != nil {
+		return nil, err
+	}
+	if err := vm.push(right); err != nil {
+		return nil, err
+	}
+	if err := vm.executeBinaryOperation(code.OpAdd); err != nil {
+		return nil, err
+	}
+	return vm.pop(), nil
+}
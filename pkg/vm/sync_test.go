@@ -0,0 +1,67 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"jabline/pkg/compiler"
+)
+
+func TestSyncModule(t *testing.T) {
+	tests := []vmTestCase{
+		{`import * as sync from "_sync"; let m = sync.Mutex(); m.lock(); let a = m.tryLock(); m.unlock(); [a, m.tryLock()]`, inspected("[false, true]")},
+		{`import * as sync from "_sync"; let m = sync.RWMutex(); m.rLock(); m.rLock(); let a = m.tryLock(); m.rUnlock(); m.rUnlock(); [a, m.tryLock()]`, inspected("[false, true]")},
+		{`import * as sync from "_sync"; let a = sync.Atomic(1); a.add(2); [a.load(), a.swap(5), a.compareAndSwap(4, 0), a.compareAndSwap(5, 0), a.load()]`, inspected("[3, 3, false, true, 0]")},
+		{`import * as sync from "_sync"; let m = sync.SharedMap({"a": 1}); m.add("a", 2); m.add("b", 1); [m.get("a"), m.get("b"), m.get("c"), m.setIfAbsent("b", 9), m.delete("a"), m.has("a"), len(m)]`, inspected("[3, 1, null, false, true, false, 1]")},
+		{`import * as sync from "_sync"; let m = sync.Mutex(); let n = 0; fn inc() { for (let i = 0; i < 50; i = i + 1) { m.lock(); n = n + 1; m.unlock(); } } let ts = []; for (let i = 0; i < 4; i = i + 1) { ts = push(ts, spawn inc()); } for (t in ts) { await t; } n`, inspected("200")},
+		{`import * as sync from "_sync"; let a = sync.Atomic(0); fn inc() { for (let i = 0; i < 50; i = i + 1) { a.add(1); } } let ts = []; for (let i = 0; i < 4; i = i + 1) { ts = push(ts, spawn inc()); } for (t in ts) { await t; } a.load()`, inspected("200")},
+		{`import * as sync from "_sync"; let m = sync.Mutex(); let r = null; try { m.unlock(); } catch (e: ValueError) { r = e.message; } r`, inspected("unlock of unlocked mutex")},
+		{`let h = {}; fn fill(id) { for (let i = 0; i < 500; i = i + 1) { set(h, i, id); let v = h[0]; } } let ts = []; for (let id = 0; id < 3; id = id + 1) { ts = push(ts, spawn fill(id)); } for (t in ts) { await t; } len(h)`, inspected("500")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestSyncModuleErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`import * as sync from "_sync"; sync.Mutex().wait();`, errorContaining("field or method 'wait' not found in MUTEX")},
+		{`import * as sync from "_sync"; sync.Atomic(0).store();`, errorContaining("wrong number of arguments to ATOMIC.store: want=1, got=0")},
+		{`import * as sync from "_sync"; sync.Atomic("a").add(1);`, errorContaining("unsupported types for add: STRING INTEGER")},
+		{`import * as sync from "_sync"; sync.SharedMap().get([1]);`, errorContaining("unusable as hash key: ARRAY")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRaceDetection(t *testing.T) {
+	tests := []struct {
+		input string
+		races int
+	}{
+		{`let n = 0; fn inc() { n = n + 1; } let a = spawn inc(); let b = spawn inc(); await a; await b;`, 1},
+		{`let n = 0; fn inc() { n = n + 1; } await spawn inc(); await spawn inc(); n = 5;`, 0},
+		{`let n = 0; let ch = make_chan(0); fn inc() { n = 1; ch <- true; } spawn inc(); <-ch; n = 2;`, 0},
+		{`import * as sync from "_sync"; let m = sync.Mutex(); let n = 0; fn inc() { m.lock(); n = n + 1; m.unlock(); } let a = spawn inc(); let b = spawn inc(); await a; await b;`, 0},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		bytecode := comp.Bytecode()
+		machine := New(bytecode.Instructions, bytecode.Constants, "race.jb")
+		var out strings.Builder
+		machine.DetectRaces(&out, bytecode.SymbolTable.LocalNames())
+		if err := machine.Run(); err != nil {
+			t.Errorf("%s: vm error: %s", tt.input, err)
+			continue
+		}
+		if got := machine.Races(); got != tt.races {
+			t.Errorf("%s: want %d races, got %d:\n%s", tt.input, tt.races, got, out.String())
+		}
+		if tt.races > 0 && !strings.Contains(out.String(), "WARNING: data race on global n") {
+			t.Errorf("%s: unexpected report:\n%s", tt.input, out.String())
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package vm
+
+import (
+	"strings"
+	"sync"
+
+	"jabline/pkg/code"
+	"jabline/pkg/object"
+)
+
+// typeSpec is a type annotation as the compiler records it for
+// OpCheckType: a name with optional type arguments (Box[int]), possibly
+// nullable (int?), or a union of such types (int | string).
+type typeSpec struct {
+	name     string
+	args     []*typeSpec
+	nullable bool
+	union    []*typeSpec
+}
+
+// typeSpecs caches parsed annotations by their source text. VMs running
+// service handlers share it, hence the sync.Map.
+var typeSpecs sync.Map
+
+func parseTypeSpec(s string) *typeSpec {
+	if cached, ok := typeSpecs.Load(s); ok {
+		return cached.(*typeSpec)
+	}
+
+	t := &typeSpec{}
+	if alts := splitTopLevel(s, '|'); len(alts) > 1 {
+		for _, alt := range alts {
+			t.union = append(t.union, parseTypeSpec(alt))
+		}
+	} else {
+		name := strings.TrimSpace(s)
+		if strings.HasSuffix(name, "?") {
+			t.nullable = true
+			name = strings.TrimSuffix(name, "?")
+		}
+		if open := strings.IndexByte(name, '['); open >= 0 && strings.HasSuffix(name, "]") {
+			for _, arg := range splitTopLevel(name[open+1:len(name)-1], ',') {
+				t.args = append(t.args, parseTypeSpec(arg))
+			}
+			name = name[:open]
+		}
+		t.name = name
+	}
+
+	typeSpecs.Store(s, t)
+	return t
+}
+
+// splitTopLevel splits s at every sep that is not inside brackets.
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+func (t *typeSpec) String() string {
+	if len(t.union) > 0 {
+		alts := make([]string, len(t.union))
+		for i, alt := range t.union {
+			alts[i] = alt.String()
+		}
+		return strings.Join(alts, " | ")
+	}
+	var out strings.Builder
+	out.WriteString(t.name)
+	if len(t.args) > 0 {
+		args := make([]string, len(t.args))
+		for i, arg := range t.args {
+			args[i] = arg.String()
+		}
+		out.WriteString("[" + strings.Join(args, ", ") + "]")
+	}
+	if t.nullable {
+		out.WriteString("?")
+	}
+	return out.String()
+}
+
+// resolve substitutes the type arguments the frame was instantiated with
+// for the type parameters in t.
+func (t *typeSpec) resolve(typeArgs map[string]string) *typeSpec {
+	if len(typeArgs) == 0 {
+		return t
+	}
+	if len(t.union) > 0 {
+		u := &typeSpec{}
+		for _, alt := range t.union {
+			u.union = append(u.union, alt.resolve(typeArgs))
+		}
+		return u
+	}
+	if resolved, ok := typeArgs[t.name]; ok && len(t.args) == 0 {
+		r := parseTypeSpec(resolved)
+		if t.nullable && !r.nullable {
+			copied := *r
+			copied.nullable = true
+			return &copied
+		}
+		return r
+	}
+	r := &typeSpec{name: t.name, nullable: t.nullable}
+	for _, arg := range t.args {
+		r.args = append(r.args, arg.resolve(typeArgs))
+	}
+	return r
+}
+
+func (vm *VM) opCheckType(ins code.Instructions, ip *int) error {
+	typeIdx := int(code.ReadUint16(ins[*ip+1:]))
+	*ip += 2
+
+	// Peek at the value on top of the stack (don't pop it!)
+	val := vm.stack[vm.sp-1]
+	frame := vm.currentFrame()
+
+	expected := parseTypeSpec(vm.constants[typeIdx].(*object.String).Value).resolve(frame.TypeArgs)
+	converted, ok, err := matchType(val, expected, frame.cl.Fn.TypeParameters)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return typeError("type error: expected type %s, got %s", expected, describeType(val))
+	}
+	vm.stack[vm.sp-1] = converted
+	return nil
+}
+
+// matchType reports whether val has type t. A plain number declared with a
+// sized type is converted to it, so the value to store is returned too.
+// Type parameters still unresolved in the running function (typeParams)
+// accept any value.
+func matchType(val object.Object, t *typeSpec, typeParams []string) (object.Object, bool, error) {
+	if len(t.union) > 0 {
+		for _, alt := range t.union {
+			if converted, ok, err := matchType(val, alt, typeParams); ok || err != nil {
+				return converted, ok, err
+			}
+		}
+		return val, false, nil
+	}
+	if t.nullable && val.Type() == object.NULL_OBJ {
+		return val, true, nil
+	}
+
+	switch t.name {
+	case "any":
+		return val, true, nil
+	case "int":
+		return val, val.Type() == object.INTEGER_OBJ, nil
+	case "float":
+		return val, val.Type() == object.FLOAT_OBJ, nil
+	case "string":
+		return val, val.Type() == object.STRING_OBJ, nil
+	case "bool":
+		return val, val.Type() == object.BOOLEAN_OBJ, nil
+	case "null":
+		return val, val.Type() == object.NULL_OBJ, nil
+	case "array", "Array":
+		return val, val.Type() == object.ARRAY_OBJ, nil
+	case "hash", "Hash", "map", "Map":
+		return val, val.Type() == object.HASH_OBJ, nil
+	case "fn":
+		switch val.(type) {
+		case *object.Closure, *object.Builtin, *object.InstantiatedFunction:
+			return val, true, nil
+		}
+		return val, false, nil
+	}
+
+	// Sized numeric types are declared by their lowercase names. A plain
+	// number declared with one is converted to it.
+	if st := object.ObjectType(strings.ToUpper(t.name)); object.IsSizedType(st) {
+		if val.Type() == st {
+			return val, true, nil
+		}
+		if val.Type() == object.INTEGER_OBJ || val.Type() == object.FLOAT_OBJ {
+			converted, err := coerceNumber(val, st)
+			if err != nil {
+				return val, false, typeError("type error: %s", err)
+			}
+			return converted, true, nil
+		}
+		return val, false, nil
+	}
+
+	for _, tp := range typeParams {
+		if tp == t.name {
+			return val, true, nil
+		}
+	}
+
+	if inst, ok := val.(*object.Instance); ok {
+		return val, instanceOf(inst.StructName, t), nil
+	}
+	if ev, ok := val.(*object.EnumValue); ok {
+		// An enum value has the type of its enum and of its variant.
+		v := ev.Variant
+		return val, t.name == v.Enum || t.name == v.Enum+"."+v.Name, nil
+	}
+	if e, ok := val.(*object.Error); ok && object.IsErrorKind(t.name) {
+		return val, e.Is(t.name), nil
+	}
+	return val, string(val.Type()) == t.name, nil
+}
+
+// instanceOf matches a struct instance by name. Type arguments are only
+// compared when both the instance and the annotation carry them.
+func instanceOf(structName string, t *typeSpec) bool {
+	base := structName
+	if open := strings.IndexByte(structName, '['); open >= 0 {
+		base = structName[:open]
+	}
+	if base != t.name {
+		return false
+	}
+	if len(t.args) == 0 || base == structName {
+		return true
+	}
+	plain := *t
+	plain.nullable = false
+	return structName == plain.String()
+}
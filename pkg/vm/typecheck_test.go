@@ -0,0 +1,22 @@
+package vm
+
+import "testing"
+
+func TestCheckTypeAnnotations(t *testing.T) {
+	tests := []vmTestCase{
+		{`let a: int? = null; a`, nil},
+		{`let a: int? = 1; a`, nil},
+		{`let a: int | string = "s"; a`, nil},
+		{`let a: any = [1]; a`, nil},
+		{`let a: Array[int] = [1]; a`, nil},
+		{`fn f(x: int8 | string) { return x; } type(f(5))`, nil},
+		{`struct Box[T] { v: T } fn f(b: Box[int]?) { return b; } f(Box[int]{ v: 1 }); f(null)`, nil},
+		{`fn id[T](x: T): T { return x; } id[int](1)`, nil},
+		{`fn f(x: int?) { return x; } f("s")`, errorContaining("type error: expected type int?, got STRING")},
+		{`fn f(x: int | bool) { return x; } f(1.5)`, errorContaining("type error: expected type int | bool, got FLOAT")},
+		{`fn f(x: Hash) { return x; } f([1])`, errorContaining("type error: expected type Hash, got ARRAY")},
+		{`fn f(x: int8?) { return x; } f(300)`, errorContaining("type error: 300 overflows INT8")},
+	}
+
+	runVmTests(t, tests)
+}
@@ -1,14 +1,22 @@
 package vm
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"jabline/pkg/code"
+	"jabline/pkg/compiler"
 	"jabline/pkg/object"
 	"jabline/pkg/stdlib"
+	"os"
+	"sync"
 )
 
 func init() {
 	stdlib.Executor = ExecuteClosureBridge
+	stdlib.Parallel = runParallel
+	stdlib.Next = resumeNext
 }
 
 const StackSize = 2048
@@ -16,11 +24,18 @@ const GlobalsSize = 65536
 const MaxFrames = 1024
 
 var (
-	True  = &object.Boolean{Value: true}
-	False = &object.Boolean{Value: false}
-	Null  = &object.Null{}
+	True  = object.TrueValue
+	False = object.FalseValue
+	Null  = object.NullValue
 )
 
+// missingArgument stands for an optional argument a call left out, until
+// the function gives its parameter the default. It reads as null, but is a
+// type of its own so that an explicit null keeps its value.
+type missingArgument struct{ object.Null }
+
+var missing object.Object = &missingArgument{}
+
 type VM struct {
 	constants []object.Object
 	stack     []object.Object
@@ -35,6 +50,31 @@ type VM struct {
 	loader   *ModuleLoader
 
 	methods map[string]map[string]*object.Closure
+
+	ctx    context.Context
+	limits Limits
+	usage  *usage
+	steps  int
+	// exceeded is the cancellation or instruction budget error already
+	// raised, and grace the instructions left before it is raised again.
+	exceeded error
+	grace    int
+
+	debugger Debugger
+
+	// shared is the lock the tasks of the program share, set once it
+	// starts one, and race the task it is when races are detected.
+	shared *sync.RWMutex
+	race   *raceTask
+
+	// groups are the group statements the VM is running, the innermost
+	// last. A task starts in the innermost group of the VM starting it.
+	groups []groupScope
+
+	// scheduled is set when the VM runs a task of the scheduler, holding
+	// one of its places, and buffers are the stacks it reuses, if any.
+	scheduled bool
+	buffers   *buffers
 }
 
 type ExceptionHandler struct {
@@ -66,6 +106,7 @@ func NewWithLoader(instructions code.Instructions, constants []object.Object, fi
 		filename:    filename,
 		loader:      loader,
 		methods:     make(map[string]map[string]*object.Closure),
+		usage:       &usage{},
 	}
 }
 
@@ -75,43 +116,83 @@ func NewWithGlobalsStore(instructions code.Instructions, constants []object.Obje
 	return vm
 }
 
-func (vm *VM) newRuntimeError(format string, a ...interface{}) *RuntimeError {
-	msg := fmt.Sprintf(format, a...)
+// SetSourceMap attaches the source map of the top-level program so runtime
+// errors raised in <main> carry line information.
+func (vm *VM) SetSourceMap(sourceMap code.SourceMap) {
+	vm.frames[0].cl.Fn.SourceMap = sourceMap
+}
 
-	var trace []CallFrame
-	for i := 0; i < vm.framesIndex; i++ {
-		frm := vm.frames[i]
-		var pos code.SourcePos
-		fnName := "<main>"
+// SetModules makes the modules bundled into a built program available to
+// imports.
+func (vm *VM) SetModules(modules map[string]*compiler.Bytecode) {
+	vm.loader.SetBundle(modules)
+}
 
-		if frm.cl != nil && frm.cl.Fn != nil {
-			if frm.cl.Fn.SourceMap != nil {
-				pos = frm.cl.Fn.SourceMap[frm.ip]
-			}
-			if frm.cl.Fn.Name != "" {
-				fnName = frm.cl.Fn.Name
-			} else if i > 0 {
-				fnName = "<anonymous>"
-			}
-		}
+// SetOutput redirects what the program and the modules it imports write.
+// Either writer may be nil to keep the current one.
+func (vm *VM) SetOutput(stdout, stderr io.Writer) {
+	if vm.loader == nil {
+		return
+	}
+	if stdout != nil {
+		vm.loader.stdout = stdout
+	}
+	if stderr != nil {
+		vm.loader.stderr = stderr
+	}
+}
 
-		trace = append(trace, CallFrame{
-			Function: fnName,
-			File:     vm.filename,
-			Line:     pos.Line,
-			Column:   pos.Column,
-		})
+func (vm *VM) stdout() io.Writer {
+	if vm.loader == nil || vm.loader.stdout == nil {
+		return os.Stdout
 	}
+	return vm.loader.stdout
+}
+
+func (vm *VM) stderr() io.Writer {
+	if vm.loader == nil || vm.loader.stderr == nil {
+		return os.Stderr
+	}
+	return vm.loader.stderr
+}
+
+// SetGlobals replaces the global store, so state can carry over between
+// programs compiled against the same symbol table.
+func (vm *VM) SetGlobals(globals []object.Object) {
+	vm.globals = globals
+}
 
+func (vm *VM) newRuntimeError(format string, a ...interface{}) *RuntimeError {
 	return &RuntimeError{
-		Message:    msg,
-		StackTrace: trace,
+		Message:    fmt.Sprintf(format, a...),
+		StackTrace: vm.CallStack(),
 	}
 }
 
-func (vm *VM) handleNativeError(msg string) error {
+// errCaught is returned by execute when an error was caught by a try
+// block, so Run resumes at its catch clause.
+var errCaught = errors.New("error caught")
+
+// raise throws err as a runtime error: to the innermost try block, or to the
+// caller of Run if there is none. Frames left on the way make their deferred
+// calls first. The catch block receives an Error classified by errorKind,
+// traced from where err was first raised.
+func (vm *VM) raise(err error) error {
+	if vm.debugger != nil {
+		vm.debugger.Exception(vm, err, len(vm.handlers) == 0)
+	}
+	re, ok := err.(*raisedError)
+	if !ok {
+		re = &raisedError{err: err, stack: vm.CallStack()}
+	}
+	if vm.unwindToDefers(vm.handlerFrame(), &unwinding{err: re}) {
+		if err := vm.runDefers(); err != nil {
+			return vm.raise(err)
+		}
+		return errCaught
+	}
 	if len(vm.handlers) == 0 {
-		return vm.newRuntimeError("%s", msg)
+		return vm.fatal(re)
 	}
 
 	handler := vm.handlers[len(vm.handlers)-1]
@@ -119,18 +200,52 @@ func (vm *VM) handleNativeError(msg string) error {
 
 	// Unwind stack
 	vm.sp = handler.StackSP
-	vm.framesIndex = handler.FrameIndex
+	vm.unwind(handler.FrameIndex)
 
-	// Convert msg to Error object and push to stack for catch
-	vm.stack[vm.sp] = &object.Error{Message: msg}
+	// Convert the error to an Error object and push it for the catch block
+	vm.stack[vm.sp] = exceptionFor(re)
 	vm.sp++
 
 	// Jump to catch block (offset by -1 because Run loop increments it)
 	vm.currentFrame().ip = handler.CatchIP - 1
-	return nil
+	return errCaught
+}
+
+// fatal reports err to the caller of Run, bypassing any try block.
+func (vm *VM) fatal(err error) error {
+	stack := vm.CallStack()
+	if re, ok := err.(*raisedError); ok {
+		err, stack = re.err, re.stack
+	}
+	if rtErr, ok := err.(*RuntimeError); ok {
+		return rtErr // an uncaught exception, traced where it was thrown
+	}
+	return &RuntimeError{Message: err.Error(), StackTrace: stack, Cause: err}
 }
 
-func (vm *VM) Run() (err error) {
+func (vm *VM) Run() error {
+	for {
+		err := vm.execute()
+		if err != errCaught {
+			if err != nil {
+				vm.abandonGenerators()
+			}
+			return err
+		}
+	}
+}
+
+// abandonGenerators ends the generators still running in the frames of a
+// VM that stopped on an error, so they cannot be resumed half way.
+func (vm *VM) abandonGenerators() {
+	for _, frame := range vm.frames[:vm.framesIndex] {
+		if frame.generator != nil {
+			frame.generator.Finish()
+		}
+	}
+}
+
+func (vm *VM) execute() (err error) {
 	var ip int
 	var ins code.Instructions
 	var op code.Opcode
@@ -139,19 +254,18 @@ func (vm *VM) Run() (err error) {
 
 	defer func() {
 		if r := recover(); r != nil {
-			errStr := fmt.Sprintf("panic: %v", r)
-			// Handle as native error if possible
-			err = vm.handleNativeError(errStr)
-			// If we handled it (err == nil), it means we jumped to catch.
-			// We need to resume execution.
-			if err == nil {
-				err = vm.Run()
-			}
+			err = vm.raise(fmt.Errorf("panic: %v", r))
 		}
 	}()
 
 	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
 		vm.currentFrame().ip++
+		if err := vm.checkLimits(); err != nil {
+			return err
+		}
+		if vm.debugger != nil {
+			vm.debugger.Step(vm)
+		}
 
 		ip = vm.currentFrame().ip
 		ins = vm.currentFrame().Instructions()
@@ -160,37 +274,37 @@ func (vm *VM) Run() (err error) {
 		switch op {
 		case code.OpConstant:
 			if err := vm.opConstant(ins, &ip); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpPop:
 			vm.opPop()
 		case code.OpDup:
 			if err := vm.opDup(); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShiftLeft, code.OpShiftRight:
 			if err := vm.opBinary(op); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpTrue:
 			if err := vm.opTrue(); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpFalse:
 			if err := vm.opFalse(); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpNull:
 			if err := vm.opNull(); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan:
 			if err := vm.opComparison(op); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpBang, code.OpMinus, code.OpBitNot:
 			if err := vm.opPrefix(op); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpJump:
 			vm.opJump(ins, &ip)
@@ -202,6 +316,8 @@ func (vm *VM) Run() (err error) {
 			vm.opJumpTruthyKeep(ins, &ip)
 		case code.OpJumpNotNull:
 			vm.opJumpNotNull(ins, &ip)
+		case code.OpJumpNotMissing:
+			vm.opJumpNotMissing(ins, &ip)
 		case code.OpJumpIfEqual:
 			vm.opJumpIfEqual(ins, &ip)
 		case code.OpJumpIfTrue:
@@ -210,39 +326,43 @@ func (vm *VM) Run() (err error) {
 			vm.opSetGlobal(ins, &ip)
 		case code.OpGetGlobal:
 			if err := vm.opGetGlobal(ins, &ip); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpSetLocal:
 			vm.opSetLocal(ins, &ip)
 		case code.OpGetLocal:
 			if err := vm.opGetLocal(ins, &ip); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpGetBuiltin:
 			if err := vm.opGetBuiltin(ins, &ip); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpGetFree:
 			if err := vm.opGetFree(ins, &ip); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpSetFree:
 			vm.opSetFree(ins, &ip)
 		case code.OpArray:
 			if err := vm.opArray(ins, &ip); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpHash:
 			if err := vm.opHash(ins, &ip); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
+			}
+		case code.OpConcat:
+			if err := vm.opConcat(ins, &ip); err != nil {
+				return vm.raise(err)
 			}
 		case code.OpIndex:
 			if err := vm.opIndex(); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpSetProperty:
 			if err := vm.opSetProperty(); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpCall:
 			// Handle OpCall manually to manage IP updates correctly before frame switch
@@ -251,13 +371,25 @@ func (vm *VM) Run() (err error) {
 			vm.currentFrame().ip = ip // Save the updated IP to the current frame (caller)
 
 			if err := vm.executeCall(numArgs); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 			continue // Continue loop with the new frame (callee)
 
+		case code.OpCallArgs:
+			numArgs, err := vm.spreadArguments(ins, &ip)
+			if err != nil {
+				return vm.raise(err)
+			}
+			vm.currentFrame().ip = ip // As for OpCall, the caller resumes after the operands
+
+			if err := vm.executeCall(numArgs); err != nil {
+				return vm.raise(err)
+			}
+			continue
+
 		case code.OpReturnValue:
 			if err := vm.opReturnValue(); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 			if vm.framesIndex == 0 {
 				return nil
@@ -265,64 +397,132 @@ func (vm *VM) Run() (err error) {
 			continue // Frame popped, refresh.
 		case code.OpReturn:
 			if err := vm.opReturn(); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 			if vm.framesIndex == 0 {
 				return nil
 			}
 			continue // Frame popped, refresh.
 
+		case code.OpYield:
+			vm.currentFrame().ip = ip // Resume after the yield
+			if err := vm.opYield(); err != nil {
+				return vm.raise(err)
+			}
+			if vm.framesIndex == 0 {
+				return nil
+			}
+			continue // Frame popped, refresh.
+		case code.OpGetIterator:
+			vm.currentFrame().ip = ip // The iter method of a struct runs in a new frame
+			if err := vm.opGetIterator(); err != nil {
+				return vm.raise(err)
+			}
+			continue
+		case code.OpIterNext:
+			vm.currentFrame().ip = ip // A generator continues in a new frame
+			if err := vm.opIterNext(); err != nil {
+				return vm.raise(err)
+			}
+			continue
+		case code.OpIterNextPair:
+			if err := vm.opIterNextPair(); err != nil {
+				return vm.raise(err)
+			}
+
 		case code.OpAwait:
 			if err := vm.opAwait(); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpGetProperty:
 			if err := vm.opIndex(); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpCheckType:
 			if err := vm.opCheckType(ins, &ip); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpSendChannel:
 			if err := vm.opSendChannel(); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpRecvChannel:
 			if err := vm.opRecvChannel(); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
+			}
+		case code.OpRecvChannelOk:
+			if err := vm.opRecvChannelOk(); err != nil {
+				return vm.raise(err)
+			}
+		case code.OpGroup:
+			if err := vm.opGroup(); err != nil {
+				return vm.raise(err)
+			}
+		case code.OpGroupEnd:
+			if err := vm.opGroupEnd(); err != nil {
+				return vm.raise(err)
 			}
 		case code.OpCurrentClosure:
 			vm.opCurrentClosure()
 		case code.OpInstantiate:
 			if err := vm.opInstantiate(ins, &ip); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpClosure:
 			if err := vm.opClosure(ins, &ip); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpInstance:
 			if err := vm.opInstance(ins, &ip); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpImport:
 			if err := vm.opImport(ins, &ip); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpSpawn:
 			if err := vm.opSpawn(ins, &ip); err != nil {
-				return vm.handleNativeError(err.Error())
+				return vm.raise(err)
 			}
 		case code.OpTry:
 			vm.opTry(ins, &ip)
 		case code.OpEndTry:
 			vm.opEndTry()
+		case code.OpDefer:
+			vm.opDefer(ins, &ip)
 		case code.OpThrow:
 			if err := vm.opThrow(); err != nil {
-				return vm.newRuntimeError("%s", err.Error())
+				return vm.fatal(err)
 			}
 			continue
+		case code.OpIsType:
+			if err := vm.opIsType(ins, &ip); err != nil {
+				return vm.raise(err)
+			}
+		case code.OpMatchValue:
+			if err := vm.opMatchValue(); err != nil {
+				return vm.raise(err)
+			}
+		case code.OpMatchRange:
+			if err := vm.opMatchRange(ins, &ip); err != nil {
+				return vm.raise(err)
+			}
+		case code.OpMatchArray:
+			if err := vm.opMatchArray(ins, &ip); err != nil {
+				return vm.raise(err)
+			}
+		case code.OpMatchHash:
+			if err := vm.opMatchHash(ins, &ip); err != nil {
+				return vm.raise(err)
+			}
+		case code.OpArrayRest:
+			if err := vm.opArrayRest(ins, &ip); err != nil {
+				return vm.raise(err)
+			}
+		case code.OpSelect:
+			if err := vm.opSelect(ins, &ip); err != nil {
+				return vm.raise(err)
+			}
 		case code.OpRegisterMethod:
 			if err := vm.opRegisterMethod(ins, &ip); err != nil {
 				return vm.newRuntimeError("%s", err.Error())
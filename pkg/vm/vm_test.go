@@ -7,7 +7,9 @@ import (
 	"jabline/pkg/lexer"
 	"jabline/pkg/object"
 	"jabline/pkg/parser"
+	"strings"
 	"testing"
+	"time"
 )
 
 func parse(input string) *ast.Program {
@@ -31,40 +33,85 @@ func testIntegerObject(expected int64, actual object.Object) error {
 	return nil
 }
 
+func testStringObject(expected string, actual object.Object) error {
+	result, ok := actual.(*object.String)
+	if !ok {
+		return fmt.Errorf("object is not String. got=%T (%+v)",
+			actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%q, want=%q",
+			result.Value, expected)
+	}
+
+	return nil
+}
+
 type vmTestCase struct {
 	input    string
 	expected interface{}
 }
 
+// Expected values runVmTests checks besides ints, strings and exact errors.
+type (
+	// inspected is the Inspect form of the value the program leaves, such
+	// as an array.
+	inspected string
+	// typed is a value of a given type, with its Inspect form.
+	typed struct {
+		typ     object.ObjectType
+		inspect string
+	}
+	// errorContaining is part of the error the program fails with.
+	errorContaining string
+)
+
+// newTestVM compiles input into a VM running it as test.jb.
+func newTestVM(t *testing.T, input string) *VM {
+	t.Helper()
+
+	comp := compiler.New()
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := comp.Bytecode()
+	return New(bytecode.Instructions, bytecode.Constants, "test.jb")
+}
+
 func runVmTests(t *testing.T, tests []vmTestCase) {
 	t.Helper()
 
 	for _, tt := range tests {
-		program := parse(tt.input)
+		vm := newTestVM(t, tt.input)
 
-		comp := compiler.New()
-		err := comp.Compile(program)
-		if err != nil {
-			t.Fatalf("compiler error: %s", err)
+		// Programs running tasks must not hang the tests.
+		var err error
+		done := make(chan error, 1)
+		go func() { done <- vm.Run() }()
+		select {
+		case err = <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: program did not end", tt.input)
 		}
 
-		bytecode := comp.Bytecode()
-		vm := New(bytecode.Instructions, bytecode.Constants, "test.jb")
-		err = vm.Run()
-		if err != nil {
-			switch expected := tt.expected.(type) {
-			case error:
-				if err.Error() != expected.Error() {
-					t.Fatalf("wrong error returned. \nwant=%q\ngot=%q", expected, err)
-				}
-				continue // Error matched, test pass
-			default:
-				t.Fatalf("vm error: %s", err)
+		switch expected := tt.expected.(type) {
+		case error:
+			if err == nil {
+				t.Errorf("%s: expected error %q but got none", tt.input, expected)
+			} else if err.Error() != expected.Error() {
+				t.Errorf("%s: wrong error returned. \nwant=%q\ngot=%q", tt.input, expected, err)
+			}
+			continue
+		case errorContaining:
+			if err == nil || !strings.Contains(err.Error(), string(expected)) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.input, expected, err)
 			}
+			continue
 		}
-
-		if _, ok := tt.expected.(error); ok {
-			t.Fatalf("expected error %q but got none", tt.expected)
+		if err != nil {
+			t.Errorf("%s: vm error: %s", tt.input, err)
+			continue
 		}
 
 		stackElem := vm.LastPoppedStackElem()
@@ -73,7 +120,20 @@ func runVmTests(t *testing.T, tests []vmTestCase) {
 		case int:
 			err := testIntegerObject(int64(expected), stackElem)
 			if err != nil {
-				t.Errorf("testIntegerObject failed: %s", err)
+				t.Errorf("%s: testIntegerObject failed: %s", tt.input, err)
+			}
+		case string:
+			err := testStringObject(expected, stackElem)
+			if err != nil {
+				t.Errorf("%s: testStringObject failed: %s", tt.input, err)
+			}
+		case inspected:
+			if got := stackElem.Inspect(); got != string(expected) {
+				t.Errorf("%s: want %s, got %s", tt.input, expected, got)
+			}
+		case typed:
+			if stackElem.Type() != expected.typ || stackElem.Inspect() != expected.inspect {
+				t.Errorf("%s: want %s %s, got %s %s", tt.input, expected.typ, expected.inspect, stackElem.Type(), stackElem.Inspect())
 			}
 		}
 	}
@@ -100,3 +160,41 @@ func TestTypeCheckingInVM(t *testing.T) {
 
 	runVmTests(t, tests)
 }
+
+func TestTemplateLiterals(t *testing.T) {
+	tests := []vmTestCase{
+		{"`hello`", "hello"},
+		{"``", ""},
+		{"let name = \"Jab\"; `hi ${name}!`", "hi Jab!"},
+		{"`${1 + 2} = ${3}`", "3 = 3"},
+		{"`${[1, 2]} ${true} ${null}`", "[1, 2] true null"},
+		{"fn twice(x) { return x * 2 }; `${twice(21)}`", "42"},
+		{"let n = 2; `outer ${`inner ${n}`}`", "outer inner 2"},
+		{"`a\\${b} {c}`", "a${b} {c}"},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestIfBranchValues(t *testing.T) {
+	tests := []vmTestCase{
+		{`if (true) { 1 } else { 2 }`, 1},
+		{`let a = 0; if (true) { a = 5; } a`, 5},
+		{`let out = []; let a = [1]; for (x in a) { if (x < 3) { a = push(a, x + 1); } out = push(out, x); } out`, inspected("[1]")},
+		{`let n = 0; let i = 0; while (i < 3) { if (i > 0) { n = n + i; } else { let z = 0; } i = i + 1; } n`, 3},
+		{`fn f(x) { if (x) { let y = 1; } } f(true)`, inspected("null")},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBoundMethodCalls(t *testing.T) {
+	tests := []vmTestCase{
+		{`struct P { x: int } fn (p P) get() { return p.x; } let p = P{ x: 3 }; p.get()`, 3},
+		{`struct P { x: int } fn (p P) add(n) { return p.x + n; } let p = P{ x: 3 }; [1, p.add(4), 2]`, inspected("[1, 7, 2]")},
+		{`struct P { x: int } fn (p P) add(a, b) { return p.x + a + b; } let p = P{ x: 1 }; 10 + p.add(2, 3)`, 16},
+		{`struct P { x: int } fn* (p P) items() { yield p.x; yield p.x * 2; } let out = []; for (x in P{ x: 3 }.items()) { out = push(out, x); } out`, inspected("[3, 6]")},
+	}
+
+	runVmTests(t, tests)
+}